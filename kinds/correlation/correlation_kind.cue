@@ -0,0 +1,88 @@
+package kind
+
+import "strings"
+
+name:     "Correlation"
+maturity: "merged"
+
+lineage: seqs: [
+	{
+		schemas: [
+			// 0.0
+			{
+				// Unique identifier of the correlation, generated on creation unless given.
+				uid: string
+				// UID of the data source the correlation originates from.
+				sourceUID: string
+				// UID of the data source the correlation targets. Unset for external and dashboard correlations.
+				targetUID?: string
+				// Label of the link shown on the source data.
+				label?: string
+				// Description of the correlation.
+				description?: string
+				// Configuration of the link and of its target.
+				config: #Config
+
+				///////////////////////////////////////
+				// Definitions (referenced above) are declared below
+
+				#Config: {
+					// Type of the target.
+					type: #ConfigType
+					// Field the link is attached to, or a list of fields holding the same value under different names.
+					field: string | [...string]
+					// Target data query, for external correlations the URL template to link to, or for dashboard
+					// correlations the dashboard to open and the values of its variables.
+					target: {...}
+					// Source data transformations.
+					transformations?: [...#Transformation]
+					// Fields or variables bound to other variable names, applied after the transformations.
+					mappings?: [...#Mapping]
+					// Schema version the config was written with.
+					version?: int64 & >=0
+					// Where the target is opened. Explore if empty.
+					openMode?: "" | "explore" | "split" | "newTab"
+					// How long to wait for the target query, in milliseconds. Zero uses the system default.
+					targetTimeoutMs?: int64 & >=0
+					// How the results of the target query are visualized. Empty lets Explore decide.
+					targetVisualization?: "" | "logs" | "table" | "graph" | "traces"
+					// Condition the field value must match for the link to be shown.
+					condition?: #Condition
+				} @cuetsy(kind="interface")
+
+				#ConfigType: "query" | "external" | "dashboard" | "trace" @cuetsy(kind="type")
+
+				#Transformation: {
+					// Type of the transformation.
+					type: "regex" | "logfmt" | "split" | "jsonpath" | "grok"
+					// Regular expression, JSONPath or grok pattern, depending on the type.
+					expression?: string
+					// Field the transformation is applied to, the correlation field if empty.
+					field?: string
+					// Name of the variable the result of the transformation is bound to.
+					mapValue?: string
+					// Delimiter a split transformation splits the field by.
+					delimiter?: string
+					// Index of the part a split transformation selects. Negative indices count from the end.
+					index?: int64
+				} @cuetsy(kind="interface")
+
+				#Mapping: {
+					// Field or variable the value is read from.
+					source: string & strings.MinRunes(1)
+					// Name of the variable the value is bound to.
+					target: string & strings.MinRunes(1)
+				} @cuetsy(kind="interface")
+
+				#Condition: {
+					// Type of the condition.
+					type: "regex" | "equals"
+					// Field whose value is matched, the correlation field if empty.
+					field?: string
+					// Regular expression the value must match, or the value it must equal.
+					value: string
+				} @cuetsy(kind="interface")
+			},
+		]
+	},
+]
@@ -7,6 +7,19 @@
 //
 // Run 'make gen-cue' from repository root to regenerate.
 
+// Raw generated types from Correlation kind.
+export type {
+  Correlation,
+  Config,
+  ConfigType,
+  Transformation,
+  Mapping,
+  Condition
+} from './raw/correlation/x/correlation_types.gen';
+
+// Raw generated enums and default consts from correlation kind.
+export { defaultConfig } from './raw/correlation/x/correlation_types.gen';
+
 // Raw generated types from Dashboard kind.
 export type {
   AnnotationTarget,
@@ -0,0 +1,140 @@
+// Code generated - EDITING IS FUTILE. DO NOT EDIT.
+//
+// Generated by:
+//     kinds/gen.go
+// Using jennies:
+//     TSTypesJenny
+//     LatestMajorsOrXJenny
+//
+// Run 'make gen-cue' from repository root to regenerate.
+
+export interface Config {
+  /**
+   * Condition the field value must match for the link to be shown.
+   */
+  condition?: Condition;
+  /**
+   * Field the link is attached to, or a list of fields holding the same value under different names.
+   */
+  field: (string | Array<string>);
+  /**
+   * Fields or variables bound to other variable names, applied after the transformations.
+   */
+  mappings?: Array<Mapping>;
+  /**
+   * Where the target is opened. Explore if empty.
+   */
+  openMode?: ('' | 'explore' | 'split' | 'newTab');
+  /**
+   * Target data query, for external correlations the URL template to link to, or for dashboard
+   * correlations the dashboard to open and the values of its variables.
+   */
+  target: Record<string, unknown>;
+  /**
+   * How long to wait for the target query, in milliseconds. Zero uses the system default.
+   */
+  targetTimeoutMs?: number;
+  /**
+   * How the results of the target query are visualized. Empty lets Explore decide.
+   */
+  targetVisualization?: ('' | 'logs' | 'table' | 'graph' | 'traces');
+  /**
+   * Source data transformations.
+   */
+  transformations?: Array<Transformation>;
+  /**
+   * Type of the target.
+   */
+  type: ConfigType;
+  /**
+   * Schema version the config was written with.
+   */
+  version?: number;
+}
+
+export const defaultConfig: Partial<Config> = {
+  mappings: [],
+  transformations: [],
+};
+
+export type ConfigType = ('query' | 'external' | 'dashboard' | 'trace');
+
+export interface Transformation {
+  /**
+   * Delimiter a split transformation splits the field by.
+   */
+  delimiter?: string;
+  /**
+   * Regular expression, JSONPath or grok pattern, depending on the type.
+   */
+  expression?: string;
+  /**
+   * Field the transformation is applied to, the correlation field if empty.
+   */
+  field?: string;
+  /**
+   * Index of the part a split transformation selects. Negative indices count from the end.
+   */
+  index?: number;
+  /**
+   * Name of the variable the result of the transformation is bound to.
+   */
+  mapValue?: string;
+  /**
+   * Type of the transformation.
+   */
+  type: ('regex' | 'logfmt' | 'split' | 'jsonpath' | 'grok');
+}
+
+export interface Mapping {
+  /**
+   * Field or variable the value is read from.
+   */
+  source: string;
+  /**
+   * Name of the variable the value is bound to.
+   */
+  target: string;
+}
+
+export interface Condition {
+  /**
+   * Field whose value is matched, the correlation field if empty.
+   */
+  field?: string;
+  /**
+   * Type of the condition.
+   */
+  type: ('regex' | 'equals');
+  /**
+   * Regular expression the value must match, or the value it must equal.
+   */
+  value: string;
+}
+
+export interface Correlation {
+  /**
+   * Configuration of the link and of its target.
+   */
+  config: Config;
+  /**
+   * Description of the correlation.
+   */
+  description?: string;
+  /**
+   * Label of the link shown on the source data.
+   */
+  label?: string;
+  /**
+   * UID of the data source the correlation originates from.
+   */
+  sourceUID: string;
+  /**
+   * UID of the data source the correlation targets. Unset for external and dashboard correlations.
+   */
+  targetUID?: string;
+  /**
+   * Unique identifier of the correlation, generated on creation unless given.
+   */
+  uid: string;
+}
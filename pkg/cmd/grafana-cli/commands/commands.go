@@ -16,6 +16,7 @@ import (
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/correlations"
 	"github.com/grafana/grafana/pkg/services/sqlstore/migrations"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -258,6 +259,74 @@ grafana-cli user-manager conflicts ingest-file <filepath>
 	},
 }
 
+var correlationsCommands = []*cli.Command{
+	{
+		Name:   "list",
+		Usage:  "list the correlations of an organization",
+		Action: runRunnerCommand(listCorrelationsCommand),
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "org-id",
+				Usage: "The ID of the organization",
+				Value: 1,
+			},
+			&cli.StringFlag{
+				Name:  "source-uid",
+				Usage: "Only list the correlations originating from the data source with this UID",
+			},
+		},
+	},
+	{
+		Name:   "export",
+		Usage:  "export the correlations of an organization as JSON",
+		Action: runRunnerCommand(exportCorrelationsCommand),
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "org-id",
+				Usage: "The ID of the organization",
+				Value: 1,
+			},
+			&cli.StringFlag{
+				Name:  "file",
+				Usage: "Write the export to this file instead of stdout",
+			},
+		},
+	},
+	{
+		Name:   "import",
+		Usage:  "import <file>",
+		Action: runRunnerCommand(importCorrelationsCommand),
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "org-id",
+				Usage: "The ID of the organization",
+				Value: 1,
+			},
+			&cli.StringFlag{
+				Name:  "uids",
+				Usage: "Whether to keep the UIDs of the export or to generate new ones: keep or generate",
+				Value: string(correlations.ImportUIDsKeep),
+			},
+			&cli.StringSliceFlag{
+				Name:  "data-source",
+				Usage: "Map a data source of the export to a data source of the organization, as <name>=<uid>. Data sources are matched by name otherwise.",
+			},
+		},
+	},
+	{
+		Name:   "delete",
+		Usage:  "delete <correlation uid> ...",
+		Action: runRunnerCommand(deleteCorrelationsCommand),
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "org-id",
+				Usage: "The ID of the organization",
+				Value: 1,
+			},
+		},
+	},
+}
+
 var Commands = []*cli.Command{
 	{
 		Name:        "plugins",
@@ -269,4 +338,9 @@ var Commands = []*cli.Command{
 		Usage:       "Grafana admin commands",
 		Subcommands: adminCommands,
 	},
+	{
+		Name:        "correlations",
+		Usage:       "Manage correlations directly in the database",
+		Subcommands: correlationsCommands,
+	},
 }
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/runner"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/services/correlations"
+)
+
+// The correlations commands use the store directly, so that correlations can be managed while the HTTP API isn't
+// available, e.g. during maintenance windows of air-gapped instances. Provisioned correlations still can't be
+// deleted.
+
+func listCorrelationsCommand(c utils.CommandLine, runner runner.Runner) error {
+	query := correlations.GetCorrelationsQuery{OrgId: int64(c.Int("org-id"))}
+	if sourceUID := c.String("source-uid"); sourceUID != "" {
+		query.SourceUIDs = []string{sourceUID}
+	}
+	return listCorrelations(context.Background(), os.Stdout, runner.CorrelationsService, query)
+}
+
+func listCorrelations(ctx context.Context, out io.Writer, svc correlations.Service, query correlations.GetCorrelationsQuery) error {
+	list, err := svc.GetCorrelations(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to list correlations: %w", err)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "UID\tSOURCE\tTARGET\tTYPE\tLABEL")
+	for _, c := range list {
+		target := "-"
+		if c.TargetUID != nil {
+			target = *c.TargetUID
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.UID, c.SourceUID, target, c.Config.Type, c.Label)
+	}
+	return w.Flush()
+}
+
+func exportCorrelationsCommand(c utils.CommandLine, runner runner.Runner) error {
+	out := io.Writer(os.Stdout)
+	if path := c.String("file"); path != "" {
+		// nolint:gosec
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				logger.Warnf("failed to close export file: %s\n", err)
+			}
+		}()
+		out = f
+	}
+	return exportCorrelations(context.Background(), out, runner.CorrelationsService, int64(c.Int("org-id")))
+}
+
+// exportCorrelations writes the correlations of an org in the format of the export API, which the import command
+// reads.
+func exportCorrelations(ctx context.Context, out io.Writer, svc correlations.Service, orgID int64) error {
+	bundle, err := svc.ExportCorrelations(ctx, correlations.ExportCorrelationsQuery{OrgId: orgID})
+	if err != nil {
+		return fmt.Errorf("failed to export correlations: %w", err)
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bundle)
+}
+
+func importCorrelationsCommand(c utils.CommandLine, runner runner.Runner) error {
+	path := c.Args().First()
+	if path == "" {
+		return fmt.Errorf("missing the path of the file to import")
+	}
+	// nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			logger.Warnf("failed to close import file: %s\n", err)
+		}
+	}()
+
+	cmd := correlations.ImportCorrelationsCommand{
+		OrgId:       int64(c.Int("org-id")),
+		UIDs:        correlations.ImportUIDMode(c.String("uids")),
+		DataSources: make(map[string]string),
+	}
+	for _, mapping := range c.StringSlice("data-source") {
+		name, uid, ok := strings.Cut(mapping, "=")
+		if !ok {
+			return fmt.Errorf("data source mapping %q must have the form <name>=<uid>", mapping)
+		}
+		cmd.DataSources[name] = uid
+	}
+
+	result, err := importCorrelations(context.Background(), f, runner.CorrelationsService, cmd)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Imported %d correlations %s\n", len(result.Correlations), color.GreenString("✔"))
+	return nil
+}
+
+// importCorrelations creates the correlations of an export read from in. Either all of them are created, or none.
+func importCorrelations(ctx context.Context, in io.Reader, svc correlations.Service, cmd correlations.ImportCorrelationsCommand) (correlations.ImportCorrelationsResult, error) {
+	if err := json.NewDecoder(in).Decode(&cmd.Bundle); err != nil {
+		return correlations.ImportCorrelationsResult{}, fmt.Errorf("failed to read import file: %w", err)
+	}
+	if err := cmd.Validate(); err != nil {
+		return correlations.ImportCorrelationsResult{}, err
+	}
+	result, err := svc.ImportCorrelations(ctx, cmd)
+	if err != nil {
+		return correlations.ImportCorrelationsResult{}, fmt.Errorf("failed to import correlations: %w", err)
+	}
+	return result, nil
+}
+
+func deleteCorrelationsCommand(c utils.CommandLine, runner runner.Runner) error {
+	cmd := correlations.DeleteCorrelationsCommand{
+		OrgId: int64(c.Int("org-id")),
+		UIDs:  c.Args().Slice(),
+	}
+	if err := cmd.Validate(); err != nil {
+		return err
+	}
+
+	result, err := runner.CorrelationsService.DeleteCorrelations(context.Background(), cmd)
+	if err != nil {
+		return fmt.Errorf("failed to delete correlations: %w", err)
+	}
+	logger.Infof("Deleted %d correlations %s\n", len(result.Deleted), color.GreenString("✔"))
+	if len(result.NotFound) > 0 {
+		logger.Warnf("Not found: %s\n", strings.Join(result.NotFound, ", "))
+	}
+	return nil
+}
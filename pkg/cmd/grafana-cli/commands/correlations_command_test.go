@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+)
+
+type fakeCorrelationsService struct {
+	correlations.Service
+	list     []correlations.Correlation
+	query    correlations.GetCorrelationsQuery
+	bundle   correlations.CorrelationsExport
+	imported correlations.ImportCorrelationsCommand
+}
+
+func (s *fakeCorrelationsService) GetCorrelations(_ context.Context, query correlations.GetCorrelationsQuery) ([]correlations.Correlation, error) {
+	s.query = query
+	return s.list, nil
+}
+
+func (s *fakeCorrelationsService) ExportCorrelations(_ context.Context, _ correlations.ExportCorrelationsQuery) (correlations.CorrelationsExport, error) {
+	return s.bundle, nil
+}
+
+func (s *fakeCorrelationsService) ImportCorrelations(_ context.Context, cmd correlations.ImportCorrelationsCommand) (correlations.ImportCorrelationsResult, error) {
+	s.imported = cmd
+	return correlations.ImportCorrelationsResult{Correlations: make([]correlations.Correlation, len(cmd.Bundle.Correlations))}, nil
+}
+
+func TestCorrelationsCommands(t *testing.T) {
+	target := "tempo-uid"
+	svc := &fakeCorrelationsService{
+		list: []correlations.Correlation{
+			{UID: "abc", SourceUID: "loki-uid", TargetUID: &target, Label: "Traces", Config: correlations.CorrelationConfig{Type: correlations.ConfigTypeQuery}},
+			{UID: "def", SourceUID: "loki-uid", Label: "Runbook", Config: correlations.CorrelationConfig{Type: correlations.ConfigTypeExternal}},
+		},
+		bundle: correlations.CorrelationsExport{
+			Version: correlations.CorrelationsExportVersion,
+			Correlations: []correlations.ExportedCorrelation{{
+				UID:    "abc",
+				Source: correlations.ExportedDataSource{Name: "Loki", Type: "loki"},
+				Target: &correlations.ExportedDataSource{Name: "Tempo", Type: "tempo"},
+				Label:  "Traces",
+				Config: correlations.CorrelationConfig{Type: correlations.ConfigTypeQuery, Field: correlations.CorrelationFields{"traceID"}, Target: map[string]interface{}{}},
+			}},
+		},
+	}
+
+	t.Run("lists correlations", func(t *testing.T) {
+		var out bytes.Buffer
+		err := listCorrelations(context.Background(), &out, svc, correlations.GetCorrelationsQuery{OrgId: 2, SourceUIDs: []string{"loki-uid"}})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), svc.query.OrgId)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 3)
+		require.Equal(t, []string{"abc", "loki-uid", "tempo-uid", "query", "Traces"}, strings.Fields(lines[1]))
+		require.Equal(t, []string{"def", "loki-uid", "-", "external", "Runbook"}, strings.Fields(lines[2]))
+	})
+
+	t.Run("exports correlations in a format the import reads", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, exportCorrelations(context.Background(), &out, svc, 1))
+
+		result, err := importCorrelations(context.Background(), &out, svc, correlations.ImportCorrelationsCommand{OrgId: 1})
+		require.NoError(t, err)
+		require.Len(t, result.Correlations, 1)
+		require.Equal(t, int64(1), svc.imported.OrgId)
+		require.Equal(t, "Traces", svc.imported.Bundle.Correlations[0].Label)
+		require.Equal(t, "Tempo", svc.imported.Bundle.Correlations[0].Target.Name)
+	})
+
+	t.Run("rejects invalid imports", func(t *testing.T) {
+		_, err := importCorrelations(context.Background(), strings.NewReader(`{"version": 1, "correlations": []}`), svc, correlations.ImportCorrelationsCommand{OrgId: 1})
+		require.ErrorIs(t, err, correlations.ErrNoCorrelationsToImport)
+
+		_, err = importCorrelations(context.Background(), strings.NewReader(`not json`), svc, correlations.ImportCorrelationsCommand{OrgId: 1})
+		require.Error(t, err)
+	})
+}
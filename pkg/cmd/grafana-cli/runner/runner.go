@@ -2,6 +2,7 @@ package runner
 
 import (
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/correlations"
 	"github.com/grafana/grafana/pkg/services/encryption"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/secrets"
@@ -11,29 +12,31 @@ import (
 )
 
 type Runner struct {
-	Cfg               *setting.Cfg
-	SQLStore          db.DB
-	SettingsProvider  setting.Provider
-	Features          featuremgmt.FeatureToggles
-	EncryptionService encryption.Internal
-	SecretsService    *manager.SecretsService
-	SecretsMigrator   secrets.Migrator
-	UserService       user.Service
+	Cfg                 *setting.Cfg
+	SQLStore            db.DB
+	SettingsProvider    setting.Provider
+	Features            featuremgmt.FeatureToggles
+	EncryptionService   encryption.Internal
+	SecretsService      *manager.SecretsService
+	SecretsMigrator     secrets.Migrator
+	UserService         user.Service
+	CorrelationsService correlations.Service
 }
 
 func New(cfg *setting.Cfg, sqlStore db.DB, settingsProvider setting.Provider,
 	encryptionService encryption.Internal, features featuremgmt.FeatureToggles,
 	secretsService *manager.SecretsService, secretsMigrator secrets.Migrator,
-	userService user.Service,
+	userService user.Service, correlationsService correlations.Service,
 ) Runner {
 	return Runner{
-		Cfg:               cfg,
-		SQLStore:          sqlStore,
-		SettingsProvider:  settingsProvider,
-		EncryptionService: encryptionService,
-		SecretsService:    secretsService,
-		SecretsMigrator:   secretsMigrator,
-		Features:          features,
-		UserService:       userService,
+		Cfg:                 cfg,
+		SQLStore:            sqlStore,
+		SettingsProvider:    settingsProvider,
+		EncryptionService:   encryptionService,
+		SecretsService:      secretsService,
+		SecretsMigrator:     secretsMigrator,
+		Features:            features,
+		UserService:         userService,
+		CorrelationsService: correlationsService,
 	}
 }
@@ -292,3 +292,508 @@ type Thing struct {
 		})
 	}
 }
+
+func TestIdentRenamer(t *testing.T) {
+	tt := map[string]struct {
+		in, out string
+	}{
+		"basic": {
+			in: `package foo
+
+type Foo struct {
+	Id int64
+	Kind Type
+}
+
+type Type string`,
+			out: `package foo
+
+type Foo struct {
+	Id   int64
+	Kind ItemType
+}
+
+type ItemType string
+`,
+		},
+		"nested": {
+			in: `package foo
+
+type Foo struct {
+	Ptr *Type
+	Slice []Type
+	PSlice *[]*Type
+	Map map[Type][]Type
+}
+
+type Type string`,
+			out: `package foo
+
+type Foo struct {
+	Ptr    *ItemType
+	Slice  []ItemType
+	PSlice *[]*ItemType
+	Map    map[ItemType][]ItemType
+}
+
+type ItemType string
+`,
+		},
+		"ignore-fieldname": {
+			in: `package foo
+
+type Foo struct {
+	Type Type
+}
+
+type Type string`,
+			out: `package foo
+
+type Foo struct {
+	Type ItemType
+}
+
+type ItemType string
+`,
+		},
+		"substring": {
+			in: `package foo
+
+// TypeMeta embeds a Type, but is not a Type.
+type TypeMeta struct {
+	Kind Type
+	Meta TypeMeta
+	Sub  SubType
+}
+
+type SubType string
+
+type Type string`,
+			out: `package foo
+
+// TypeMeta embeds a ItemType, but is not a ItemType.
+type TypeMeta struct {
+	Kind ItemType
+	Meta TypeMeta
+	Sub  SubType
+}
+
+type SubType string
+
+type ItemType string
+`,
+		},
+		"const": {
+			in: `package foo
+
+const one Type = "boop"
+
+const (
+	two   Type = "boop"
+	three Type = "boop"
+)
+
+// Type is a kind of thing.
+type Type string
+`,
+			out: `package foo
+
+const one ItemType = "boop"
+
+const (
+	two   ItemType = "boop"
+	three ItemType = "boop"
+)
+
+// ItemType is a kind of thing.
+type ItemType string
+`,
+		},
+	}
+
+	for name, it := range tt {
+		item := it
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+			fset := token.NewFileSet()
+			inf, err := decorator.ParseFile(fset, "input.go", item.in, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dstutil.Apply(inf, IdentRenamer("Type", "ItemType"), nil)
+			buf := new(bytes.Buffer)
+			err = decorator.Fprint(buf, inf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			is.Equal(item.out, buf.String())
+		})
+	}
+}
+
+func TestAffixModifier(t *testing.T) {
+	in := `package foo
+
+// Foo is a thing.
+type Foo struct {
+	Id int64
+	Ref FooThing
+	Other FooOther
+}
+
+// FooThing is also a thing. Even if FooThing were not a Foo thing.
+type FooThing struct {
+	Id int64
+}
+
+type FooOther string
+`
+
+	apply := func(t *testing.T, fn dstutil.ApplyFunc) string {
+		t.Helper()
+		fset := token.NewFileSet()
+		inf, err := decorator.ParseFile(fset, "input.go", in, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dstutil.Apply(inf, fn, nil)
+		buf := new(bytes.Buffer)
+		if err := decorator.Fprint(buf, inf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	t.Run("PrefixDropper is equivalent to a prefix config", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(apply(t, PrefixDropper("Foo")), apply(t, AffixModifier(AffixConfig{Position: AffixPrefix, Value: "Foo"})))
+	})
+
+	t.Run("PrefixReplacer is equivalent to a prefix config with replace", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(apply(t, PrefixReplacer("Foo", "Bar")), apply(t, AffixModifier(AffixConfig{Position: AffixPrefix, Value: "Foo", Replace: "Bar"})))
+	})
+
+	t.Run("idents only", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(`package foo
+
+// Foo is a thing.
+type Foo struct {
+	Id    int64
+	Ref   Thing
+	Other Other
+}
+
+// FooThing is also a thing. Even if FooThing were not a Foo thing.
+type Thing struct {
+	Id int64
+}
+
+type Other string
+`, apply(t, AffixModifier(AffixConfig{Position: AffixPrefix, Value: "Foo", IdentsOnly: true})))
+	})
+
+	t.Run("except", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(`package foo
+
+// Foo is a thing.
+type Foo struct {
+	Id    int64
+	Ref   Thing
+	Other FooOther
+}
+
+// Thing is also a thing. Even if Thing were not a Foo thing.
+type Thing struct {
+	Id int64
+}
+
+type FooOther string
+`, apply(t, AffixModifier(AffixConfig{Position: AffixPrefix, Value: "Foo", Except: []string{"FooOther"}})))
+	})
+}
+
+func TestDeadTypeEliminator(t *testing.T) {
+	tt := map[string]struct {
+		in, out string
+	}{
+		"chain": {
+			in: `package foo
+
+// Root is the kind's root type.
+type Root struct {
+	Spec Spec
+}
+
+type Spec struct {
+	Id int64
+}
+
+// Orphan is referenced by nothing.
+type Orphan struct {
+	Inner *OrphanInner
+}
+
+type OrphanInner struct {
+	Leaf []OrphanLeaf
+}
+
+type OrphanLeaf string
+`,
+			out: `package foo
+
+// Root is the kind's root type.
+type Root struct {
+	Spec Spec
+}
+
+type Spec struct {
+	Id int64
+}
+`,
+		},
+		"self reference": {
+			in: `package foo
+
+type Root struct {
+	Id int64
+}
+
+type Node struct {
+	Children []Node
+}
+`,
+			out: `package foo
+
+type Root struct {
+	Id int64
+}
+`,
+		},
+		"field names are not references": {
+			in: `package foo
+
+type Root struct {
+	Spec string
+}
+
+type Spec struct {
+	Id int64
+}
+`,
+			out: `package foo
+
+type Root struct {
+	Spec string
+}
+`,
+		},
+		"grouped": {
+			in: `package foo
+
+type (
+	Root struct {
+		Status Status
+	}
+	Status string
+	Unused string
+)
+
+const (
+	StatusOk Status = "ok"
+)
+`,
+			out: `package foo
+
+type (
+	Root struct {
+		Status Status
+	}
+	Status string
+)
+
+const (
+	StatusOk Status = "ok"
+)
+`,
+		},
+		"methods and directives": {
+			in: `package foo
+
+type Root struct {
+	Id int64
+}
+
+type Stringer struct{}
+
+func (Stringer) String() string { return "" }
+
+// +k8s:deepcopy-gen=true
+type Marked struct{}
+`,
+			out: `package foo
+
+type Root struct {
+	Id int64
+}
+
+type Stringer struct{}
+
+func (Stringer) String() string { return "" }
+
+// +k8s:deepcopy-gen=true
+type Marked struct{}
+`,
+		},
+	}
+
+	for name, it := range tt {
+		item := it
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+			fset := token.NewFileSet()
+			inf, err := decorator.ParseFile(fset, "input.go", item.in, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dstutil.Apply(inf, DeadTypeEliminator("Root"), nil)
+			buf := new(bytes.Buffer)
+			err = decorator.Fprint(buf, inf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			is.Equal(item.out, buf.String())
+		})
+	}
+}
+
+func TestDoublePointerCollapser(t *testing.T) {
+	tt := map[string]struct {
+		in, out string
+	}{
+		"double pointer": {
+			in: `package foo
+
+type Foo struct {
+	Ref **Thing
+	Deep ***Thing
+}
+
+type Thing struct{}
+`,
+			out: `package foo
+
+type Foo struct {
+	Ref  *Thing
+	Deep *Thing
+}
+
+type Thing struct{}
+`,
+		},
+		"pointer to slice and slice of pointers": {
+			in: `package foo
+
+type Foo struct {
+	Refs  *[]Thing
+	Items []*Thing
+	Nested []**Thing
+	Ref   *Thing
+}
+
+type Thing struct{}
+`,
+			out: `package foo
+
+type Foo struct {
+	Refs   *[]Thing
+	Items  []*Thing
+	Nested []**Thing
+	Ref    *Thing
+}
+
+type Thing struct{}
+`,
+		},
+		"nested struct": {
+			in: `package foo
+
+type Foo struct {
+	Inner struct {
+		Ref **string
+	}
+}
+`,
+			out: `package foo
+
+type Foo struct {
+	Inner struct {
+		Ref *string
+	}
+}
+`,
+		},
+		"fields used by code": {
+			in: `package foo
+
+type Foo struct {
+	Ref   **string
+	Other **string
+}
+
+func (f Foo) Get() string { return **f.Ref }
+
+var defaultFoo = Foo{Other: nil}
+`,
+			out: `package foo
+
+type Foo struct {
+	Ref   **string
+	Other **string
+}
+
+func (f Foo) Get() string { return **f.Ref }
+
+var defaultFoo = Foo{Other: nil}
+`,
+		},
+		"function parameters": {
+			in: `package foo
+
+type Setter func(v **string)
+
+func set(v **string) {}
+`,
+			out: `package foo
+
+type Setter func(v **string)
+
+func set(v **string) {}
+`,
+		},
+	}
+
+	for name, it := range tt {
+		item := it
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+			fset := token.NewFileSet()
+			inf, err := decorator.ParseFile(fset, "input.go", item.in, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dstutil.Apply(inf, DoublePointerCollapser(), nil)
+			buf := new(bytes.Buffer)
+			err = decorator.Fprint(buf, inf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			is.Equal(item.out, buf.String())
+		})
+	}
+}
@@ -42,8 +42,9 @@ func (gen *genBaseRegistry) Generate(decls ...*DeclForGen) (*codejen.File, error
 	}
 
 	b, err := postprocessGoFile(genGoFile{
-		path: gen.path,
-		in:   buf.Bytes(),
+		path:   gen.path,
+		in:     buf.Bytes(),
+		verify: true,
 	})
 	if err != nil {
 		return nil, err
@@ -3,7 +3,6 @@ package codegen
 import (
 	"bytes"
 	"fmt"
-	"go/format"
 	"go/parser"
 	"go/token"
 	"os"
@@ -21,9 +20,57 @@ type genGoFile struct {
 	path   string
 	walker dstutil.ApplyFunc
 	in     []byte
+	// verify makes postprocessGoFile run a second pass over its own output, and fail
+	// if that pass changes anything. See postprocessGoFile.
+	verify bool
 }
 
+// postprocessGoFile applies the walker, if any, to a generated Go file, formats
+// it and fixes up its imports.
+//
+// If verify is set, the result is postprocessed once more and must come out
+// unchanged. A failure means that the output is not stable, typically because
+// format.Node and imports.Process disagree about some construct emitted by a
+// template, and regenerating would produce spurious diffs. To diagnose a
+// failure, look at the first differing line reported in the error, then find
+// the template or walker producing that construct; comments attached to
+// removed or moved nodes and import grouping are the usual suspects.
 func postprocessGoFile(cfg genGoFile) ([]byte, error) {
+	byt, err := processGoFile(cfg)
+	if err != nil || !cfg.verify {
+		return byt, err
+	}
+
+	again, err := processGoFile(genGoFile{path: cfg.path, walker: cfg.walker, in: byt})
+	if err != nil {
+		return nil, fmt.Errorf("error postprocessing %s a second time: %w", cfg.path, err)
+	}
+	if !bytes.Equal(byt, again) {
+		line, first, second := firstDifference(byt, again)
+		return nil, fmt.Errorf("postprocessing %s is not idempotent, line %d changed from %q to %q on the second pass", cfg.path, line, first, second)
+	}
+	return byt, nil
+}
+
+// firstDifference returns the number and contents of the first line that differs between a and b.
+func firstDifference(a, b []byte) (int, string, string) {
+	al, bl := strings.Split(string(a), "\n"), strings.Split(string(b), "\n")
+	for i := 0; i < len(al) || i < len(bl); i++ {
+		var x, y string
+		if i < len(al) {
+			x = al[i]
+		}
+		if i < len(bl) {
+			y = bl[i]
+		}
+		if x != y {
+			return i + 1, x, y
+		}
+	}
+	return 0, "", ""
+}
+
+func processGoFile(cfg genGoFile) ([]byte, error) {
 	fname := filepath.Base(cfg.path)
 	buf := new(bytes.Buffer)
 	fset := token.NewFileSet()
@@ -35,7 +82,7 @@ func postprocessGoFile(cfg genGoFile) ([]byte, error) {
 	if cfg.walker != nil {
 		dstutil.Apply(gf, cfg.walker, nil)
 
-		err = format.Node(buf, fset, gf)
+		err = decorator.Fprint(buf, gf)
 		if err != nil {
 			return nil, fmt.Errorf("error formatting Go AST: %w", err)
 		}
@@ -69,22 +116,69 @@ func postprocessGoFile(cfg genGoFile) ([]byte, error) {
 	return byt, nil
 }
 
+// AffixPosition is the position of an affix within an identifier.
+type AffixPosition int
+
+const (
+	// AffixPrefix is an affix at the start of an identifier.
+	AffixPrefix AffixPosition = iota
+)
+
+// AffixConfig describes how an affix is modified in a generated Go file by
+// the dstutil.ApplyFunc returned from AffixModifier.
+type AffixConfig struct {
+	// Position is where the affix appears in identifiers.
+	Position AffixPosition
+	// Value is the affix to remove.
+	Value string
+	// Replace, if not empty, is substituted for identifiers exactly matching Value.
+	Replace string
+	// IdentsOnly leaves comments untouched, only modifying identifiers.
+	IdentsOnly bool
+	// Except lists identifiers that are left untouched. Comments are not affected.
+	Except []string
+}
+
 type prefixmod struct {
-	prefix  string
-	replace string
-	rxp     *regexp.Regexp
-	rxpsuff *regexp.Regexp
+	prefix     string
+	replace    string
+	identsOnly bool
+	except     map[string]bool
+	rxp        *regexp.Regexp
+	rxpsuff    *regexp.Regexp
+}
+
+// AffixModifier returns a dstutil.ApplyFunc that removes the affix described
+// by the provided config from type names, var names, and comments in a
+// generated Go file.
+func AffixModifier(cfg AffixConfig) dstutil.ApplyFunc {
+	switch cfg.Position {
+	case AffixPrefix:
+		except := make(map[string]bool, len(cfg.Except))
+		for _, e := range cfg.Except {
+			except[e] = true
+		}
+		return (&prefixmod{
+			prefix:     cfg.Value,
+			replace:    cfg.Replace,
+			identsOnly: cfg.IdentsOnly,
+			except:     except,
+			rxpsuff:    regexp.MustCompile(fmt.Sprintf(`%s([a-zA-Z_]+)`, cfg.Value)),
+			rxp:        regexp.MustCompile(fmt.Sprintf(`%s([\s.,;-])`, cfg.Value)),
+		}).applyfunc
+	default:
+		panic(fmt.Sprintf("unsupported affix position %d", cfg.Position))
+	}
 }
 
 // PrefixDropper returns a dstutil.ApplyFunc that removes the provided prefix
 // string when it appears as a leading sequence in type names, var names, and
 // comments in a generated Go file.
 func PrefixDropper(prefix string) dstutil.ApplyFunc {
-	return (&prefixmod{
-		prefix:  prefix,
-		rxpsuff: regexp.MustCompile(fmt.Sprintf(`%s([a-zA-Z_]+)`, prefix)),
-		rxp:     regexp.MustCompile(fmt.Sprintf(`%s([\s.,;-])`, prefix)),
-	}).applyfunc
+	return AffixModifier(AffixConfig{
+		Position: AffixPrefix,
+		Value:    prefix,
+	})
 }
 
 // PrefixReplacer returns a dstutil.ApplyFunc that removes the provided prefix
@@ -94,12 +188,11 @@ func PrefixDropper(prefix string) dstutil.ApplyFunc {
 // When an exact match for prefix is found, the provided replace string
 // is substituted.
 func PrefixReplacer(prefix, replace string) dstutil.ApplyFunc {
-	return (&prefixmod{
-		prefix:  prefix,
-		replace: replace,
-		rxpsuff: regexp.MustCompile(fmt.Sprintf(`%s([a-zA-Z_]+)`, prefix)),
-		rxp:     regexp.MustCompile(fmt.Sprintf(`%s([\s.,;-])`, prefix)),
-	}).applyfunc
+	return AffixModifier(AffixConfig{
+		Position: AffixPrefix,
+		Value:    prefix,
+		Replace:  replace,
+	})
 }
 
 func depoint(e dst.Expr) dst.Expr {
@@ -126,6 +219,9 @@ func (d prefixmod) applyfunc(c *dstutil.Cursor) bool {
 		// field value specifications that reference those types.
 		d.handleExpr(x.Type)
 	case *dst.File:
+		if d.identsOnly {
+			break
+		}
 		for _, decl := range x.Decls {
 			comments := decl.Decorations().Start.All()
 			decl.Decorations().Start.Clear()
@@ -166,9 +262,281 @@ func (d prefixmod) handleExpr(e dst.Expr) {
 }
 
 func (d prefixmod) do(n *dst.Ident) {
+	if d.except[n.Name] {
+		return
+	}
 	if n.Name != d.prefix {
 		n.Name = strings.TrimPrefix(n.Name, d.prefix)
 	} else if d.replace != "" {
 		n.Name = d.replace
 	}
 }
+
+type identrenamer struct {
+	from string
+	to   string
+	rxp  *regexp.Regexp
+}
+
+// IdentRenamer returns a dstutil.ApplyFunc that renames all occurrences of the
+// identifier from to to in type names, var and const names, field type
+// references, and comments in a generated Go file.
+//
+// Only exact matches are renamed; identifiers that merely contain from as a
+// substring are left alone, as are struct field names.
+func IdentRenamer(from, to string) dstutil.ApplyFunc {
+	return (&identrenamer{
+		from: from,
+		to:   to,
+		rxp:  regexp.MustCompile(fmt.Sprintf(`\b%s\b`, regexp.QuoteMeta(from))),
+	}).applyfunc
+}
+
+func (r identrenamer) applyfunc(c *dstutil.Cursor) bool {
+	n := c.Node()
+
+	switch x := n.(type) {
+	case *dst.ValueSpec:
+		r.handleExpr(x.Type)
+		for _, id := range x.Names {
+			r.do(id)
+		}
+	case *dst.TypeSpec:
+		r.do(x.Name)
+		r.handleExpr(x.Type)
+	case *dst.Field:
+		// Don't rename struct fields, only the types they reference.
+		r.handleExpr(x.Type)
+	case *dst.File:
+		for _, decl := range x.Decls {
+			comments := decl.Decorations().Start.All()
+			decl.Decorations().Start.Clear()
+			for _, c := range comments {
+				decl.Decorations().Start.Append(r.rxp.ReplaceAllString(c, r.to))
+			}
+		}
+	}
+	return true
+}
+
+func (r identrenamer) handleExpr(e dst.Expr) {
+	switch x := e.(type) {
+	case *dst.Ident:
+		r.do(x)
+	case *dst.StarExpr:
+		r.handleExpr(x.X)
+	case *dst.ArrayType:
+		r.handleExpr(x.Elt)
+	case *dst.MapType:
+		r.handleExpr(x.Key)
+		r.handleExpr(x.Value)
+	}
+}
+
+func (r identrenamer) do(n *dst.Ident) {
+	if n.Name == r.from {
+		n.Name = r.to
+	}
+}
+
+type deadtypes struct {
+	keep map[string]bool
+}
+
+// DeadTypeEliminator returns a dstutil.ApplyFunc that removes type
+// declarations which are not referenced by any other declaration in a
+// generated Go file. Removal is repeated until no more unused types remain,
+// so a type that is only referenced by an unused type is removed as well.
+//
+// Types named in keep are never removed, and neither are types whose doc
+// comment carries a directive (a line starting with "//go:" or "// +"), as
+// those may be significant outside the file.
+func DeadTypeEliminator(keep ...string) dstutil.ApplyFunc {
+	d := &deadtypes{keep: make(map[string]bool, len(keep))}
+	for _, k := range keep {
+		d.keep[k] = true
+	}
+	return d.applyfunc
+}
+
+func (d deadtypes) applyfunc(c *dstutil.Cursor) bool {
+	f, is := c.Node().(*dst.File)
+	if !is {
+		return true
+	}
+
+	for d.eliminate(f) {
+	}
+	// Nothing below the file level needs visiting.
+	return false
+}
+
+// eliminate removes one round of unreferenced type specs from the file,
+// reporting whether anything was removed.
+func (d deadtypes) eliminate(f *dst.File) bool {
+	refs := make(map[string]int)
+	for _, decl := range f.Decls {
+		gd, is := decl.(*dst.GenDecl)
+		if !is {
+			countRefs(decl, "", refs)
+			continue
+		}
+		for _, spec := range gd.Specs {
+			self := ""
+			if ts, is := spec.(*dst.TypeSpec); is {
+				self = ts.Name.Name
+			}
+			countRefs(spec, self, refs)
+		}
+	}
+
+	var removed bool
+	decls := f.Decls[:0]
+	for _, decl := range f.Decls {
+		gd, is := decl.(*dst.GenDecl)
+		if !is || gd.Tok != token.TYPE {
+			decls = append(decls, decl)
+			continue
+		}
+
+		specs := gd.Specs[:0]
+		for _, spec := range gd.Specs {
+			ts := spec.(*dst.TypeSpec)
+			if refs[ts.Name.Name] == 0 && !d.keep[ts.Name.Name] && !hasDirective(spec.Decorations(), gd.Decorations()) {
+				removed = true
+				continue
+			}
+			specs = append(specs, spec)
+		}
+		gd.Specs = specs
+		if len(specs) != 0 {
+			decls = append(decls, decl)
+		}
+	}
+	f.Decls = decls
+	return removed
+}
+
+// countRefs counts the identifiers referenced within n, ignoring references
+// to self as well as the names of declared types and struct fields.
+func countRefs(n dst.Node, self string, refs map[string]int) {
+	dst.Inspect(n, func(n dst.Node) bool {
+		switch x := n.(type) {
+		case *dst.TypeSpec:
+			countRefs(x.Type, self, refs)
+			return false
+		case *dst.Field:
+			if x.Type != nil {
+				countRefs(x.Type, self, refs)
+			}
+			return false
+		case *dst.Ident:
+			if x.Name != self {
+				refs[x.Name]++
+			}
+		}
+		return true
+	})
+}
+
+func hasDirective(decs ...*dst.NodeDecs) bool {
+	for _, dec := range decs {
+		for _, c := range dec.Start.All() {
+			if strings.HasPrefix(c, "//go:") || strings.HasPrefix(c, "// +") || strings.HasPrefix(c, "//+") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DoublePointerCollapser returns a dstutil.ApplyFunc that collapses struct
+// fields of pointer-to-pointer type, e.g. **Foo, to a single pointer, as
+// generated from chained optionality in some OpenAPI schemas.
+//
+// Only the types of struct fields are changed. A field is left alone if its
+// name is selected anywhere in the file outside of type declarations, as
+// such code may depend on the additional indirection.
+func DoublePointerCollapser() dstutil.ApplyFunc {
+	return func(c *dstutil.Cursor) bool {
+		f, is := c.Node().(*dst.File)
+		if !is {
+			return true
+		}
+
+		used := selectedNames(f)
+		for _, decl := range f.Decls {
+			gd, is := decl.(*dst.GenDecl)
+			if !is || gd.Tok != token.TYPE {
+				continue
+			}
+			dst.Inspect(gd, func(n dst.Node) bool {
+				st, is := n.(*dst.StructType)
+				if !is {
+					return true
+				}
+				for _, field := range st.Fields.List {
+					if collapsible(field, used) {
+						field.Type = collapsePointers(field.Type)
+					}
+				}
+				return true
+			})
+		}
+		// Nothing below the file level needs visiting.
+		return false
+	}
+}
+
+// collapsible reports whether field is a named field of pointer-to-pointer
+// type that no code in the file refers to.
+func collapsible(field *dst.Field, used map[string]bool) bool {
+	star, is := field.Type.(*dst.StarExpr)
+	if !is {
+		return false
+	}
+	if _, is := star.X.(*dst.StarExpr); !is || len(field.Names) == 0 {
+		return false
+	}
+	for _, name := range field.Names {
+		if used[name.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// collapsePointers strips all but the outermost of a chain of pointers.
+func collapsePointers(e dst.Expr) dst.Expr {
+	star := e.(*dst.StarExpr)
+	for {
+		inner, is := star.X.(*dst.StarExpr)
+		if !is {
+			return star
+		}
+		star.X = inner.X
+	}
+}
+
+// selectedNames returns the names selected by selector expressions and used
+// as keys of composite literals outside of type declarations in the file.
+func selectedNames(f *dst.File) map[string]bool {
+	used := make(map[string]bool)
+	for _, decl := range f.Decls {
+		if gd, is := decl.(*dst.GenDecl); is && gd.Tok == token.TYPE {
+			continue
+		}
+		dst.Inspect(decl, func(n dst.Node) bool {
+			switch x := n.(type) {
+			case *dst.SelectorExpr:
+				used[x.Sel.Name] = true
+			case *dst.KeyValueExpr:
+				if id, is := x.Key.(*dst.Ident); is {
+					used[id.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return used
+}
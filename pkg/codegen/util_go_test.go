@@ -0,0 +1,63 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/dstutil"
+	"github.com/matryer/is"
+)
+
+func TestPostprocessGoFileVerify(t *testing.T) {
+	in := `package foo
+
+import "fmt"
+
+type   Root struct {
+	Id int64
+	Name    string
+}
+
+func (r Root) String() string { return fmt.Sprint(r.Id) }
+`
+
+	t.Run("stable output passes", func(t *testing.T) {
+		is := is.New(t)
+		out, err := postprocessGoFile(genGoFile{
+			path:   "foo/root_gen.go",
+			walker: DeadTypeEliminator("Root"),
+			in:     []byte(in),
+			verify: true,
+		})
+		is.NoErr(err)
+		is.True(strings.Contains(string(out), "type Root struct"))
+	})
+
+	t.Run("unstable output fails", func(t *testing.T) {
+		is := is.New(t)
+		// Renames types on every pass, so a second pass never agrees with the first.
+		suffixer := func(c *dstutil.Cursor) bool {
+			if spec, ok := c.Node().(*dst.TypeSpec); ok {
+				spec.Name.Name += "X"
+			}
+			return true
+		}
+
+		_, err := postprocessGoFile(genGoFile{
+			path:   "foo/root_gen.go",
+			walker: suffixer,
+			in:     []byte(in),
+		})
+		is.NoErr(err)
+
+		_, err = postprocessGoFile(genGoFile{
+			path:   "foo/root_gen.go",
+			walker: suffixer,
+			in:     []byte(in),
+			verify: true,
+		})
+		is.True(err != nil)
+		is.True(strings.Contains(err.Error(), "is not idempotent, line 5"))
+	})
+}
@@ -71,6 +71,30 @@ type DataSourceCreated struct {
 	OrgID     int64     `json:"org_id"`
 }
 
+type CorrelationCreated struct {
+	Timestamp time.Time `json:"timestamp"`
+	UID       string    `json:"uid"`
+	SourceUID string    `json:"source_uid"`
+	TargetUID string    `json:"target_uid"`
+	OrgID     int64     `json:"org_id"`
+}
+
+type CorrelationUpdated struct {
+	Timestamp time.Time `json:"timestamp"`
+	UID       string    `json:"uid"`
+	SourceUID string    `json:"source_uid"`
+	TargetUID string    `json:"target_uid"`
+	OrgID     int64     `json:"org_id"`
+}
+
+type CorrelationDeleted struct {
+	Timestamp time.Time `json:"timestamp"`
+	UID       string    `json:"uid"`
+	SourceUID string    `json:"source_uid"`
+	TargetUID string    `json:"target_uid"`
+	OrgID     int64     `json:"org_id"`
+}
+
 type FolderTitleUpdated struct {
 	Timestamp time.Time `json:"timestamp"`
 	Title     string    `json:"name"`
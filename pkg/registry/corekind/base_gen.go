@@ -12,6 +12,7 @@ package corekind
 import (
 	"fmt"
 
+	"github.com/grafana/grafana/pkg/kinds/correlation"
 	"github.com/grafana/grafana/pkg/kinds/dashboard"
 	"github.com/grafana/grafana/pkg/kinds/playlist"
 	"github.com/grafana/grafana/pkg/kinds/team"
@@ -30,19 +31,26 @@ import (
 // Prefer All*() methods when performing operations generically across all kinds.
 // For example, a validation HTTP middleware for any kind-schematized object type.
 type Base struct {
-	all       []kindsys.Core
-	dashboard *dashboard.Kind
-	playlist  *playlist.Kind
-	team      *team.Kind
+	all         []kindsys.Core
+	correlation *correlation.Kind
+	dashboard   *dashboard.Kind
+	playlist    *playlist.Kind
+	team        *team.Kind
 }
 
 // type guards
 var (
+	_ kindsys.Core = &correlation.Kind{}
 	_ kindsys.Core = &dashboard.Kind{}
 	_ kindsys.Core = &playlist.Kind{}
 	_ kindsys.Core = &team.Kind{}
 )
 
+// Correlation returns the [kindsys.Interface] implementation for the correlation kind.
+func (b *Base) Correlation() *correlation.Kind {
+	return b.correlation
+}
+
 // Dashboard returns the [kindsys.Interface] implementation for the dashboard kind.
 func (b *Base) Dashboard() *dashboard.Kind {
 	return b.dashboard
@@ -62,6 +70,12 @@ func doNewBase(rt *thema.Runtime) *Base {
 	var err error
 	reg := &Base{}
 
+	reg.correlation, err = correlation.NewKind(rt)
+	if err != nil {
+		panic(fmt.Sprintf("error while initializing the correlation Kind: %s", err))
+	}
+	reg.all = append(reg.all, reg.correlation)
+
 	reg.dashboard, err = dashboard.NewKind(rt)
 	if err != nil {
 		panic(fmt.Sprintf("error while initializing the dashboard Kind: %s", err))
@@ -1,11 +1,75 @@
 package correlations
 
 import (
+	"xorm.io/xorm"
+
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// Correlations are scoped by the data source they originate from, e.g. datasources:uid:<sourceUID>.
+const (
+	ActionRead   = "correlations:read"
+	ActionWrite  = "correlations:write"
+	ActionDelete = "correlations:delete"
 )
 
 var (
 	// ConfigurationPageAccess is used to protect the "Configure > correlations" tab access
-	ConfigurationPageAccess = accesscontrol.EvalPermission(datasources.ActionRead)
+	ConfigurationPageAccess = accesscontrol.EvalPermission(ActionRead)
 )
+
+var (
+	correlationsReaderRole = accesscontrol.RoleDTO{
+		Name:        "fixed:correlations:reader",
+		DisplayName: "Correlations reader",
+		Description: "Read the correlations of all data sources.",
+		Group:       "Correlations",
+		Permissions: []accesscontrol.Permission{
+			{Action: ActionRead, Scope: datasources.ScopeAll},
+		},
+	}
+
+	correlationsWriterRole = accesscontrol.RoleDTO{
+		Name:        "fixed:correlations:writer",
+		DisplayName: "Correlations writer",
+		Description: "Create, update, delete and read the correlations of all data sources.",
+		Group:       "Correlations",
+		Permissions: []accesscontrol.Permission{
+			{Action: ActionRead, Scope: datasources.ScopeAll},
+			{Action: ActionWrite, Scope: datasources.ScopeAll},
+			{Action: ActionDelete, Scope: datasources.ScopeAll},
+		},
+	}
+)
+
+// declareFixedRoles grants reading correlations to viewers and managing them to admins, the org roles the API
+// required before correlations had actions of their own.
+func declareFixedRoles(service accesscontrol.Service) error {
+	reader := accesscontrol.RoleRegistration{
+		Role:   correlationsReaderRole,
+		Grants: []string{string(org.RoleViewer)},
+	}
+	writer := accesscontrol.RoleRegistration{
+		Role:   correlationsWriterRole,
+		Grants: []string{string(org.RoleAdmin)},
+	}
+
+	return service.DeclareFixedRoles(reader, writer)
+}
+
+// readableBy restricts a session to the correlations originating from data sources whose correlations the user can
+// read. Without a user, e.g. for the CLI or exports, the session isn't restricted.
+func (s CorrelationsService) readableBy(q *xorm.Session, signedInUser *user.SignedInUser) (*xorm.Session, error) {
+	if signedInUser == nil || s.AccessControl == nil || s.AccessControl.IsDisabled() {
+		return q, nil
+	}
+
+	filter, err := accesscontrol.Filter(signedInUser, "correlation.source_uid", datasources.ScopePrefix, ActionRead)
+	if err != nil {
+		return nil, err
+	}
+	return q.And(filter.Where, filter.Args...), nil
+}
@@ -0,0 +1,36 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+func TestDeclareFixedRoles(t *testing.T) {
+	service := mock.New()
+	var registrations []accesscontrol.RoleRegistration
+	service.DeclareFixedRolesFunc = func(r ...accesscontrol.RoleRegistration) error {
+		registrations = r
+		return nil
+	}
+	require.NoError(t, declareFixedRoles(service))
+
+	grants := make(map[string][]string)
+	actions := make(map[string][]string)
+	for _, registration := range registrations {
+		grants[registration.Role.Name] = registration.Grants
+		for _, permission := range registration.Role.Permissions {
+			actions[registration.Role.Name] = append(actions[registration.Role.Name], permission.Action)
+		}
+	}
+	require.Equal(t, map[string][]string{
+		"fixed:correlations:reader": {string(org.RoleViewer)},
+		"fixed:correlations:writer": {string(org.RoleAdmin)},
+	}, grants)
+	require.Equal(t, []string{ActionRead}, actions["fixed:correlations:reader"])
+	require.ElementsMatch(t, []string{ActionRead, ActionWrite, ActionDelete}, actions["fixed:correlations:writer"])
+}
@@ -0,0 +1,275 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const (
+	// analyticsFlushInterval is how long analytics events are counted in memory before the counts are written.
+	analyticsFlushInterval = 10 * time.Second
+	// MaxAnalyticsEvents is the maximum number of events in a single report.
+	MaxAnalyticsEvents = 500
+)
+
+// AnalyticsEventType is what happened to the link of a correlation.
+type AnalyticsEventType string
+
+const (
+	// AnalyticsEventRendered is reported when the link of a correlation is shown, e.g. in a log line.
+	AnalyticsEventRendered AnalyticsEventType = "rendered"
+	// AnalyticsEventClicked is reported when the link of a correlation is followed.
+	AnalyticsEventClicked AnalyticsEventType = "clicked"
+)
+
+// CorrelationAnalyticsEvent is something that happened to the link of a correlation
+// swagger:model
+type CorrelationAnalyticsEvent struct {
+	// UID of the correlation
+	// example: 50xhMlg9k
+	UID string `json:"uid" binding:"Required"`
+	// UID of the data source the correlation originates from
+	// example: d0oxYRg4z
+	SourceUID string `json:"sourceUID" binding:"Required"`
+	// What happened to the link, either "rendered" or "clicked"
+	// example: clicked
+	Type AnalyticsEventType `json:"type" binding:"Required"`
+}
+
+// ReportCorrelationAnalyticsCommand is the command to report what happened to the links of correlations
+// swagger:model
+type ReportCorrelationAnalyticsCommand struct {
+	OrgId int64 `json:"-"`
+	// Events to count, at most 500
+	Events []CorrelationAnalyticsEvent `json:"events" binding:"Required"`
+}
+
+func (c ReportCorrelationAnalyticsCommand) Validate() error {
+	if len(c.Events) > MaxAnalyticsEvents {
+		return fmt.Errorf("%w: at most %d events can be reported at once", ErrInvalidAnalyticsEvent, MaxAnalyticsEvents)
+	}
+	for _, event := range c.Events {
+		if event.UID == "" || event.SourceUID == "" {
+			return fmt.Errorf("%w: uid and sourceUID are required", ErrInvalidAnalyticsEvent)
+		}
+		if event.Type != AnalyticsEventRendered && event.Type != AnalyticsEventClicked {
+			return fmt.Errorf("%w: unknown type %q", ErrInvalidAnalyticsEvent, event.Type)
+		}
+	}
+	return nil
+}
+
+// GetCorrelationStatsQuery is the query to get how often the links of the correlations of an org were shown and followed
+type GetCorrelationStatsQuery struct {
+	OrgId int64 `json:"-"`
+}
+
+// CorrelationStats is how often the link of a correlation was shown and followed
+// swagger:model
+type CorrelationStats struct {
+	// UID of the correlation
+	// example: 50xhMlg9k
+	UID string `json:"uid" xorm:"uid"`
+	// UID of the data source the correlation originates from
+	// example: d0oxYRg4z
+	SourceUID string `json:"sourceUID" xorm:"source_uid"`
+	// Label of the correlation
+	// example: My label
+	Label string `json:"label" xorm:"label"`
+	// Number of times the link of the correlation was shown
+	// example: 1200
+	Rendered int64 `json:"rendered" xorm:"rendered"`
+	// Number of times the link of the correlation was followed
+	// example: 35
+	Clicked int64 `json:"clicked" xorm:"clicked"`
+	// When the link was last shown, in seconds since the epoch, or 0 if it never was
+	// example: 1672531200
+	LastRendered int64 `json:"lastRendered" xorm:"last_rendered"`
+	// When the link was last followed, in seconds since the epoch, or 0 if it never was
+	// example: 1672531200
+	LastClicked int64 `json:"lastClicked" xorm:"last_clicked"`
+}
+
+// correlationStats is a row of the correlation_stats table, holding the counted events of a correlation.
+type correlationStats struct {
+	ID             int64  `xorm:"pk autoincr 'id'"`
+	OrgID          int64  `xorm:"org_id"`
+	CorrelationUID string `xorm:"correlation_uid"`
+	SourceUID      string `xorm:"source_uid"`
+	Rendered       int64  `xorm:"rendered"`
+	Clicked        int64  `xorm:"clicked"`
+	LastRendered   int64  `xorm:"last_rendered"`
+	LastClicked    int64  `xorm:"last_clicked"`
+}
+
+func (s correlationStats) TableName() string {
+	return "correlation_stats"
+}
+
+// analyticsCounts are the events counted for a correlation since the last flush.
+type analyticsCounts struct {
+	rendered     int64
+	clicked      int64
+	lastRendered time.Time
+	lastClicked  time.Time
+}
+
+// analyticsCollector counts analytics events off the request path. Links are rendered far more often than
+// correlations change, so events are counted in memory and the counts are added to the stored ones in batches.
+type analyticsCollector struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	flushWait time.Duration
+	// pending holds the counts that have not been written yet.
+	pending   map[usageKey]*analyticsCounts
+	scheduled bool
+	write     func(ctx context.Context, counts map[usageKey]*analyticsCounts) error
+	log       log.Logger
+}
+
+func newAnalyticsCollector(clk clock.Clock, write func(ctx context.Context, counts map[usageKey]*analyticsCounts) error, logger log.Logger) *analyticsCollector {
+	return &analyticsCollector{
+		clock:     clk,
+		flushWait: analyticsFlushInterval,
+		pending:   make(map[usageKey]*analyticsCounts),
+		write:     write,
+		log:       logger,
+	}
+}
+
+// count counts an event of a correlation. It is written with the next flush, which is scheduled if needed.
+func (a *analyticsCollector) count(key usageKey, eventType AnalyticsEventType) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	counts, ok := a.pending[key]
+	if !ok {
+		counts = &analyticsCounts{}
+		a.pending[key] = counts
+	}
+	now := a.clock.Now()
+	switch eventType {
+	case AnalyticsEventRendered:
+		counts.rendered++
+		counts.lastRendered = now
+	case AnalyticsEventClicked:
+		counts.clicked++
+		counts.lastClicked = now
+	}
+
+	if !a.scheduled {
+		a.scheduled = true
+		a.clock.AfterFunc(a.flushWait, func() {
+			if err := a.flush(context.Background()); err != nil {
+				a.log.Warn("Failed to record correlation analytics", "error", err)
+			}
+		})
+	}
+}
+
+// flush writes all pending counts.
+func (a *analyticsCollector) flush(ctx context.Context) error {
+	a.mu.Lock()
+	counts := a.pending
+	a.pending = make(map[usageKey]*analyticsCounts)
+	a.scheduled = false
+	a.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+	return a.write(ctx, counts)
+}
+
+// writeAnalytics adds the given counts to the stored counts of the correlations. Counts of correlations whose source
+// data source does not belong to the org the events were reported in, including correlations that don't exist, are
+// dropped. Counts are added in the database, so that instances writing at the same time don't lose any.
+func (s CorrelationsService) writeAnalytics(ctx context.Context, counts map[usageKey]*analyticsCounts) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		for key, c := range counts {
+			exists, err := session.Table("correlation").
+				Where("uid = ? AND source_uid = ? AND source_uid IN (SELECT uid FROM data_source WHERE org_id = ?)", key.uid, key.sourceUID, key.orgID).
+				And(notDeleted).
+				Exist()
+			if err != nil {
+				return err
+			}
+			if !exists {
+				continue
+			}
+
+			lastRendered, lastClicked := unixOrZero(c.lastRendered), unixOrZero(c.lastClicked)
+			res, err := session.Exec(
+				"UPDATE correlation_stats SET rendered = rendered + ?, clicked = clicked + ?, "+
+					"last_rendered = CASE WHEN last_rendered < ? THEN ? ELSE last_rendered END, "+
+					"last_clicked = CASE WHEN last_clicked < ? THEN ? ELSE last_clicked END "+
+					"WHERE correlation_uid = ? AND source_uid = ?",
+				c.rendered, c.clicked, lastRendered, lastRendered, lastClicked, lastClicked, key.uid, key.sourceUID,
+			)
+			if err != nil {
+				return err
+			}
+			updated, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if updated > 0 {
+				continue
+			}
+
+			if _, err := session.Insert(&correlationStats{
+				OrgID:          key.orgID,
+				CorrelationUID: key.uid,
+				SourceUID:      key.sourceUID,
+				Rendered:       c.rendered,
+				Clicked:        c.clicked,
+				LastRendered:   lastRendered,
+				LastClicked:    lastClicked,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// getCorrelationStats lists the counted events of all correlations of an org, the least used first so that unused
+// correlations can be found and pruned. Correlations without any event are listed with zero counts.
+func (s CorrelationsService) getCorrelationStats(ctx context.Context, query GetCorrelationStatsQuery) ([]CorrelationStats, error) {
+	stats := make([]CorrelationStats, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		return session.Table("correlation").
+			Select("correlation.uid, correlation.source_uid, correlation.label, "+
+				"COALESCE(st.rendered, 0) AS rendered, COALESCE(st.clicked, 0) AS clicked, "+
+				"COALESCE(st.last_rendered, 0) AS last_rendered, COALESCE(st.last_clicked, 0) AS last_clicked").
+			Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", query.OrgId).
+			Join("LEFT", "correlation_stats AS st", "st.correlation_uid = correlation.uid AND st.source_uid = correlation.source_uid").
+			Where(notDeleted).
+			OrderBy("clicked ASC, rendered ASC, correlation.source_uid, correlation.uid").
+			Find(&stats)
+	})
+	if err != nil {
+		return []CorrelationStats{}, err
+	}
+	return stats, nil
+}
+
+// deleteStats drops the counted events of a correlation.
+func deleteStats(session *db.Session, uid, sourceUID string) error {
+	_, err := session.Where("source_uid = ? AND correlation_uid = ?", sourceUID, uid).Delete(&correlationStats{})
+	return err
+}
@@ -0,0 +1,101 @@
+package correlations
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+func TestAnalyticsCollector(t *testing.T) {
+	setup := func() (*analyticsCollector, *clock.Mock, *[]map[usageKey]*analyticsCounts) {
+		clk := clock.NewMock()
+		var mu sync.Mutex
+		written := make([]map[usageKey]*analyticsCounts, 0)
+		collector := newAnalyticsCollector(clk, func(_ context.Context, counts map[usageKey]*analyticsCounts) error {
+			mu.Lock()
+			defer mu.Unlock()
+			written = append(written, counts)
+			return nil
+		}, log.NewNopLogger())
+		return collector, clk, &written
+	}
+
+	a := usageKey{uid: "a", sourceUID: "loki", orgID: 1}
+	b := usageKey{uid: "b", sourceUID: "loki", orgID: 1}
+
+	t.Run("events are counted and written in one batch after the flush interval", func(t *testing.T) {
+		collector, clk, written := setup()
+		start := clk.Now()
+
+		for i := 0; i < 3; i++ {
+			collector.count(a, AnalyticsEventRendered)
+		}
+		collector.count(a, AnalyticsEventClicked)
+		collector.count(b, AnalyticsEventRendered)
+		require.Empty(t, *written)
+
+		clk.Add(analyticsFlushInterval)
+		require.Len(t, *written, 1)
+		require.Equal(t, map[usageKey]*analyticsCounts{
+			a: {rendered: 3, clicked: 1, lastRendered: start, lastClicked: start},
+			b: {rendered: 1, lastRendered: start},
+		}, (*written)[0])
+	})
+
+	t.Run("counts are only written once", func(t *testing.T) {
+		collector, clk, written := setup()
+
+		collector.count(a, AnalyticsEventClicked)
+		clk.Add(analyticsFlushInterval)
+		collector.count(a, AnalyticsEventClicked)
+		clk.Add(analyticsFlushInterval)
+
+		require.Len(t, *written, 2)
+		require.Equal(t, int64(1), (*written)[1][a].clicked)
+	})
+
+	t.Run("flushing without counts writes nothing", func(t *testing.T) {
+		collector, _, written := setup()
+
+		require.NoError(t, collector.flush(context.Background()))
+		require.Empty(t, *written)
+	})
+}
+
+func TestReportCorrelationAnalyticsCommandValidate(t *testing.T) {
+	event := CorrelationAnalyticsEvent{UID: "a", SourceUID: "loki", Type: AnalyticsEventClicked}
+
+	t.Run("rendered and clicked events are valid", func(t *testing.T) {
+		rendered := event
+		rendered.Type = AnalyticsEventRendered
+		require.NoError(t, ReportCorrelationAnalyticsCommand{Events: []CorrelationAnalyticsEvent{event, rendered}}.Validate())
+	})
+
+	t.Run("unknown event types are rejected", func(t *testing.T) {
+		hovered := event
+		hovered.Type = "hovered"
+		err := ReportCorrelationAnalyticsCommand{Events: []CorrelationAnalyticsEvent{hovered}}.Validate()
+		require.ErrorIs(t, err, ErrInvalidAnalyticsEvent)
+	})
+
+	t.Run("events need a correlation", func(t *testing.T) {
+		missing := event
+		missing.SourceUID = ""
+		err := ReportCorrelationAnalyticsCommand{Events: []CorrelationAnalyticsEvent{missing}}.Validate()
+		require.ErrorIs(t, err, ErrInvalidAnalyticsEvent)
+	})
+
+	t.Run("reports have a maximum number of events", func(t *testing.T) {
+		events := make([]CorrelationAnalyticsEvent, MaxAnalyticsEvents+1)
+		for i := range events {
+			events[i] = event
+		}
+		err := ReportCorrelationAnalyticsCommand{Events: events}.Validate()
+		require.ErrorIs(t, err, ErrInvalidAnalyticsEvent)
+	})
+}
@@ -3,6 +3,8 @@ package correlations
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
@@ -18,16 +20,46 @@ func (s *CorrelationsService) registerAPIEndpoints() {
 	uidScope := datasources.ScopeProvider.GetResourceScopeUID(ac.Parameter(":uid"))
 	authorize := ac.Middleware(s.AccessControl)
 
-	s.RouteRegister.Get("/api/datasources/correlations", middleware.ReqSignedIn, authorize(middleware.ReqSignedIn, ac.EvalPermission(datasources.ActionRead)), routing.Wrap(s.getCorrelationsHandler))
+	// Only the correlations originating from data sources whose correlations the user can read are listed.
+	s.RouteRegister.Get("/api/datasources/correlations", middleware.ReqSignedIn, authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead)), routing.Wrap(s.getCorrelationsHandler))
+	// Correlations targeting a data source may originate from any data source, so they're listed like all correlations.
+	s.RouteRegister.Get("/api/datasources/correlations/target/:uid", middleware.ReqSignedIn, authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead)), routing.Wrap(s.getCorrelationsByTargetUIDHandler))
+	s.RouteRegister.Get("/api/datasources/correlations/export", middleware.ReqSignedIn, authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead, datasources.ScopeAll)), routing.Wrap(s.exportCorrelationsHandler))
+	// Imported correlations may originate from any data source, as with bulk deletion.
+	s.RouteRegister.Post("/api/datasources/correlations/import", middleware.ReqSignedIn, authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionWrite, datasources.ScopeAll)), routing.Wrap(s.importCorrelationsHandler))
+	// The settings don't reveal any correlation, so they're shown to anyone who can read some correlations.
+	s.RouteRegister.Get("/api/datasources/correlations/settings", middleware.ReqSignedIn, authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead)), routing.Wrap(s.getSettingsHandler))
+	// The settings apply to the correlations of all data sources.
+	s.RouteRegister.Put("/api/datasources/correlations/settings", middleware.ReqSignedIn, authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionWrite, datasources.ScopeAll)), routing.Wrap(s.updateSettingsHandler))
+	// Events may be reported for the correlations of any data source, and are dropped if the correlation isn't found
+	// in the org or the user can't read the correlations of its data source.
+	s.RouteRegister.Post("/api/datasources/correlations/analytics", middleware.ReqSignedIn, authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead)), routing.Wrap(s.reportAnalyticsHandler))
+	// The stats are meant to find unused correlations to delete, so they're only shown to those who can delete the
+	// correlations of all data sources.
+	s.RouteRegister.Get("/api/datasources/correlations/stats", middleware.ReqSignedIn, authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionDelete, datasources.ScopeAll)), routing.Wrap(s.getStatsHandler))
+	// Transformations are only tested against the given values, not against stored correlations.
+	s.RouteRegister.Post("/api/datasources/correlations/transformations/test", middleware.ReqSignedIn, authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead)), routing.Wrap(s.testTransformationsHandler))
+	// Correlations deleted in bulk may originate from any data source, so deleting the correlations of all of them is
+	// required.
+	s.RouteRegister.Post("/api/datasources/correlations/delete", middleware.ReqSignedIn, authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionDelete, datasources.ScopeAll)), routing.Wrap(s.deleteCorrelationsHandler))
+	// As with bulk deletion, patched correlations may originate from any data source.
+	s.RouteRegister.Patch("/api/datasources/correlations", middleware.ReqSignedIn, authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionWrite, datasources.ScopeAll)), routing.Wrap(s.patchCorrelationsHandler))
 
 	s.RouteRegister.Group("/api/datasources/uid/:uid/correlations", func(entities routing.RouteRegister) {
-		entities.Get("/", authorize(middleware.ReqSignedIn, ac.EvalPermission(datasources.ActionRead)), routing.Wrap(s.getCorrelationsBySourceUIDHandler))
-		entities.Post("/", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(s.createHandler))
+		entities.Get("/", authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead, uidScope)), routing.Wrap(s.getCorrelationsBySourceUIDHandler))
+		entities.Post("/", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionWrite, uidScope)), routing.Wrap(s.createHandler))
 
 		entities.Group("/:correlationUID", func(entities routing.RouteRegister) {
-			entities.Get("/", authorize(middleware.ReqSignedIn, ac.EvalPermission(datasources.ActionRead)), routing.Wrap(s.getCorrelationHandler))
-			entities.Delete("/", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(s.deleteHandler))
-			entities.Patch("/", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(s.updateHandler))
+			entities.Get("/", authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead, uidScope)), routing.Wrap(s.getCorrelationHandler))
+			entities.Delete("/", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionDelete, uidScope)), routing.Wrap(s.deleteHandler))
+			entities.Patch("/", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionWrite, uidScope)), routing.Wrap(s.updateHandler))
+			entities.Post("/used", authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead, uidScope)), routing.Wrap(s.markUsedHandler))
+			entities.Post("/preview", authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead, uidScope)), routing.Wrap(s.previewHandler))
+			entities.Get("/history", authorize(middleware.ReqSignedIn, ac.EvalPermission(ActionRead, uidScope)), routing.Wrap(s.getCorrelationHistoryHandler))
+			entities.Post("/restore", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionWrite, uidScope)), routing.Wrap(s.undeleteHandler))
+			entities.Post("/restore/:version", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionWrite, uidScope)), routing.Wrap(s.restoreHandler))
+			// Copying to another data source additionally requires writing its correlations, see duplicateHandler.
+			entities.Post("/duplicate", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ActionWrite, uidScope)), routing.Wrap(s.duplicateHandler))
 		})
 	}, middleware.ReqSignedIn)
 }
@@ -42,10 +74,19 @@ func (s *CorrelationsService) registerAPIEndpoints() {
 // 401: unauthorisedError
 // 403: forbiddenError
 // 404: notFoundError
+// 409: conflictError
 // 500: internalServerError
 func (s *CorrelationsService) createHandler(c *models.ReqContext) response.Response {
 	cmd := CreateCorrelationCommand{}
 	if err := web.Bind(c.Req, &cmd); err != nil {
+		if invalid := (ConfigSchemaError{}); errors.As(err, &invalid) {
+			return invalidConfigResponse(invalid)
+		}
+
+		if unresolved := (UnresolvedVariablesError{}); errors.As(err, &unresolved) {
+			return unresolvedVariablesResponse(unresolved)
+		}
+
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
 	cmd.SourceUID = web.Params(c.Req)[":uid"]
@@ -57,14 +98,85 @@ func (s *CorrelationsService) createHandler(c *models.ReqContext) response.Respo
 			return response.Error(http.StatusNotFound, "Data source not found", err)
 		}
 
+		if errors.Is(err, ErrTargetDashboardDoesNotExist) {
+			return response.Error(http.StatusNotFound, "Target dashboard not found", err)
+		}
+
 		if errors.Is(err, ErrSourceDataSourceReadOnly) {
 			return response.Error(http.StatusForbidden, "Data source is read only", err)
 		}
 
+		if errors.Is(err, ErrQuotaReached) {
+			return response.Error(http.StatusForbidden, "Quota reached", err)
+		}
+
+		if errors.Is(err, ErrIncompatibleTargetDataSource) {
+			return response.Error(http.StatusBadRequest, "Target query does not fit the target data source", err)
+		}
+
+		if errors.Is(err, ErrInvalidSourceType) {
+			return response.Error(http.StatusBadRequest, "Source type does not match the source data source", err)
+		}
+
+		if errors.Is(err, ErrCorrelationUIDConflict) {
+			return response.Error(http.StatusConflict, "Correlation already exists", err)
+		}
+
 		return response.Error(http.StatusInternalServerError, "Failed to add correlation", err)
 	}
 
-	return response.JSON(http.StatusOK, CreateCorrelationResponseBody{Result: correlation, Message: "Correlation created"})
+	return response.JSON(http.StatusOK, CreateCorrelationResponseBody{Result: correlation, Message: "Correlation created", Warnings: s.targetQueryWarnings(c, correlation)})
+}
+
+// unresolvedVariablesResponse lists the variables the target of a correlation references, but the correlation does
+// not provide, so that they can all be fixed at once.
+func unresolvedVariablesResponse(err UnresolvedVariablesError) response.Response {
+	return response.JSON(http.StatusBadRequest, UnresolvedVariablesResponseBody{
+		Message:          "Target references variables the correlation does not provide",
+		MissingVariables: err.Variables,
+	})
+}
+
+// invalidConfigResponse lists every field of a correlation config that doesn't fit the correlation schema, with the
+// path of the field, so that they can all be fixed at once.
+func invalidConfigResponse(err ConfigSchemaError) response.Response {
+	return response.JSON(http.StatusBadRequest, InvalidConfigResponseBody{
+		Message: "Invalid correlation config",
+		Errors:  err.Fields,
+	})
+}
+
+// correlationETag returns the ETag of a correlation, which is its quoted version.
+func correlationETag(correlation Correlation) string {
+	return strconv.Quote(strconv.FormatInt(correlation.Version, 10))
+}
+
+// ifMatchVersion returns the version of the correlation the If-Match header of the request expects, or nil if it
+// doesn't expect any. The header holds an ETag returned when the correlation was read or updated.
+func ifMatchVersion(req *http.Request) (*int64, error) {
+	etag := strings.TrimPrefix(strings.TrimSpace(req.Header.Get("If-Match")), "W/")
+	if etag == "" || etag == "*" {
+		return nil, nil
+	}
+	version, err := strconv.ParseInt(strings.Trim(etag, `"`), 10, 64)
+	if err != nil || version < 1 {
+		return nil, ErrInvalidCorrelationVersion
+	}
+	return &version, nil
+}
+
+// targetQueryWarnings validates the target query of a correlation that was just saved, if the request asks for it.
+// The correlation is saved either way, so failing to validate it is only logged.
+func (s *CorrelationsService) targetQueryWarnings(c *models.ReqContext, correlation Correlation) []TargetQueryWarning {
+	if !c.QueryBool("validateTarget") {
+		return nil
+	}
+	warnings, err := s.ValidateTargetQuery(c.Req.Context(), c.OrgID, correlation)
+	if err != nil {
+		s.log.FromContext(c.Req.Context()).Warn("Failed to validate correlation target query", "uid", correlation.UID, "sourceUID", correlation.SourceUID, "error", err)
+		return nil
+	}
+	return warnings
 }
 
 // swagger:parameters createCorrelation
@@ -75,6 +187,10 @@ type CreateCorrelationParams struct {
 	// in:path
 	// required:true
 	SourceUID string `json:"sourceUID"`
+	// Check the target query against the target data source, and return its problems as warnings
+	// in:query
+	// required:false
+	ValidateTarget bool `json:"validateTarget"`
 }
 
 //swagger:response createCorrelationResponse
@@ -87,6 +203,8 @@ type CreateCorrelationResponse struct {
 //
 // Delete a correlation.
 //
+// Deleted correlations can be restored until they're purged, 30 days after they were deleted.
+//
 // Responses:
 // 200: deleteCorrelationResponse
 // 401: unauthorisedError
@@ -100,7 +218,7 @@ func (s *CorrelationsService) deleteHandler(c *models.ReqContext) response.Respo
 		OrgId:     c.OrgID,
 	}
 
-	err := s.DeleteCorrelation(c.Req.Context(), cmd)
+	_, err := s.DeleteCorrelation(c.Req.Context(), cmd)
 	if err != nil {
 		if errors.Is(err, ErrSourceDataSourceDoesNotExists) {
 			return response.Error(http.StatusNotFound, "Data source not found", err)
@@ -114,6 +232,10 @@ func (s *CorrelationsService) deleteHandler(c *models.ReqContext) response.Respo
 			return response.Error(http.StatusForbidden, "Data source is read only", err)
 		}
 
+		if errors.Is(err, ErrCorrelationReadOnly) {
+			return response.Error(http.StatusForbidden, "Correlation can only be edited via provisioning", err)
+		}
+
 		return response.Error(http.StatusInternalServerError, "Failed to delete correlation", err)
 	}
 
@@ -136,16 +258,222 @@ type DeleteCorrelationResponse struct {
 	Body DeleteCorrelationResponseBody `json:"body"`
 }
 
+// swagger:route POST /datasources/correlations/delete correlations deleteCorrelations
+//
+// Deletes several correlations at once. Either all of them are deleted, or none.
+//
+// Responses:
+// 200: deleteCorrelationsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *CorrelationsService) deleteCorrelationsHandler(c *models.ReqContext) response.Response {
+	cmd := DeleteCorrelationsCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgId = c.OrgID
+
+	result, err := s.DeleteCorrelations(c.Req.Context(), cmd)
+	if err != nil {
+		if errors.Is(err, ErrSourceDataSourceReadOnly) {
+			return response.Error(http.StatusForbidden, "Data source is read only", err)
+		}
+
+		if errors.Is(err, ErrCorrelationReadOnly) {
+			return response.Error(http.StatusForbidden, "Correlation can only be edited via provisioning", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to delete correlations", err)
+	}
+
+	return response.JSON(http.StatusOK, result)
+}
+
+// swagger:parameters deleteCorrelations
+type DeleteCorrelationsParams struct {
+	// in:body
+	// required:true
+	Body DeleteCorrelationsCommand `json:"body"`
+}
+
+//swagger:response deleteCorrelationsResponse
+type DeleteCorrelationsResponse struct {
+	// in: body
+	Body DeleteCorrelationsResult `json:"body"`
+}
+
+// swagger:route PATCH /datasources/correlations correlations patchCorrelations
+//
+// Applies the same changes to several correlations at once. Either all of them are updated, or none.
+//
+// Responses:
+// 200: patchCorrelationsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (s *CorrelationsService) patchCorrelationsHandler(c *models.ReqContext) response.Response {
+	cmd := PatchCorrelationsCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		if errors.Is(err, ErrUpdateCorrelationEmptyParams) {
+			return response.Error(http.StatusBadRequest, "At least one of label, description or targetUID is required", err)
+		}
+
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgId = c.OrgID
+
+	correlations, err := s.PatchCorrelations(c.Req.Context(), cmd)
+	if err != nil {
+		if errors.Is(err, ErrCorrelationNotFound) {
+			return response.Error(http.StatusNotFound, "Correlation not found", err)
+		}
+
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) || errors.Is(err, ErrTargetDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Data source not found", err)
+		}
+
+		if errors.Is(err, ErrSourceDataSourceReadOnly) {
+			return response.Error(http.StatusForbidden, "Data source is read only", err)
+		}
+
+		if errors.Is(err, ErrCorrelationReadOnly) {
+			return response.Error(http.StatusForbidden, "Correlation can only be edited via provisioning", err)
+		}
+
+		if errors.Is(err, ErrInvalidTargetUID) {
+			return response.Error(http.StatusBadRequest, "Invalid correlation target", err)
+		}
+
+		if errors.Is(err, ErrIncompatibleTargetDataSource) {
+			return response.Error(http.StatusBadRequest, "Target query does not fit the target data source", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to update correlations", err)
+	}
+
+	return response.JSON(http.StatusOK, PatchCorrelationsResponseBody{Message: "Correlations updated", Result: correlations})
+}
+
+// swagger:parameters patchCorrelations
+type PatchCorrelationsParams struct {
+	// in:body
+	// required:true
+	Body PatchCorrelationsCommand `json:"body"`
+}
+
+//swagger:response patchCorrelationsResponse
+type PatchCorrelationsResponse struct {
+	// in: body
+	Body PatchCorrelationsResponseBody `json:"body"`
+}
+
+// swagger:route GET /datasources/correlations/export correlations exportCorrelations
+//
+// Exports all correlations of the org as a bundle that can be imported into another org or instance. Data sources
+// are identified by name rather than UID.
+//
+// Responses:
+// 200: exportCorrelationsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *CorrelationsService) exportCorrelationsHandler(c *models.ReqContext) response.Response {
+	export, err := s.ExportCorrelations(c.Req.Context(), ExportCorrelationsQuery{OrgId: c.OrgID})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to export correlations", err)
+	}
+
+	return response.JSON(http.StatusOK, export)
+}
+
+//swagger:response exportCorrelationsResponse
+type ExportCorrelationsResponse struct {
+	// in: body
+	Body CorrelationsExport `json:"body"`
+}
+
+// swagger:route POST /datasources/correlations/import correlations importCorrelations
+//
+// Imports the correlations of an export. Either all of them are imported, or none.
+//
+// Responses:
+// 200: importCorrelationsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 409: conflictError
+// 500: internalServerError
+func (s *CorrelationsService) importCorrelationsHandler(c *models.ReqContext) response.Response {
+	cmd := ImportCorrelationsCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgId = c.OrgID
+
+	result, err := s.ImportCorrelations(c.Req.Context(), cmd)
+	if err != nil {
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) || errors.Is(err, ErrTargetDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Data source not found", err)
+		}
+
+		if errors.Is(err, ErrTargetDashboardDoesNotExist) {
+			return response.Error(http.StatusNotFound, "Target dashboard not found", err)
+		}
+
+		if errors.Is(err, ErrSourceDataSourceReadOnly) {
+			return response.Error(http.StatusForbidden, "Data source is read only", err)
+		}
+
+		if errors.Is(err, ErrQuotaReached) {
+			return response.Error(http.StatusForbidden, "Quota reached", err)
+		}
+
+		if errors.Is(err, ErrCorrelationUIDConflict) {
+			return response.Error(http.StatusConflict, "Correlation already exists", err)
+		}
+
+		if errors.Is(err, ErrIncompatibleTargetDataSource) {
+			return response.Error(http.StatusBadRequest, "Target query does not fit the target data source", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to import correlations", err)
+	}
+
+	return response.JSON(http.StatusOK, result)
+}
+
+// swagger:parameters importCorrelations
+type ImportCorrelationsParams struct {
+	// in:body
+	// required:true
+	Body ImportCorrelationsCommand `json:"body"`
+}
+
+//swagger:response importCorrelationsResponse
+type ImportCorrelationsResponse struct {
+	// in: body
+	Body ImportCorrelationsResult `json:"body"`
+}
+
 // swagger:route PATCH /datasources/uid/{sourceUID}/correlations/{correlationUID} correlations updateCorrelation
 //
 // Updates a correlation.
 //
+// If the version of the correlation the update is based on is given, in the body or as the ETag of the correlation in
+// the If-Match header, the update fails with a 409 if the correlation has been changed since.
+//
 // Responses:
 // 200: updateCorrelationResponse
 // 400: badRequestError
 // 401: unauthorisedError
 // 403: forbiddenError
 // 404: notFoundError
+// 409: conflictError
 // 500: internalServerError
 func (s *CorrelationsService) updateHandler(c *models.ReqContext) response.Response {
 	cmd := UpdateCorrelationCommand{}
@@ -154,19 +482,42 @@ func (s *CorrelationsService) updateHandler(c *models.ReqContext) response.Respo
 			return response.Error(http.StatusBadRequest, "At least one of label, description or config is required", err)
 		}
 
+		if invalid := (ConfigSchemaError{}); errors.As(err, &invalid) {
+			return invalidConfigResponse(invalid)
+		}
+
+		if errors.Is(err, ErrInvalidCorrelationVersion) {
+			return response.Error(http.StatusBadRequest, "Invalid correlation version", err)
+		}
+
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
 
+	version, err := ifMatchVersion(c.Req)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Invalid correlation version", err)
+	}
+	if version != nil {
+		if cmd.Version != nil && *cmd.Version != *version {
+			return response.Error(http.StatusBadRequest, "Version and If-Match header do not match", ErrInvalidCorrelationVersion)
+		}
+		cmd.Version = version
+	}
+
 	cmd.UID = web.Params(c.Req)[":correlationUID"]
 	cmd.SourceUID = web.Params(c.Req)[":uid"]
 	cmd.OrgId = c.OrgID
 
 	correlation, err := s.UpdateCorrelation(c.Req.Context(), cmd)
 	if err != nil {
-		if errors.Is(err, ErrSourceDataSourceDoesNotExists) {
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) || errors.Is(err, ErrTargetDataSourceDoesNotExists) {
 			return response.Error(http.StatusNotFound, "Data source not found", err)
 		}
 
+		if errors.Is(err, ErrTargetDashboardDoesNotExist) {
+			return response.Error(http.StatusNotFound, "Target dashboard not found", err)
+		}
+
 		if errors.Is(err, ErrCorrelationNotFound) {
 			return response.Error(http.StatusNotFound, "Correlation not found", err)
 		}
@@ -175,10 +526,35 @@ func (s *CorrelationsService) updateHandler(c *models.ReqContext) response.Respo
 			return response.Error(http.StatusForbidden, "Data source is read only", err)
 		}
 
+		if errors.Is(err, ErrCorrelationReadOnly) {
+			return response.Error(http.StatusForbidden, "Correlation can only be edited via provisioning", err)
+		}
+
+		if errors.Is(err, ErrCorrelationVersionConflict) {
+			return response.Error(http.StatusConflict, "Correlation has been changed since the given version", err)
+		}
+
+		if errors.Is(err, ErrUnsupportedTransformation) {
+			return response.Error(http.StatusBadRequest, "Transformation not supported", err)
+		}
+
+		if unresolved := (UnresolvedVariablesError{}); errors.As(err, &unresolved) {
+			return unresolvedVariablesResponse(unresolved)
+		}
+
+		if errors.Is(err, ErrInvalidTargetUID) || errors.Is(err, ErrInvalidExternalTarget) || errors.Is(err, ErrInvalidDashboardTarget) || errors.Is(err, ErrUnresolvableTargetVariable) {
+			return response.Error(http.StatusBadRequest, "Invalid correlation target", err)
+		}
+
+		if errors.Is(err, ErrIncompatibleTargetDataSource) {
+			return response.Error(http.StatusBadRequest, "Target query does not fit the target data source", err)
+		}
+
 		return response.Error(http.StatusInternalServerError, "Failed to update correlation", err)
 	}
 
-	return response.JSON(http.StatusOK, UpdateCorrelationResponseBody{Message: "Correlation updated", Result: correlation})
+	return response.JSON(http.StatusOK, UpdateCorrelationResponseBody{Message: "Correlation updated", Result: correlation, Warnings: s.targetQueryWarnings(c, correlation)}).
+		SetHeader("ETag", correlationETag(correlation))
 }
 
 // swagger:parameters updateCorrelation
@@ -191,6 +567,14 @@ type UpdateCorrelationParams struct {
 	CorrelationUID string `json:"correlationUID"`
 	// in: body
 	Body UpdateCorrelationCommand `json:"body"`
+	// ETag of the correlation the update is based on, as returned when it was read or updated
+	// in:header
+	// required:false
+	IfMatch string `json:"If-Match"`
+	// Check the target query against the target data source, and return its problems as warnings
+	// in:query
+	// required:false
+	ValidateTarget bool `json:"validateTarget"`
 }
 
 //swagger:response updateCorrelationResponse
@@ -199,39 +583,51 @@ type UpdateCorrelationResponse struct {
 	Body UpdateCorrelationResponseBody `json:"body"`
 }
 
-// swagger:route GET /datasources/uid/{sourceUID}/correlations/{correlationUID} correlations getCorrelation
+// swagger:route POST /datasources/uid/{sourceUID}/correlations/{correlationUID}/restore correlations undeleteCorrelation
 //
-// Gets a correlation.
+// Restores a deleted correlation.
+//
+// Deleted correlations can be restored until they're purged, 30 days after they were deleted.
 //
 // Responses:
-// 200: getCorrelationResponse
+// 200: undeleteCorrelationResponse
 // 401: unauthorisedError
+// 403: forbiddenError
 // 404: notFoundError
 // 500: internalServerError
-func (s *CorrelationsService) getCorrelationHandler(c *models.ReqContext) response.Response {
-	query := GetCorrelationQuery{
+func (s *CorrelationsService) undeleteHandler(c *models.ReqContext) response.Response {
+	cmd := UndeleteCorrelationCommand{
 		UID:       web.Params(c.Req)[":correlationUID"],
 		SourceUID: web.Params(c.Req)[":uid"],
 		OrgId:     c.OrgID,
 	}
 
-	correlation, err := s.getCorrelation(c.Req.Context(), query)
+	correlation, err := s.UndeleteCorrelation(c.Req.Context(), cmd)
 	if err != nil {
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) || errors.Is(err, ErrTargetDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Data source not found", err)
+		}
+
 		if errors.Is(err, ErrCorrelationNotFound) {
-			return response.Error(http.StatusNotFound, "Correlation not found", err)
+			return response.Error(http.StatusNotFound, "Deleted correlation not found", err)
 		}
-		if errors.Is(err, ErrSourceDataSourceDoesNotExists) {
-			return response.Error(http.StatusNotFound, "Source data source not found", err)
+
+		if errors.Is(err, ErrSourceDataSourceReadOnly) {
+			return response.Error(http.StatusForbidden, "Data source is read only", err)
 		}
 
-		return response.Error(http.StatusInternalServerError, "Failed to get correlation", err)
+		if errors.Is(err, ErrQuotaReached) {
+			return response.Error(http.StatusForbidden, "Quota reached", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to restore correlation", err)
 	}
 
-	return response.JSON(http.StatusOK, correlation)
+	return response.JSON(http.StatusOK, UpdateCorrelationResponseBody{Message: "Correlation restored", Result: correlation})
 }
 
-// swagger:parameters getCorrelation
-type GetCorrelationParams struct {
+// swagger:parameters undeleteCorrelation
+type UndeleteCorrelationParams struct {
 	// in:path
 	// required:true
 	DatasourceUID string `json:"sourceUID"`
@@ -240,70 +636,552 @@ type GetCorrelationParams struct {
 	CorrelationUID string `json:"correlationUID"`
 }
 
-//swagger:response getCorrelationResponse
-type GetCorrelationResponse struct {
+//swagger:response undeleteCorrelationResponse
+type UndeleteCorrelationResponse struct {
 	// in: body
-	Body Correlation `json:"body"`
+	Body UpdateCorrelationResponseBody `json:"body"`
 }
 
-// swagger:route GET /datasources/uid/{sourceUID}/correlations correlations getCorrelationsBySourceUID
+// swagger:route POST /datasources/uid/{sourceUID}/correlations/{correlationUID}/restore/{version} correlations restoreCorrelation
 //
-// Gets all correlations originating from the given data source.
+// Restores a correlation to an earlier version.
+//
+// The label, description, notes, tags, target, config and enabled state of the version are saved as a new version.
+// Only the last versions of a correlation are kept.
 //
 // Responses:
-// 200: getCorrelationsBySourceUIDResponse
+// 200: restoreCorrelationResponse
+// 400: badRequestError
 // 401: unauthorisedError
+// 403: forbiddenError
 // 404: notFoundError
 // 500: internalServerError
-func (s *CorrelationsService) getCorrelationsBySourceUIDHandler(c *models.ReqContext) response.Response {
-	query := GetCorrelationsBySourceUIDQuery{
+func (s *CorrelationsService) restoreHandler(c *models.ReqContext) response.Response {
+	version, err := strconv.ParseInt(web.Params(c.Req)[":version"], 10, 64)
+	if err != nil || version < 1 {
+		return response.Error(http.StatusBadRequest, "Invalid correlation version", err)
+	}
+	cmd := RestoreCorrelationCommand{
+		UID:       web.Params(c.Req)[":correlationUID"],
 		SourceUID: web.Params(c.Req)[":uid"],
 		OrgId:     c.OrgID,
+		Version:   version,
 	}
 
-	correlations, err := s.getCorrelationsBySourceUID(c.Req.Context(), query)
+	correlation, err := s.RestoreCorrelation(c.Req.Context(), cmd)
 	if err != nil {
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) || errors.Is(err, ErrTargetDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Data source not found", err)
+		}
+
+		if errors.Is(err, ErrTargetDashboardDoesNotExist) {
+			return response.Error(http.StatusNotFound, "Target dashboard not found", err)
+		}
+
 		if errors.Is(err, ErrCorrelationNotFound) {
-			return response.Error(http.StatusNotFound, "No correlation found", err)
+			return response.Error(http.StatusNotFound, "Correlation not found", err)
 		}
-		if errors.Is(err, ErrSourceDataSourceDoesNotExists) {
-			return response.Error(http.StatusNotFound, "Source data source not found", err)
+
+		if errors.Is(err, ErrCorrelationVersionNotFound) {
+			return response.Error(http.StatusNotFound, "Correlation version not found", err)
 		}
 
-		return response.Error(http.StatusInternalServerError, "Failed to get correlations", err)
+		if errors.Is(err, ErrSourceDataSourceReadOnly) {
+			return response.Error(http.StatusForbidden, "Data source is read only", err)
+		}
+
+		if errors.Is(err, ErrCorrelationReadOnly) {
+			return response.Error(http.StatusForbidden, "Correlation can only be edited via provisioning", err)
+		}
+
+		if errors.Is(err, ErrIncompatibleTargetDataSource) {
+			return response.Error(http.StatusBadRequest, "Target query does not fit the target data source", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to restore correlation", err)
 	}
 
-	return response.JSON(http.StatusOK, correlations)
+	return response.JSON(http.StatusOK, UpdateCorrelationResponseBody{Message: "Correlation restored", Result: correlation})
 }
 
-// swagger:parameters getCorrelationsBySourceUID
-type GetCorrelationsBySourceUIDParams struct {
+// swagger:parameters restoreCorrelation
+type RestoreCorrelationParams struct {
 	// in:path
 	// required:true
 	DatasourceUID string `json:"sourceUID"`
+	// in:path
+	// required:true
+	CorrelationUID string `json:"correlationUID"`
+	// in:path
+	// required:true
+	Version int64 `json:"version"`
 }
 
-//swagger:response getCorrelationsBySourceUIDResponse
-type GetCorrelationsBySourceUIDResponse struct {
+//swagger:response restoreCorrelationResponse
+type RestoreCorrelationResponse struct {
 	// in: body
-	Body []Correlation `json:"body"`
+	Body UpdateCorrelationResponseBody `json:"body"`
 }
 
-// swagger:route GET /datasources/correlations correlations getCorrelations
+// swagger:route GET /datasources/uid/{sourceUID}/correlations/{correlationUID} correlations getCorrelation
 //
-// Gets all correlations.
+// Gets a correlation.
+//
+// Responses:
+// 200: getCorrelationResponse
+// 401: unauthorisedError
+// 404: notFoundError
+// 500: internalServerError
+func (s *CorrelationsService) getCorrelationHandler(c *models.ReqContext) response.Response {
+	query := GetCorrelationQuery{
+		UID:       web.Params(c.Req)[":correlationUID"],
+		SourceUID: web.Params(c.Req)[":uid"],
+		OrgId:     c.OrgID,
+	}
+
+	correlation, err := s.GetCorrelation(c.Req.Context(), query)
+	if err != nil {
+		if errors.Is(err, ErrCorrelationNotFound) {
+			return response.Error(http.StatusNotFound, "Correlation not found", err)
+		}
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Source data source not found", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to get correlation", err)
+	}
+
+	return response.JSON(http.StatusOK, correlation).SetHeader("ETag", correlationETag(correlation))
+}
+
+// swagger:parameters getCorrelation
+type GetCorrelationParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"sourceUID"`
+	// in:path
+	// required:true
+	CorrelationUID string `json:"correlationUID"`
+}
+
+//swagger:response getCorrelationResponse
+type GetCorrelationResponse struct {
+	// in: body
+	Body Correlation `json:"body"`
+}
+
+// swagger:route POST /datasources/uid/{sourceUID}/correlations/{correlationUID}/used correlations markCorrelationUsed
+//
+// Records that a correlation was used.
+//
+// The use is recorded asynchronously. Repeated uses within a few minutes are recorded once.
+//
+// Responses:
+// 200: okResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+func (s *CorrelationsService) markUsedHandler(c *models.ReqContext) response.Response {
+	s.MarkCorrelationUsed(web.Params(c.Req)[":correlationUID"], web.Params(c.Req)[":uid"], c.OrgID)
+	return response.Success("Correlation use recorded")
+}
+
+// swagger:parameters markCorrelationUsed
+type MarkCorrelationUsedParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"sourceUID"`
+	// in:path
+	// required:true
+	CorrelationUID string `json:"correlationUID"`
+}
+
+// swagger:route POST /datasources/correlations/analytics correlations reportCorrelationAnalytics
+//
+// Reports that the links of correlations were shown or followed.
+//
+// Events are counted asynchronously, per correlation. Following a link also records a use of the correlation.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+func (s *CorrelationsService) reportAnalyticsHandler(c *models.ReqContext) response.Response {
+	cmd := ReportCorrelationAnalyticsCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgId = c.OrgID
+
+	if !s.AccessControl.IsDisabled() {
+		readable := cmd.Events[:0]
+		for _, event := range cmd.Events {
+			canRead, err := s.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, ac.EvalPermission(ActionRead, datasources.ScopeProvider.GetResourceScopeUID(event.SourceUID)))
+			if err != nil {
+				return response.Error(http.StatusInternalServerError, "Failed to check permissions", err)
+			}
+			if canRead {
+				readable = append(readable, event)
+			}
+		}
+		cmd.Events = readable
+	}
+
+	s.ReportCorrelationAnalytics(cmd)
+	return response.Success("Correlation analytics recorded")
+}
+
+// swagger:parameters reportCorrelationAnalytics
+type ReportCorrelationAnalyticsParams struct {
+	// in:body
+	// required:true
+	Body ReportCorrelationAnalyticsCommand `json:"body"`
+}
+
+// swagger:route GET /datasources/correlations/stats correlations getCorrelationStats
+//
+// Gets how often the links of all correlations of the org were shown and followed.
+//
+// The least used correlations are listed first, including those whose links were never shown.
+//
+// Responses:
+// 200: getCorrelationStatsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *CorrelationsService) getStatsHandler(c *models.ReqContext) response.Response {
+	stats, err := s.GetCorrelationStats(c.Req.Context(), GetCorrelationStatsQuery{OrgId: c.OrgID})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get correlation stats", err)
+	}
+
+	return response.JSON(http.StatusOK, stats)
+}
+
+//swagger:response getCorrelationStatsResponse
+type GetCorrelationStatsResponse struct {
+	// in: body
+	Body []CorrelationStats `json:"body"`
+}
+
+// swagger:route POST /datasources/uid/{sourceUID}/correlations/{correlationUID}/preview correlations previewCorrelation
+//
+// Previews the target of a correlation.
+//
+// Resolves the variables of the target as if the correlation was followed from a row with the given field values.
+//
+// Responses:
+// 200: previewCorrelationResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (s *CorrelationsService) previewHandler(c *models.ReqContext) response.Response {
+	query := PreviewCorrelationQuery{}
+	if err := web.Bind(c.Req, &query); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	query.UID = web.Params(c.Req)[":correlationUID"]
+	query.SourceUID = web.Params(c.Req)[":uid"]
+	query.OrgId = c.OrgID
+
+	preview, err := s.PreviewCorrelation(c.Req.Context(), query)
+	if err != nil {
+		if errors.Is(err, ErrCorrelationNotFound) {
+			return response.Error(http.StatusNotFound, "Correlation not found", err)
+		}
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Source data source not found", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to preview correlation", err)
+	}
+
+	return response.JSON(http.StatusOK, preview)
+}
+
+// swagger:parameters previewCorrelation
+type PreviewCorrelationParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"sourceUID"`
+	// in:path
+	// required:true
+	CorrelationUID string `json:"correlationUID"`
+	// in: body
+	// required:true
+	Body PreviewCorrelationQuery `json:"body"`
+}
+
+//swagger:response previewCorrelationResponse
+type PreviewCorrelationResponse struct {
+	// in: body
+	Body CorrelationPreview `json:"body"`
+}
+
+// swagger:route POST /datasources/correlations/transformations/test correlations testTransformations
+//
+// Tests transformations.
+//
+// Applies a chain of transformations to a sample field value and returns the variables they extract, without
+// saving a correlation.
+//
+// Responses:
+// 200: testTransformationsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+func (s *CorrelationsService) testTransformationsHandler(c *models.ReqContext) response.Response {
+	query := TestTransformationsQuery{}
+	if err := web.Bind(c.Req, &query); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	return response.JSON(http.StatusOK, s.TestTransformations(query))
+}
+
+// swagger:parameters testTransformations
+type TestTransformationsParams struct {
+	// in: body
+	// required:true
+	Body TestTransformationsQuery `json:"body"`
+}
+
+//swagger:response testTransformationsResponse
+type TestTransformationsResponse struct {
+	// in: body
+	Body TestTransformationsResult `json:"body"`
+}
+
+// swagger:route GET /datasources/uid/{sourceUID}/correlations/{correlationUID}/history correlations getCorrelationHistory
+//
+// Gets the history of a correlation.
+//
+// Returns who created, updated or deleted the correlation and when, newest first. The history of a deleted
+// correlation is kept.
+//
+// Responses:
+// 200: getCorrelationHistoryResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *CorrelationsService) getCorrelationHistoryHandler(c *models.ReqContext) response.Response {
+	query := GetCorrelationHistoryQuery{
+		UID:       web.Params(c.Req)[":correlationUID"],
+		SourceUID: web.Params(c.Req)[":uid"],
+		OrgId:     c.OrgID,
+	}
+
+	history, err := s.GetCorrelationHistory(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get correlation history", err)
+	}
+
+	return response.JSON(http.StatusOK, history)
+}
+
+// swagger:parameters getCorrelationHistory
+type GetCorrelationHistoryParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"sourceUID"`
+	// in:path
+	// required:true
+	CorrelationUID string `json:"correlationUID"`
+}
+
+//swagger:response getCorrelationHistoryResponse
+type GetCorrelationHistoryResponse struct {
+	// in: body
+	Body []CorrelationHistoryEntry `json:"body"`
+}
+
+// swagger:route GET /datasources/uid/{sourceUID}/correlations correlations getCorrelationsBySourceUID
+//
+// Gets all correlations originating from the given data source.
+//
+// Responses:
+// 200: getCorrelationsBySourceUIDResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 404: notFoundError
+// 500: internalServerError
+func (s *CorrelationsService) getCorrelationsBySourceUIDHandler(c *models.ReqContext) response.Response {
+	sortBy, sortDirection, err := ParseCorrelationsSort(c.Query("sort"), c.Query("sortDirection"))
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Invalid sort", err)
+	}
+
+	query := GetCorrelationsBySourceUIDQuery{
+		SourceUID:     web.Params(c.Req)[":uid"],
+		OrgId:         c.OrgID,
+		EnabledOnly:   c.QueryBool("enabled"),
+		Tags:          c.QueryStrings("tag"),
+		OrphanedOnly:  c.QueryBool("orphaned"),
+		Query:         c.Query("query"),
+		SortBy:        sortBy,
+		SortDirection: sortDirection,
+	}
+
+	correlations, err := s.GetCorrelationsBySourceUID(c.Req.Context(), query)
+	if err != nil {
+		if errors.Is(err, ErrCorrelationNotFound) {
+			return response.Error(http.StatusNotFound, "No correlation found", err)
+		}
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Source data source not found", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to get correlations", err)
+	}
+
+	return response.JSON(http.StatusOK, newCorrelationListItems(correlations))
+}
+
+// swagger:parameters getCorrelationsBySourceUID
+type GetCorrelationsBySourceUIDParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"sourceUID"`
+	// Only return enabled correlations
+	// in:query
+	// required:false
+	Enabled bool `json:"enabled"`
+	// Only return correlations that have all of these tags
+	// in:query
+	// required:false
+	Tags []string `json:"tag"`
+	// Only return correlations whose target data source was deleted
+	// in:query
+	// required:false
+	Orphaned bool `json:"orphaned"`
+	// Only return correlations whose label, description or config, such as the target query, contains this text
+	// in:query
+	// required:false
+	Query string `json:"query"`
+	// Sort the correlations by label, or by when they were created or last updated
+	// in:query
+	// required:false
+	// enum: label,created,updated
+	Sort string `json:"sort"`
+	// Direction of the sort
+	// in:query
+	// required:false
+	// enum: asc,desc
+	// default: asc
+	SortDirection string `json:"sortDirection"`
+}
+
+//swagger:response getCorrelationsBySourceUIDResponse
+type GetCorrelationsBySourceUIDResponse struct {
+	// in: body
+	Body []CorrelationListItem `json:"body"`
+}
+
+// swagger:route GET /datasources/correlations/target/{targetUID} correlations getCorrelationsByTargetUID
+//
+// Gets all correlations targeting the given data source, from any source data source.
+//
+// These are the correlations that are deleted, or orphaned, when the data source is deleted.
+//
+// Responses:
+// 200: getCorrelationsByTargetUIDResponse
+// 401: unauthorisedError
+// 404: notFoundError
+// 500: internalServerError
+func (s *CorrelationsService) getCorrelationsByTargetUIDHandler(c *models.ReqContext) response.Response {
+	query := GetCorrelationsByTargetUIDQuery{
+		TargetUID:    web.Params(c.Req)[":uid"],
+		OrgId:        c.OrgID,
+		EnabledOnly:  c.QueryBool("enabled"),
+		SignedInUser: c.SignedInUser,
+	}
+
+	correlations, err := s.GetCorrelationsByTargetUID(c.Req.Context(), query)
+	if err != nil {
+		if errors.Is(err, ErrTargetDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Target data source not found", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to get correlations", err)
+	}
+
+	return response.JSON(http.StatusOK, newCorrelationListItems(correlations))
+}
+
+// swagger:parameters getCorrelationsByTargetUID
+type GetCorrelationsByTargetUIDParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"targetUID"`
+	// Only return enabled correlations
+	// in:query
+	// required:false
+	Enabled bool `json:"enabled"`
+}
+
+//swagger:response getCorrelationsByTargetUIDResponse
+type GetCorrelationsByTargetUIDResponse struct {
+	// in: body
+	Body []CorrelationListItem `json:"body"`
+}
+
+const (
+	// defaultCorrelationsLimit is the size of a page of correlations when no limit is requested
+	defaultCorrelationsLimit = 100
+	// maxCorrelationsLimit caps the size of a page of correlations
+	maxCorrelationsLimit = 1000
+)
+
+// swagger:route GET /datasources/correlations correlations getCorrelations
+//
+// Gets all correlations, a page at a time. Without the limit and page parameters, all correlations are returned as a
+// plain list instead, as they were before the list was paginated.
 //
 // Responses:
 // 200: getCorrelationsResponse
+// 400: badRequestError
 // 401: unauthorisedError
 // 404: notFoundError
 // 500: internalServerError
 func (s *CorrelationsService) getCorrelationsHandler(c *models.ReqContext) response.Response {
+	paged := c.Query("limit") != "" || c.Query("page") != ""
+	var limit, page int64
+	if paged {
+		limit = c.QueryInt64("limit")
+		if limit <= 0 {
+			limit = defaultCorrelationsLimit
+		} else if limit > maxCorrelationsLimit {
+			limit = maxCorrelationsLimit
+		}
+		page = c.QueryInt64("page")
+		if page < 1 {
+			page = 1
+		}
+	}
+	sortBy, sortDirection, err := ParseCorrelationsSort(c.Query("sort"), c.Query("sortDirection"))
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Invalid sort", err)
+	}
+
 	query := GetCorrelationsQuery{
-		OrgId: c.OrgID,
+		OrgId:         c.OrgID,
+		EnabledOnly:   c.QueryBool("enabled"),
+		Limit:         limit,
+		Page:          page,
+		SourceUIDs:    c.QueryStrings("sourceUID"),
+		TargetUIDs:    c.QueryStrings("targetUID"),
+		Label:         c.Query("label"),
+		Tags:          c.QueryStrings("tag"),
+		OrphanedOnly:  c.QueryBool("orphaned"),
+		Query:         c.Query("query"),
+		SortBy:        sortBy,
+		SortDirection: sortDirection,
+		SignedInUser:  c.SignedInUser,
 	}
 
-	correlations, err := s.getCorrelations(c.Req.Context(), query)
+	correlations, err := s.GetCorrelations(c.Req.Context(), query)
 	if err != nil {
 		if errors.Is(err, ErrCorrelationNotFound) {
 			return response.Error(http.StatusNotFound, "No correlation found", err)
@@ -312,11 +1190,213 @@ func (s *CorrelationsService) getCorrelationsHandler(c *models.ReqContext) respo
 		return response.Error(http.StatusInternalServerError, "Failed to get correlations", err)
 	}
 
-	return response.JSON(http.StatusOK, correlations)
+	if !paged {
+		return response.JSON(http.StatusOK, newCorrelationListItems(correlations))
+	}
+
+	totalCount, err := s.CountCorrelations(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to count correlations", err)
+	}
+
+	return response.JSON(http.StatusOK, GetCorrelationsResponseBody{
+		Correlations: newCorrelationListItems(correlations),
+		TotalCount:   totalCount,
+		Page:         page,
+		Limit:        limit,
+	})
+}
+
+// swagger:parameters getCorrelations
+type GetCorrelationsParams struct {
+	// Only return enabled correlations
+	// in:query
+	// required:false
+	Enabled bool `json:"enabled"`
+	// Maximum number of correlations to return, 100 if only the page is given
+	// in:query
+	// required:false
+	// maximum:1000
+	Limit int64 `json:"limit"`
+	// Page of correlations to return, starting at 1, the first one if only the limit is given
+	// in:query
+	// required:false
+	Page int64 `json:"page"`
+	// Only return correlations originating from one of these data sources
+	// in:query
+	// required:false
+	SourceUIDs []string `json:"sourceUID"`
+	// Only return correlations targeting one of these data sources
+	// in:query
+	// required:false
+	TargetUIDs []string `json:"targetUID"`
+	// Only return correlations whose label contains this text
+	// in:query
+	// required:false
+	Label string `json:"label"`
+	// Only return correlations that have all of these tags
+	// in:query
+	// required:false
+	Tags []string `json:"tag"`
+	// Only return correlations whose target data source was deleted
+	// in:query
+	// required:false
+	Orphaned bool `json:"orphaned"`
+	// Only return correlations whose label, description or config, such as the target query, contains this text
+	// in:query
+	// required:false
+	Query string `json:"query"`
+	// Sort the correlations by label, or by when they were created or last updated
+	// in:query
+	// required:false
+	// enum: label,created,updated
+	Sort string `json:"sort"`
+	// Direction of the sort
+	// in:query
+	// required:false
+	// enum: asc,desc
+	// default: asc
+	SortDirection string `json:"sortDirection"`
 }
 
 //swagger:response getCorrelationsResponse
 type GetCorrelationsResponse struct {
 	// in: body
-	Body []Correlation `json:"body"`
+	Body GetCorrelationsResponseBody `json:"body"`
+}
+
+// swagger:route POST /datasources/uid/{sourceUID}/correlations/{correlationUID}/duplicate correlations duplicateCorrelation
+//
+// Copies a correlation under a new UID, with "(copy)" appended to its label. The copy may originate from another
+// data source.
+//
+// Responses:
+// 200: createCorrelationResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (s *CorrelationsService) duplicateHandler(c *models.ReqContext) response.Response {
+	cmd := DuplicateCorrelationCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.UID = web.Params(c.Req)[":correlationUID"]
+	cmd.SourceUID = web.Params(c.Req)[":uid"]
+	cmd.OrgId = c.OrgID
+
+	if cmd.ToSourceUID != "" && cmd.ToSourceUID != cmd.SourceUID {
+		evaluator := ac.EvalPermission(ActionWrite, datasources.ScopeProvider.GetResourceScopeUID(cmd.ToSourceUID))
+		canWrite, err := s.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, evaluator)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to check permissions", err)
+		}
+		if !canWrite {
+			return response.Error(http.StatusForbidden, "Not allowed to add correlations to the data source", nil)
+		}
+	}
+
+	correlation, err := s.DuplicateCorrelation(c.Req.Context(), cmd)
+	if err != nil {
+		if errors.Is(err, ErrCorrelationNotFound) {
+			return response.Error(http.StatusNotFound, "Correlation not found", err)
+		}
+
+		if errors.Is(err, ErrSourceDataSourceDoesNotExists) || errors.Is(err, ErrTargetDataSourceDoesNotExists) {
+			return response.Error(http.StatusNotFound, "Data source not found", err)
+		}
+
+		if errors.Is(err, ErrTargetDashboardDoesNotExist) {
+			return response.Error(http.StatusNotFound, "Target dashboard not found", err)
+		}
+
+		if errors.Is(err, ErrSourceDataSourceReadOnly) {
+			return response.Error(http.StatusForbidden, "Data source is read only", err)
+		}
+
+		if errors.Is(err, ErrQuotaReached) {
+			return response.Error(http.StatusForbidden, "Quota reached", err)
+		}
+
+		if errors.Is(err, ErrInvalidTargetUID) {
+			return response.Error(http.StatusBadRequest, "Invalid correlation target", err)
+		}
+
+		if errors.Is(err, ErrIncompatibleTargetDataSource) {
+			return response.Error(http.StatusBadRequest, "Target query does not fit the target data source", err)
+		}
+
+		return response.Error(http.StatusInternalServerError, "Failed to duplicate correlation", err)
+	}
+
+	return response.JSON(http.StatusOK, CreateCorrelationResponseBody{Result: correlation, Message: "Correlation duplicated"})
+}
+
+// swagger:parameters duplicateCorrelation
+type DuplicateCorrelationParams struct {
+	// in:path
+	// required:true
+	DatasourceUID string `json:"sourceUID"`
+	// in:path
+	// required:true
+	CorrelationUID string `json:"correlationUID"`
+	// in:body
+	Body DuplicateCorrelationCommand `json:"body"`
+}
+
+// swagger:route GET /datasources/correlations/settings correlations getCorrelationSettings
+//
+// Gets the correlation settings of the org.
+//
+// Responses:
+// 200: correlationSettingsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *CorrelationsService) getSettingsHandler(c *models.ReqContext) response.Response {
+	settings, err := s.GetSettings(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get correlation settings", err)
+	}
+
+	return response.JSON(http.StatusOK, settings)
+}
+
+// swagger:route PUT /datasources/correlations/settings correlations updateCorrelationSettings
+//
+// Changes the correlation settings of the org.
+//
+// Responses:
+// 200: correlationSettingsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (s *CorrelationsService) updateSettingsHandler(c *models.ReqContext) response.Response {
+	cmd := UpdateCorrelationSettingsCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgId = c.OrgID
+
+	settings, err := s.UpdateSettings(c.Req.Context(), cmd)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to update correlation settings", err)
+	}
+
+	return response.JSON(http.StatusOK, settings)
+}
+
+// swagger:parameters updateCorrelationSettings
+type UpdateCorrelationSettingsParams struct {
+	// in:body
+	// required:true
+	Body UpdateCorrelationSettingsCommand `json:"body"`
+}
+
+//swagger:response correlationSettingsResponse
+type CorrelationSettingsResponse struct {
+	// in: body
+	Body CorrelationSettings `json:"body"`
 }
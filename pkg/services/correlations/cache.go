@@ -0,0 +1,99 @@
+package correlations
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+)
+
+// sourceCacheTTL is how long the correlations of a data source stay cached. Writes through this Grafana instance
+// empty the cache right away, so this only delays writes made through other instances sharing the database.
+const sourceCacheTTL = 30 * time.Second
+
+var (
+	sourceCacheHits   prometheus.Counter
+	sourceCacheMisses prometheus.Counter
+)
+
+func init() {
+	sourceCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name:      "correlations_source_cache_hits_total",
+		Help:      "Number of reads of the correlations of a data source served from the cache",
+		Namespace: "grafana",
+	})
+
+	sourceCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name:      "correlations_source_cache_misses_total",
+		Help:      "Number of reads of the correlations of a data source that missed the cache",
+		Namespace: "grafana",
+	})
+}
+
+// sourceCache caches the correlations read by source UID, which Explore asks for on every navigation. Any write to
+// correlations empties the whole cache rather than the entries it affects: writes are rare compared to reads, and a
+// single write, e.g. deleting a data source or re-syncing a template, can change the correlations of many sources.
+// Usage isn't a write in that sense, so cached correlations may report an outdated lastUsed.
+type sourceCache struct {
+	mu sync.Mutex
+	// generation counts how often the cache was emptied, to tell whether it was while correlations were loaded
+	generation uint64
+	entries    *localcache.CacheService
+}
+
+func newSourceCache(ttl time.Duration) *sourceCache {
+	return &sourceCache{entries: localcache.New(ttl, 2*ttl)}
+}
+
+// get returns the cached correlations of a query, or loads and caches them. Correlations loaded while the cache was
+// emptied aren't cached, as they may predate the write that emptied it. Callers get their own slice, since resolving
+// labels changes the correlations in it.
+func (c *sourceCache) get(query GetCorrelationsBySourceUIDQuery, load func() ([]Correlation, error)) ([]Correlation, error) {
+	if c == nil {
+		return load()
+	}
+	key := fmt.Sprintf("%#v", query)
+
+	c.mu.Lock()
+	generation := c.generation
+	cached, found := c.entries.Get(key)
+	c.mu.Unlock()
+	if found {
+		sourceCacheHits.Inc()
+		return copyCorrelations(cached.([]Correlation)), nil
+	}
+
+	sourceCacheMisses.Inc()
+	correlations, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.entries.SetDefault(key, copyCorrelations(correlations))
+	}
+	return correlations, nil
+}
+
+// invalidate empties the cache after correlations were written.
+func (c *sourceCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries.Flush()
+}
+
+func copyCorrelations(correlations []Correlation) []Correlation {
+	copied := make([]Correlation, len(correlations))
+	copy(copied, correlations)
+	return copied
+}
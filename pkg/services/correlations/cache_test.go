@@ -0,0 +1,120 @@
+package correlations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceCache(t *testing.T) {
+	query := GetCorrelationsBySourceUIDQuery{OrgId: 1, SourceUID: "loki"}
+	loader := func(loaded *int, correlations ...Correlation) func() ([]Correlation, error) {
+		return func() ([]Correlation, error) {
+			*loaded++
+			return correlations, nil
+		}
+	}
+
+	t.Run("serves repeated queries from the cache", func(t *testing.T) {
+		cache := newSourceCache(time.Minute)
+		hits, misses := testutil.ToFloat64(sourceCacheHits), testutil.ToFloat64(sourceCacheMisses)
+		loaded := 0
+
+		for i := 0; i < 3; i++ {
+			correlations, err := cache.get(query, loader(&loaded, Correlation{UID: "a", Label: "Logs"}))
+			require.NoError(t, err)
+			require.Equal(t, []Correlation{{UID: "a", Label: "Logs"}}, correlations)
+		}
+		require.Equal(t, 1, loaded)
+		require.Equal(t, hits+2, testutil.ToFloat64(sourceCacheHits))
+		require.Equal(t, misses+1, testutil.ToFloat64(sourceCacheMisses))
+	})
+
+	t.Run("caches each query on its own", func(t *testing.T) {
+		cache := newSourceCache(time.Minute)
+		loaded := 0
+
+		_, err := cache.get(query, loader(&loaded))
+		require.NoError(t, err)
+		_, err = cache.get(GetCorrelationsBySourceUIDQuery{OrgId: 1, SourceUID: "loki", EnabledOnly: true}, loader(&loaded))
+		require.NoError(t, err)
+		_, err = cache.get(GetCorrelationsBySourceUIDQuery{OrgId: 2, SourceUID: "loki"}, loader(&loaded))
+		require.NoError(t, err)
+		require.Equal(t, 3, loaded)
+	})
+
+	t.Run("callers can change the correlations they get", func(t *testing.T) {
+		cache := newSourceCache(time.Minute)
+		loaded := 0
+
+		correlations, err := cache.get(query, loader(&loaded, Correlation{UID: "a", Label: "${sourceName}"}))
+		require.NoError(t, err)
+		correlations[0].Label = "Loki"
+
+		correlations, err = cache.get(query, loader(&loaded))
+		require.NoError(t, err)
+		require.Equal(t, "${sourceName}", correlations[0].Label)
+		correlations[0].Label = "Loki"
+
+		correlations, err = cache.get(query, loader(&loaded))
+		require.NoError(t, err)
+		require.Equal(t, "${sourceName}", correlations[0].Label)
+	})
+
+	t.Run("writes empty the cache", func(t *testing.T) {
+		cache := newSourceCache(time.Minute)
+		loaded := 0
+
+		_, err := cache.get(query, loader(&loaded))
+		require.NoError(t, err)
+		cache.invalidate()
+		_, err = cache.get(query, loader(&loaded))
+		require.NoError(t, err)
+		require.Equal(t, 2, loaded)
+	})
+
+	t.Run("correlations loaded during a write aren't cached", func(t *testing.T) {
+		cache := newSourceCache(time.Minute)
+		loaded := 0
+
+		_, err := cache.get(query, func() ([]Correlation, error) {
+			loaded++
+			cache.invalidate()
+			return []Correlation{{UID: "a"}}, nil
+		})
+		require.NoError(t, err)
+		_, err = cache.get(query, loader(&loaded))
+		require.NoError(t, err)
+		require.Equal(t, 2, loaded)
+	})
+
+	t.Run("errors aren't cached", func(t *testing.T) {
+		cache := newSourceCache(time.Minute)
+		loadErr := errors.New("unavailable")
+
+		_, err := cache.get(query, func() ([]Correlation, error) {
+			return nil, loadErr
+		})
+		require.ErrorIs(t, err, loadErr)
+
+		loaded := 0
+		_, err = cache.get(query, loader(&loaded))
+		require.NoError(t, err)
+		require.Equal(t, 1, loaded)
+	})
+
+	t.Run("services without a cache always load correlations", func(t *testing.T) {
+		var cache *sourceCache
+		loaded := 0
+
+		for i := 0; i < 2; i++ {
+			_, err := cache.get(query, loader(&loaded))
+			require.NoError(t, err)
+		}
+		cache.invalidate()
+		require.Equal(t, 2, loaded)
+	})
+}
@@ -0,0 +1,88 @@
+package correlations
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"unicode/utf8"
+)
+
+type ConditionType string
+
+const (
+	// ConditionRegex matches values against a regular expression, in the JavaScript syntax the frontend uses.
+	ConditionRegex ConditionType = "regex"
+	// ConditionEquals matches values equal to the given value.
+	ConditionEquals ConditionType = "equals"
+)
+
+// CorrelationCondition restricts the rows a correlation link is shown on to those whose field value matches, so
+// that rows lacking the value the target needs don't get a dead link.
+// swagger:model
+type CorrelationCondition struct {
+	// Condition type
+	// required:true
+	// example: regex
+	Type ConditionType `json:"type"`
+	// Field whose value is matched. The correlation field if empty.
+	// example: traceID
+	Field string `json:"field,omitempty"`
+	// Regular expression the value must match for regex conditions, or the value it must equal for equals conditions
+	// example: ^[0-9a-f]{16,32}$
+	Value string `json:"value"`
+}
+
+// IsZero reports whether the condition is empty, which removes the condition of a correlation when updating it.
+func (c CorrelationCondition) IsZero() bool {
+	return c == CorrelationCondition{}
+}
+
+func (c CorrelationCondition) Validate() error {
+	if c.Field != "" {
+		if err := validateFieldName(c.Field); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidCorrelationCondition, err)
+		}
+	}
+
+	switch c.Type {
+	case ConditionEquals:
+	case ConditionRegex:
+		if c.Value == "" {
+			return fmt.Errorf("%w: regex conditions require a value", ErrInvalidCorrelationCondition)
+		}
+		if length := utf8.RuneCountInString(c.Value); length > MaxTransformationExpressionLength {
+			return fmt.Errorf("%w: %d characters, the maximum is %d", ErrInvalidCorrelationCondition, length, MaxTransformationExpressionLength)
+		}
+		// As for regex transformations, syntax Go doesn't understand is left to the frontend.
+		if re, err := syntax.Parse(toGoRegex(c.Value), syntax.Perl); err == nil && hasNestedUnboundedQuantifier(re, false) {
+			return fmt.Errorf("%w: %q", ErrTransformationRegexUnsafe, c.Value)
+		}
+	default:
+		return fmt.Errorf("%w: invalid type %q", ErrInvalidCorrelationCondition, c.Type)
+	}
+	return nil
+}
+
+// matches reports whether the link of a correlation attached to the given fields is shown on a row with the given
+// field values. Rows without the matched field never match. Regexes Go can't compile, e.g. because of lookarounds,
+// can only be evaluated by the frontend, so they are assumed to match.
+func (c CorrelationCondition) matches(fields CorrelationFields, row map[string]string) bool {
+	value, ok := row[c.Field]
+	if c.Field == "" {
+		value, ok = fields.value(row)
+	}
+	if !ok {
+		return false
+	}
+
+	switch c.Type {
+	case ConditionEquals:
+		return value == c.Value
+	case ConditionRegex:
+		re, err := compileRegex(c.Value)
+		if err != nil {
+			return true
+		}
+		return re.MatchString(value)
+	}
+	return false
+}
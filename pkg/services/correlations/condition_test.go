@@ -0,0 +1,67 @@
+package correlations
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelationCondition(t *testing.T) {
+	t.Run("Validate", func(t *testing.T) {
+		require.NoError(t, CorrelationCondition{Type: ConditionEquals, Value: ""}.Validate())
+		require.NoError(t, CorrelationCondition{Type: ConditionRegex, Field: "traceID", Value: `^[0-9a-f]+$`}.Validate())
+		// Lookarounds are left to the frontend.
+		require.NoError(t, CorrelationCondition{Type: ConditionRegex, Value: `(?<=id=)\w+`}.Validate())
+
+		require.ErrorIs(t, CorrelationCondition{Type: "contains", Value: "x"}.Validate(), ErrInvalidCorrelationCondition)
+		require.ErrorIs(t, CorrelationCondition{Type: ConditionRegex}.Validate(), ErrInvalidCorrelationCondition)
+		require.ErrorIs(t, CorrelationCondition{Type: ConditionRegex, Value: strings.Repeat("a", MaxTransformationExpressionLength+1)}.Validate(), ErrInvalidCorrelationCondition)
+		require.ErrorIs(t, CorrelationCondition{Type: ConditionRegex, Value: `(a+)+`}.Validate(), ErrTransformationRegexUnsafe)
+		require.ErrorIs(t, CorrelationCondition{Type: ConditionEquals, Field: " traceID"}.Validate(), ErrInvalidCorrelationCondition)
+	})
+
+	t.Run("Matches the value of the correlation field", func(t *testing.T) {
+		fields := CorrelationFields{"traceID", "trace_id"}
+		condition := CorrelationCondition{Type: ConditionRegex, Value: `^[0-9a-f]{8}$`}
+
+		require.True(t, condition.matches(fields, map[string]string{"trace_id": "0af7651b"}))
+		require.False(t, condition.matches(fields, map[string]string{"traceID": "-"}))
+		require.False(t, condition.matches(fields, map[string]string{"message": "0af7651b"}))
+	})
+
+	t.Run("Matches the value of another field", func(t *testing.T) {
+		condition := CorrelationCondition{Type: ConditionEquals, Field: "level", Value: "error"}
+
+		require.True(t, condition.matches(CorrelationFields{"message"}, map[string]string{"message": "failed", "level": "error"}))
+		require.False(t, condition.matches(CorrelationFields{"message"}, map[string]string{"message": "failed", "level": "info"}))
+		require.False(t, condition.matches(CorrelationFields{"message"}, map[string]string{"message": "failed"}))
+	})
+
+	t.Run("Regexes Go can't evaluate are assumed to match", func(t *testing.T) {
+		condition := CorrelationCondition{Type: ConditionRegex, Value: `(?<=id=)\w+`}
+		require.True(t, condition.matches(CorrelationFields{"message"}, map[string]string{"message": "nothing"}))
+	})
+
+	t.Run("Conditions are part of the config", func(t *testing.T) {
+		var config CorrelationConfig
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"type": "query",
+			"field": "traceID",
+			"target": {},
+			"condition": {"type": "regex", "value": "^[0-9a-f]+$"}
+		}`), &config))
+		require.Equal(t, &CorrelationCondition{Type: ConditionRegex, Value: "^[0-9a-f]+$"}, config.Condition)
+		require.NoError(t, config.Validate())
+
+		data, err := json.Marshal(config)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"condition":{"type":"regex","value":"^[0-9a-f]+$"}`)
+
+		config.Condition = nil
+		data, err = json.Marshal(config)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "condition")
+	})
+}
@@ -2,6 +2,9 @@ package correlations
 
 import (
 	"context"
+	"time"
+
+	"github.com/benbjohnson/clock"
 
 	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/bus"
@@ -11,29 +14,66 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/quota"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
-func ProvideService(sqlStore db.DB, routeRegister routing.RouteRegister, ds datasources.DataSourceService, ac accesscontrol.AccessControl, bus bus.Bus) *CorrelationsService {
+func ProvideService(sqlStore db.DB, routeRegister routing.RouteRegister, ds datasources.DataSourceService, ac accesscontrol.AccessControl, acService accesscontrol.Service, bus bus.Bus, quotaService quota.Service, cfg *setting.Cfg) (*CorrelationsService, error) {
 	s := &CorrelationsService{
 		SQLStore:          sqlStore,
 		RouteRegister:     routeRegister,
 		log:               log.New("correlations"),
 		DataSourceService: ds,
 		AccessControl:     ac,
+		QuotaService:      quotaService,
+	}
+	s.usage = newUsageTracker(clock.New(), s.writeUsage, s.log)
+	s.analytics = newAnalyticsCollector(clock.New(), s.writeAnalytics, s.log)
+	s.cache = newSourceCache(sourceCacheTTL)
+
+	if err := declareFixedRoles(acService); err != nil {
+		return nil, err
+	}
+
+	defaultLimits, err := readQuotaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := quotaService.RegisterQuotaReporter(&quota.NewUsageReporter{
+		TargetSrv:     QuotaTargetSrv,
+		DefaultLimits: defaultLimits,
+		Reporter:      s.Usage,
+	}); err != nil {
+		return nil, err
 	}
 
 	s.registerAPIEndpoints()
 
 	bus.AddEventListener(s.handleDatasourceDeletion)
 
-	return s
+	return s, nil
 }
 
 type Service interface {
 	CreateCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error)
-	DeleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) error
+	CreateCorrelations(ctx context.Context, cmds []CreateCorrelationCommand) ([]Correlation, error)
+	DeleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) (bool, error)
+	DeleteCorrelations(ctx context.Context, cmd DeleteCorrelationsCommand) (DeleteCorrelationsResult, error)
+	GetCorrelations(ctx context.Context, cmd GetCorrelationsQuery) ([]Correlation, error)
+	ExportCorrelations(ctx context.Context, query ExportCorrelationsQuery) (CorrelationsExport, error)
+	ImportCorrelations(ctx context.Context, cmd ImportCorrelationsCommand) (ImportCorrelationsResult, error)
+	ProvisionCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error)
+	DeleteStaleProvisionedCorrelations(ctx context.Context, provisioned []CorrelationKey) ([]Correlation, error)
 	DeleteCorrelationsBySourceUID(ctx context.Context, cmd DeleteCorrelationsBySourceUIDCommand) error
 	DeleteCorrelationsByTargetUID(ctx context.Context, cmd DeleteCorrelationsByTargetUIDCommand) error
+	UpdateCorrelations(ctx context.Context, cmd UpdateCorrelationsCommand) ([]UpdateCorrelationResult, error)
+	CountCorrelationsByDataSource(ctx context.Context, cmd CorrelationsCountByDataSourceQuery) ([]DataSourceCorrelationsCount, error)
+	DiffCorrelations(ctx context.Context, cmd DiffCorrelationsCommand) (CorrelationsDiff, error)
+	CreateCorrelationTemplate(ctx context.Context, cmd CreateCorrelationTemplateCommand) (CorrelationTemplate, error)
+	UpdateCorrelationTemplate(ctx context.Context, cmd UpdateCorrelationTemplateCommand) (CorrelationTemplate, error)
+	ApplyTemplate(ctx context.Context, cmd ApplyTemplateCommand) ([]Correlation, error)
+	PurgeDeletedCorrelations(ctx context.Context, deletedBefore time.Time) (int64, error)
+	ExpandCorrelationTarget(ctx context.Context, query ExpandCorrelationTargetQuery) (CorrelationPreview, error)
 }
 
 type CorrelationsService struct {
@@ -42,50 +82,402 @@ type CorrelationsService struct {
 	log               log.Logger
 	DataSourceService datasources.DataSourceService
 	AccessControl     accesscontrol.AccessControl
+	QuotaService      quota.Service
+	// StrictTargetTypeCheck rejects query correlations whose target query doesn't fit the type of the target data
+	// source, rather than only logging a warning.
+	StrictTargetTypeCheck bool
+	usage                 *usageTracker
+	analytics             *analyticsCollector
+	cache                 *sourceCache
 }
 
+// CreateCorrelation adds a correlation, unless the org has reached its correlations quota.
 func (s CorrelationsService) CreateCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error) {
+	if err := s.checkQuota(ctx, cmd.OrgId); err != nil {
+		return Correlation{}, err
+	}
+	defer s.cache.invalidate()
 	return s.createCorrelation(ctx, cmd)
 }
 
-func (s CorrelationsService) DeleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) error {
+// CreateCorrelations adds several correlations at once, e.g. when importing them. Either all of them are added or,
+// if any refers to a missing or read-only data source, or an org can't have that many more correlations, none of
+// them.
+func (s CorrelationsService) CreateCorrelations(ctx context.Context, cmds []CreateCorrelationCommand) ([]Correlation, error) {
+	counts := make(map[int64]int64)
+	orgs := make([]int64, 0)
+	for _, cmd := range cmds {
+		if _, ok := counts[cmd.OrgId]; !ok {
+			orgs = append(orgs, cmd.OrgId)
+		}
+		counts[cmd.OrgId]++
+	}
+	for _, orgID := range orgs {
+		if err := s.checkQuotaFor(ctx, orgID, counts[orgID]); err != nil {
+			return nil, err
+		}
+	}
+	defer s.cache.invalidate()
+	return s.createCorrelations(ctx, cmds)
+}
+
+// ProvisionCorrelation creates or replaces a correlation read from a provisioning file. Unlike correlations created
+// through the API, it can't be edited or deleted afterwards, other than by provisioning it again.
+func (s CorrelationsService) ProvisionCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error) {
+	defer s.cache.invalidate()
+	return s.provisionCorrelation(ctx, cmd)
+}
+
+// DeleteStaleProvisionedCorrelations deletes the provisioned correlations that aren't among the given ones, because
+// they were removed from the provisioning files, and returns them. They're deleted like correlations deleted through
+// the API, but are no longer marked as provisioned, so that they can be edited if they're restored.
+func (s CorrelationsService) DeleteStaleProvisionedCorrelations(ctx context.Context, provisioned []CorrelationKey) ([]Correlation, error) {
+	defer s.cache.invalidate()
+	return s.deleteStaleProvisionedCorrelations(ctx, provisioned)
+}
+
+// DeleteCorrelation deletes a correlation, and reports whether it was actually deleted. Deleted correlations can be
+// restored until they're purged, DeletedCorrelationsRetention after they were deleted.
+func (s CorrelationsService) DeleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) (bool, error) {
+	defer s.cache.invalidate()
 	return s.deleteCorrelation(ctx, cmd)
 }
 
+// DeleteCorrelations deletes several correlations of an org at once, reporting which UIDs matched no correlation.
+func (s CorrelationsService) DeleteCorrelations(ctx context.Context, cmd DeleteCorrelationsCommand) (DeleteCorrelationsResult, error) {
+	defer s.cache.invalidate()
+	return s.deleteCorrelations(ctx, cmd)
+}
+
 func (s CorrelationsService) UpdateCorrelation(ctx context.Context, cmd UpdateCorrelationCommand) (Correlation, error) {
+	defer s.cache.invalidate()
 	return s.updateCorrelation(ctx, cmd)
 }
 
+// UpdateCorrelations applies several updates to correlations of an org. By default, all updates are applied in a
+// single transaction, and an UpdateCorrelationsError identifies the failed update if any fails. In best-effort mode,
+// every update is applied on its own, and failures are only reported in the per-update results.
+func (s CorrelationsService) UpdateCorrelations(ctx context.Context, cmd UpdateCorrelationsCommand) ([]UpdateCorrelationResult, error) {
+	defer s.cache.invalidate()
+	return s.updateCorrelations(ctx, cmd)
+}
+
+// PatchCorrelations applies the same changes to several correlations of an org in a single transaction, and returns
+// the updated correlations.
+func (s CorrelationsService) PatchCorrelations(ctx context.Context, cmd PatchCorrelationsCommand) ([]Correlation, error) {
+	defer s.cache.invalidate()
+	return s.patchCorrelations(ctx, cmd)
+}
+
+// GetCorrelation returns a correlation, with the tokens in its label resolved.
 func (s CorrelationsService) GetCorrelation(ctx context.Context, cmd GetCorrelationQuery) (Correlation, error) {
-	return s.getCorrelation(ctx, cmd)
+	correlation, err := s.getCorrelation(ctx, cmd)
+	if err != nil {
+		return Correlation{}, err
+	}
+	correlations := []Correlation{correlation}
+	s.resolveLabels(ctx, cmd.OrgId, correlations)
+	return correlations[0], nil
+}
+
+// GetCorrelationHistory returns the changes of a correlation, newest first, including those of deleted correlations.
+func (s CorrelationsService) GetCorrelationHistory(ctx context.Context, query GetCorrelationHistoryQuery) ([]CorrelationHistoryEntry, error) {
+	return s.getCorrelationHistory(ctx, query)
+}
+
+// UndeleteCorrelation restores a deleted correlation, unless the org has reached its correlations quota.
+func (s CorrelationsService) UndeleteCorrelation(ctx context.Context, cmd UndeleteCorrelationCommand) (Correlation, error) {
+	if err := s.checkQuota(ctx, cmd.OrgId); err != nil {
+		return Correlation{}, err
+	}
+	defer s.cache.invalidate()
+	return s.undeleteCorrelation(ctx, cmd)
+}
+
+// PurgeDeletedCorrelations drops the correlations deleted before the given time for good, and returns how many were
+// purged.
+func (s CorrelationsService) PurgeDeletedCorrelations(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return s.purgeDeletedCorrelations(ctx, deletedBefore)
 }
 
+// RestoreCorrelation restores a correlation to one of its last MaxCorrelationVersions versions, as a new version.
+func (s CorrelationsService) RestoreCorrelation(ctx context.Context, cmd RestoreCorrelationCommand) (Correlation, error) {
+	defer s.cache.invalidate()
+	correlation, err := s.restoreCorrelation(ctx, cmd)
+	if err != nil {
+		return Correlation{}, err
+	}
+	s.warnUnusedVariables(ctx, cmd.OrgId, correlation)
+	return correlation, nil
+}
+
+// GetCorrelationsBySourceUID returns the correlations of a source data source, with the tokens in their labels resolved.
 func (s CorrelationsService) GetCorrelationsBySourceUID(ctx context.Context, cmd GetCorrelationsBySourceUIDQuery) ([]Correlation, error) {
-	return s.getCorrelationsBySourceUID(ctx, cmd)
+	correlations, err := s.cache.get(cmd, func() ([]Correlation, error) {
+		return s.getCorrelationsBySourceUID(ctx, cmd)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.resolveLabels(ctx, cmd.OrgId, correlations)
+	return correlations, nil
 }
 
+// GetCorrelations returns the correlations of an org, with the tokens in their labels resolved.
 func (s CorrelationsService) GetCorrelations(ctx context.Context, cmd GetCorrelationsQuery) ([]Correlation, error) {
-	return s.getCorrelations(ctx, cmd)
+	correlations, err := s.getCorrelations(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	s.resolveLabels(ctx, cmd.OrgId, correlations)
+	return correlations, nil
+}
+
+// GetCorrelationsByTargetUID returns the correlations of an org targeting a data source, with the tokens in their
+// labels resolved, e.g. to find out what deleting the data source affects.
+func (s CorrelationsService) GetCorrelationsByTargetUID(ctx context.Context, cmd GetCorrelationsByTargetUIDQuery) ([]Correlation, error) {
+	correlations, err := s.getCorrelationsByTargetUID(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	s.resolveLabels(ctx, cmd.OrgId, correlations)
+	return correlations, nil
+}
+
+// CountCorrelations returns the number of correlations of an org matching the query, across all pages.
+func (s CorrelationsService) CountCorrelations(ctx context.Context, cmd GetCorrelationsQuery) (int64, error) {
+	return s.countCorrelations(ctx, cmd)
+}
+
+// SearchCorrelationsByConfig returns the correlations of an org whose target query contains the given term, with the
+// tokens in their labels resolved.
+func (s CorrelationsService) SearchCorrelationsByConfig(ctx context.Context, cmd SearchCorrelationsByConfigQuery) ([]Correlation, error) {
+	correlations, err := s.searchCorrelationsByConfig(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	s.resolveLabels(ctx, cmd.OrgId, correlations)
+	return correlations, nil
+}
+
+func (s CorrelationsService) CountCorrelationsByDataSource(ctx context.Context, cmd CorrelationsCountByDataSourceQuery) ([]DataSourceCorrelationsCount, error) {
+	return s.countCorrelationsByDataSource(ctx, cmd)
+}
+
+// DiffCorrelations compares two sets of correlations, e.g. of a staging and a production org, and returns the
+// correlations that were added, removed or changed between them.
+func (s CorrelationsService) DiffCorrelations(ctx context.Context, cmd DiffCorrelationsCommand) (CorrelationsDiff, error) {
+	base, err := s.resolveCorrelationSet(ctx, cmd.Base)
+	if err != nil {
+		return CorrelationsDiff{}, err
+	}
+	compare, err := s.resolveCorrelationSet(ctx, cmd.Compare)
+	if err != nil {
+		return CorrelationsDiff{}, err
+	}
+	return diffCorrelations(base, compare, cmd.MatchBy)
+}
+
+func (s CorrelationsService) resolveCorrelationSet(ctx context.Context, set CorrelationSet) ([]Correlation, error) {
+	if set.Correlations != nil {
+		return set.Correlations, nil
+	}
+	return s.getCorrelations(ctx, GetCorrelationsQuery{OrgId: set.OrgId})
+}
+
+// ExportCorrelations returns all correlations of an org as a bundle that can be imported into another org or
+// instance.
+func (s CorrelationsService) ExportCorrelations(ctx context.Context, query ExportCorrelationsQuery) (CorrelationsExport, error) {
+	return s.exportCorrelations(ctx, query)
+}
+
+// ImportCorrelations creates the correlations of an export in an org. Either all of them are created, or none.
+func (s CorrelationsService) ImportCorrelations(ctx context.Context, cmd ImportCorrelationsCommand) (ImportCorrelationsResult, error) {
+	return s.importCorrelations(ctx, cmd)
+}
+
+// FindCorrelationCycles returns the navigational loops formed by the correlations of an org, e.g. a correlation
+// from data source A to B, another from B to C and a third from C back to A. Each cycle is given as the UIDs
+// of the data sources along it.
+func (s CorrelationsService) FindCorrelationCycles(ctx context.Context, orgID int64) ([][]string, error) {
+	correlations, err := s.getCorrelations(ctx, GetCorrelationsQuery{OrgId: orgID})
+	if err != nil {
+		return nil, err
+	}
+	return findCycles(correlations), nil
+}
+
+// PreviewCorrelation simulates following a correlation from a row of its source data source, and returns the
+// resulting target, as ExpandCorrelationTarget does.
+func (s CorrelationsService) PreviewCorrelation(ctx context.Context, query PreviewCorrelationQuery) (CorrelationPreview, error) {
+	correlation, err := s.getCorrelation(ctx, GetCorrelationQuery{UID: query.UID, SourceUID: query.SourceUID, OrgId: query.OrgId})
+	if err != nil {
+		return CorrelationPreview{}, err
+	}
+	return s.ExpandCorrelationTarget(ctx, ExpandCorrelationTargetQuery{
+		OrgId:       query.OrgId,
+		Correlation: correlation,
+		Fields:      query.Fields,
+		From:        query.From,
+		To:          query.To,
+	})
+}
+
+// ExpandCorrelationTarget returns the target a correlation links to when followed from a row of its source data
+// source, without the frontend. Fields of the row, variables bound by transformations, built-in variables and macros
+// are resolved, and the condition of the correlation is evaluated against the row.
+func (s CorrelationsService) ExpandCorrelationTarget(ctx context.Context, query ExpandCorrelationTargetQuery) (CorrelationPreview, error) {
+	correlation := query.Correlation
+	dsQuery := &datasources.GetDataSourceQuery{OrgId: query.OrgId, Uid: correlation.SourceUID}
+	if err := s.DataSourceService.GetDataSource(ctx, dsQuery); err != nil {
+		return CorrelationPreview{}, ErrSourceDataSourceDoesNotExists
+	}
+
+	builtIns := builtInVariables(dsQuery.Result.Uid, dsQuery.Result.Name, query.From, query.To)
+	for name, value := range macroVariables(correlation.Config, query.Fields, query.From, query.To) {
+		builtIns[name] = value
+	}
+	vars := resolveVariables(correlation.Config, query.Fields, builtIns)
+	target, unresolved := interpolateTarget(correlation.Config.Target, vars)
+	matched := correlation.Config.Condition == nil || correlation.Config.Condition.matches(correlation.Config.Field, query.Fields)
+	return CorrelationPreview{Target: target, Unresolved: unresolved, Matched: matched}, nil
+}
+
+// ValidateTargetQuery returns the problems of the target query of a correlation that are likely to make it fail
+// against the target data source. Correlations with problems can still be saved, so this is up to the caller.
+func (s CorrelationsService) ValidateTargetQuery(ctx context.Context, orgID int64, correlation Correlation) ([]TargetQueryWarning, error) {
+	return s.validateTargetQuery(ctx, orgID, correlation)
+}
+
+// TestTransformations applies a chain of transformations to a sample field value, and returns the variables they
+// bind. Later transformations overwrite the variables of earlier ones, as when a correlation is followed.
+func (s CorrelationsService) TestTransformations(query TestTransformationsQuery) TestTransformationsResult {
+	result := TestTransformationsResult{Variables: make(map[string]string), Unmatched: make([]int, 0)}
+	for i, t := range query.Transformations {
+		vars := t.extract(query.Field, query.Value)
+		if len(vars) == 0 {
+			result.Unmatched = append(result.Unmatched, i)
+			continue
+		}
+		for name, v := range vars {
+			result.Variables[name] = v
+		}
+	}
+	return result
+}
+
+func (s CorrelationsService) CreateCorrelationTemplate(ctx context.Context, cmd CreateCorrelationTemplateCommand) (CorrelationTemplate, error) {
+	return s.createCorrelationTemplate(ctx, cmd)
+}
+
+// UpdateCorrelationTemplate updates a correlation template and, if requested, all correlations materialized from it.
+func (s CorrelationsService) UpdateCorrelationTemplate(ctx context.Context, cmd UpdateCorrelationTemplateCommand) (CorrelationTemplate, error) {
+	defer s.cache.invalidate()
+	return s.updateCorrelationTemplate(ctx, cmd)
+}
+
+// ApplyTemplate materializes a correlation template for each of the given source data sources, unless the org can't
+// have as many more correlations as there are sources without a correlation of the template yet.
+func (s CorrelationsService) ApplyTemplate(ctx context.Context, cmd ApplyTemplateCommand) ([]Correlation, error) {
+	added, err := s.countUnmaterializedSources(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkQuotaFor(ctx, cmd.OrgId, added); err != nil {
+		return nil, err
+	}
+	defer s.cache.invalidate()
+	return s.applyTemplate(ctx, cmd)
+}
+
+// MarkCorrelationUsed records that a correlation was used, e.g. that its link was clicked. The use is written
+// asynchronously, and repeated uses of the same correlation within a few minutes are only recorded once.
+func (s CorrelationsService) MarkCorrelationUsed(uid, sourceUID string, orgID int64) {
+	if s.usage == nil {
+		return
+	}
+	s.usage.mark(usageKey{uid: uid, sourceUID: sourceUID, orgID: orgID})
+}
+
+// FlushCorrelationUsage writes all uses recorded by MarkCorrelationUsed that have not been written yet.
+func (s CorrelationsService) FlushCorrelationUsage(ctx context.Context) error {
+	if s.usage == nil {
+		return nil
+	}
+	return s.usage.flush(ctx)
+}
+
+// ReportCorrelationAnalytics counts what happened to the links of correlations. The counts are written
+// asynchronously. Following a link is also recorded as a use of the correlation, as MarkCorrelationUsed does.
+func (s CorrelationsService) ReportCorrelationAnalytics(cmd ReportCorrelationAnalyticsCommand) {
+	for _, event := range cmd.Events {
+		key := usageKey{uid: event.UID, sourceUID: event.SourceUID, orgID: cmd.OrgId}
+		if s.analytics != nil {
+			s.analytics.count(key, event.Type)
+		}
+		if event.Type == AnalyticsEventClicked {
+			s.MarkCorrelationUsed(event.UID, event.SourceUID, cmd.OrgId)
+		}
+	}
+}
+
+// FlushCorrelationAnalytics writes all counts of ReportCorrelationAnalytics that have not been written yet.
+func (s CorrelationsService) FlushCorrelationAnalytics(ctx context.Context) error {
+	if s.analytics == nil {
+		return nil
+	}
+	return s.analytics.flush(ctx)
+}
+
+// GetCorrelationStats lists how often the links of the correlations of an org were shown and followed.
+func (s CorrelationsService) GetCorrelationStats(ctx context.Context, query GetCorrelationStatsQuery) ([]CorrelationStats, error) {
+	return s.getCorrelationStats(ctx, query)
 }
 
 func (s CorrelationsService) DeleteCorrelationsBySourceUID(ctx context.Context, cmd DeleteCorrelationsBySourceUIDCommand) error {
+	defer s.cache.invalidate()
 	return s.deleteCorrelationsBySourceUID(ctx, cmd)
 }
 
 func (s CorrelationsService) DeleteCorrelationsByTargetUID(ctx context.Context, cmd DeleteCorrelationsByTargetUIDCommand) error {
+	defer s.cache.invalidate()
 	return s.deleteCorrelationsByTargetUID(ctx, cmd)
 }
 
+// DuplicateCorrelation copies a correlation under a new UID, labelled as a copy. The copy may originate from another
+// data source than the copied correlation.
+func (s CorrelationsService) DuplicateCorrelation(ctx context.Context, cmd DuplicateCorrelationCommand) (Correlation, error) {
+	return s.duplicateCorrelation(ctx, cmd)
+}
+
+// GetSettings returns the correlation settings of an org, or the defaults if it never changed them.
+func (s CorrelationsService) GetSettings(ctx context.Context, orgID int64) (CorrelationSettings, error) {
+	return s.getSettings(ctx, orgID)
+}
+
+// UpdateSettings changes the correlation settings of an org.
+func (s CorrelationsService) UpdateSettings(ctx context.Context, cmd UpdateCorrelationSettingsCommand) (CorrelationSettings, error) {
+	return s.updateSettings(ctx, cmd)
+}
+
 func (s CorrelationsService) handleDatasourceDeletion(ctx context.Context, event *events.DataSourceDeleted) error {
+	defer s.cache.invalidate()
 	return s.SQLStore.InTransaction(ctx, func(ctx context.Context) error {
 		if err := s.deleteCorrelationsBySourceUID(ctx, DeleteCorrelationsBySourceUIDCommand{
 			SourceUID: event.UID,
+			OrgId:     event.OrgID,
 		}); err != nil {
 			return err
 		}
 
+		settings, err := s.getSettings(ctx, event.OrgID)
+		if err != nil {
+			return err
+		}
 		if err := s.deleteCorrelationsByTargetUID(ctx, DeleteCorrelationsByTargetUIDCommand{
 			TargetUID: event.UID,
+			OrgId:     event.OrgID,
+			Orphan:    settings.OnTargetDeleted == TargetDeletionOrphan,
 		}); err != nil {
 			return err
 		}
@@ -0,0 +1,66 @@
+package correlations
+
+import "sort"
+
+// findCycles returns the cycles in the graph of data sources linked by the given correlations, where every
+// correlation is an edge from its source to its target data source. Correlations without a target are leaves.
+//
+// Each cycle is returned once, as the data source UIDs along the cycle, starting with the smallest UID.
+// A correlation pointing back at its own source data source is a cycle of one. Cycles are sorted.
+func findCycles(correlations []Correlation) [][]string {
+	edges := make(map[string]map[string]struct{})
+	for _, c := range correlations {
+		if c.TargetUID == nil {
+			continue
+		}
+		if edges[c.SourceUID] == nil {
+			edges[c.SourceUID] = make(map[string]struct{})
+		}
+		edges[c.SourceUID][*c.TargetUID] = struct{}{}
+	}
+
+	adjacency := make(map[string][]string, len(edges))
+	nodes := make([]string, 0, len(edges))
+	for source, targets := range edges {
+		nodes = append(nodes, source)
+		for target := range targets {
+			adjacency[source] = append(adjacency[source], target)
+		}
+		sort.Strings(adjacency[source])
+	}
+	sort.Strings(nodes)
+
+	cycles := make([][]string, 0)
+	for _, start := range nodes {
+		// Only visit nodes greater than start, so that every cycle is found once, from its smallest node.
+		path := []string{start}
+		onPath := map[string]bool{start: true}
+		var visit func(node string)
+		visit = func(node string) {
+			for _, next := range adjacency[node] {
+				switch {
+				case next == start:
+					cycles = append(cycles, append([]string(nil), path...))
+				case next > start && !onPath[next]:
+					path = append(path, next)
+					onPath[next] = true
+					visit(next)
+					onPath[next] = false
+					path = path[:len(path)-1]
+				}
+			}
+		}
+		visit(start)
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		a, b := cycles[i], cycles[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return cycles
+}
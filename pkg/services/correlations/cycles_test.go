@@ -0,0 +1,57 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCycles(t *testing.T) {
+	edge := func(source string, target string) Correlation {
+		return Correlation{SourceUID: source, TargetUID: &target}
+	}
+	leaf := func(source string) Correlation {
+		return Correlation{SourceUID: source}
+	}
+
+	t.Run("Returns no cycles for an acyclic graph", func(t *testing.T) {
+		cycles := findCycles([]Correlation{
+			edge("a", "b"),
+			edge("b", "c"),
+			edge("a", "c"),
+			leaf("c"),
+		})
+		require.Empty(t, cycles)
+	})
+
+	t.Run("Finds a cycle once, starting from its smallest data source", func(t *testing.T) {
+		cycles := findCycles([]Correlation{
+			edge("b", "c"),
+			edge("c", "a"),
+			edge("a", "b"),
+			edge("a", "b"),
+			leaf("b"),
+		})
+		require.Equal(t, [][]string{{"a", "b", "c"}}, cycles)
+	})
+
+	t.Run("Finds self-loops", func(t *testing.T) {
+		cycles := findCycles([]Correlation{
+			edge("a", "a"),
+			edge("a", "b"),
+		})
+		require.Equal(t, [][]string{{"a"}}, cycles)
+	})
+
+	t.Run("Finds all cycles sharing data sources", func(t *testing.T) {
+		cycles := findCycles([]Correlation{
+			edge("a", "b"),
+			edge("b", "a"),
+			edge("b", "c"),
+			edge("c", "a"),
+			edge("d", "d"),
+			edge("c", "e"),
+		})
+		require.Equal(t, [][]string{{"a", "b"}, {"a", "b", "c"}, {"d"}}, cycles)
+	})
+}
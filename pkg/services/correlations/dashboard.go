@@ -0,0 +1,86 @@
+package correlations
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// Keys of the target of dashboard correlations.
+const (
+	// DashboardTargetUID is the key of the UID of the dashboard to open.
+	DashboardTargetUID = "dashboardUID"
+	// DashboardTargetVariables is the key of the values of the dashboard's template variables, keyed by variable
+	// name. Values may reference the variables of the correlation, e.g. { "service": "${service}" }.
+	DashboardTargetVariables = "variables"
+)
+
+// validateDashboardTarget checks the target of a dashboard correlation. It must reference a dashboard by UID, and
+// every variable its variable values reference must be provided by the correlation. Whether the dashboard exists
+// is only known to the org, see verifyTargetDashboard.
+func (c CorrelationConfig) validateDashboardTarget() error {
+	uid, ok := c.Target[DashboardTargetUID].(string)
+	if !ok || uid == "" {
+		return fmt.Errorf("%w: target must contain the %q of a dashboard", ErrInvalidDashboardTarget, DashboardTargetUID)
+	}
+	if util.IsShortUIDTooLong(uid) || !util.IsValidShortUID(uid) {
+		return fmt.Errorf("%w: %q is not a valid dashboard UID", ErrInvalidDashboardTarget, uid)
+	}
+	for key := range c.Target {
+		if key != DashboardTargetUID && key != DashboardTargetVariables {
+			return fmt.Errorf("%w: unknown target key %q", ErrInvalidDashboardTarget, key)
+		}
+	}
+
+	variables, err := c.dashboardVariables()
+	if err != nil {
+		return err
+	}
+	values := make([]string, 0, len(variables))
+	for name, value := range variables {
+		if !variableNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: %q is not a valid dashboard variable name", ErrInvalidDashboardTarget, name)
+		}
+		values = append(values, value)
+	}
+	return c.validateReferencedVariables(values...)
+}
+
+// dashboardVariables returns the values of the dashboard variables set by the target of a dashboard correlation.
+func (c CorrelationConfig) dashboardVariables() (map[string]string, error) {
+	raw, ok := c.Target[DashboardTargetVariables]
+	if !ok || raw == nil {
+		return map[string]string{}, nil
+	}
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %q must be an object of variable values", ErrInvalidDashboardTarget, DashboardTargetVariables)
+	}
+	variables := make(map[string]string, len(values))
+	for name, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: the value of variable %q must be a string", ErrInvalidDashboardTarget, name)
+		}
+		variables[name] = s
+	}
+	return variables, nil
+}
+
+// verifyTargetDashboard checks that the dashboard a dashboard correlation opens exists in the org. Folders can't be
+// opened, so they don't count. Correlations of other types have no target dashboard and always pass.
+func verifyTargetDashboard(session *db.Session, orgID int64, config CorrelationConfig) error {
+	if config.Type != ConfigTypeDashboard {
+		return nil
+	}
+	uid, _ := config.Target[DashboardTargetUID].(string)
+	exists, err := session.Table("dashboard").Where("org_id = ? AND uid = ? AND is_folder = ?", orgID, uid, false).Exist()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTargetDashboardDoesNotExist, uid)
+	}
+	return nil
+}
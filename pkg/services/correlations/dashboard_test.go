@@ -0,0 +1,69 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardTarget(t *testing.T) {
+	dashboard := func(target map[string]interface{}, transformations ...Transformation) CorrelationConfig {
+		return CorrelationConfig{
+			Type:            ConfigTypeDashboard,
+			Field:           CorrelationFields{"message"},
+			Target:          target,
+			Transformations: transformations,
+		}
+	}
+	service := Transformation{Type: TransformationRegex, Expression: `service=(\w+)`, MapValue: "service"}
+
+	t.Run("accepts a dashboard with variables set from the source data", func(t *testing.T) {
+		config := dashboard(map[string]interface{}{
+			DashboardTargetUID: "7CpzLfWnz",
+			DashboardTargetVariables: map[string]interface{}{
+				"service": "${service}",
+				"env":     "production",
+				"from":    "${__from}",
+			},
+		}, service)
+		require.NoError(t, config.Validate())
+		require.NoError(t, validateTargetUID(config, nil))
+		require.Error(t, validateTargetUID(config, &service.MapValue))
+	})
+
+	t.Run("accepts a dashboard without variables", func(t *testing.T) {
+		require.NoError(t, dashboard(map[string]interface{}{DashboardTargetUID: "7CpzLfWnz"}).Validate())
+	})
+
+	t.Run("rejects invalid targets", func(t *testing.T) {
+		targets := []map[string]interface{}{
+			{},
+			{DashboardTargetUID: ""},
+			{DashboardTargetUID: "not/valid"},
+			{DashboardTargetUID: "7CpzLfWnz", "url": "https://example.com"},
+			{DashboardTargetUID: "7CpzLfWnz", DashboardTargetVariables: []interface{}{"service"}},
+			{DashboardTargetUID: "7CpzLfWnz", DashboardTargetVariables: map[string]interface{}{"service": 1}},
+			{DashboardTargetUID: "7CpzLfWnz", DashboardTargetVariables: map[string]interface{}{"var-service": "${service}"}},
+		}
+		for _, target := range targets {
+			require.ErrorIs(t, dashboard(target, service).Validate(), ErrInvalidDashboardTarget, target)
+		}
+	})
+
+	t.Run("rejects variable values referencing variables the correlation does not provide", func(t *testing.T) {
+		config := dashboard(map[string]interface{}{
+			DashboardTargetUID:       "7CpzLfWnz",
+			DashboardTargetVariables: map[string]interface{}{"service": "${svc}"},
+		}, service)
+		require.ErrorIs(t, config.Validate(), ErrUnresolvableTargetVariable)
+	})
+
+	t.Run("reports variables no variable value references", func(t *testing.T) {
+		team := Transformation{Type: TransformationSplit, Delimiter: "/", MapValue: "team"}
+		config := dashboard(map[string]interface{}{
+			DashboardTargetUID:       "7CpzLfWnz",
+			DashboardTargetVariables: map[string]interface{}{"service": "${service}"},
+		}, service, team)
+		require.Equal(t, []string{"team"}, config.unusedVariables())
+	})
+}
@@ -2,22 +2,66 @@ package correlations
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"xorm.io/xorm"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/util"
 )
 
-// createCorrelation adds a correlation
-func (s CorrelationsService) createCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error) {
+// targetExists filters correlations, read with a left join of their target data source as dst, down to those whose
+// target data source exists. Correlations without a target, such as external ones, are kept.
+const targetExists = "(correlation.target_uid IS NULL OR dst.uid IS NOT NULL)"
+
+// notDeleted filters out deleted correlations, which are kept until they're purged so that they can be restored.
+const notDeleted = "correlation.deleted_at = 0"
+
+// likeEscaper escapes the wildcards of text matched with LIKE, for conditions built by containsCond. The escape
+// character isn't a backslash, which MySQL also interprets in string literals.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// containsCond returns the condition matching the values of the column that contain the pattern returned by
+// containsPattern, ignoring case.
+func (s CorrelationsService) containsCond(column string) string {
+	return column + " " + s.SQLStore.GetDialect().LikeStr() + " ? ESCAPE '!'"
+}
+
+// containsPattern returns the pattern of containsCond matching the values that contain the text.
+func containsPattern(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
+
+// newCorrelation returns the correlation created by a command, with a generated UID unless the command sets one. It
+// is created by the signed in user of the context, if any.
+func newCorrelation(ctx context.Context, cmd CreateCorrelationCommand) Correlation {
 	correlation := Correlation{
 		UID:         util.GenerateShortUID(),
 		SourceUID:   cmd.SourceUID,
 		TargetUID:   cmd.TargetUID,
 		Label:       cmd.Label,
 		Description: cmd.Description,
+		Notes:       cmd.Notes,
 		Config:      cmd.Config,
+		Enabled:     cmd.Enabled == nil || *cmd.Enabled,
+		Version:     1,
+		Tags:        normalizeTags(cmd.Tags),
+		SourceType:  cmd.SourceType,
+		CreatedBy:   contextUserID(ctx),
 	}
+	if cmd.UID != "" {
+		correlation.UID = cmd.UID
+	}
+	correlation.Config.OpenMode = correlation.Config.OpenMode.OrDefault()
+	return correlation
+}
+
+// createCorrelation adds a correlation
+func (s CorrelationsService) createCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error) {
+	correlation := newCorrelation(ctx, cmd)
 
 	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
 		var err error
@@ -33,24 +77,241 @@ func (s CorrelationsService) createCorrelation(ctx context.Context, cmd CreateCo
 		if !cmd.SkipReadOnlyCheck && query.Result.ReadOnly {
 			return ErrSourceDataSourceReadOnly
 		}
+		if err = checkSourceType(cmd.SourceType, query.Result); err != nil {
+			return err
+		}
 
 		if cmd.TargetUID != nil {
-			if err = s.DataSourceService.GetDataSource(ctx, &datasources.GetDataSourceQuery{
+			targetQuery := &datasources.GetDataSourceQuery{
 				OrgId: cmd.OrgId,
 				Uid:   *cmd.TargetUID,
-			}); err != nil {
+			}
+			if err = s.DataSourceService.GetDataSource(ctx, targetQuery); err != nil {
 				return ErrTargetDataSourceDoesNotExists
 			}
+			if err = generateTraceTarget(&correlation.Config, targetQuery.Result.Type); err != nil {
+				return err
+			}
+			if err = s.verifyTargetType(ctx, cmd.OrgId, correlation, targetQuery.Result); err != nil {
+				return err
+			}
+		}
+		if err = verifyTargetDashboard(session, cmd.OrgId, correlation.Config); err != nil {
+			return err
+		}
+		if cmd.UID != "" {
+			if err = checkUIDConflict(session, cmd.OrgId, cmd.UID); err != nil {
+				return err
+			}
 		}
 
-		_, err = session.Insert(correlation)
+		_, err = session.Insert(&correlation)
 		if err != nil {
 			return err
 		}
+		if err = saveTags(session, correlation); err != nil {
+			return err
+		}
+		if err = saveVersion(session, cmd.OrgId, correlation); err != nil {
+			return err
+		}
 
+		return recordHistory(ctx, session, cmd.OrgId, HistoryActionCreated, nil, &correlation)
+	})
+
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	s.warnUnusedVariables(ctx, cmd.OrgId, correlation)
+	return correlation, nil
+}
+
+// checkUIDConflict returns ErrCorrelationUIDConflict if a correlation of the org already has the UID. Client-provided
+// UIDs identify a correlation within the org, whatever data source it originates from, so correlations of every data
+// source are checked, including deleted ones that weren't purged yet and could still be restored. Correlations
+// originating from the data sources in except are ignored, for callers replacing them.
+func checkUIDConflict(session *db.Session, orgID int64, uid string, except ...string) error {
+	q := session.Table("correlation").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", orgID).Where("correlation.uid = ?", uid)
+	if len(except) > 0 {
+		q = q.NotIn("correlation.source_uid", except)
+	}
+	existing, err := q.Count()
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return fmt.Errorf("%w: %s", ErrCorrelationUIDConflict, uid)
+	}
+	return nil
+}
+
+// createCorrelations adds several correlations in a single transaction. The data sources they refer to are looked up
+// once per org rather than for every correlation.
+func (s CorrelationsService) createCorrelations(ctx context.Context, cmds []CreateCorrelationCommand) ([]Correlation, error) {
+	sets := make(map[int64]dataSourceSet)
+	for _, cmd := range cmds {
+		if _, ok := sets[cmd.OrgId]; ok {
+			continue
+		}
+		set, err := s.lookupDataSources(ctx, cmd.OrgId)
+		if err != nil {
+			return nil, err
+		}
+		sets[cmd.OrgId] = set
+	}
+
+	correlations := make([]Correlation, 0, len(cmds))
+	for _, cmd := range cmds {
+		set := sets[cmd.OrgId]
+		targetUIDs := []string{}
+		if cmd.TargetUID != nil {
+			targetUIDs = append(targetUIDs, *cmd.TargetUID)
+		}
+		missing := set.missing([]string{cmd.SourceUID}, targetUIDs)
+		if err, ok := missing[cmd.SourceUID]; ok {
+			return nil, fmt.Errorf("%w: %s", err, cmd.SourceUID)
+		}
+		if cmd.TargetUID != nil {
+			if err, ok := missing[*cmd.TargetUID]; ok {
+				return nil, fmt.Errorf("%w: %s", err, *cmd.TargetUID)
+			}
+		}
+		if !cmd.SkipReadOnlyCheck && set[cmd.SourceUID].ReadOnly {
+			return nil, fmt.Errorf("%w: %s", ErrSourceDataSourceReadOnly, cmd.SourceUID)
+		}
+		if err := checkSourceType(cmd.SourceType, set[cmd.SourceUID]); err != nil {
+			return nil, err
+		}
+		correlation := newCorrelation(ctx, cmd)
+		if cmd.TargetUID != nil {
+			if err := generateTraceTarget(&correlation.Config, set[*cmd.TargetUID].Type); err != nil {
+				return nil, err
+			}
+			if err := s.verifyTargetType(ctx, cmd.OrgId, correlation, set[*cmd.TargetUID]); err != nil {
+				return nil, err
+			}
+		}
+		correlations = append(correlations, correlation)
+	}
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		for i := range correlations {
+			if err := verifyTargetDashboard(session, cmds[i].OrgId, correlations[i].Config); err != nil {
+				return err
+			}
+			// Correlations inserted earlier in the transaction are checked too, so UIDs can't repeat in the batch.
+			if cmds[i].UID != "" {
+				if err := checkUIDConflict(session, cmds[i].OrgId, cmds[i].UID); err != nil {
+					return err
+				}
+			}
+			if _, err := session.Insert(&correlations[i]); err != nil {
+				return err
+			}
+			if err := saveTags(session, correlations[i]); err != nil {
+				return err
+			}
+			if err := saveVersion(session, cmds[i].OrgId, correlations[i]); err != nil {
+				return err
+			}
+			if err := recordHistory(ctx, session, cmds[i].OrgId, HistoryActionCreated, nil, &correlations[i]); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, correlation := range correlations {
+		s.warnUnusedVariables(ctx, cmds[i].OrgId, correlation)
+	}
+	return correlations, nil
+}
+
+// provisionCorrelation creates the correlation of a provisioning file, or replaces the correlation with the same
+// UID and source created by an earlier run, so that provisioning the same file again changes nothing. Either way,
+// the correlation is marked as provisioned.
+func (s CorrelationsService) provisionCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error) {
+	if cmd.UID == "" {
+		return Correlation{}, fmt.Errorf("%w: provisioned correlations must have a UID", ErrInvalidCorrelationUID)
+	}
+	correlation := newCorrelation(ctx, cmd)
+	correlation.Provisioned = true
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		query := &datasources.GetDataSourceQuery{
+			OrgId: cmd.OrgId,
+			Uid:   cmd.SourceUID,
+		}
+		if err := s.DataSourceService.GetDataSource(ctx, query); err != nil {
+			return ErrSourceDataSourceDoesNotExists
+		}
+		if err := checkSourceType(cmd.SourceType, query.Result); err != nil {
+			return err
+		}
+
+		if cmd.TargetUID != nil {
+			targetQuery := &datasources.GetDataSourceQuery{
+				OrgId: cmd.OrgId,
+				Uid:   *cmd.TargetUID,
+			}
+			if err := s.DataSourceService.GetDataSource(ctx, targetQuery); err != nil {
+				return ErrTargetDataSourceDoesNotExists
+			}
+			if err := generateTraceTarget(&correlation.Config, targetQuery.Result.Type); err != nil {
+				return err
+			}
+			if err := s.verifyTargetType(ctx, cmd.OrgId, correlation, targetQuery.Result); err != nil {
+				return err
+			}
+		}
+		if err := verifyTargetDashboard(session, cmd.OrgId, correlation.Config); err != nil {
+			return err
+		}
+
+		// The correlation may replace one from the same source, but not take the UID of one from another.
+		if err := checkUIDConflict(session, cmd.OrgId, correlation.UID, correlation.SourceUID); err != nil {
+			return err
+		}
+		existing := Correlation{UID: correlation.UID, SourceUID: correlation.SourceUID}
+		found, err := session.Get(&existing)
+		if err != nil {
+			return err
+		}
+		if !found {
+			if _, err := session.Insert(&correlation); err != nil {
+				return err
+			}
+			if err := saveTags(session, correlation); err != nil {
+				return err
+			}
+			if err := saveVersion(session, cmd.OrgId, correlation); err != nil {
+				return err
+			}
+			return recordHistory(ctx, session, cmd.OrgId, HistoryActionCreated, nil, &correlation)
+		}
+		if err := loadCorrelationTags(session, &existing); err != nil {
+			return err
+		}
 
+		correlation.Created = existing.Created
+		correlation.CreatedBy = existing.CreatedBy
+		correlation.LastUsed = existing.LastUsed
+		correlation.Version = existing.Version + 1
+		if _, err := session.Where("uid = ? AND source_uid = ?", correlation.UID, correlation.SourceUID).AllCols().Update(&correlation); err != nil {
+			return err
+		}
+		if err := saveTags(session, correlation); err != nil {
+			return err
+		}
+		if err := saveVersion(session, cmd.OrgId, correlation); err != nil {
+			return err
+		}
+		return recordHistory(ctx, session, cmd.OrgId, HistoryActionUpdated, &existing, &correlation)
+	})
 	if err != nil {
 		return Correlation{}, err
 	}
@@ -58,8 +319,8 @@ func (s CorrelationsService) createCorrelation(ctx context.Context, cmd CreateCo
 	return correlation, nil
 }
 
-func (s CorrelationsService) deleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) error {
-	return s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+func (s CorrelationsService) deleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) (bool, error) {
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
 		query := &datasources.GetDataSourceQuery{
 			OrgId: cmd.OrgId,
 			Uid:   cmd.SourceUID,
@@ -72,12 +333,144 @@ func (s CorrelationsService) deleteCorrelation(ctx context.Context, cmd DeleteCo
 			return ErrSourceDataSourceReadOnly
 		}
 
-		deletedCount, err := session.Delete(&Correlation{UID: cmd.UID, SourceUID: cmd.SourceUID})
-		if deletedCount == 0 {
+		existing := Correlation{UID: cmd.UID, SourceUID: cmd.SourceUID}
+		found, err := session.Where(notDeleted).Get(&existing)
+		if err != nil {
+			return err
+		}
+		if !found {
 			return ErrCorrelationNotFound
 		}
-		return err
+		if existing.Provisioned {
+			return ErrCorrelationReadOnly
+		}
+		if err := loadCorrelationTags(session, &existing); err != nil {
+			return err
+		}
+
+		if err := softDelete(session, cmd.UID, cmd.SourceUID); err != nil {
+			return err
+		}
+		return recordHistory(ctx, session, cmd.OrgId, HistoryActionDeleted, &existing, nil)
 	})
+
+	if err != nil {
+		if cmd.IgnoreNotFound && (errors.Is(err, ErrCorrelationNotFound) || errors.Is(err, ErrSourceDataSourceDoesNotExists)) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s CorrelationsService) deleteStaleProvisionedCorrelations(ctx context.Context, provisioned []CorrelationKey) ([]Correlation, error) {
+	keep := make(map[CorrelationKey]struct{}, len(provisioned))
+	for _, key := range provisioned {
+		keep[key] = struct{}{}
+	}
+
+	stale := make([]Correlation, 0)
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		// Provisioning files may add correlations to any org, so the stale ones are reported in the org of their
+		// source data source.
+		found := make([]orgCorrelation, 0)
+		if err := session.Table("correlation").Select("correlation.*, dss.org_id").Join("LEFT", "data_source AS dss", "correlation.source_uid = dss.uid").
+			Where("correlation.provisioned = ?", true).And(notDeleted).Find(&found); err != nil {
+			return err
+		}
+
+		for _, f := range found {
+			if _, ok := keep[CorrelationKey{UID: f.UID, SourceUID: f.SourceUID}]; ok {
+				continue
+			}
+			correlation := f.Correlation
+			if err := loadCorrelationTags(session, &correlation); err != nil {
+				return err
+			}
+			if _, err := session.Table("correlation").Where("uid = ? AND source_uid = ?", correlation.UID, correlation.SourceUID).
+				Update(map[string]interface{}{"provisioned": false}); err != nil {
+				return err
+			}
+			if err := softDelete(session, correlation.UID, correlation.SourceUID); err != nil {
+				return err
+			}
+			if err := recordHistory(ctx, session, f.OrgID, HistoryActionDeleted, &correlation, nil); err != nil {
+				return err
+			}
+			stale = append(stale, correlation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stale, nil
+}
+
+// deleteCorrelations deletes the correlations of an org with the given UIDs in a single transaction. If any of them
+// can't be deleted, because it's provisioned or its source data source is read only, none are.
+func (s CorrelationsService) deleteCorrelations(ctx context.Context, cmd DeleteCorrelationsCommand) (DeleteCorrelationsResult, error) {
+	result := DeleteCorrelationsResult{Deleted: []string{}, NotFound: []string{}}
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		found := make([]Correlation, 0)
+		if err := session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Where(notDeleted).In("correlation.uid", cmd.UIDs).Find(&found); err != nil {
+			return err
+		}
+
+		readOnlySources := map[string]bool{}
+		for _, correlation := range found {
+			if correlation.Provisioned {
+				return fmt.Errorf("%w: %s", ErrCorrelationReadOnly, correlation.UID)
+			}
+			readOnly, ok := readOnlySources[correlation.SourceUID]
+			if !ok {
+				query := &datasources.GetDataSourceQuery{OrgId: cmd.OrgId, Uid: correlation.SourceUID}
+				if err := s.DataSourceService.GetDataSource(ctx, query); err != nil {
+					return err
+				}
+				readOnly = query.Result.ReadOnly
+				readOnlySources[correlation.SourceUID] = readOnly
+			}
+			if readOnly {
+				return fmt.Errorf("%w: %s", ErrSourceDataSourceReadOnly, correlation.SourceUID)
+			}
+		}
+
+		if err := loadTags(session, found); err != nil {
+			return err
+		}
+
+		deleted := map[string]struct{}{}
+		for i, correlation := range found {
+			if err := softDelete(session, correlation.UID, correlation.SourceUID); err != nil {
+				return err
+			}
+			if err := recordHistory(ctx, session, cmd.OrgId, HistoryActionDeleted, &found[i], nil); err != nil {
+				return err
+			}
+			deleted[correlation.UID] = struct{}{}
+		}
+
+		seen := map[string]struct{}{}
+		for _, uid := range cmd.UIDs {
+			if _, ok := seen[uid]; ok {
+				continue
+			}
+			seen[uid] = struct{}{}
+			if _, ok := deleted[uid]; ok {
+				result.Deleted = append(result.Deleted, uid)
+			} else {
+				result.NotFound = append(result.NotFound, uid)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return DeleteCorrelationsResult{}, err
+	}
+	return result, nil
 }
 
 func (s CorrelationsService) updateCorrelation(ctx context.Context, cmd UpdateCorrelationCommand) (Correlation, error) {
@@ -99,13 +492,24 @@ func (s CorrelationsService) updateCorrelation(ctx context.Context, cmd UpdateCo
 			return ErrSourceDataSourceReadOnly
 		}
 
-		found, err := session.Get(&correlation)
+		found, err := session.Where(notDeleted).Get(&correlation)
 		if !found {
 			return ErrCorrelationNotFound
 		}
 		if err != nil {
 			return err
 		}
+		if correlation.Provisioned {
+			return ErrCorrelationReadOnly
+		}
+		if cmd.Version != nil && *cmd.Version != correlation.Version {
+			return fmt.Errorf("%w: expected version %d, found %d", ErrCorrelationVersionConflict, *cmd.Version, correlation.Version)
+		}
+		s.migrateConfig(&correlation)
+		if err := loadCorrelationTags(session, &correlation); err != nil {
+			return err
+		}
+		before := correlation
 
 		if cmd.Label != nil {
 			correlation.Label = *cmd.Label
@@ -115,6 +519,22 @@ func (s CorrelationsService) updateCorrelation(ctx context.Context, cmd UpdateCo
 			correlation.Description = *cmd.Description
 			session.MustCols("description")
 		}
+		if cmd.Notes != nil {
+			correlation.Notes = *cmd.Notes
+			session.MustCols("notes")
+		}
+		if cmd.Enabled != nil {
+			correlation.Enabled = *cmd.Enabled
+			session.MustCols("enabled")
+		}
+		if cmd.Tags != nil {
+			correlation.Tags = normalizeTags(*cmd.Tags)
+		}
+		if cmd.TargetUID != nil {
+			correlation.TargetUID = cmd.TargetUID
+			correlation.Orphaned = false
+			session.MustCols("target_uid", "orphaned")
+		}
 		if cmd.Config != nil {
 			session.MustCols("config")
 			if cmd.Config.Field != nil {
@@ -126,22 +546,192 @@ func (s CorrelationsService) updateCorrelation(ctx context.Context, cmd UpdateCo
 			if cmd.Config.Target != nil {
 				correlation.Config.Target = *cmd.Config.Target
 			}
+			if cmd.Config.Transformations != nil {
+				correlation.Config.Transformations = cmd.Config.Transformations
+			}
+			if cmd.Config.Mappings != nil {
+				correlation.Config.Mappings = cmd.Config.Mappings
+			}
+			if cmd.Config.OpenMode != nil {
+				correlation.Config.OpenMode = *cmd.Config.OpenMode
+			}
+			if cmd.Config.TargetTimeoutMs != nil {
+				correlation.Config.TargetTimeoutMs = *cmd.Config.TargetTimeoutMs
+			}
+			if cmd.Config.TargetVisualization != nil {
+				correlation.Config.TargetVisualization = *cmd.Config.TargetVisualization
+			}
+			if cmd.Config.Condition != nil {
+				correlation.Config.Condition = cmd.Config.Condition
+				if cmd.Config.Condition.IsZero() {
+					correlation.Config.Condition = nil
+				}
+			}
+			if cmd.Config.Type != nil || cmd.Config.Transformations != nil {
+				if err := correlation.Config.Type.validateTransformations(correlation.Config.Transformations); err != nil {
+					return err
+				}
+			}
+			if cmd.Config.Type != nil && cmd.TargetUID == nil {
+				if err := validateTargetUID(correlation.Config, correlation.TargetUID); err != nil {
+					return err
+				}
+			}
+			if cmd.Config.Type != nil || cmd.Config.Target != nil || cmd.Config.Transformations != nil || cmd.Config.Mappings != nil || cmd.Config.Field != nil {
+				if err := correlation.Config.validateTarget(); err != nil {
+					return err
+				}
+			}
+			if cmd.Config.Type != nil || cmd.Config.Target != nil {
+				if err := verifyTargetDashboard(session, cmd.OrgId, correlation.Config); err != nil {
+					return err
+				}
+			}
+		}
+		if cmd.TargetUID != nil {
+			if err := validateTargetUID(correlation.Config, correlation.TargetUID); err != nil {
+				return err
+			}
+		}
+		// The target query of trace correlations is generated from the field as well.
+		targetChanged := cmd.TargetUID != nil || (cmd.Config != nil && (cmd.Config.Type != nil || cmd.Config.Target != nil ||
+			(cmd.Config.Field != nil && correlation.Config.Type == ConfigTypeTrace)))
+		if targetChanged && correlation.TargetUID != nil {
+			targetQuery := &datasources.GetDataSourceQuery{
+				OrgId: cmd.OrgId,
+				Uid:   *correlation.TargetUID,
+			}
+			if err := s.DataSourceService.GetDataSource(ctx, targetQuery); err != nil {
+				return ErrTargetDataSourceDoesNotExists
+			}
+			if err := generateTraceTarget(&correlation.Config, targetQuery.Result.Type); err != nil {
+				return err
+			}
+			if err := s.verifyTargetType(ctx, cmd.OrgId, correlation, targetQuery.Result); err != nil {
+				return err
+			}
 		}
 
-		updateCount, err := session.Where("uid = ? AND source_uid = ?", correlation.UID, correlation.SourceUID).Limit(1).Update(correlation)
+		correlation.Version++
+		// The version condition catches updates that were applied concurrently, since the correlation was read.
+		updateCount, err := session.Where("uid = ? AND source_uid = ? AND version = ?", correlation.UID, correlation.SourceUID, before.Version).Limit(1).Update(&correlation)
+		if err != nil {
+			return err
+		}
 		if updateCount == 0 {
-			return ErrCorrelationNotFound
+			return ErrCorrelationVersionConflict
 		}
-		return err
+		if cmd.Tags != nil {
+			if err := saveTags(session, correlation); err != nil {
+				return err
+			}
+		}
+		if err := saveVersion(session, cmd.OrgId, correlation); err != nil {
+			return err
+		}
+		return recordHistory(ctx, session, cmd.OrgId, HistoryActionUpdated, &before, &correlation)
 	})
 
 	if err != nil {
 		return Correlation{}, err
 	}
 
+	s.warnUnusedVariables(ctx, cmd.OrgId, correlation)
 	return correlation, nil
 }
 
+func (s CorrelationsService) updateCorrelations(ctx context.Context, cmd UpdateCorrelationsCommand) ([]UpdateCorrelationResult, error) {
+	results := make([]UpdateCorrelationResult, len(cmd.Updates))
+	for i := range cmd.Updates {
+		results[i].Index = i
+		cmd.Updates[i].OrgId = cmd.OrgId
+	}
+
+	if cmd.BestEffort {
+		for i, update := range cmd.Updates {
+			if err := cmd.validateUpdate(update); err != nil {
+				results[i].Err = err
+				continue
+			}
+			correlation, err := s.updateCorrelation(ctx, update)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			results[i].Correlation = &correlation
+		}
+		return results, nil
+	}
+
+	if err := cmd.Validate(); err != nil {
+		return nil, err
+	}
+	err := s.SQLStore.InTransaction(ctx, func(ctx context.Context) error {
+		for i, update := range cmd.Updates {
+			correlation, err := s.updateCorrelation(ctx, update)
+			if err != nil {
+				return UpdateCorrelationsError{Index: i, Err: err}
+			}
+			results[i].Correlation = &correlation
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// patchCorrelations applies the changes of the command to every correlation of the org with one of its UIDs, in a
+// single transaction. If any UID matches no correlation, or any update fails, none is applied.
+func (s CorrelationsService) patchCorrelations(ctx context.Context, cmd PatchCorrelationsCommand) ([]Correlation, error) {
+	patched := make([]Correlation, 0, len(cmd.UIDs))
+
+	err := s.SQLStore.InTransaction(ctx, func(ctx context.Context) error {
+		found := make([]Correlation, 0)
+		err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+			return session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Where(notDeleted).In("correlation.uid", cmd.UIDs).OrderBy("correlation.source_uid, correlation.uid").Find(&found)
+		})
+		if err != nil {
+			return err
+		}
+
+		matched := map[string]struct{}{}
+		for _, correlation := range found {
+			matched[correlation.UID] = struct{}{}
+		}
+		for _, uid := range cmd.UIDs {
+			if _, ok := matched[uid]; !ok {
+				return fmt.Errorf("%w: %s", ErrCorrelationNotFound, uid)
+			}
+		}
+
+		updates := UpdateCorrelationsCommand{OrgId: cmd.OrgId, Updates: make([]UpdateCorrelationCommand, 0, len(found))}
+		for _, correlation := range found {
+			updates.Updates = append(updates.Updates, UpdateCorrelationCommand{
+				UID:         correlation.UID,
+				SourceUID:   correlation.SourceUID,
+				OrgId:       cmd.OrgId,
+				Label:       cmd.Label,
+				Description: cmd.Description,
+				TargetUID:   cmd.TargetUID,
+			})
+		}
+		results, err := s.updateCorrelations(ctx, updates)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			patched = append(patched, *result.Correlation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
 func (s CorrelationsService) getCorrelation(ctx context.Context, cmd GetCorrelationQuery) (Correlation, error) {
 	correlation := Correlation{
 		UID:       cmd.UID,
@@ -157,17 +747,21 @@ func (s CorrelationsService) getCorrelation(ctx context.Context, cmd GetCorrelat
 			return ErrSourceDataSourceDoesNotExists
 		}
 
-		found, err := session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Join("", "data_source AS dst", "correlation.target_uid = dst.uid and dst.org_id = ?", cmd.OrgId).Where("correlation.uid = ? AND correlation.source_uid = ?", correlation.UID, correlation.SourceUID).Get(&correlation)
+		found, err := session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Join("LEFT", "data_source AS dst", "correlation.target_uid = dst.uid and dst.org_id = ?", cmd.OrgId).Where("correlation.uid = ? AND correlation.source_uid = ?", correlation.UID, correlation.SourceUID).And(targetExists).And(notDeleted).Get(&correlation)
 		if !found {
 			return ErrCorrelationNotFound
 		}
-		return err
+		if err != nil {
+			return err
+		}
+		return loadCorrelationTags(session, &correlation)
 	})
 
 	if err != nil {
 		return Correlation{}, err
 	}
 
+	s.migrateConfig(&correlation)
 	return correlation, nil
 }
 
@@ -183,13 +777,64 @@ func (s CorrelationsService) getCorrelationsBySourceUID(ctx context.Context, cmd
 			return ErrSourceDataSourceDoesNotExists
 		}
 
-		return session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Join("", "data_source AS dst", "correlation.target_uid = dst.uid and dst.org_id = ?", cmd.OrgId).Where("correlation.source_uid = ?", cmd.SourceUID).Find(&correlations)
+		q := session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Join("LEFT", "data_source AS dst", "correlation.target_uid = dst.uid and dst.org_id = ?", cmd.OrgId).Where("(correlation.source_uid = ? OR correlation.source_type = ?)", cmd.SourceUID, query.Result.Type).And(targetExists).And(notDeleted)
+		if cmd.EnabledOnly {
+			q = q.And("correlation.enabled = ?", true)
+		}
+		if cmd.OrphanedOnly {
+			q = q.And("correlation.orphaned = ?", true)
+		}
+		q = s.filterByText(filterByTags(q, cmd.Tags), cmd.Query)
+		if err := sortCorrelations(q, cmd.SortBy, cmd.SortDirection).Find(&correlations); err != nil {
+			return err
+		}
+		return loadTags(session, correlations)
 	})
 
 	if err != nil {
 		return []Correlation{}, err
 	}
 
+	for i := range correlations {
+		s.migrateConfig(&correlations[i])
+	}
+	return correlations, nil
+}
+
+// getCorrelationsByTargetUID returns the correlations of an org targeting a data source, which are deleted or orphaned
+// when the data source is deleted.
+func (s CorrelationsService) getCorrelationsByTargetUID(ctx context.Context, cmd GetCorrelationsByTargetUIDQuery) ([]Correlation, error) {
+	correlations := make([]Correlation, 0)
+
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		query := &datasources.GetDataSourceQuery{
+			OrgId: cmd.OrgId,
+			Uid:   cmd.TargetUID,
+		}
+		if err := s.DataSourceService.GetDataSource(ctx, query); err != nil {
+			return ErrTargetDataSourceDoesNotExists
+		}
+
+		q, err := s.readableBy(session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Where("correlation.target_uid = ?", cmd.TargetUID).And(notDeleted), cmd.SignedInUser)
+		if err != nil {
+			return err
+		}
+		if cmd.EnabledOnly {
+			q = q.And("correlation.enabled = ?", true)
+		}
+		if err := q.OrderBy("correlation.source_uid, correlation.uid").Find(&correlations); err != nil {
+			return err
+		}
+		return loadTags(session, correlations)
+	})
+
+	if err != nil {
+		return []Correlation{}, err
+	}
+
+	for i := range correlations {
+		s.migrateConfig(&correlations[i])
+	}
 	return correlations, nil
 }
 
@@ -197,25 +842,405 @@ func (s CorrelationsService) getCorrelations(ctx context.Context, cmd GetCorrela
 	correlations := make([]Correlation, 0)
 
 	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
-		return session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Join("", "data_source AS dst", "correlation.target_uid = dst.uid and dst.org_id = ?", cmd.OrgId).Find(&correlations)
+		q, err := s.filterCorrelations(session.Select("correlation.*"), cmd)
+		if err != nil {
+			return err
+		}
+		q = sortCorrelations(q, cmd.SortBy, cmd.SortDirection)
+		if cmd.Limit > 0 {
+			page := cmd.Page
+			if page < 1 {
+				page = 1
+			}
+			// Pages are only stable if the correlations are returned in a fixed order.
+			if cmd.SortBy == "" {
+				q = q.OrderBy("correlation.source_uid, correlation.uid")
+			}
+			q = q.Limit(int(cmd.Limit), int((page-1)*cmd.Limit))
+		}
+		if err := q.Find(&correlations); err != nil {
+			return err
+		}
+		return loadTags(session, correlations)
 	})
 	if err != nil {
 		return []Correlation{}, err
 	}
 
+	for i := range correlations {
+		s.migrateConfig(&correlations[i])
+	}
 	return correlations, nil
 }
 
+// countCorrelations returns the number of correlations matching the query, regardless of its limit and page.
+func (s CorrelationsService) countCorrelations(ctx context.Context, cmd GetCorrelationsQuery) (int64, error) {
+	var count int64
+
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		q, err := s.filterCorrelations(session.Table("correlation"), cmd)
+		if err != nil {
+			return err
+		}
+		count, err = q.Count()
+		return err
+	})
+	return count, err
+}
+
+// filterCorrelations restricts a session to the correlations of an org, as selected by the query.
+func (s CorrelationsService) filterCorrelations(session *xorm.Session, cmd GetCorrelationsQuery) (*xorm.Session, error) {
+	q, err := s.readableBy(session.Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Join("LEFT", "data_source AS dst", "correlation.target_uid = dst.uid and dst.org_id = ?", cmd.OrgId).Where(targetExists).And(notDeleted), cmd.SignedInUser)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.EnabledOnly {
+		q = q.And("correlation.enabled = ?", true)
+	}
+	if cmd.OrphanedOnly {
+		q = q.And("correlation.orphaned = ?", true)
+	}
+	if len(cmd.SourceUIDs) > 0 {
+		q = q.In("correlation.source_uid", cmd.SourceUIDs)
+	}
+	if len(cmd.TargetUIDs) > 0 {
+		q = q.In("correlation.target_uid", cmd.TargetUIDs)
+	}
+	if cmd.Label != "" {
+		q = q.And(s.containsCond("correlation.label"), containsPattern(cmd.Label))
+	}
+	return s.filterByText(filterByTags(q, cmd.Tags), cmd.Query), nil
+}
+
+// migrateConfig upgrades the config of a loaded correlation to the current schema version, if needed.
+func (s CorrelationsService) migrateConfig(correlation *Correlation) {
+	if !correlation.Config.NeedsMigration() {
+		return
+	}
+	if err := correlation.Config.Migrate(); err != nil {
+		s.log.Warn("Failed to migrate correlation config", "uid", correlation.UID, "error", err)
+	}
+}
+
+func (s CorrelationsService) countCorrelationsByDataSource(ctx context.Context, cmd CorrelationsCountByDataSourceQuery) ([]DataSourceCorrelationsCount, error) {
+	counts := make([]DataSourceCorrelationsCount, 0)
+
+	joinColumn := "source_uid"
+	if cmd.ByTarget {
+		joinColumn = "target_uid"
+	}
+
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		rawSQL := "SELECT ds.uid AS uid, COUNT(correlation.uid) AS count FROM data_source AS ds LEFT JOIN correlation ON correlation." + joinColumn + " = ds.uid AND " + notDeleted + " WHERE ds.org_id = ? GROUP BY ds.uid"
+		if !cmd.IncludeEmpty {
+			rawSQL += " HAVING COUNT(correlation.uid) > 0"
+		}
+		rawSQL += " ORDER BY count DESC, ds.uid ASC"
+
+		return session.SQL(rawSQL, cmd.OrgId).Find(&counts)
+	})
+	if err != nil {
+		return []DataSourceCorrelationsCount{}, err
+	}
+
+	return counts, nil
+}
+
 func (s CorrelationsService) deleteCorrelationsBySourceUID(ctx context.Context, cmd DeleteCorrelationsBySourceUIDCommand) error {
-	return s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		// Correlations deleted before are already in the history, and are only purged along with the others.
+		deleted := make([]Correlation, 0)
+		if err := session.Where("source_uid = ?", cmd.SourceUID).And(notDeleted).Find(&deleted); err != nil {
+			return err
+		}
+		if err := loadTags(session, deleted); err != nil {
+			return err
+		}
+		for i := range deleted {
+			if err := recordHistory(ctx, session, cmd.OrgId, HistoryActionDeleted, &deleted[i], nil); err != nil {
+				return err
+			}
+		}
+
+		if _, err := session.Where("source_uid = ?", cmd.SourceUID).Delete(&correlationTag{}); err != nil {
+			return err
+		}
+		if _, err := session.Where("source_uid = ?", cmd.SourceUID).Delete(&correlationVersion{}); err != nil {
+			return err
+		}
+		if _, err := session.Where("source_uid = ?", cmd.SourceUID).Delete(&correlationStats{}); err != nil {
+			return err
+		}
 		_, err := session.Delete(&Correlation{SourceUID: cmd.SourceUID})
 		return err
 	})
 }
 
+// orgCorrelation is a correlation along with the org of its source data source.
+type orgCorrelation struct {
+	Correlation `xorm:"extends"`
+	OrgID       int64 `xorm:"org_id"`
+}
+
 func (s CorrelationsService) deleteCorrelationsByTargetUID(ctx context.Context, cmd DeleteCorrelationsByTargetUIDCommand) error {
-	return s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+	if cmd.Orphan {
+		return s.orphanCorrelationsByTargetUID(ctx, cmd)
+	}
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		// The correlations of every org targeting the data source are deleted, so each is reported in the org of its
+		// source data source, if that still exists.
+		targeting := make([]orgCorrelation, 0)
+		if err := session.Table("correlation").Select("correlation.*, dss.org_id").Join("LEFT", "data_source AS dss", "correlation.source_uid = dss.uid").
+			Where("correlation.target_uid = ?", cmd.TargetUID).Find(&targeting); err != nil {
+			return err
+		}
+		for _, t := range targeting {
+			if t.DeletedAt == 0 {
+				orgID := t.OrgID
+				if orgID == 0 {
+					orgID = cmd.OrgId
+				}
+				correlation := t.Correlation
+				if err := loadCorrelationTags(session, &correlation); err != nil {
+					return err
+				}
+				if err := recordHistory(ctx, session, orgID, HistoryActionDeleted, &correlation, nil); err != nil {
+					return err
+				}
+			}
+			if err := deleteTags(session, t.UID, t.SourceUID); err != nil {
+				return err
+			}
+			if err := deleteVersions(session, t.UID, t.SourceUID); err != nil {
+				return err
+			}
+			if err := deleteStats(session, t.UID, t.SourceUID); err != nil {
+				return err
+			}
+		}
 		_, err := session.Delete(&Correlation{TargetUID: &cmd.TargetUID})
 		return err
 	})
 }
+
+func (s CorrelationsService) createCorrelationTemplate(ctx context.Context, cmd CreateCorrelationTemplateCommand) (CorrelationTemplate, error) {
+	template := CorrelationTemplate{
+		UID:         util.GenerateShortUID(),
+		OrgId:       cmd.OrgId,
+		TargetUID:   cmd.TargetUID,
+		Label:       cmd.Label,
+		Description: cmd.Description,
+		Config:      cmd.Config,
+	}
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		if cmd.TargetUID != nil {
+			if err := s.DataSourceService.GetDataSource(ctx, &datasources.GetDataSourceQuery{
+				OrgId: cmd.OrgId,
+				Uid:   *cmd.TargetUID,
+			}); err != nil {
+				return ErrTargetDataSourceDoesNotExists
+			}
+		}
+
+		_, err := session.Insert(template)
+		return err
+	})
+
+	if err != nil {
+		return CorrelationTemplate{}, err
+	}
+
+	return template, nil
+}
+
+func (s CorrelationsService) updateCorrelationTemplate(ctx context.Context, cmd UpdateCorrelationTemplateCommand) (CorrelationTemplate, error) {
+	template := CorrelationTemplate{}
+
+	err := s.SQLStore.InTransaction(ctx, func(ctx context.Context) error {
+		materialized := make([]Correlation, 0)
+		err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+			found, err := session.Where("uid = ? AND org_id = ?", cmd.UID, cmd.OrgId).Get(&template)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return ErrCorrelationTemplateNotFound
+			}
+
+			if cmd.Label != nil {
+				template.Label = *cmd.Label
+			}
+			if cmd.Description != nil {
+				template.Description = *cmd.Description
+			}
+			if cmd.Config != nil {
+				template.Config = *cmd.Config
+			}
+
+			if _, err := session.Where("uid = ? AND org_id = ?", cmd.UID, cmd.OrgId).MustCols("label", "description", "config").Update(template); err != nil {
+				return err
+			}
+
+			if !cmd.Resync {
+				return nil
+			}
+			return session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).
+				Where("correlation.template_uid = ?", template.UID).And(notDeleted).Find(&materialized)
+		})
+		if err != nil {
+			return err
+		}
+
+		// Re-synced correlations are updated one by one, so that they're versioned and their changes recorded like
+		// any other update. Provisioned correlations are left to their provisioning files.
+		for _, correlation := range materialized {
+			if correlation.Provisioned {
+				continue
+			}
+			if _, err := s.updateCorrelation(ctx, UpdateCorrelationCommand{
+				UID:         correlation.UID,
+				SourceUID:   correlation.SourceUID,
+				OrgId:       cmd.OrgId,
+				Label:       &template.Label,
+				Description: &template.Description,
+				Config:      template.Config.updateDTO(),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return CorrelationTemplate{}, err
+	}
+
+	return template, nil
+}
+
+func (s CorrelationsService) applyTemplate(ctx context.Context, cmd ApplyTemplateCommand) ([]Correlation, error) {
+	correlations := make([]Correlation, 0, len(cmd.SourceUIDs))
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		template := CorrelationTemplate{}
+		found, err := session.Where("uid = ? AND org_id = ?", cmd.TemplateID, cmd.OrgId).Get(&template)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrCorrelationTemplateNotFound
+		}
+
+		set, err := s.lookupDataSources(ctx, cmd.OrgId)
+		if err != nil {
+			return err
+		}
+		var targetUIDs []string
+		if template.TargetUID != nil {
+			targetUIDs = append(targetUIDs, *template.TargetUID)
+		}
+		missing := set.missing(cmd.SourceUIDs, targetUIDs)
+
+		for _, sourceUID := range cmd.SourceUIDs {
+			if err, ok := missing[sourceUID]; ok {
+				return fmt.Errorf("%w: %s", err, sourceUID)
+			}
+			if set[sourceUID].ReadOnly {
+				return fmt.Errorf("%w: %s", ErrSourceDataSourceReadOnly, sourceUID)
+			}
+			if template.TargetUID != nil {
+				if err, ok := missing[*template.TargetUID]; ok {
+					return fmt.Errorf("%w: %s", err, *template.TargetUID)
+				}
+			}
+
+			correlation := materialize(template, sourceUID)
+			existing := Correlation{}
+			found, err := session.Where("template_uid = ? AND source_uid = ?", template.UID, sourceUID).And(notDeleted).Get(&existing)
+			if err != nil {
+				return err
+			}
+			if found {
+				if err := loadCorrelationTags(session, &existing); err != nil {
+					return err
+				}
+				correlation.UID = existing.UID
+				correlation.Tags = existing.Tags
+				correlation.Enabled = existing.Enabled
+				correlation.Notes = existing.Notes
+				correlation.Created = existing.Created
+				correlation.CreatedBy = existing.CreatedBy
+				correlation.Version = existing.Version + 1
+				if _, err := session.Where("uid = ? AND source_uid = ?", existing.UID, sourceUID).MustCols("label", "description", "config").Update(&correlation); err != nil {
+					return err
+				}
+				if err := saveVersion(session, cmd.OrgId, correlation); err != nil {
+					return err
+				}
+				if err := recordHistory(ctx, session, cmd.OrgId, HistoryActionUpdated, &existing, &correlation); err != nil {
+					return err
+				}
+			} else {
+				correlation.UID = util.GenerateShortUID()
+				correlation.Enabled = true
+				correlation.Version = 1
+				correlation.CreatedBy = contextUserID(ctx)
+				correlation.Tags = []string{}
+				if _, err := session.Insert(&correlation); err != nil {
+					return err
+				}
+				if err := saveVersion(session, cmd.OrgId, correlation); err != nil {
+					return err
+				}
+				if err := recordHistory(ctx, session, cmd.OrgId, HistoryActionCreated, nil, &correlation); err != nil {
+					return err
+				}
+			}
+			correlations = append(correlations, correlation)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return correlations, nil
+}
+
+// countUnmaterializedSources counts the source data sources a template would add a correlation to, rather than
+// update the one it added before.
+func (s CorrelationsService) countUnmaterializedSources(ctx context.Context, cmd ApplyTemplateCommand) (int64, error) {
+	sourceUIDs := make(map[string]struct{}, len(cmd.SourceUIDs))
+	for _, sourceUID := range cmd.SourceUIDs {
+		sourceUIDs[sourceUID] = struct{}{}
+	}
+	if len(sourceUIDs) == 0 {
+		return 0, nil
+	}
+
+	var materialized int64
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		var err error
+		materialized, err = session.Table("correlation").Where("template_uid = ?", cmd.TemplateID).In("source_uid", cmd.SourceUIDs).And(notDeleted).Count()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(sourceUIDs)) - materialized, nil
+}
+
+// materialize returns the correlation defined by a template for the given source data source.
+func materialize(template CorrelationTemplate, sourceUID string) Correlation {
+	templateUID := template.UID
+	return Correlation{
+		SourceUID:   sourceUID,
+		TargetUID:   template.TargetUID,
+		Label:       template.Label,
+		Description: template.Description,
+		Config:      template.Config,
+		TemplateUID: &templateUID,
+	}
+}
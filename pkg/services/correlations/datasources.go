@@ -0,0 +1,52 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// dataSourceSet holds the data sources of an org, keyed by UID.
+type dataSourceSet map[string]*datasources.DataSource
+
+// lookupDataSources fetches all data sources of an org with a single query, so that the data sources referenced by
+// several correlations can be checked without looking each of them up.
+func (s CorrelationsService) lookupDataSources(ctx context.Context, orgID int64) (dataSourceSet, error) {
+	query := &datasources.GetDataSourcesQuery{OrgId: orgID}
+	if err := s.DataSourceService.GetDataSources(ctx, query); err != nil {
+		return nil, err
+	}
+	set := make(dataSourceSet, len(query.Result))
+	for _, ds := range query.Result {
+		set[ds.Uid] = ds
+	}
+	return set, nil
+}
+
+// missing returns the source and target UIDs that are not in the set, mapped to ErrSourceDataSourceDoesNotExists
+// or ErrTargetDataSourceDoesNotExists respectively. A UID missing both as a source and as a target is reported as
+// a missing source.
+func (d dataSourceSet) missing(sourceUIDs, targetUIDs []string) map[string]error {
+	missing := make(map[string]error)
+	for _, uid := range targetUIDs {
+		if _, ok := d[uid]; !ok {
+			missing[uid] = ErrTargetDataSourceDoesNotExists
+		}
+	}
+	for _, uid := range sourceUIDs {
+		if _, ok := d[uid]; !ok {
+			missing[uid] = ErrSourceDataSourceDoesNotExists
+		}
+	}
+	return missing
+}
+
+// checkSourceType checks that the source type of a correlation, if it has one, is the type of its source data
+// source. Correlations can only be made to apply to every data source of a type through one of these data sources.
+func checkSourceType(sourceType string, source *datasources.DataSource) error {
+	if sourceType == "" || sourceType == source.Type {
+		return nil
+	}
+	return fmt.Errorf("%w: %q, the source data source is of type %q", ErrInvalidSourceType, sourceType, source.Type)
+}
@@ -0,0 +1,46 @@
+package correlations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+)
+
+func TestLookupDataSources(t *testing.T) {
+	s := CorrelationsService{
+		DataSourceService: &fakeDatasources.FakeDataSourceService{
+			DataSources: []*datasources.DataSource{
+				{Uid: "loki", OrgId: 1},
+				{Uid: "tempo", OrgId: 1},
+				{Uid: "prometheus", OrgId: 2},
+			},
+		},
+	}
+
+	set, err := s.lookupDataSources(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, set, 2)
+
+	t.Run("reports nothing if all data sources exist", func(t *testing.T) {
+		require.Empty(t, set.missing([]string{"loki", "tempo"}, []string{"tempo"}))
+	})
+
+	t.Run("reports missing sources and targets per UID", func(t *testing.T) {
+		missing := set.missing([]string{"loki", "elastic"}, []string{"tempo", "jaeger", "prometheus"})
+		require.Len(t, missing, 3)
+		require.ErrorIs(t, missing["elastic"], ErrSourceDataSourceDoesNotExists)
+		require.ErrorIs(t, missing["jaeger"], ErrTargetDataSourceDoesNotExists)
+		// data sources of other orgs don't count
+		require.ErrorIs(t, missing["prometheus"], ErrTargetDataSourceDoesNotExists)
+	})
+
+	t.Run("reports UIDs missing as both source and target as missing sources", func(t *testing.T) {
+		missing := set.missing([]string{"elastic"}, []string{"elastic"})
+		require.Len(t, missing, 1)
+		require.ErrorIs(t, missing["elastic"], ErrSourceDataSourceDoesNotExists)
+	})
+}
@@ -0,0 +1,108 @@
+package correlations
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// DeletedCorrelationsRetention is how long deleted correlations are kept, and can be restored, before they're purged.
+const DeletedCorrelationsRetention = 30 * 24 * time.Hour
+
+// UndeleteCorrelationCommand is the command to restore a deleted correlation
+type UndeleteCorrelationCommand struct {
+	UID       string `json:"-"`
+	SourceUID string `json:"-"`
+	OrgId     int64  `json:"-"`
+}
+
+// softDelete marks a correlation as deleted. Its tags, versions and stats are kept, so that it can be restored as it
+// was, until it's purged.
+func softDelete(session *db.Session, uid, sourceUID string) error {
+	_, err := session.Table("correlation").Where("uid = ? AND source_uid = ?", uid, sourceUID).
+		Update(map[string]interface{}{"deleted_at": time.Now().Unix()})
+	return err
+}
+
+// undeleteCorrelation restores a deleted correlation that wasn't purged yet. Like any other change, this makes a new
+// version of the correlation. It is recorded in its history as restored, and published as the correlation being
+// created again.
+func (s CorrelationsService) undeleteCorrelation(ctx context.Context, cmd UndeleteCorrelationCommand) (Correlation, error) {
+	correlation := Correlation{UID: cmd.UID, SourceUID: cmd.SourceUID}
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		query := &datasources.GetDataSourceQuery{OrgId: cmd.OrgId, Uid: cmd.SourceUID}
+		if err := s.DataSourceService.GetDataSource(ctx, query); err != nil {
+			return ErrSourceDataSourceDoesNotExists
+		}
+		if query.Result.ReadOnly {
+			return ErrSourceDataSourceReadOnly
+		}
+
+		found, err := session.Where("deleted_at <> 0").Get(&correlation)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrCorrelationNotFound
+		}
+		if correlation.TargetUID != nil {
+			targetQuery := &datasources.GetDataSourceQuery{OrgId: cmd.OrgId, Uid: *correlation.TargetUID}
+			if err := s.DataSourceService.GetDataSource(ctx, targetQuery); err != nil {
+				return ErrTargetDataSourceDoesNotExists
+			}
+		}
+		s.migrateConfig(&correlation)
+		if err := loadCorrelationTags(session, &correlation); err != nil {
+			return err
+		}
+
+		correlation.DeletedAt = 0
+		correlation.Version++
+		if _, err := session.Where("uid = ? AND source_uid = ?", correlation.UID, correlation.SourceUID).
+			AllCols().Update(&correlation); err != nil {
+			return err
+		}
+		if err := saveVersion(session, cmd.OrgId, correlation); err != nil {
+			return err
+		}
+		return recordHistory(ctx, session, cmd.OrgId, HistoryActionRestored, nil, &correlation)
+	})
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	return correlation, nil
+}
+
+// purgeDeletedCorrelations drops the correlations deleted before the given time, along with their tags, versions and
+// stats, and returns how many were purged. Their history is kept.
+func (s CorrelationsService) purgeDeletedCorrelations(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		expired := make([]Correlation, 0)
+		if err := session.Cols("uid", "source_uid").Where("deleted_at <> 0 AND deleted_at < ?", deletedBefore.Unix()).Find(&expired); err != nil {
+			return err
+		}
+		for _, correlation := range expired {
+			if err := deleteTags(session, correlation.UID, correlation.SourceUID); err != nil {
+				return err
+			}
+			if err := deleteVersions(session, correlation.UID, correlation.SourceUID); err != nil {
+				return err
+			}
+			if err := deleteStats(session, correlation.UID, correlation.SourceUID); err != nil {
+				return err
+			}
+			if _, err := session.Delete(&Correlation{UID: correlation.UID, SourceUID: correlation.SourceUID}); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(expired))
+		return nil
+	})
+	return purged, err
+}
@@ -0,0 +1,135 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// diffCorrelations returns the differences between the base and compare sets of correlations, matching
+// correlations by the given key. Correlations are returned in the order of the set they were taken from.
+func diffCorrelations(base, compare []Correlation, matchBy DiffMatchKey) (CorrelationsDiff, error) {
+	if matchBy == "" {
+		matchBy = DiffMatchByUID
+	}
+	var key func(Correlation) string
+	switch matchBy {
+	case DiffMatchByUID:
+		key = func(c Correlation) string { return c.UID }
+	case DiffMatchBySourceAndLabel:
+		key = func(c Correlation) string { return c.SourceUID + "/" + c.Label }
+	default:
+		return CorrelationsDiff{}, fmt.Errorf("%w: %s", ErrInvalidDiffMatchKey, matchBy)
+	}
+
+	// Correlations are not guaranteed to have unique labels, so every key maps to the indices of all
+	// correlations sharing it. These are paired up in order.
+	unmatched := make(map[string][]int, len(compare))
+	for i, c := range compare {
+		k := key(c)
+		unmatched[k] = append(unmatched[k], i)
+	}
+
+	diff := CorrelationsDiff{
+		Added:   make([]Correlation, 0),
+		Removed: make([]Correlation, 0),
+		Changed: make([]CorrelationChange, 0),
+	}
+	matched := make([]bool, len(compare))
+	for _, b := range base {
+		k := key(b)
+		candidates := unmatched[k]
+		if len(candidates) == 0 {
+			diff.Removed = append(diff.Removed, b)
+			continue
+		}
+		unmatched[k] = candidates[1:]
+		c := compare[candidates[0]]
+		matched[candidates[0]] = true
+
+		fields, err := correlationDifferences(b, c, matchBy)
+		if err != nil {
+			return CorrelationsDiff{}, err
+		}
+		if len(fields) > 0 {
+			diff.Changed = append(diff.Changed, CorrelationChange{Base: b, Compare: c, Fields: fields})
+		}
+	}
+	for i, c := range compare {
+		if !matched[i] {
+			diff.Added = append(diff.Added, c)
+		}
+	}
+
+	return diff, nil
+}
+
+// correlationDifferences returns the names of the properties that differ between two correlations. Properties
+// used as match key are never reported.
+func correlationDifferences(a, b Correlation, matchBy DiffMatchKey) ([]string, error) {
+	var fields []string
+	if matchBy != DiffMatchByUID && a.UID != b.UID {
+		fields = append(fields, "uid")
+	}
+	if matchBy != DiffMatchBySourceAndLabel {
+		if a.SourceUID != b.SourceUID {
+			fields = append(fields, "sourceUID")
+		}
+		if a.Label != b.Label {
+			fields = append(fields, "label")
+		}
+	}
+	if !reflect.DeepEqual(a.TargetUID, b.TargetUID) {
+		fields = append(fields, "targetUID")
+	}
+	if a.Description != b.Description {
+		fields = append(fields, "description")
+	}
+	if a.Config.Type != b.Config.Type {
+		fields = append(fields, "config.type")
+	}
+	if !reflect.DeepEqual(a.Config.Field, b.Config.Field) {
+		fields = append(fields, "config.field")
+	}
+
+	// Targets are compared by their JSON representation, as the same query may be represented with
+	// different Go types depending on where it was read from. Missing and empty values are equivalent.
+	if len(a.Config.Target) > 0 || len(b.Config.Target) > 0 {
+		equal, err := jsonEqual(a.Config.Target, b.Config.Target)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			fields = append(fields, "config.target")
+		}
+	}
+	if len(a.Config.Transformations) > 0 || len(b.Config.Transformations) > 0 {
+		equal, err := jsonEqual(a.Config.Transformations, b.Config.Transformations)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			fields = append(fields, "config.transformations")
+		}
+	}
+	if (len(a.Config.Mappings) > 0 || len(b.Config.Mappings) > 0) && !reflect.DeepEqual(a.Config.Mappings, b.Config.Mappings) {
+		fields = append(fields, "config.mappings")
+	}
+	if !reflect.DeepEqual(a.Config.Condition, b.Config.Condition) {
+		fields = append(fields, "config.condition")
+	}
+
+	return fields, nil
+}
+
+func jsonEqual(a, b interface{}) (bool, error) {
+	encodedA, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	encodedB, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return string(encodedA) == string(encodedB), nil
+}
@@ -0,0 +1,103 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffCorrelations(t *testing.T) {
+	target := "target-uid"
+	otherTarget := "other-target-uid"
+	correlation := func(uid, source, label string) Correlation {
+		return Correlation{
+			UID:       uid,
+			SourceUID: source,
+			TargetUID: &target,
+			Label:     label,
+			Config: CorrelationConfig{
+				Type:   ConfigTypeQuery,
+				Field:  CorrelationFields{"message"},
+				Target: map[string]interface{}{"expr": "foo", "limit": 10},
+			},
+		}
+	}
+
+	t.Run("returns additions and removals", func(t *testing.T) {
+		base := []Correlation{correlation("a", "ds", "A"), correlation("b", "ds", "B")}
+		compare := []Correlation{correlation("b", "ds", "B"), correlation("c", "ds", "C")}
+
+		diff, err := diffCorrelations(base, compare, DiffMatchByUID)
+		require.NoError(t, err)
+		require.Equal(t, []Correlation{compare[1]}, diff.Added)
+		require.Equal(t, []Correlation{base[0]}, diff.Removed)
+		require.Empty(t, diff.Changed)
+	})
+
+	t.Run("returns changed fields of matching correlations", func(t *testing.T) {
+		changed := correlation("a", "ds", "new label")
+		changed.TargetUID = &otherTarget
+		changed.Config.Target = map[string]interface{}{"expr": "bar", "limit": 10}
+		changed.Config.Transformations = Transformations{{Type: TransformationLogfmt}}
+
+		diff, err := diffCorrelations([]Correlation{correlation("a", "ds", "A")}, []Correlation{changed}, "")
+		require.NoError(t, err)
+		require.Empty(t, diff.Added)
+		require.Empty(t, diff.Removed)
+		require.Len(t, diff.Changed, 1)
+		require.Equal(t, []string{"label", "targetUID", "config.target", "config.transformations"}, diff.Changed[0].Fields)
+	})
+
+	t.Run("detects transformation differences", func(t *testing.T) {
+		a := correlation("a", "ds", "A")
+		a.Config.Transformations = Transformations{{Type: TransformationRegex, Expression: `id=(\w+)`, MapValue: "id"}}
+		b := correlation("a", "ds", "A")
+		b.Config.Transformations = Transformations{{Type: TransformationRegex, Expression: `id=(\d+)`, MapValue: "id"}}
+
+		diff, err := diffCorrelations([]Correlation{a}, []Correlation{b}, DiffMatchByUID)
+		require.NoError(t, err)
+		require.Len(t, diff.Changed, 1)
+		require.Equal(t, []string{"config.transformations"}, diff.Changed[0].Fields)
+	})
+
+	t.Run("detects condition differences", func(t *testing.T) {
+		changed := correlation("a", "ds", "A")
+		changed.Config.Condition = &CorrelationCondition{Type: ConditionEquals, Field: "level", Value: "error"}
+
+		diff, err := diffCorrelations([]Correlation{correlation("a", "ds", "A")}, []Correlation{changed}, DiffMatchByUID)
+		require.NoError(t, err)
+		require.Len(t, diff.Changed, 1)
+		require.Equal(t, []string{"config.condition"}, diff.Changed[0].Fields)
+	})
+
+	t.Run("equivalent targets are not reported", func(t *testing.T) {
+		a := correlation("a", "ds", "A")
+		b := correlation("a", "ds", "A")
+		b.Config.Target = map[string]interface{}{"limit": float64(10), "expr": "foo"}
+		c := correlation("b", "ds", "B")
+		c.Config.Target = nil
+		d := correlation("b", "ds", "B")
+		d.Config.Target = map[string]interface{}{}
+
+		diff, err := diffCorrelations([]Correlation{a, c}, []Correlation{b, d}, DiffMatchByUID)
+		require.NoError(t, err)
+		require.Empty(t, diff.Changed)
+	})
+
+	t.Run("matches by source and label", func(t *testing.T) {
+		base := []Correlation{correlation("staging-uid", "ds", "A")}
+		compare := []Correlation{correlation("production-uid", "ds", "A")}
+
+		diff, err := diffCorrelations(base, compare, DiffMatchBySourceAndLabel)
+		require.NoError(t, err)
+		require.Empty(t, diff.Added)
+		require.Empty(t, diff.Removed)
+		require.Len(t, diff.Changed, 1)
+		require.Equal(t, []string{"uid"}, diff.Changed[0].Fields)
+	})
+
+	t.Run("fails on unknown match key", func(t *testing.T) {
+		_, err := diffCorrelations(nil, nil, "target")
+		require.ErrorIs(t, err, ErrInvalidDiffMatchKey)
+	})
+}
@@ -0,0 +1,56 @@
+package correlations
+
+import (
+	"context"
+	"strings"
+)
+
+// duplicateLabelSuffix is appended to the label of a correlation to label its copy.
+const duplicateLabelSuffix = "(copy)"
+
+// DuplicateCorrelationCommand is the command to copy a correlation, possibly to another source data source
+// swagger:model
+type DuplicateCorrelationCommand struct {
+	// UID of the correlation to copy
+	UID       string `json:"-"`
+	SourceUID string `json:"-"`
+	OrgId     int64  `json:"-"`
+	// Optional UID of the data source the copy originates from, defaults to the source of the copied correlation
+	// example: uyBf2637k
+	ToSourceUID string `json:"sourceUID"`
+}
+
+// duplicateCorrelation copies a correlation under a new UID. The copy is a new correlation: it starts at its first
+// version, is never provisioned and isn't linked to the template of the copied correlation. Otherwise it's created
+// like any other correlation, so the copy is checked against its source data source, including the quota.
+func (s CorrelationsService) duplicateCorrelation(ctx context.Context, cmd DuplicateCorrelationCommand) (Correlation, error) {
+	original, err := s.getCorrelation(ctx, GetCorrelationQuery{UID: cmd.UID, SourceUID: cmd.SourceUID, OrgId: cmd.OrgId})
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	sourceUID := cmd.ToSourceUID
+	if sourceUID == "" {
+		sourceUID = original.SourceUID
+	}
+	enabled := original.Enabled
+	create := CreateCorrelationCommand{
+		SourceUID:   sourceUID,
+		OrgId:       cmd.OrgId,
+		TargetUID:   original.TargetUID,
+		Label:       strings.TrimSpace(original.Label + " " + duplicateLabelSuffix),
+		Description: original.Description,
+		Notes:       original.Notes,
+		Config:      original.Config,
+		Enabled:     &enabled,
+		Tags:        original.Tags,
+	}
+	// Copies to other data sources only apply to these data sources.
+	if sourceUID == original.SourceUID {
+		create.SourceType = original.SourceType
+	}
+	if err := create.Validate(); err != nil {
+		return Correlation{}, err
+	}
+	return s.CreateCorrelation(ctx, create)
+}
@@ -0,0 +1,49 @@
+package correlations
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// publishChange publishes the event of a change to a correlation once the transaction making it is committed, so that
+// other services only learn about changes that were kept. before is nil for created correlations and after for
+// deleted ones. Sessions without a transaction never publish their events.
+func publishChange(session *db.Session, orgID int64, before, after *Correlation) {
+	now := time.Now()
+	switch {
+	case before == nil && after != nil:
+		session.PublishAfterCommit(&events.CorrelationCreated{
+			Timestamp: now,
+			UID:       after.UID,
+			SourceUID: after.SourceUID,
+			TargetUID: targetUIDOf(*after),
+			OrgID:     orgID,
+		})
+	case before != nil && after != nil:
+		session.PublishAfterCommit(&events.CorrelationUpdated{
+			Timestamp: now,
+			UID:       after.UID,
+			SourceUID: after.SourceUID,
+			TargetUID: targetUIDOf(*after),
+			OrgID:     orgID,
+		})
+	case before != nil:
+		session.PublishAfterCommit(&events.CorrelationDeleted{
+			Timestamp: now,
+			UID:       before.UID,
+			SourceUID: before.SourceUID,
+			TargetUID: targetUIDOf(*before),
+			OrgID:     orgID,
+		})
+	}
+}
+
+// targetUIDOf returns the UID of the target data source of a correlation, or an empty string if it has none.
+func targetUIDOf(correlation Correlation) string {
+	if correlation.TargetUID == nil {
+		return ""
+	}
+	return *correlation.TargetUID
+}
@@ -0,0 +1,255 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// CorrelationsExportVersion is the version of the format of correlation exports.
+const CorrelationsExportVersion = 1
+
+// ExportedDataSource identifies a data source in a correlations export. UIDs usually differ between instances, so
+// data sources are identified by name.
+// swagger:model
+type ExportedDataSource struct {
+	// Name of the data source
+	// example: Loki
+	Name string `json:"name"`
+	// Type of the data source, for reference when mapping it to a data source of another instance
+	// example: loki
+	Type string `json:"type"`
+}
+
+// ExportedCorrelation is a correlation of a correlations export
+// swagger:model
+type ExportedCorrelation struct {
+	// UID of the correlation in the exporting org
+	// example: 50xhMlg9k
+	UID string `json:"uid"`
+	// Data source the correlation originates from
+	Source ExportedDataSource `json:"source"`
+	// Data source the correlation points to, unset for external correlations
+	Target *ExportedDataSource `json:"target,omitempty"`
+	// example: My Label
+	Label string `json:"label"`
+	// example: Logs to Traces
+	Description string            `json:"description"`
+	Notes       string            `json:"notes"`
+	Config      CorrelationConfig `json:"config"`
+	// example: true
+	Enabled bool `json:"enabled"`
+	// example: ["payments"]
+	Tags []string `json:"tags,omitempty"`
+}
+
+// CorrelationsExport is a portable bundle of the correlations of an org
+// swagger:model
+type CorrelationsExport struct {
+	// Version of the format of the export
+	// example: 1
+	Version int `json:"version"`
+	// The exported correlations, sorted by the name of their source data source and UID
+	Correlations []ExportedCorrelation `json:"correlations"`
+}
+
+// ImportUIDMode controls which UIDs imported correlations get.
+type ImportUIDMode string
+
+const (
+	// ImportUIDsKeep keeps the UIDs of the export. Importing fails if one of them is taken.
+	ImportUIDsKeep ImportUIDMode = "keep"
+	// ImportUIDsGenerate generates new UIDs, e.g. to import the same export into an org more than once.
+	ImportUIDsGenerate ImportUIDMode = "generate"
+)
+
+// Validate accepts the known modes. An empty mode is accepted as well and means ImportUIDsKeep.
+func (m ImportUIDMode) Validate() error {
+	switch m {
+	case "", ImportUIDsKeep, ImportUIDsGenerate:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidImportUIDMode, m)
+}
+
+// ExportCorrelationsQuery is the query to export all correlations of an org
+type ExportCorrelationsQuery struct {
+	OrgId int64 `json:"-"`
+}
+
+// ImportCorrelationsCommand is the command to import the correlations of an export into an org
+// swagger:model
+type ImportCorrelationsCommand struct {
+	OrgId int64 `json:"-"`
+	// The export to import
+	// required:true
+	Bundle CorrelationsExport `json:"bundle"`
+	// UIDs of the data sources of the org to use for the data sources of the export, keyed by their name in the
+	// export. Data sources that aren't mapped are matched by name.
+	// example: {"Loki (staging)": "P8E80F9AEF21F6940"}
+	DataSources map[string]string `json:"dataSources"`
+	// Whether the UIDs of the export are kept, or new ones generated. Defaults to keep.
+	// example: generate
+	UIDs ImportUIDMode `json:"uids"`
+}
+
+func (c ImportCorrelationsCommand) Validate() error {
+	if c.Bundle.Version != CorrelationsExportVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedExportVersion, c.Bundle.Version)
+	}
+	if len(c.Bundle.Correlations) == 0 {
+		return ErrNoCorrelationsToImport
+	}
+	if err := c.UIDs.Validate(); err != nil {
+		return err
+	}
+
+	seen := make(map[[2]string]struct{}, len(c.Bundle.Correlations))
+	for i, exported := range c.Bundle.Correlations {
+		// Data sources are only resolved when importing, their names stand in for the UIDs here.
+		if err := exported.createCommand(exported.Source.Name, exported.targetName()).Validate(); err != nil {
+			return fmt.Errorf("correlation %d: %w", i, err)
+		}
+		if c.UIDs == ImportUIDsGenerate {
+			continue
+		}
+		if err := ValidateCorrelationUID(exported.UID); err != nil {
+			return fmt.Errorf("correlation %d: %w", i, err)
+		}
+		key := [2]string{exported.UID, exported.Source.Name}
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("correlation %d: %w: %s", i, ErrCorrelationUIDConflict, exported.UID)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+// ImportCorrelationsResult is the result of an ImportCorrelationsCommand
+// swagger:model
+type ImportCorrelationsResult struct {
+	// The imported correlations
+	Correlations []Correlation `json:"correlations"`
+}
+
+func (e ExportedCorrelation) targetName() *string {
+	if e.Target == nil {
+		return nil
+	}
+	return &e.Target.Name
+}
+
+// createCommand returns the command creating the exported correlation from the given data sources.
+func (e ExportedCorrelation) createCommand(sourceUID string, targetUID *string) CreateCorrelationCommand {
+	enabled := e.Enabled
+	return CreateCorrelationCommand{
+		SourceUID:   sourceUID,
+		TargetUID:   targetUID,
+		UID:         e.UID,
+		Label:       e.Label,
+		Description: e.Description,
+		Notes:       e.Notes,
+		Config:      e.Config,
+		Enabled:     &enabled,
+		Tags:        e.Tags,
+	}
+}
+
+// exportCorrelations returns all correlations of an org, with the UIDs of their data sources resolved to names.
+// Whether a correlation was provisioned or materialized from a template is specific to the org, and not exported.
+func (s CorrelationsService) exportCorrelations(ctx context.Context, query ExportCorrelationsQuery) (CorrelationsExport, error) {
+	correlations, err := s.getCorrelations(ctx, GetCorrelationsQuery{OrgId: query.OrgId})
+	if err != nil {
+		return CorrelationsExport{}, err
+	}
+	set, err := s.lookupDataSources(ctx, query.OrgId)
+	if err != nil {
+		return CorrelationsExport{}, err
+	}
+
+	export := CorrelationsExport{Version: CorrelationsExportVersion, Correlations: make([]ExportedCorrelation, 0, len(correlations))}
+	for _, correlation := range correlations {
+		source, ok := set[correlation.SourceUID]
+		if !ok {
+			continue
+		}
+		exported := ExportedCorrelation{
+			UID:         correlation.UID,
+			Source:      ExportedDataSource{Name: source.Name, Type: source.Type},
+			Label:       correlation.Label,
+			Description: correlation.Description,
+			Notes:       correlation.Notes,
+			Config:      correlation.Config,
+			Enabled:     correlation.Enabled,
+			Tags:        correlation.Tags,
+		}
+		if correlation.TargetUID != nil {
+			target, ok := set[*correlation.TargetUID]
+			if !ok {
+				continue
+			}
+			exported.Target = &ExportedDataSource{Name: target.Name, Type: target.Type}
+		}
+		export.Correlations = append(export.Correlations, exported)
+	}
+
+	sort.Slice(export.Correlations, func(i, j int) bool {
+		a, b := export.Correlations[i], export.Correlations[j]
+		if a.Source.Name != b.Source.Name {
+			return a.Source.Name < b.Source.Name
+		}
+		return a.UID < b.UID
+	})
+	return export, nil
+}
+
+// importCorrelations creates the correlations of an export in an org, in a single transaction. Data sources are
+// resolved by the mapping of the command, or else by name. Kept UIDs conflict with those of any correlation of the
+// org, including deleted ones and those imported before them, which createCorrelations checks.
+func (s CorrelationsService) importCorrelations(ctx context.Context, cmd ImportCorrelationsCommand) (ImportCorrelationsResult, error) {
+	set, err := s.lookupDataSources(ctx, cmd.OrgId)
+	if err != nil {
+		return ImportCorrelationsResult{}, err
+	}
+	byName := make(map[string]string, len(set))
+	for uid, ds := range set {
+		byName[ds.Name] = uid
+	}
+	resolve := func(name string) (string, bool) {
+		if uid, ok := cmd.DataSources[name]; ok {
+			_, exists := set[uid]
+			return uid, exists
+		}
+		uid, ok := byName[name]
+		return uid, ok
+	}
+
+	cmds := make([]CreateCorrelationCommand, 0, len(cmd.Bundle.Correlations))
+	for _, exported := range cmd.Bundle.Correlations {
+		sourceUID, ok := resolve(exported.Source.Name)
+		if !ok {
+			return ImportCorrelationsResult{}, fmt.Errorf("%w: %s", ErrSourceDataSourceDoesNotExists, exported.Source.Name)
+		}
+		var targetUID *string
+		if exported.Target != nil {
+			uid, ok := resolve(exported.Target.Name)
+			if !ok {
+				return ImportCorrelationsResult{}, fmt.Errorf("%w: %s", ErrTargetDataSourceDoesNotExists, exported.Target.Name)
+			}
+			targetUID = &uid
+		}
+
+		create := exported.createCommand(sourceUID, targetUID)
+		create.OrgId = cmd.OrgId
+		if cmd.UIDs == ImportUIDsGenerate {
+			create.UID = ""
+		}
+		cmds = append(cmds, create)
+	}
+
+	correlations, err := s.CreateCorrelations(ctx, cmds)
+	if err != nil {
+		return ImportCorrelationsResult{}, err
+	}
+	return ImportCorrelationsResult{Correlations: correlations}, nil
+}
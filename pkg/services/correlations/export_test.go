@@ -0,0 +1,80 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportCorrelationsCommand(t *testing.T) {
+	exported := func(uid, source string) ExportedCorrelation {
+		return ExportedCorrelation{
+			UID:    uid,
+			Source: ExportedDataSource{Name: source, Type: "loki"},
+			Target: &ExportedDataSource{Name: "Tempo", Type: "tempo"},
+			Config: CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Target: map[string]interface{}{}},
+		}
+	}
+	bundle := func(correlations ...ExportedCorrelation) CorrelationsExport {
+		return CorrelationsExport{Version: CorrelationsExportVersion, Correlations: correlations}
+	}
+
+	testCases := []struct {
+		name string
+		cmd  ImportCorrelationsCommand
+		err  error
+	}{
+		{
+			name: "valid",
+			cmd:  ImportCorrelationsCommand{Bundle: bundle(exported("a", "Loki"), exported("a", "Loki (logs)"))},
+		},
+		{
+			name: "unsupported version",
+			cmd:  ImportCorrelationsCommand{Bundle: CorrelationsExport{Version: 2, Correlations: []ExportedCorrelation{exported("a", "Loki")}}},
+			err:  ErrUnsupportedExportVersion,
+		},
+		{
+			name: "no correlations",
+			cmd:  ImportCorrelationsCommand{Bundle: bundle()},
+			err:  ErrNoCorrelationsToImport,
+		},
+		{
+			name: "unknown UID mode",
+			cmd:  ImportCorrelationsCommand{Bundle: bundle(exported("a", "Loki")), UIDs: "reuse"},
+			err:  ErrInvalidImportUIDMode,
+		},
+		{
+			name: "duplicate UIDs of a source",
+			cmd:  ImportCorrelationsCommand{Bundle: bundle(exported("a", "Loki"), exported("a", "Loki"))},
+			err:  ErrCorrelationUIDConflict,
+		},
+		{
+			name: "duplicate UIDs are replaced when generating UIDs",
+			cmd:  ImportCorrelationsCommand{Bundle: bundle(exported("a", "Loki"), exported("a", "Loki")), UIDs: ImportUIDsGenerate},
+		},
+		{
+			name: "missing UID",
+			cmd:  ImportCorrelationsCommand{Bundle: bundle(exported("", "Loki"))},
+			err:  ErrInvalidCorrelationUID,
+		},
+		{
+			name: "query correlation without target",
+			cmd: ImportCorrelationsCommand{Bundle: bundle(func() ExportedCorrelation {
+				c := exported("a", "Loki")
+				c.Target = nil
+				return c
+			}())},
+			err: ErrInvalidTargetUID,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cmd.Validate()
+			if tc.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
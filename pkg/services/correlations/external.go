@@ -0,0 +1,26 @@
+package correlations
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// validateExternalTarget checks the URL template of an external correlation. It must be an absolute http or https
+// URL once its variables are interpolated, and every variable it references must be provided by the correlation.
+func (c CorrelationConfig) validateExternalTarget() error {
+	raw, ok := c.Target[ExternalTargetURL].(string)
+	if !ok || raw == "" || len(c.Target) != 1 {
+		return fmt.Errorf("%w: target must only contain the %q URL template", ErrInvalidExternalTarget, ExternalTargetURL)
+	}
+
+	// The variables may expand to anything, but a placeholder keeps the scheme and host of the template intact.
+	u, err := url.Parse(variablePattern.ReplaceAllString(raw, "x"))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidExternalTarget, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %q is not an absolute http or https URL", ErrInvalidExternalTarget, raw)
+	}
+
+	return c.validateReferencedVariables(raw)
+}
@@ -0,0 +1,84 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MaxCorrelationFields is the maximum number of fields a correlation can be attached to.
+const MaxCorrelationFields = 10
+
+// CorrelationFields are the names of the fields a correlation link is attached to, so that a single correlation
+// covers fields holding the same value under different names, e.g. traceID and trace_id. Correlations used to be
+// attached to a single field, written as a string, so a single field is still written as a string and either form
+// is read.
+type CorrelationFields []string
+
+func (f CorrelationFields) MarshalJSON() ([]byte, error) {
+	switch len(f) {
+	case 0:
+		return json.Marshal("")
+	case 1:
+		return json.Marshal(f[0])
+	}
+	return json.Marshal([]string(f))
+}
+
+func (f *CorrelationFields) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*f = nil
+		if name != "" {
+			*f = CorrelationFields{name}
+		}
+		return nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("%w: field must be a field name or a list of field names", ErrInvalidCorrelationField)
+	}
+	*f = names
+	return nil
+}
+
+// Validate checks that there is at least one field, that every field name is valid and that no field is listed
+// twice.
+func (f CorrelationFields) Validate() error {
+	if len(f) == 0 {
+		return validateFieldName("")
+	}
+	if len(f) > MaxCorrelationFields {
+		return fmt.Errorf("%w: %d fields, the maximum is %d", ErrInvalidCorrelationField, len(f), MaxCorrelationFields)
+	}
+	seen := make(map[string]struct{}, len(f))
+	for _, name := range f {
+		if err := validateFieldName(name); err != nil {
+			return err
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("%w: field %q is listed twice", ErrInvalidCorrelationField, name)
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
+// Primary returns the first field. Variables bound after the correlation field, e.g. by regex transformations
+// without mapValue, are named after it.
+func (f CorrelationFields) Primary() string {
+	if len(f) == 0 {
+		return ""
+	}
+	return f[0]
+}
+
+// value returns the value of the first of the fields that the given row has.
+func (f CorrelationFields) value(row map[string]string) (string, bool) {
+	for _, name := range f {
+		if value, ok := row[name]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,60 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelationFields(t *testing.T) {
+	t.Run("A single field is written as a string", func(t *testing.T) {
+		data, err := json.Marshal(CorrelationFields{"traceID"})
+		require.NoError(t, err)
+		require.JSONEq(t, `"traceID"`, string(data))
+
+		data, err = json.Marshal(CorrelationFields{"traceID", "trace_id"})
+		require.NoError(t, err)
+		require.JSONEq(t, `["traceID", "trace_id"]`, string(data))
+	})
+
+	t.Run("Fields are read from a string or a list", func(t *testing.T) {
+		var fields CorrelationFields
+		require.NoError(t, json.Unmarshal([]byte(`"traceID"`), &fields))
+		require.Equal(t, CorrelationFields{"traceID"}, fields)
+
+		require.NoError(t, json.Unmarshal([]byte(`["traceID", "trace_id"]`), &fields))
+		require.Equal(t, CorrelationFields{"traceID", "trace_id"}, fields)
+
+		require.NoError(t, json.Unmarshal([]byte(`""`), &fields))
+		require.Empty(t, fields)
+
+		require.ErrorIs(t, json.Unmarshal([]byte(`{"name": "traceID"}`), &fields), ErrInvalidCorrelationField)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		require.NoError(t, CorrelationFields{"traceID", "trace_id"}.Validate())
+		require.ErrorIs(t, CorrelationFields{}.Validate(), ErrInvalidCorrelationField)
+		require.ErrorIs(t, CorrelationFields{"traceID", ""}.Validate(), ErrInvalidCorrelationField)
+		require.ErrorIs(t, CorrelationFields{"traceID", "traceID"}.Validate(), ErrInvalidCorrelationField)
+
+		tooMany := make(CorrelationFields, MaxCorrelationFields+1)
+		for i := range tooMany {
+			tooMany[i] = fmt.Sprintf("field%d", i)
+		}
+		require.ErrorIs(t, tooMany.Validate(), ErrInvalidCorrelationField)
+	})
+
+	t.Run("Values are read from the first field the row has", func(t *testing.T) {
+		fields := CorrelationFields{"traceID", "trace_id"}
+		require.Equal(t, "traceID", fields.Primary())
+
+		value, ok := fields.value(map[string]string{"trace_id": "abc", "other": "def"})
+		require.True(t, ok)
+		require.Equal(t, "abc", value)
+
+		_, ok = fields.value(map[string]string{"other": "def"})
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,167 @@
+package correlations
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// grokPatterns is the library of patterns grok transformations can reference, a curated subset of the Logstash
+// patterns for common log formats such as syslog and Apache access logs. Patterns only use non-capturing groups, so
+// that the fields named in a grok expression are the only capturing groups of the compiled regex.
+var grokPatterns = map[string]string{
+	"USERNAME":   `[a-zA-Z0-9._-]+`,
+	"USER":       `%{USERNAME}`,
+	"INT":        `(?:[+-]?(?:[0-9]+))`,
+	"BASE10NUM":  `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":     `(?:%{BASE10NUM})`,
+	"POSINT":     `\b(?:[1-9][0-9]*)\b`,
+	"NONNEGINT":  `\b(?:[0-9]+)\b`,
+	"WORD":       `\b\w+\b`,
+	"NOTSPACE":   `\S+`,
+	"SPACE":      `\s*`,
+	"DATA":       `.*?`,
+	"GREEDYDATA": `.*`,
+	"QS":         `"(?:[^"\\]|\\.)*"`,
+	"UUID":       `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+
+	"IPV4":     `(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])`,
+	"IPV6":     `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":       `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME": `\b(?:[0-9A-Za-z][0-9A-Za-z-]{0,62})(?:\.(?:[0-9A-Za-z][0-9A-Za-z-]{0,62}))*\.?`,
+	"IPORHOST": `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT": `%{IPORHOST}:%{POSINT}`,
+
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":     `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+
+	"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]un(?:e)?|[Jj]ul(?:y)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9])`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `(?:[0-5][0-9])`,
+	"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?)`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+
+	"LOGLEVEL": `(?:[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo|INFO|[Ww]arn?(?:ing)?|WARN?(?:ING)?|[Ee]rr?(?:or)?|ERR?(?:OR)?|[Cc]rit?(?:ical)?|CRIT?(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|EMERG(?:ENCY)?|[Ee]merg(?:ency)?)`,
+
+	"PROG":       `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"SYSLOGPROG": `%{PROG:program}(?:\[%{POSINT:pid}\])?`,
+	"SYSLOGHOST": `%{IPORHOST}`,
+	"SYSLOGBASE": `%{SYSLOGTIMESTAMP:timestamp} (?:%{SYSLOGHOST:logsource} )?%{SYSLOGPROG}:`,
+	"SYSLOGLINE": `%{SYSLOGBASE} %{GREEDYDATA:message}`,
+
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+}
+
+// grokReferencePattern matches a reference to a library pattern, %{NAME}, optionally naming the field its match is
+// bound to, %{NAME:field}. Logstash also allows a type, %{NAME:field:int}, which is accepted, but ignored: variables
+// are strings.
+var grokReferencePattern = regexp.MustCompile(`%\{(\w+)(?::([\w.@-]+))?(?::\w+)?\}`)
+
+// maxGrokDepth limits how deeply patterns may reference each other, which also stops reference cycles.
+const maxGrokDepth = 16
+
+// grokExpression is a compiled grok expression. The capturing groups of its regex match the fields, in order.
+type grokExpression struct {
+	re     *regexp.Regexp
+	fields []string
+}
+
+// compileGrok compiles a grok expression, a regex that may reference the library patterns, into a regex whose
+// capturing groups are the fields the expression names. Regex syntax Go doesn't support is an error, as the value
+// couldn't be extracted by the server.
+func compileGrok(expr string) (*grokExpression, error) {
+	g := &grokExpression{}
+	expanded, err := g.expand(expr, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.fields) == 0 {
+		return nil, fmt.Errorf("%w: %q names no field, e.g. %%{IP:client}", ErrInvalidGrokPattern, expr)
+	}
+	if g.re, err = regexp.Compile(expanded); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGrokPattern, err)
+	}
+	if g.re.NumSubexp() != len(g.fields) {
+		return nil, fmt.Errorf("%w: %q has capturing groups outside of fields, use (?:...) for grouping", ErrInvalidGrokPattern, expr)
+	}
+	return g, nil
+}
+
+func (g *grokExpression) expand(pattern string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("%w: patterns are nested more than %d levels deep", ErrInvalidGrokPattern, maxGrokDepth)
+	}
+
+	var expanded strings.Builder
+	last := 0
+	for _, match := range grokReferencePattern.FindAllStringSubmatchIndex(pattern, -1) {
+		expanded.WriteString(pattern[last:match[0]])
+		last = match[1]
+
+		name := pattern[match[2]:match[3]]
+		library, ok := grokPatterns[name]
+		if !ok {
+			return "", fmt.Errorf("%w: unknown pattern %q", ErrInvalidGrokPattern, name)
+		}
+		if match[4] >= 0 {
+			g.fields = append(g.fields, pattern[match[4]:match[5]])
+			expanded.WriteString("(")
+		} else {
+			expanded.WriteString("(?:")
+		}
+		sub, err := g.expand(library, depth+1)
+		if err != nil {
+			return "", err
+		}
+		expanded.WriteString(sub)
+		expanded.WriteString(")")
+	}
+	expanded.WriteString(pattern[last:])
+	return expanded.String(), nil
+}
+
+// Grok returns the values of the fields a grok transformation extracts from value, keyed by field name. Fields that
+// are not part of the match, e.g. in an optional part of the expression, are left out. It reports false if the
+// expression doesn't match.
+func (t Transformation) Grok(value string) (map[string]string, bool) {
+	g, err := compileGrok(t.Expression)
+	if err != nil {
+		return nil, false
+	}
+	match := g.re.FindStringSubmatchIndex(value)
+	if match == nil {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(g.fields))
+	for i, field := range g.fields {
+		start, end := match[2*(i+1)], match[2*(i+1)+1]
+		if start < 0 {
+			continue
+		}
+		values[field] = value[start:end]
+	}
+	return values, true
+}
+
+// grokFields returns the names of the fields a grok transformation binds. Other transformations, and grok
+// transformations with an invalid expression, bind none.
+func (t Transformation) grokFields() []string {
+	if t.Type != TransformationGrok {
+		return nil
+	}
+	g, err := compileGrok(t.Expression)
+	if err != nil {
+		return nil
+	}
+	return g.fields
+}
@@ -0,0 +1,106 @@
+package correlations
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrok(t *testing.T) {
+	t.Run("Compiles every library pattern", func(t *testing.T) {
+		for name := range grokPatterns {
+			_, err := compileGrok("%{" + name + ":value}")
+			require.NoError(t, err, name)
+		}
+	})
+
+	t.Run("Rejects invalid expressions", func(t *testing.T) {
+		for _, expr := range []string{
+			"%{IP}",                  // names no field
+			"%{NOPE:value}",          // unknown pattern
+			"(%{IP:client})",         // capturing group outside of fields
+			"%{IP:client} (?<=x)",    // syntax Go doesn't support
+			"%{IP:client} [unclosed", // invalid regex
+		} {
+			_, err := compileGrok(expr)
+			require.ErrorIs(t, err, ErrInvalidGrokPattern, expr)
+		}
+	})
+
+	t.Run("Extracts fields of Apache access logs", func(t *testing.T) {
+		line := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?x=1 HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`
+		values, ok := Transformation{Type: TransformationGrok, Expression: "%{COMBINEDAPACHELOG}"}.Grok(line)
+		require.True(t, ok)
+		require.Equal(t, "127.0.0.1", values["clientip"])
+		require.Equal(t, "frank", values["auth"])
+		require.Equal(t, "10/Oct/2000:13:55:36 -0700", values["timestamp"])
+		require.Equal(t, "GET", values["verb"])
+		require.Equal(t, "/apache_pb.gif?x=1", values["request"])
+		require.Equal(t, "200", values["response"])
+		require.Equal(t, `"Mozilla/4.08"`, values["agent"])
+		require.NotContains(t, values, "rawrequest")
+	})
+
+	t.Run("Extracts fields of syslog lines", func(t *testing.T) {
+		values, ok := Transformation{Type: TransformationGrok, Expression: "%{SYSLOGLINE}"}.Grok("Mar  7 04:02:16 web-1 sshd[4242]: Accepted publickey for deploy")
+		require.True(t, ok)
+		require.Equal(t, map[string]string{
+			"timestamp": "Mar  7 04:02:16",
+			"logsource": "web-1",
+			"program":   "sshd",
+			"pid":       "4242",
+			"message":   "Accepted publickey for deploy",
+		}, values)
+	})
+
+	t.Run("Ignores the type of a field", func(t *testing.T) {
+		values, ok := Transformation{Type: TransformationGrok, Expression: `took %{NUMBER:duration:float}ms`}.Grok("request took 12.5ms")
+		require.True(t, ok)
+		require.Equal(t, map[string]string{"duration": "12.5"}, values)
+	})
+
+	t.Run("Binds nothing if the expression doesn't match", func(t *testing.T) {
+		_, ok := Transformation{Type: TransformationGrok, Expression: "%{IPV4:client}"}.Grok("no address here")
+		require.False(t, ok)
+	})
+
+	t.Run("Binds fields in previews and for external correlations", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeExternal,
+			Field:           CorrelationFields{"line"},
+			Target:          map[string]interface{}{ExternalTargetURL: "https://hosts.example.com/${client}/${status}"},
+			Transformations: Transformations{{Type: TransformationGrok, Expression: `%{IP:client} %{WORD:method} %{INT:status}`}},
+		}
+		require.NoError(t, config.Validate())
+
+		target, unresolved := interpolateTarget(config.Target, resolveVariables(config, map[string]string{"line": "10.0.0.1 GET 404"}, nil))
+		require.Empty(t, unresolved)
+		require.Equal(t, "https://hosts.example.com/10.0.0.1/404", target[ExternalTargetURL])
+
+		config.Target = map[string]interface{}{ExternalTargetURL: "https://hosts.example.com/${host}"}
+		require.ErrorIs(t, config.Validate(), ErrUnresolvableTargetVariable)
+	})
+
+	t.Run("Round-trips through the config", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeQuery,
+			Field:           CorrelationFields{"line"},
+			Target:          map[string]interface{}{},
+			Transformations: Transformations{{Type: TransformationGrok, Expression: "%{IP:client}"}},
+		}
+		encoded, err := json.Marshal(config)
+		require.NoError(t, err)
+
+		var decoded CorrelationConfig
+		require.NoError(t, json.Unmarshal(encoded, &decoded))
+		require.Equal(t, config.Transformations, decoded.Transformations)
+		require.Equal(t, KindMapped, Correlation{Config: decoded}.Kind())
+	})
+
+	t.Run("Validates grok transformations", func(t *testing.T) {
+		require.ErrorIs(t, Transformations{{Type: TransformationGrok}}.Validate(), ErrTransformationGrokReqExp)
+		require.ErrorIs(t, Transformations{{Type: TransformationGrok, Expression: "%{IP}"}}.Validate(), ErrInvalidGrokPattern)
+		require.NoError(t, Transformations{{Type: TransformationGrok, Expression: "%{IP:client}"}}.Validate())
+	})
+}
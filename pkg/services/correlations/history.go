@@ -0,0 +1,163 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/appcontext"
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// CorrelationHistoryAction is the kind of change recorded in the history of a correlation.
+type CorrelationHistoryAction string
+
+const (
+	HistoryActionCreated CorrelationHistoryAction = "created"
+	HistoryActionUpdated CorrelationHistoryAction = "updated"
+	HistoryActionDeleted CorrelationHistoryAction = "deleted"
+	// HistoryActionRestored is the restoration of a deleted correlation.
+	HistoryActionRestored CorrelationHistoryAction = "restored"
+)
+
+// contextUserID returns the ID of the signed in user of the context, or 0 if there is none.
+func contextUserID(ctx context.Context) int64 {
+	if u, err := appcontext.User(ctx); err == nil {
+		return u.UserID
+	}
+	return 0
+}
+
+// correlationHistoryRecord is a row of the correlation_history table. The correlation before and after the change
+// are stored as JSON, and are empty if the correlation didn't exist.
+type correlationHistoryRecord struct {
+	ID             int64                    `xorm:"pk autoincr 'id'"`
+	OrgID          int64                    `xorm:"org_id"`
+	CorrelationUID string                   `xorm:"correlation_uid"`
+	SourceUID      string                   `xorm:"source_uid"`
+	Action         CorrelationHistoryAction `xorm:"action"`
+	UserID         int64                    `xorm:"user_id"`
+	Created        int64                    `xorm:"created"`
+	OldCorrelation string                   `xorm:"old_correlation"`
+	NewCorrelation string                   `xorm:"new_correlation"`
+}
+
+func (r correlationHistoryRecord) TableName() string {
+	return "correlation_history"
+}
+
+// CorrelationHistoryEntry is a change of a correlation
+// swagger:model
+type CorrelationHistoryEntry struct {
+	// example: 42
+	ID int64 `json:"id"`
+	// Kind of change, one of created, updated, deleted or restored
+	// example: updated
+	Action CorrelationHistoryAction `json:"action"`
+	// ID of the user who made the change, 0 if Grafana made it, e.g. when provisioning
+	// example: 1
+	UserID int64 `json:"userId"`
+	// Unix timestamp, in seconds, of the change
+	// example: 1672531200
+	Created int64 `json:"created"`
+	// The correlation before the change, unset if it was created
+	Before *Correlation `json:"before,omitempty"`
+	// The correlation after the change, unset if it was deleted
+	After *Correlation `json:"after,omitempty"`
+	// Properties of the correlation changed by an update, as reported by the diff of correlations
+	// example: ["label", "config.target"]
+	Changes []string `json:"changes"`
+}
+
+// GetCorrelationHistoryQuery is the query to retrieve the history of a correlation
+type GetCorrelationHistoryQuery struct {
+	UID       string `json:"-"`
+	SourceUID string `json:"-"`
+	OrgId     int64  `json:"-"`
+}
+
+// recordHistory adds a change of a correlation to its history, in the session making the change so that both are
+// rolled back together, and publishes the event of the change. before is nil for created correlations and after for
+// deleted ones. The user making the change is taken from the context, changes made without a signed in user are
+// recorded with user ID 0.
+func recordHistory(ctx context.Context, session *db.Session, orgID int64, action CorrelationHistoryAction, before, after *Correlation) error {
+	record := correlationHistoryRecord{
+		OrgID:   orgID,
+		Action:  action,
+		UserID:  contextUserID(ctx),
+		Created: time.Now().Unix(),
+	}
+
+	for _, c := range []struct {
+		correlation *Correlation
+		column      *string
+	}{{before, &record.OldCorrelation}, {after, &record.NewCorrelation}} {
+		if c.correlation == nil {
+			continue
+		}
+		record.CorrelationUID = c.correlation.UID
+		record.SourceUID = c.correlation.SourceUID
+		encoded, err := json.Marshal(c.correlation)
+		if err != nil {
+			return err
+		}
+		*c.column = string(encoded)
+	}
+
+	if _, err := session.Insert(&record); err != nil {
+		return err
+	}
+	publishChange(session, orgID, before, after)
+	return nil
+}
+
+// getCorrelationHistory returns the changes of a correlation, newest first. The history is kept after the correlation
+// is deleted.
+func (s CorrelationsService) getCorrelationHistory(ctx context.Context, query GetCorrelationHistoryQuery) ([]CorrelationHistoryEntry, error) {
+	records := make([]correlationHistoryRecord, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		return session.Where("org_id = ? AND source_uid = ? AND correlation_uid = ?", query.OrgId, query.SourceUID, query.UID).
+			Desc("id").Find(&records)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CorrelationHistoryEntry, 0, len(records))
+	for _, record := range records {
+		entry := CorrelationHistoryEntry{
+			ID:      record.ID,
+			Action:  record.Action,
+			UserID:  record.UserID,
+			Created: record.Created,
+			Changes: make([]string, 0),
+		}
+		if entry.Before, err = s.decodeHistoryCorrelation(record.OldCorrelation); err != nil {
+			return nil, err
+		}
+		if entry.After, err = s.decodeHistoryCorrelation(record.NewCorrelation); err != nil {
+			return nil, err
+		}
+		if entry.Before != nil && entry.After != nil {
+			changes, err := correlationDifferences(*entry.Before, *entry.After, DiffMatchByUID)
+			if err != nil {
+				return nil, err
+			}
+			entry.Changes = append(entry.Changes, changes...)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s CorrelationsService) decodeHistoryCorrelation(encoded string) (*Correlation, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	var correlation Correlation
+	if err := json.Unmarshal([]byte(encoded), &correlation); err != nil {
+		return nil, err
+	}
+	s.migrateConfig(&correlation)
+	return &correlation, nil
+}
@@ -0,0 +1,139 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathSegment is a step of a JSONPath expression, selecting either a key of an object or an element of an array.
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses the subset of JSONPath that selects a single value: the root $ followed by keys, given as
+// .key or ['key'], and array indices, given as [1]. Negative indices count from the end. Wildcards, slices,
+// recursive descent and filters select several values, which can't be bound to one variable, so they're not
+// supported.
+func parseJSONPath(expr string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("%w: %q must start with $", ErrInvalidJSONPath, expr)
+	}
+
+	segments := make([]jsonPathSegment, 0)
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" || key == "*" || strings.ContainsAny(key, "] \t") {
+				return nil, fmt.Errorf("%w: %q has an invalid key at %q", ErrInvalidJSONPath, expr, rest)
+			}
+			segments = append(segments, jsonPathSegment{key: key})
+			rest = rest[end+1:]
+		case '[':
+			segment, n, err := parseJSONPathBracket(rest)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q %s", ErrInvalidJSONPath, expr, err)
+			}
+			segments = append(segments, segment)
+			rest = rest[n:]
+		default:
+			return nil, fmt.Errorf("%w: %q has unexpected %q", ErrInvalidJSONPath, expr, rest)
+		}
+	}
+	return segments, nil
+}
+
+// parseJSONPathBracket parses a bracketed key or index at the start of s, returning it and its length.
+func parseJSONPathBracket(s string) (jsonPathSegment, int, error) {
+	if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+		quote := s[1]
+		var key strings.Builder
+		for i := 2; i < len(s); i++ {
+			switch {
+			case s[i] == '\\' && i+1 < len(s):
+				i++
+				key.WriteByte(s[i])
+			case s[i] == quote:
+				if i+1 >= len(s) || s[i+1] != ']' {
+					return jsonPathSegment{}, 0, fmt.Errorf("has an unterminated key at %q", s)
+				}
+				return jsonPathSegment{key: key.String()}, i + 2, nil
+			default:
+				key.WriteByte(s[i])
+			}
+		}
+		return jsonPathSegment{}, 0, fmt.Errorf("has an unterminated key at %q", s)
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return jsonPathSegment{}, 0, fmt.Errorf("has an unterminated index at %q", s)
+	}
+	index, err := strconv.Atoi(s[1:end])
+	if err != nil {
+		return jsonPathSegment{}, 0, fmt.Errorf("has an invalid index at %q", s)
+	}
+	return jsonPathSegment{index: index, isIndex: true}, end + 1, nil
+}
+
+// JSONPath returns the value a jsonpath transformation selects from a JSON document. Strings are returned as they
+// are, other values as JSON. It reports false if the value is not JSON, or the path selects nothing or null.
+func (t Transformation) JSONPath(value string) (string, bool) {
+	segments, err := parseJSONPath(t.Expression)
+	if err != nil {
+		return "", false
+	}
+
+	// Numbers are kept as they're written, as IDs often exceed the precision of a float64.
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	var current interface{}
+	if err := decoder.Decode(&current); err != nil || decoder.More() {
+		return "", false
+	}
+	for _, segment := range segments {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			if segment.isIndex {
+				return "", false
+			}
+			current = v[segment.key]
+		case []interface{}:
+			if !segment.isIndex {
+				return "", false
+			}
+			i := segment.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return "", false
+			}
+			current = v[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := current.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(encoded), true
+	}
+}
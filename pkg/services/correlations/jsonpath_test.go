@@ -0,0 +1,69 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+	t.Run("Parses paths selecting a single value", func(t *testing.T) {
+		for _, expr := range []string{"$", "$.user.id", "$.spans[0].traceId", "$['user name'][\"id\"]", "$.items[-1]", `$['it\'s']`} {
+			_, err := parseJSONPath(expr)
+			require.NoError(t, err, expr)
+		}
+	})
+
+	t.Run("Rejects invalid paths and paths selecting several values", func(t *testing.T) {
+		for _, expr := range []string{"", "user.id", "$.", "$..id", "$.user.*", "$.items[*]", "$.items[0:2]", "$.items[?(@.id)]", "$['id'", "$[1", "$ .id"} {
+			_, err := parseJSONPath(expr)
+			require.ErrorIs(t, err, ErrInvalidJSONPath, expr)
+		}
+	})
+
+	t.Run("Selects values", func(t *testing.T) {
+		document := `{"user": {"id": 12345678901234567890, "name": "Jane", "admin": true, "tags": null}, "spans": [{"traceId": "abc"}, {"traceId": "def"}], "user name": {"id": "x"}}`
+		testCases := []struct {
+			expr     string
+			expected string
+			ok       bool
+		}{
+			{expr: "$.user.id", expected: "12345678901234567890", ok: true},
+			{expr: "$.user.name", expected: "Jane", ok: true},
+			{expr: "$.user.admin", expected: "true", ok: true},
+			{expr: "$.spans[1].traceId", expected: "def", ok: true},
+			{expr: "$.spans[-2].traceId", expected: "abc", ok: true},
+			{expr: "$['user name'].id", expected: "x", ok: true},
+			{expr: "$.spans[0]", expected: `{"traceId":"abc"}`, ok: true},
+			{expr: "$.user.tags"},
+			{expr: "$.user.missing"},
+			{expr: "$.spans[2]"},
+			{expr: "$.spans.traceId"},
+			{expr: "$.user[0]"},
+		}
+		for _, tc := range testCases {
+			value, ok := Transformation{Type: TransformationJSONPath, Expression: tc.expr, MapValue: "v"}.JSONPath(document)
+			require.Equal(t, tc.ok, ok, tc.expr)
+			require.Equal(t, tc.expected, value, tc.expr)
+		}
+	})
+
+	t.Run("Selects nothing from values that are not JSON", func(t *testing.T) {
+		_, ok := Transformation{Type: TransformationJSONPath, Expression: "$.id"}.JSONPath(`level=error id=1`)
+		require.False(t, ok)
+	})
+
+	t.Run("Binds the selected value in previews", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeQuery,
+			Field:           CorrelationFields{"line"},
+			Target:          map[string]interface{}{"query": "${traceId}"},
+			Transformations: Transformations{{Type: TransformationJSONPath, Expression: "$.trace.id", MapValue: "traceId"}},
+		}
+		require.NoError(t, config.Validate())
+
+		target, unresolved := interpolateTarget(config.Target, resolveVariables(config, map[string]string{"line": `{"trace": {"id": "abc123"}}`}, nil))
+		require.Empty(t, unresolved)
+		require.Equal(t, "abc123", target["query"])
+	})
+}
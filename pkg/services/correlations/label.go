@@ -0,0 +1,93 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// Built-in tokens that can be used in the label of a correlation, e.g. "Traces in ${__targetName}". Labels are
+// stored with the tokens and resolved when correlations are read.
+const (
+	// LabelTokenSourceUID is the UID of the source data source.
+	LabelTokenSourceUID = VariableSourceUID
+	// LabelTokenSourceName is the name of the source data source.
+	LabelTokenSourceName = VariableSourceName
+	// LabelTokenTargetUID is the UID of the target data source.
+	LabelTokenTargetUID = "__targetUID"
+	// LabelTokenTargetName is the name of the target data source.
+	LabelTokenTargetName = "__targetName"
+)
+
+// LabelTokens are the names of all tokens that can be used in labels.
+var LabelTokens = []string{LabelTokenSourceUID, LabelTokenSourceName, LabelTokenTargetUID, LabelTokenTargetName}
+
+// validateLabel checks that the label only uses known tokens.
+func validateLabel(label string) error {
+	for _, match := range variablePattern.FindAllStringSubmatch(label, -1) {
+		if !isLabelToken(match[1]) {
+			return fmt.Errorf("%w: unknown token %q", ErrInvalidCorrelationLabel, match[0])
+		}
+	}
+	return nil
+}
+
+func isLabelToken(name string) bool {
+	for _, t := range LabelTokens {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLabel replaces the tokens of the label with their values. Tokens without a value, e.g. the target tokens
+// of a correlation without a target data source, are left as they are.
+func resolveLabel(label string, values map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(label, func(token string) string {
+		if v, ok := values[token[2:len(token)-1]]; ok {
+			return v
+		}
+		return token
+	})
+}
+
+// resolveLabels resolves the tokens in the labels of the given correlations of an org. The data sources of the org
+// are only looked up if any label uses a token. If they can't be looked up, the labels are left unresolved.
+func (s CorrelationsService) resolveLabels(ctx context.Context, orgID int64, correlations []Correlation) {
+	templated := false
+	for _, c := range correlations {
+		if variablePattern.MatchString(c.Label) {
+			templated = true
+			break
+		}
+	}
+	if !templated {
+		return
+	}
+
+	query := &datasources.GetDataSourcesQuery{OrgId: orgID}
+	if err := s.DataSourceService.GetDataSources(ctx, query); err != nil {
+		s.log.Warn("Failed to look up data sources to resolve correlation labels", "error", err)
+		return
+	}
+	names := make(map[string]string, len(query.Result))
+	for _, ds := range query.Result {
+		names[ds.Uid] = ds.Name
+	}
+
+	for i, c := range correlations {
+		values := map[string]string{LabelTokenSourceUID: c.SourceUID}
+		if name, ok := names[c.SourceUID]; ok {
+			values[LabelTokenSourceName] = name
+		}
+		if c.TargetUID != nil {
+			values[LabelTokenTargetUID] = *c.TargetUID
+			if name, ok := names[*c.TargetUID]; ok {
+				values[LabelTokenTargetName] = name
+			}
+		}
+		correlations[i].Label = resolveLabel(c.Label, values)
+	}
+}
@@ -0,0 +1,30 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLabel(t *testing.T) {
+	require.NoError(t, validateLabel("Logs to traces"))
+	require.NoError(t, validateLabel("${__sourceName} to ${__targetName} (${__targetUID})"))
+
+	err := validateLabel("Traces in ${__targetColor}")
+	require.ErrorIs(t, err, ErrInvalidCorrelationLabel)
+
+	label := "${hero}"
+	require.ErrorIs(t, UpdateCorrelationCommand{Label: &label}.Validate(), ErrInvalidCorrelationLabel)
+	require.ErrorIs(t, CreateCorrelationCommand{Label: label}.Validate(), ErrInvalidCorrelationLabel)
+}
+
+func TestResolveLabel(t *testing.T) {
+	values := map[string]string{LabelTokenSourceName: "logs", LabelTokenTargetName: "traces"}
+
+	require.Equal(t, "logs to traces", resolveLabel("${__sourceName} to ${__targetName}", values))
+	require.Equal(t, "Plain label", resolveLabel("Plain label", values))
+
+	t.Run("leaves tokens without a value unresolved", func(t *testing.T) {
+		require.Equal(t, "logs to ${__targetName}", resolveLabel("${__sourceName} to ${__targetName}", map[string]string{LabelTokenSourceName: "logs"}))
+	})
+}
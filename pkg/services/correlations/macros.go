@@ -0,0 +1,53 @@
+package correlations
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Macros that can be used in the target of a correlation, following the syntax of data links in the frontend.
+// Unlike variables, the fields they reference aren't known before the correlation is followed, so they are never
+// reported as missing when a correlation is saved.
+const (
+	// MacroValueRaw is the value of the correlation field of the source row.
+	MacroValueRaw = "__value.raw"
+	// MacroValueText is the value of the correlation field of the source row, as displayed. The server doesn't
+	// format values, so it is the same as MacroValueRaw.
+	MacroValueText = "__value.text"
+)
+
+// macroPattern matches the names of macros: the value of the correlation field, the value of any field of the
+// source row as __data.fields[name], __data.fields["name"] or __data.fields.name, and the time range of the source
+// query as __from:date or __to:date, optionally formatted as iso or seconds.
+var macroPattern = regexp.MustCompile(`^(__value\.(raw|text)|__data\.fields(\["[^"]+"\]|\[[^\]"]+\]|\..+)|__(from|to):date(:(iso|seconds))?)$`)
+
+// isoDateLayout formats dates the way the frontend does, as UTC with milliseconds.
+const isoDateLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// IsMacro reports whether name is the name of a macro.
+func IsMacro(name string) bool {
+	return macroPattern.MatchString(name)
+}
+
+// macroVariables returns the values of the macros when a correlation is followed from a row with the given field
+// values, in a query with the given time range. Macros referencing fields the row doesn't have are left out.
+func macroVariables(config CorrelationConfig, fields map[string]string, from, to time.Time) map[string]string {
+	vars := make(map[string]string, 3*len(fields)+8)
+	if value, ok := config.Field.value(fields); ok {
+		vars[MacroValueRaw] = value
+		vars[MacroValueText] = value
+	}
+	for name, value := range fields {
+		vars["__data.fields["+name+"]"] = value
+		vars[`__data.fields["`+name+`"]`] = value
+		vars["__data.fields."+name] = value
+	}
+	for name, t := range map[string]time.Time{VariableFrom: from, VariableTo: to} {
+		iso := t.UTC().Format(isoDateLayout)
+		vars[name+":date"] = iso
+		vars[name+":date:iso"] = iso
+		vars[name+":date:seconds"] = strconv.FormatInt(t.Unix(), 10)
+	}
+	return vars
+}
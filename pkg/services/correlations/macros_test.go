@@ -0,0 +1,86 @@
+package correlations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+)
+
+func TestExpandCorrelationTarget(t *testing.T) {
+	s := CorrelationsService{
+		DataSourceService: &fakeDatasources.FakeDataSourceService{
+			DataSources: []*datasources.DataSource{{Uid: "loki-uid", Name: "Loki", OrgId: 1}},
+		},
+	}
+	from := time.UnixMilli(1672531200000)
+	to := from.Add(time.Hour)
+
+	expand := func(t *testing.T, config CorrelationConfig, fields map[string]string) CorrelationPreview {
+		t.Helper()
+		preview, err := s.ExpandCorrelationTarget(context.Background(), ExpandCorrelationTargetQuery{
+			OrgId:       1,
+			Correlation: Correlation{SourceUID: "loki-uid", Config: config},
+			Fields:      fields,
+			From:        from,
+			To:          to,
+		})
+		require.NoError(t, err)
+		return preview
+	}
+
+	t.Run("Resolves value and field macros", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:   ConfigTypeQuery,
+			Field:  CorrelationFields{"traceID", "trace_id"},
+			Target: map[string]interface{}{"query": `${__value.raw} ${__value.text} ${__data.fields[service]} ${__data.fields["service"]} ${__data.fields.service} ${__data.fields[missing]}`},
+		}
+		require.NoError(t, config.Validate())
+
+		preview := expand(t, config, map[string]string{"trace_id": "abc123", "service": "checkout"})
+		require.Equal(t, "abc123 abc123 checkout checkout checkout ${__data.fields[missing]}", preview.Target["query"])
+		require.Equal(t, []string{"__data.fields[missing]"}, preview.Unresolved)
+	})
+
+	t.Run("Resolves time range macros", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:  ConfigTypeQuery,
+			Field: CorrelationFields{"message"},
+			Target: map[string]interface{}{
+				"range": map[string]interface{}{"from": "${__from:date}", "to": "${__to:date:iso}"},
+				"start": "${__from:date:seconds}",
+				"end":   "${__to}",
+			},
+		}
+		require.NoError(t, config.Validate())
+
+		preview := expand(t, config, nil)
+		require.Empty(t, preview.Unresolved)
+		require.Equal(t, map[string]interface{}{
+			"range": map[string]interface{}{"from": "2023-01-01T00:00:00.000Z", "to": "2023-01-01T01:00:00.000Z"},
+			"start": "1672531200",
+			"end":   "1672534800000",
+		}, preview.Target)
+	})
+
+	t.Run("Fails if the source data source doesn't exist", func(t *testing.T) {
+		_, err := s.ExpandCorrelationTarget(context.Background(), ExpandCorrelationTargetQuery{
+			OrgId:       1,
+			Correlation: Correlation{SourceUID: "deleted-uid", Config: CorrelationConfig{Type: ConfigTypeQuery}},
+		})
+		require.ErrorIs(t, err, ErrSourceDataSourceDoesNotExists)
+	})
+}
+
+func TestIsMacro(t *testing.T) {
+	for _, name := range []string{"__value.raw", "__value.text", "__data.fields[trace id]", `__data.fields["traceID"]`, "__data.fields.traceID", "__from:date", "__to:date:iso", "__from:date:seconds"} {
+		require.True(t, IsMacro(name), name)
+	}
+	for _, name := range []string{"__value", "__value.numeric", "__data.fields", "__data.fields[]", "__from", "__from:date:YYYY", "traceID"} {
+		require.False(t, IsMacro(name), name)
+	}
+}
@@ -0,0 +1,87 @@
+package correlations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Mapping binds the value of a field of the source data, or of a variable bound by a transformation, to a variable
+// of the target, e.g. to use a traceID field as ${traceId}.
+// swagger:model
+type Mapping struct {
+	// Field or variable the value is read from
+	// required:true
+	// example: traceID
+	Source string `json:"source"`
+	// Name of the variable the value is bound to
+	// required:true
+	// example: traceId
+	Target string `json:"target"`
+}
+
+func (m Mapping) Validate() error {
+	if err := validateFieldName(m.Source); err != nil {
+		return fmt.Errorf("%w: source: %s", ErrInvalidCorrelationMapping, err)
+	}
+	if m.Target == "" {
+		return fmt.Errorf("%w: target must not be empty", ErrInvalidCorrelationMapping)
+	}
+	// The variable has to be referenced as ${target}, so it can't contain the closing brace or blanks.
+	if strings.IndexFunc(m.Target, func(r rune) bool { return r == '}' || unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("%w: target %q is not a valid variable name", ErrInvalidCorrelationMapping, m.Target)
+	}
+	if IsBuiltInVariable(m.Target) {
+		return fmt.Errorf("%w: target %q is a built-in variable", ErrInvalidCorrelationMapping, m.Target)
+	}
+	return nil
+}
+
+type Mappings []Mapping
+
+func (m Mappings) Validate() error {
+	targets := make(map[string]struct{}, len(m))
+	for i, mapping := range m {
+		if err := mapping.Validate(); err != nil {
+			return fmt.Errorf("mapping %d: %w", i, err)
+		}
+		if _, ok := targets[mapping.Target]; ok {
+			return fmt.Errorf("mapping %d: %w: target %q is mapped more than once", i, ErrInvalidCorrelationMapping, mapping.Target)
+		}
+		targets[mapping.Target] = struct{}{}
+	}
+	return nil
+}
+
+// UnmarshalJSON reads mappings given as a list of source and target pairs. Mappings written before they were typed
+// may also be an object of targets keyed by source, which is read as pairs sorted by source.
+func (m *Mappings) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		var bySource map[string]string
+		if err := json.Unmarshal(data, &bySource); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidCorrelationMapping, err)
+		}
+		mappings := make(Mappings, 0, len(bySource))
+		for source, target := range bySource {
+			mappings = append(mappings, Mapping{Source: source, Target: target})
+		}
+		sort.Slice(mappings, func(i, j int) bool { return mappings[i].Source < mappings[j].Source })
+		*m = mappings
+		return nil
+	}
+
+	var mappings []Mapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCorrelationMapping, err)
+	}
+	*m = mappings
+	return nil
+}
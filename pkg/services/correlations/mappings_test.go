@@ -0,0 +1,86 @@
+package correlations
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappings(t *testing.T) {
+	t.Run("Reads mappings as a list of pairs", func(t *testing.T) {
+		var config CorrelationConfig
+		require.NoError(t, json.Unmarshal([]byte(`{"type": "query", "field": "message", "target": {}, "mappings": [{"source": "traceID", "target": "traceId"}]}`), &config))
+		require.Equal(t, Mappings{{Source: "traceID", Target: "traceId"}}, config.Mappings)
+	})
+
+	t.Run("Reads legacy mappings keyed by source", func(t *testing.T) {
+		var mappings Mappings
+		require.NoError(t, json.Unmarshal([]byte(`{"traceID": "traceId", "service": "svc"}`), &mappings))
+		require.Equal(t, Mappings{{Source: "service", Target: "svc"}, {Source: "traceID", Target: "traceId"}}, mappings)
+	})
+
+	t.Run("Reads null mappings as none", func(t *testing.T) {
+		var config CorrelationConfig
+		require.NoError(t, json.Unmarshal([]byte(`{"type": "query", "field": "message", "target": {}, "mappings": null}`), &config))
+		require.Nil(t, config.Mappings)
+	})
+
+	t.Run("Rejects mappings of other shapes", func(t *testing.T) {
+		for _, raw := range []string{`"traceID"`, `{"traceID": 1}`, `[{"source": 1}]`, `[["traceID", "traceId"]]`} {
+			var mappings Mappings
+			require.ErrorIs(t, json.Unmarshal([]byte(raw), &mappings), ErrInvalidCorrelationMapping, raw)
+		}
+	})
+
+	t.Run("Writes mappings as a list of pairs, omitted if empty", func(t *testing.T) {
+		config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Mappings: Mappings{{Source: "traceID", Target: "traceId"}}}
+		encoded, err := json.Marshal(config)
+		require.NoError(t, err)
+		require.Contains(t, string(encoded), `"mappings":[{"source":"traceID","target":"traceId"}]`)
+
+		config.Mappings = nil
+		encoded, err = json.Marshal(config)
+		require.NoError(t, err)
+		require.NotContains(t, string(encoded), "mappings")
+	})
+
+	t.Run("Validates mappings", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			mappings Mappings
+			valid    bool
+		}{
+			{name: "valid", mappings: Mappings{{Source: "traceID", Target: "traceId"}, {Source: "traceID", Target: "trace"}}, valid: true},
+			{name: "empty source", mappings: Mappings{{Source: "", Target: "traceId"}}},
+			{name: "empty target", mappings: Mappings{{Source: "traceID", Target: ""}}},
+			{name: "target with a closing brace", mappings: Mappings{{Source: "traceID", Target: "trace}"}}},
+			{name: "target with a space", mappings: Mappings{{Source: "traceID", Target: "trace id"}}},
+			{name: "built-in target", mappings: Mappings{{Source: "traceID", Target: VariableSourceUID}}},
+			{name: "duplicate target", mappings: Mappings{{Source: "traceID", Target: "id"}, {Source: "spanID", Target: "id"}}},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.mappings.Validate()
+				if tc.valid {
+					require.NoError(t, err)
+				} else {
+					require.ErrorIs(t, err, ErrInvalidCorrelationMapping)
+				}
+			})
+		}
+	})
+
+	t.Run("Mapped variables can be used by external correlations", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:     ConfigTypeExternal,
+			Field:    CorrelationFields{"message"},
+			Target:   map[string]interface{}{ExternalTargetURL: "https://traces.example.com/${traceId}"},
+			Mappings: Mappings{{Source: "traceID", Target: "traceId"}},
+		}
+		require.NoError(t, config.Validate())
+
+		config.Mappings = nil
+		require.ErrorIs(t, config.Validate(), ErrUnresolvableTargetVariable)
+	})
+}
@@ -4,6 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp/syntax"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
 )
 
 var (
@@ -14,34 +22,520 @@ var (
 	ErrCorrelationNotFound                = errors.New("correlation not found")
 	ErrUpdateCorrelationEmptyParams       = errors.New("not enough parameters to edit correlation")
 	ErrInvalidConfigType                  = errors.New("invalid correlation config type")
+	ErrInvalidTransformationType          = errors.New("invalid transformation type")
+	ErrTransformationRegexReqExp          = errors.New("regex transformations require expression")
+	ErrTransformationExpressionTooLong    = errors.New("transformation expression is too long")
+	ErrTransformationRegexUnsafe          = errors.New("regex transformation expression contains nested unbounded quantifiers")
+	ErrTransformationSplitReqDelimiter    = errors.New("split transformations require delimiter")
+	ErrTransformationSplitReqMapValue     = errors.New("split transformations require mapValue")
+	ErrInvalidCorrelationField            = errors.New("invalid correlation field")
+	ErrInvalidDiffMatchKey                = errors.New("invalid correlations diff match key")
+	ErrCorrelationTemplateNotFound        = errors.New("correlation template not found")
+	ErrUnsupportedConfigVersion           = errors.New("correlation config was written by a newer version")
+	ErrInvalidOpenMode                    = errors.New("invalid open mode")
+	ErrInvalidTargetVisualization         = errors.New("invalid target visualization")
+	ErrIncompatibleTargetDataSource       = errors.New("target query does not fit the target data source type")
+	ErrUpdateCorrelationsOrgMismatch      = errors.New("all updates must belong to the same org")
+	ErrInvalidTargetTimeout               = errors.New("invalid target timeout")
+	ErrInvalidCorrelationLabel            = errors.New("invalid correlation label")
+	ErrUnsupportedTransformation          = errors.New("transformation is not supported by the correlation config type")
+	ErrCorrelationNotesTooLong            = errors.New("correlation notes are too long")
+	ErrInvalidCorrelationUID              = errors.New("invalid correlation UID")
+	ErrInvalidExternalTarget              = errors.New("invalid external correlation target")
+	ErrUnresolvableTargetVariable         = errors.New("target references a variable the correlation does not provide")
+	ErrInvalidTargetUID                   = errors.New("invalid correlation targetUID")
+	ErrCorrelationReadOnly                = errors.New("correlation can only be edited via provisioning")
+	ErrNoCorrelationsToDelete             = errors.New("no correlation UIDs to delete")
+	ErrNoCorrelationsToPatch              = errors.New("no correlation UIDs to update")
+	ErrInvalidCorrelationMapping          = errors.New("invalid correlation mapping")
+	ErrTransformationJSONPathReqExp       = errors.New("jsonpath transformations require expression")
+	ErrTransformationJSONPathReqMapValue  = errors.New("jsonpath transformations require mapValue")
+	ErrInvalidJSONPath                    = errors.New("invalid JSONPath expression")
+	ErrTransformationGrokReqExp           = errors.New("grok transformations require expression")
+	ErrInvalidGrokPattern                 = errors.New("invalid grok pattern")
+	ErrInvalidCaptureGroupName            = errors.New("invalid regex capture group name")
+	ErrTransformationRegexGroupsMapValue  = errors.New("regex transformations with named capture groups can't set mapValue")
+	ErrTransformationVariableCollision    = errors.New("variable is bound by several transformations")
+	ErrNoTransformationsToTest            = errors.New("no transformations to test")
+	ErrUnsupportedExportVersion           = errors.New("unsupported correlations export version")
+	ErrNoCorrelationsToImport             = errors.New("no correlations to import")
+	ErrInvalidImportUIDMode               = errors.New("invalid import UID mode")
+	ErrCorrelationUIDConflict             = errors.New("a correlation with this UID already exists")
+	ErrCorrelationVersionNotFound         = errors.New("correlation version not found")
+	ErrInvalidCorrelationTag              = errors.New("invalid correlation tag")
+	ErrInvalidDashboardTarget             = errors.New("invalid dashboard correlation target")
+	ErrTargetDashboardDoesNotExist        = errors.New("target dashboard does not exist")
+	ErrQuotaReached                       = errors.New("correlations quota reached")
+	ErrInvalidCorrelationSettings         = errors.New("invalid correlation settings")
+	ErrInvalidCorrelationsSort            = errors.New("invalid correlations sort")
+	ErrInvalidAnalyticsEvent              = errors.New("invalid correlation analytics event")
+	ErrInvalidSourceType                  = errors.New("invalid correlation source type")
+	ErrInvalidCorrelationCondition        = errors.New("invalid correlation condition")
+	ErrInvalidCorrelationConfig           = errors.New("correlation config does not match the correlation schema")
+	ErrInvalidCorrelationVersion          = errors.New("invalid correlation version")
+	ErrCorrelationVersionConflict         = errors.New("correlation was changed since the expected version")
 )
 
+// MaxTransformationExpressionLength is the maximum length, in characters, of a transformation expression.
+var MaxTransformationExpressionLength = 1024
+
+// MaxTargetTimeoutMs is the maximum target timeout, in milliseconds, a correlation config can set.
+var MaxTargetTimeoutMs = 5 * 60 * 1000
+
+// ValidateCorrelationUID checks that a client-supplied correlation UID follows the rules of Grafana's UIDs: it must
+// not be empty, must be at most 40 characters long and may only contain letters, digits, dashes and underscores.
+func ValidateCorrelationUID(uid string) error {
+	if uid == "" {
+		return fmt.Errorf("%w: must not be empty", ErrInvalidCorrelationUID)
+	}
+	if util.IsShortUIDTooLong(uid) {
+		return fmt.Errorf("%w: %q is longer than 40 characters", ErrInvalidCorrelationUID, uid)
+	}
+	if !util.IsValidShortUID(uid) {
+		return fmt.Errorf("%w: %q may only contain letters, digits, dashes and underscores", ErrInvalidCorrelationUID, uid)
+	}
+	return nil
+}
+
+// MaxNotesLength is the maximum length, in characters, of a correlation's notes.
+var MaxNotesLength = 4096
+
+func validateNotes(notes string) error {
+	if n := utf8.RuneCountInString(notes); n > MaxNotesLength {
+		return fmt.Errorf("%w: %d characters, the maximum is %d", ErrCorrelationNotesTooLong, n, MaxNotesLength)
+	}
+	return nil
+}
+
+// validateTargetTimeout checks that a target timeout doesn't exceed MaxTargetTimeoutMs. The correlation kind schema
+// already rejects negative timeouts.
+func validateTargetTimeout(ms int) error {
+	if ms > MaxTargetTimeoutMs {
+		return fmt.Errorf("%w: %dms, the maximum is %dms", ErrInvalidTargetTimeout, ms, MaxTargetTimeoutMs)
+	}
+	return nil
+}
+
 type CorrelationConfigType string
 
 const (
 	ConfigTypeQuery CorrelationConfigType = "query"
+	// ConfigTypeExternal links to a URL outside of Grafana, e.g. of a ticketing system or a runbook, instead of
+	// querying a target data source.
+	ConfigTypeExternal CorrelationConfigType = "external"
+	// ConfigTypeDashboard opens a dashboard, with its template variables set from the source data, instead of
+	// querying a target data source.
+	ConfigTypeDashboard CorrelationConfigType = "dashboard"
+	// ConfigTypeTrace looks up the trace whose ID is the value of the field in a tracing data source. The target query
+	// is generated for the type of the target data source rather than written by hand.
+	ConfigTypeTrace CorrelationConfigType = "trace"
 )
 
-func (t CorrelationConfigType) Validate() error {
-	if t != ConfigTypeQuery {
-		return fmt.Errorf("%s: \"%s\"", ErrInvalidConfigType, t)
+// ExternalTargetURL is the key of the URL template in the target of external correlations.
+const ExternalTargetURL = "url"
+
+// configTypeCapabilities describes what correlations of a config type support.
+type configTypeCapabilities struct {
+	// transformations are the types of transformations that produce variables the target can use.
+	transformations []TransformationType
+	// targetDataSource is set if the target is queried from a data source, which correlations then must have.
+	targetDataSource bool
+}
+
+// configTypes declares the capabilities of every config type the correlation kind schema declares.
+var configTypes = map[CorrelationConfigType]configTypeCapabilities{
+	ConfigTypeQuery: {
+		transformations:  []TransformationType{TransformationRegex, TransformationLogfmt, TransformationSplit, TransformationJSONPath, TransformationGrok},
+		targetDataSource: true,
+	},
+	ConfigTypeExternal: {
+		transformations: []TransformationType{TransformationRegex, TransformationLogfmt, TransformationSplit, TransformationJSONPath, TransformationGrok},
+	},
+	ConfigTypeDashboard: {
+		transformations: []TransformationType{TransformationRegex, TransformationLogfmt, TransformationSplit, TransformationJSONPath, TransformationGrok},
+	},
+	ConfigTypeTrace: {
+		targetDataSource: true,
+	},
+}
+
+// validateTransformations checks that the config type supports all of the given transformations. Transformations
+// that are meaningless for a type would silently do nothing.
+func (t CorrelationConfigType) validateTransformations(transformations Transformations) error {
+	supported := configTypes[t].transformations
+	for i, transformation := range transformations {
+		ok := false
+		for _, s := range supported {
+			if s == transformation.Type {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("transformation %d: %w: %q transformations can't be used with correlations of type %q", i, ErrUnsupportedTransformation, transformation.Type, t)
+		}
+	}
+	return nil
+}
+
+// validateTargetUID checks that a correlation has a target data source if, and only if, its config type queries one.
+func validateTargetUID(config CorrelationConfig, targetUID *string) error {
+	queried := configTypes[config.Type].targetDataSource
+	if queried && targetUID == nil {
+		return fmt.Errorf("%w: correlations of type \"%s\" must have a targetUID", ErrInvalidTargetUID, config.Type)
+	}
+	if !queried && targetUID != nil {
+		return fmt.Errorf("%w: correlations of type \"%s\" can't have a targetUID", ErrInvalidTargetUID, config.Type)
 	}
 	return nil
 }
 
+// CorrelationOpenMode controls where the target of a correlation is opened.
+type CorrelationOpenMode string
+
+const (
+	OpenModeExplore CorrelationOpenMode = "explore"
+	OpenModeSplit   CorrelationOpenMode = "split"
+	OpenModeNewTab  CorrelationOpenMode = "newTab"
+)
+
+// OrDefault returns the mode, or OpenModeExplore if no mode is set. Correlations created before open modes
+// were introduced have no mode and keep opening in Explore.
+func (m CorrelationOpenMode) OrDefault() CorrelationOpenMode {
+	if m == "" {
+		return OpenModeExplore
+	}
+	return m
+}
+
+// CorrelationVisualization hints how Explore visualizes the results of the target query.
+type CorrelationVisualization string
+
+const (
+	VisualizationLogs   CorrelationVisualization = "logs"
+	VisualizationTable  CorrelationVisualization = "table"
+	VisualizationGraph  CorrelationVisualization = "graph"
+	VisualizationTraces CorrelationVisualization = "traces"
+)
+
+type TransformationType string
+
+const (
+	TransformationRegex    TransformationType = "regex"
+	TransformationLogfmt   TransformationType = "logfmt"
+	TransformationSplit    TransformationType = "split"
+	TransformationJSONPath TransformationType = "jsonpath"
+	TransformationGrok     TransformationType = "grok"
+)
+
+// swagger:model
+type Transformation struct {
+	// Transformation type
+	// required:true
+	// example: regex
+	Type TransformationType `json:"type"`
+	// Expression used by the transformation, e.g. the regular expression of a regex transformation, the path of a
+	// jsonpath transformation, such as $.user.id, or the pattern of a grok transformation, such as
+	// %{IP:client} %{WORD:method}
+	// example: (Superman|Batman)
+	Expression string `json:"expression,omitempty"`
+	// Field the transformation is applied to, defaults to the correlation field
+	// example: name
+	Field string `json:"field,omitempty"`
+	// Name of the variable the result of the transformation is bound to. Regex transformations with named capture
+	// groups bind every group to a variable of its name instead.
+	// example: hero
+	MapValue string `json:"mapValue,omitempty"`
+	// Delimiter a split transformation splits the field by
+	// example: /
+	Delimiter string `json:"delimiter,omitempty"`
+	// Index of the part a split transformation selects. Negative indices count from the end.
+	// example: 2
+	Index int `json:"index,omitempty"`
+}
+
+func (t Transformation) Validate() error {
+	if length := utf8.RuneCountInString(t.Expression); length > MaxTransformationExpressionLength {
+		return fmt.Errorf("%w: %d characters, the maximum is %d", ErrTransformationExpressionTooLong, length, MaxTransformationExpressionLength)
+	}
+
+	switch t.Type {
+	case TransformationRegex:
+		if t.Expression == "" {
+			return ErrTransformationRegexReqExp
+		}
+		// Expressions are evaluated by the frontend, so syntax Go doesn't understand (e.g. lookarounds)
+		// is not an error. We just can't check those for unsafe constructs.
+		if re, err := syntax.Parse(toGoRegex(t.Expression), syntax.Perl); err == nil && hasNestedUnboundedQuantifier(re, false) {
+			return fmt.Errorf("%w: %q", ErrTransformationRegexUnsafe, t.Expression)
+		}
+		if names := captureGroupNames(t.Expression); len(names) > 0 {
+			if t.MapValue != "" {
+				return ErrTransformationRegexGroupsMapValue
+			}
+			for _, name := range names {
+				if !variableNamePattern.MatchString(name) || IsBuiltInVariable(name) {
+					return fmt.Errorf("%w: %q", ErrInvalidCaptureGroupName, name)
+				}
+			}
+		}
+	case TransformationLogfmt:
+	case TransformationSplit:
+		if t.Delimiter == "" {
+			return ErrTransformationSplitReqDelimiter
+		}
+		if t.MapValue == "" {
+			return ErrTransformationSplitReqMapValue
+		}
+	case TransformationGrok:
+		if t.Expression == "" {
+			return ErrTransformationGrokReqExp
+		}
+		if _, err := compileGrok(t.Expression); err != nil {
+			return err
+		}
+	case TransformationJSONPath:
+		if t.Expression == "" {
+			return ErrTransformationJSONPathReqExp
+		}
+		if t.MapValue == "" {
+			return ErrTransformationJSONPathReqMapValue
+		}
+		if _, err := parseJSONPath(t.Expression); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: \"%s\"", ErrInvalidTransformationType, t.Type)
+	}
+
+	return nil
+}
+
+// Split returns the part of value selected by a split transformation. It reports false if the index is out
+// of range, in which case the variable falls back to its default, or is empty.
+func (t Transformation) Split(value string) (string, bool) {
+	parts := strings.Split(value, t.Delimiter)
+	i := t.Index
+	if i < 0 {
+		i += len(parts)
+	}
+	if i < 0 || i >= len(parts) {
+		return "", false
+	}
+	return parts[i], true
+}
+
+type Transformations []Transformation
+
+func (t Transformations) Validate() error {
+	for i, transformation := range t {
+		if err := transformation.Validate(); err != nil {
+			return fmt.Errorf("transformation %d: %w", i, err)
+		}
+	}
+	return t.validateCaptureGroups()
+}
+
+// validateCaptureGroups checks that the variables bound by named capture groups aren't bound by another group, or
+// by any other transformation of the chain, which would make their value depend on the order of the
+// transformations. Variables bound otherwise may still be rebound, as they could before named groups were supported.
+func (t Transformations) validateCaptureGroups() error {
+	for i, transformation := range t {
+		if transformation.Type != TransformationRegex {
+			continue
+		}
+		seen := map[string]struct{}{}
+		for _, name := range captureGroupNames(transformation.Expression) {
+			if _, ok := seen[name]; ok {
+				return fmt.Errorf("transformation %d: %w: %q", i, ErrTransformationVariableCollision, name)
+			}
+			seen[name] = struct{}{}
+			for j, other := range t {
+				if j == i {
+					continue
+				}
+				for _, bound := range other.boundVariables("") {
+					if bound == name {
+						return fmt.Errorf("transformation %d: %w: %q is also bound by transformation %d", i, ErrTransformationVariableCollision, name, j)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hasNestedUnboundedQuantifier reports whether re contains an unbounded quantifier (*, + or {n,})
+// nested inside another one, e.g. (a+)+. Such patterns are prone to catastrophic backtracking
+// in the backtracking regex engines used by browsers, even though Go's engine is not affected.
+func hasNestedUnboundedQuantifier(re *syntax.Regexp, inUnbounded bool) bool {
+	unbounded := re.Op == syntax.OpStar || re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Max == -1)
+	if unbounded && inUnbounded {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if hasNestedUnboundedQuantifier(sub, inUnbounded || unbounded) {
+			return true
+		}
+	}
+	return false
+}
+
+// SchemaVersion is the version of the CorrelationConfig shape written by this version of Grafana. It must be
+// increased, along with a new step in CorrelationConfig.Migrate, whenever the shape changes incompatibly.
+const SchemaVersion = 0
+
 // swagger:model
 type CorrelationConfig struct {
-	// Field used to attach the correlation link
+	// Field used to attach the correlation link, or a list of fields to attach it to each of them
 	// required:true
 	// example: message
-	Field string `json:"field" binding:"Required"`
+	Field CorrelationFields `json:"field" binding:"Required"`
 	// Target type
 	// required:true
 	Type CorrelationConfigType `json:"type" binding:"Required"`
-	// Target data query
+	// Target data query, for external correlations the URL template to link to, e.g.
+	// { "url": "https://tickets.example.com/browse/${ticket}" }, or for dashboard correlations the dashboard to open
+	// and the values of its variables, e.g. { "dashboardUID": "7CpzLfWnz", "variables": { "service": "${service}" } }.
+	// The target of trace correlations is generated from the field.
 	// required:true
 	// example: { "expr": "job=app" }
 	Target map[string]interface{} `json:"target" binding:"Required"`
+	// Source data transformations
+	// example: [{"type": "logfmt"}]
+	Transformations Transformations `json:"transformations,omitempty"`
+	// Fields or variables bound to other variable names, applied after the transformations
+	// example: [{"source": "traceID", "target": "traceId"}]
+	Mappings Mappings `json:"mappings,omitempty"`
+	// Schema version the config was written with. Omitted for version 0.
+	Version int `json:"version,omitempty"`
+	// Where the target is opened
+	// example: split
+	OpenMode CorrelationOpenMode `json:"openMode,omitempty"`
+	// How long to wait for the target query, in milliseconds. Zero uses the system default.
+	// example: 30000
+	TargetTimeoutMs int `json:"targetTimeoutMs,omitempty"`
+	// How the results of the target query are visualized. Empty lets Explore decide.
+	// example: traces
+	TargetVisualization CorrelationVisualization `json:"targetVisualization,omitempty"`
+	// Condition the field value must match for the link to be shown. The link is shown on every row if empty.
+	Condition *CorrelationCondition `json:"condition,omitempty"`
+}
+
+// Validate checks the config against the schema of the correlation kind, then checks what the schema can't
+// express, such as the expressions of the transformations and the variables the target references.
+func (c CorrelationConfig) Validate() error {
+	if err := validateConfigSchema(c.schemaValue(), true); err != nil {
+		return err
+	}
+	if err := c.Field.Validate(); err != nil {
+		return err
+	}
+	if err := validateTargetTimeout(c.TargetTimeoutMs); err != nil {
+		return err
+	}
+	if err := c.Transformations.Validate(); err != nil {
+		return err
+	}
+	if err := c.Type.validateTransformations(c.Transformations); err != nil {
+		return err
+	}
+	if err := c.Mappings.Validate(); err != nil {
+		return err
+	}
+	if c.Condition != nil {
+		if err := c.Condition.Validate(); err != nil {
+			return err
+		}
+	}
+	return c.validateTarget()
+}
+
+// schemaValue returns the config as it's checked against the schema of the correlation kind: as given, without the
+// defaults MarshalJSON fills in. Empty values are left out, so that they're reported as missing if they're required.
+func (c CorrelationConfig) schemaValue() map[string]interface{} {
+	value := map[string]interface{}{"target": c.Target}
+	if c.Target == nil {
+		value["target"] = map[string]interface{}{}
+	}
+	if c.Type != "" {
+		value["type"] = c.Type
+	}
+	if len(c.Field) > 0 {
+		value["field"] = c.Field
+	}
+	if c.Transformations != nil {
+		value["transformations"] = c.Transformations
+	}
+	if c.Mappings != nil {
+		value["mappings"] = c.Mappings
+	}
+	if c.Version != 0 {
+		value["version"] = c.Version
+	}
+	if c.OpenMode != "" {
+		value["openMode"] = c.OpenMode
+	}
+	if c.TargetTimeoutMs != 0 {
+		value["targetTimeoutMs"] = c.TargetTimeoutMs
+	}
+	if c.TargetVisualization != "" {
+		value["targetVisualization"] = c.TargetVisualization
+	}
+	if c.Condition != nil {
+		value["condition"] = *c.Condition
+	}
+	return value
+}
+
+// validateTarget checks the target against the config type. The shape of queries is up to the target data source,
+// so only the variables they reference are checked.
+func (c CorrelationConfig) validateTarget() error {
+	switch c.Type {
+	case ConfigTypeExternal:
+		return c.validateExternalTarget()
+	case ConfigTypeDashboard:
+		return c.validateDashboardTarget()
+	case ConfigTypeQuery:
+		return c.validateReferencedVariables(c.targetTemplates()...)
+	}
+	return nil
+}
+
+// validateFieldName checks that name could plausibly be the name of a data frame field. Names that can never
+// match a field, e.g. because of a copy-paste or templating mistake, are rejected.
+func validateFieldName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: field name must not be empty", ErrInvalidCorrelationField)
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("%w: field name %q has leading or trailing whitespace", ErrInvalidCorrelationField, name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%w: field name %q contains control character %U", ErrInvalidCorrelationField, name, r)
+		}
+	}
+	return nil
+}
+
+// NeedsMigration reports whether the config was written in an older shape than the current SchemaVersion.
+// Configs stored before correlation types were introduced have no type.
+func (c CorrelationConfig) NeedsMigration() bool {
+	return c.Version < SchemaVersion || c.Type == ""
+}
+
+// Migrate upgrades a config written in an older shape to the current SchemaVersion. Configs written by a newer
+// version of Grafana can't be downgraded and are left untouched.
+func (c *CorrelationConfig) Migrate() error {
+	if c.Version > SchemaVersion {
+		return fmt.Errorf("%w: version %d, supported up to %d", ErrUnsupportedConfigVersion, c.Version, SchemaVersion)
+	}
+	if c.Type == "" {
+		c.Type = ConfigTypeQuery
+	}
+	c.Version = SchemaVersion
+	return nil
 }
 
 func (c CorrelationConfig) MarshalJSON() ([]byte, error) {
@@ -49,14 +543,32 @@ func (c CorrelationConfig) MarshalJSON() ([]byte, error) {
 	if target == nil {
 		target = map[string]interface{}{}
 	}
+	configType := c.Type
+	if configType == "" {
+		configType = ConfigTypeQuery
+	}
 	return json.Marshal(struct {
-		Type   CorrelationConfigType  `json:"type"`
-		Field  string                 `json:"field"`
-		Target map[string]interface{} `json:"target"`
+		Type                CorrelationConfigType    `json:"type"`
+		Field               CorrelationFields        `json:"field"`
+		Target              map[string]interface{}   `json:"target"`
+		Transformations     Transformations          `json:"transformations,omitempty"`
+		Mappings            Mappings                 `json:"mappings,omitempty"`
+		Version             int                      `json:"version,omitempty"`
+		OpenMode            CorrelationOpenMode      `json:"openMode"`
+		TargetTimeoutMs     int                      `json:"targetTimeoutMs,omitempty"`
+		TargetVisualization CorrelationVisualization `json:"targetVisualization,omitempty"`
+		Condition           *CorrelationCondition    `json:"condition,omitempty"`
 	}{
-		Type:   ConfigTypeQuery,
-		Field:  c.Field,
-		Target: target,
+		Type:                configType,
+		Field:               c.Field,
+		Target:              target,
+		Transformations:     c.Transformations,
+		Mappings:            c.Mappings,
+		Version:             SchemaVersion,
+		OpenMode:            c.OpenMode.OrDefault(),
+		TargetTimeoutMs:     c.TargetTimeoutMs,
+		TargetVisualization: c.TargetVisualization,
+		Condition:           c.Condition,
 	})
 }
 
@@ -69,6 +581,10 @@ type Correlation struct {
 	// UID of the data source the correlation originates from
 	// example:d0oxYRg4z
 	SourceUID string `json:"sourceUID" xorm:"pk 'source_uid'"`
+	// Type of the data sources the correlation originates from. If set, the correlation applies to every data source
+	// of this type in the org, not only to the source data source.
+	// example: loki
+	SourceType string `json:"sourceType,omitempty" xorm:"source_type"`
 	// UID of the data source the correlation points to
 	// example:PE1C5CBDA0504A6A3
 	TargetUID *string `json:"targetUID" xorm:"target_uid"`
@@ -78,8 +594,98 @@ type Correlation struct {
 	// Description of the correlation
 	// example: Logs to Traces
 	Description string `json:"description" xorm:"description"`
+	// Free-form notes for editors of the correlation, e.g. runbook links. Not shown when following the correlation.
+	// example: See the tracing runbook before changing the target
+	Notes string `json:"notes" xorm:"notes"`
 	// Correlation Configuration
 	Config CorrelationConfig `json:"config" xorm:"jsonb config"`
+	// UID of the template the correlation was materialized from, if any
+	// example: 8xDdz3M4k
+	TemplateUID *string `json:"templateUID,omitempty" xorm:"template_uid"`
+	// Unix timestamp, in seconds, of when the correlation was last used. Omitted if it was never used.
+	// example: 1672531200
+	LastUsed int64 `json:"lastUsed,omitempty" xorm:"last_used"`
+	// Unix timestamp, in seconds, of when the correlation was created
+	// example: 1672531200
+	Created int64 `json:"created" xorm:"created"`
+	// Unix timestamp, in seconds, of the last change to the correlation
+	// example: 1672617600
+	Updated int64 `json:"updated" xorm:"updated"`
+	// ID of the user who created the correlation, 0 if Grafana created it, e.g. when provisioning
+	// example: 1
+	CreatedBy int64 `json:"createdBy" xorm:"created_by"`
+	// Whether the correlation is enabled. Disabled correlations are kept, but not offered as links.
+	// example: true
+	Enabled bool `json:"enabled" xorm:"enabled"`
+	// Whether the correlation was provisioned from a file. Provisioned correlations can't be edited through the API.
+	// example: false
+	Provisioned bool `json:"provisioned" xorm:"provisioned"`
+	// Version of the correlation, starting at 1 and incremented on every change
+	// example: 3
+	Version int64 `json:"version" xorm:"'version'"`
+	// Whether the target data source of the correlation was deleted, leaving it without a target until it is given
+	// a new one
+	// example: false
+	Orphaned bool `json:"orphaned" xorm:"orphaned"`
+	// Unix timestamp, in seconds, of when the correlation was deleted. Deleted correlations can be restored until
+	// they're purged. Omitted if it wasn't deleted.
+	// example: 1672704000
+	DeletedAt int64 `json:"deletedAt,omitempty" xorm:"deleted_at"`
+	// Tags grouping the correlation with others, e.g. by team
+	// example: ["payments", "infra"]
+	Tags []string `json:"tags" xorm:"-"`
+}
+
+// CorrelationKind classifies a correlation by the shape of its config.
+type CorrelationKind string
+
+const (
+	// KindLink links to the target without transforming the source data.
+	KindLink CorrelationKind = "link"
+	// KindExtract extracts a single value from the source field with a regex, split or jsonpath transformation.
+	KindExtract CorrelationKind = "extract"
+	// KindMapped maps parsed key/value pairs of the source data to variables, e.g. with a logfmt or grok
+	// transformation, or maps variables to others with mappings.
+	KindMapped CorrelationKind = "mapped"
+	// KindTransform chains several extracting transformations.
+	KindTransform CorrelationKind = "transform"
+)
+
+// Kind derives the kind of the correlation from its transformations and mappings.
+func (c Correlation) Kind() CorrelationKind {
+	if len(c.Config.Mappings) > 0 {
+		return KindMapped
+	}
+	transformations := c.Config.Transformations
+	if len(transformations) == 0 {
+		return KindLink
+	}
+	for _, t := range transformations {
+		if t.Type == TransformationLogfmt || t.Type == TransformationGrok {
+			return KindMapped
+		}
+	}
+	if len(transformations) == 1 {
+		return KindExtract
+	}
+	return KindTransform
+}
+
+// CorrelationListItem is a correlation as returned in list responses, along with its derived kind.
+// swagger:model
+type CorrelationListItem struct {
+	Correlation
+	// Kind of the correlation derived from its config
+	// example: extract
+	Kind CorrelationKind `json:"kind"`
+}
+
+func newCorrelationListItems(correlations []Correlation) []CorrelationListItem {
+	items := make([]CorrelationListItem, 0, len(correlations))
+	for _, c := range correlations {
+		items = append(items, CorrelationListItem{Correlation: c, Kind: c.Kind()})
+	}
+	return items
 }
 
 // CreateCorrelationResponse is the response struct for CreateCorrelationCommand
@@ -88,6 +694,8 @@ type CreateCorrelationResponseBody struct {
 	Result Correlation `json:"result"`
 	// example: Correlation created
 	Message string `json:"message"`
+	// Problems of the target query, only set if its validation was requested
+	Warnings []TargetQueryWarning `json:"warnings,omitempty"`
 }
 
 // CreateCorrelationCommand is the command for creating a correlation
@@ -97,7 +705,11 @@ type CreateCorrelationCommand struct {
 	SourceUID         string `json:"-"`
 	OrgId             int64  `json:"-"`
 	SkipReadOnlyCheck bool   `json:"-"`
-	// Target data source UID to which the correlation is created. required if config.type = query
+	// Optional UID of the correlation, generated if not set
+	// example: 50xhMlg9k
+	UID string `json:"uid"`
+	// Target data source UID to which the correlation is created. required if config.type = query, not allowed
+	// if config.type = external
 	// example:PE1C5CBDA0504A6A3
 	TargetUID *string `json:"targetUID"`
 	// Optional label identifying the correlation
@@ -106,18 +718,42 @@ type CreateCorrelationCommand struct {
 	// Optional description of the correlation
 	// example: Logs to Traces
 	Description string `json:"description"`
+	// Optional notes for editors of the correlation
+	// example: See the tracing runbook before changing the target
+	Notes string `json:"notes"`
 	// Arbitrary configuration object handled in frontend
 	Config CorrelationConfig `json:"config" binding:"Required"`
+	// Whether the correlation is enabled, defaults to true
+	// example: false
+	Enabled *bool `json:"enabled"`
+	// Optional tags grouping the correlation with others
+	// example: ["payments", "infra"]
+	Tags []string `json:"tags"`
+	// Optional type of the source data source. If set, the correlation applies to every data source of this type in
+	// the org.
+	// example: loki
+	SourceType string `json:"sourceType"`
 }
 
 func (c CreateCorrelationCommand) Validate() error {
-	if err := c.Config.Type.Validate(); err != nil {
+	if c.UID != "" {
+		if err := ValidateCorrelationUID(c.UID); err != nil {
+			return err
+		}
+	}
+	if err := validateLabel(c.Label); err != nil {
 		return err
 	}
-	if c.TargetUID == nil && c.Config.Type == ConfigTypeQuery {
-		return fmt.Errorf("correlations of type \"%s\" must have a targetUID", ConfigTypeQuery)
+	if err := validateNotes(c.Notes); err != nil {
+		return err
 	}
-	return nil
+	if err := validateTags(c.Tags); err != nil {
+		return err
+	}
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+	return validateTargetUID(c.Config, c.TargetUID)
 }
 
 // swagger:model
@@ -132,6 +768,75 @@ type DeleteCorrelationCommand struct {
 	UID       string
 	SourceUID string
 	OrgId     int64
+	// IgnoreNotFound makes deleting a correlation that does not exist, or whose source data source does not exist, succeed.
+	IgnoreNotFound bool
+}
+
+// DeleteCorrelationsCommand is the command for deleting several correlations of an org at once
+// swagger:model
+type DeleteCorrelationsCommand struct {
+	// UIDs of the correlations to delete
+	// example: ["J6gn7d31L", "uWCpURgVk"]
+	UIDs  []string `json:"uids"`
+	OrgId int64    `json:"-"`
+}
+
+func (c DeleteCorrelationsCommand) Validate() error {
+	if len(c.UIDs) == 0 {
+		return ErrNoCorrelationsToDelete
+	}
+	return nil
+}
+
+// PatchCorrelationsCommand is the command for applying the same changes to several correlations of an org at once,
+// e.g. to point them to a new target data source after a migration
+// swagger:model
+type PatchCorrelationsCommand struct {
+	// UIDs of the correlations to update
+	// example: ["J6gn7d31L", "uWCpURgVk"]
+	UIDs  []string `json:"uids"`
+	OrgId int64    `json:"-"`
+	// Label to set on every correlation
+	// example: Logs to Traces
+	Label *string `json:"label"`
+	// Description to set on every correlation
+	// example: Logs to Traces
+	Description *string `json:"description"`
+	// UID of the data source every correlation should point to
+	// example: PE1C5CBDA0504A6A3
+	TargetUID *string `json:"targetUID"`
+}
+
+func (c PatchCorrelationsCommand) Validate() error {
+	if len(c.UIDs) == 0 {
+		return ErrNoCorrelationsToPatch
+	}
+	if c.Label == nil && c.Description == nil && c.TargetUID == nil {
+		return ErrUpdateCorrelationEmptyParams
+	}
+	if c.Label != nil {
+		if err := validateLabel(*c.Label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// swagger:model
+type PatchCorrelationsResponseBody struct {
+	// The updated correlations
+	Result []Correlation `json:"result"`
+	// example: Correlations updated
+	Message string `json:"message"`
+}
+
+// DeleteCorrelationsResult summarizes a DeleteCorrelationsCommand
+// swagger:model
+type DeleteCorrelationsResult struct {
+	// UIDs of the deleted correlations
+	Deleted []string `json:"deleted"`
+	// UIDs that matched no correlation of the org
+	NotFound []string `json:"notFound"`
 }
 
 // swagger:model
@@ -139,23 +844,120 @@ type UpdateCorrelationResponseBody struct {
 	Result Correlation `json:"result"`
 	// example: Correlation updated
 	Message string `json:"message"`
+	// Problems of the target query, only set if its validation was requested
+	Warnings []TargetQueryWarning `json:"warnings,omitempty"`
+}
+
+// InvalidConfigResponseBody is returned when a correlation config doesn't fit the correlation schema.
+// swagger:model
+type InvalidConfigResponseBody struct {
+	// example: Invalid correlation config
+	Message string `json:"message"`
+	// Every field that doesn't fit the schema
+	Errors []ConfigFieldError `json:"errors"`
+}
+
+// UnresolvedVariablesResponseBody is returned when the target of a correlation references variables the correlation
+// does not provide.
+// swagger:model
+type UnresolvedVariablesResponseBody struct {
+	// example: Target references variables the correlation does not provide
+	Message string `json:"message"`
+	// Names of the variables no field, transformation or mapping of the correlation provides
+	// example: ["traceId"]
+	MissingVariables []string `json:"missingVariables"`
 }
 
 // swagger:model
 type CorrelationConfigUpdateDTO struct {
-	// Field used to attach the correlation link
+	// Field used to attach the correlation link, or a list of fields to attach it to each of them
 	// example: message
-	Field *string `json:"field"`
+	Field *CorrelationFields `json:"field"`
 	// Target type
 	Type *CorrelationConfigType `json:"type"`
-	// Target data query
+	// Target data query, or for external correlations the URL template to link to
 	// example: { "expr": "job=app" }
 	Target *map[string]interface{} `json:"target"`
+	// Source data transformations
+	// example: [{"type": "logfmt"}]
+	Transformations Transformations `json:"transformations"`
+	// Fields or variables bound to other variable names
+	// example: [{"source": "traceID", "target": "traceId"}]
+	Mappings Mappings `json:"mappings"`
+	// Where the target is opened
+	// example: newTab
+	OpenMode *CorrelationOpenMode `json:"openMode"`
+	// How long to wait for the target query, in milliseconds. Zero uses the system default.
+	// example: 30000
+	TargetTimeoutMs *int `json:"targetTimeoutMs"`
+	// How the results of the target query are visualized. An empty value lets Explore decide.
+	// example: traces
+	TargetVisualization *CorrelationVisualization `json:"targetVisualization"`
+	// Condition the field value must match for the link to be shown. An empty condition removes it.
+	Condition *CorrelationCondition `json:"condition"`
+}
+
+// updateDTO returns the update replacing every part of a config with that of c.
+func (c CorrelationConfig) updateDTO() *CorrelationConfigUpdateDTO {
+	update := &CorrelationConfigUpdateDTO{
+		Field:               &c.Field,
+		Type:                &c.Type,
+		Target:              &c.Target,
+		Transformations:     c.Transformations,
+		Mappings:            c.Mappings,
+		OpenMode:            &c.OpenMode,
+		TargetTimeoutMs:     &c.TargetTimeoutMs,
+		TargetVisualization: &c.TargetVisualization,
+		Condition:           c.Condition,
+	}
+	// Unset lists and conditions leave those of the updated config as is, so they're cleared explicitly.
+	if update.Transformations == nil {
+		update.Transformations = Transformations{}
+	}
+	if update.Mappings == nil {
+		update.Mappings = Mappings{}
+	}
+	if update.Condition == nil {
+		update.Condition = &CorrelationCondition{}
+	}
+	return update
 }
 
 func (c CorrelationConfigUpdateDTO) Validate() error {
+	if err := validateConfigSchema(c.schemaValue(), false); err != nil {
+		return err
+	}
+
+	// An empty field has always been accepted when updating.
+	if c.Field != nil && len(*c.Field) > 0 {
+		if err := c.Field.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.TargetTimeoutMs != nil {
+		if err := validateTargetTimeout(*c.TargetTimeoutMs); err != nil {
+			return err
+		}
+	}
+
+	if c.Condition != nil && !c.Condition.IsZero() {
+		if err := c.Condition.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Transformations.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Mappings.Validate(); err != nil {
+		return err
+	}
+
+	// Otherwise, the transformations are checked against the type once the update is applied.
 	if c.Type != nil {
-		if err := c.Type.Validate(); err != nil {
+		if err := c.Type.validateTransformations(c.Transformations); err != nil {
 			return err
 		}
 	}
@@ -163,6 +965,40 @@ func (c CorrelationConfigUpdateDTO) Validate() error {
 	return nil
 }
 
+// schemaValue returns the parts of the config the update sets, as they're checked against the schema of the
+// correlation kind. Empty fields and conditions, which the update accepts, are left out.
+func (c CorrelationConfigUpdateDTO) schemaValue() map[string]interface{} {
+	value := map[string]interface{}{}
+	if c.Field != nil && len(*c.Field) > 0 {
+		value["field"] = *c.Field
+	}
+	if c.Type != nil {
+		value["type"] = *c.Type
+	}
+	if c.Target != nil {
+		value["target"] = *c.Target
+	}
+	if c.Transformations != nil {
+		value["transformations"] = c.Transformations
+	}
+	if c.Mappings != nil {
+		value["mappings"] = c.Mappings
+	}
+	if c.OpenMode != nil {
+		value["openMode"] = *c.OpenMode
+	}
+	if c.TargetTimeoutMs != nil {
+		value["targetTimeoutMs"] = *c.TargetTimeoutMs
+	}
+	if c.TargetVisualization != nil {
+		value["targetVisualization"] = *c.TargetVisualization
+	}
+	if c.Condition != nil && !c.Condition.IsZero() {
+		value["condition"] = *c.Condition
+	}
+	return value
+}
+
 // UpdateCorrelationCommand is the command for updating a correlation
 // swagger:model
 type UpdateCorrelationCommand struct {
@@ -177,24 +1013,188 @@ type UpdateCorrelationCommand struct {
 	// Optional description of the correlation
 	// example: Logs to Traces
 	Description *string `json:"description"`
+	// Optional notes for editors of the correlation
+	// example: See the tracing runbook before changing the target
+	Notes *string `json:"notes"`
+	// Optional UID of a new target data source, e.g. for orphaned correlations
+	// example: PE1C5CBDA0504A6A3
+	TargetUID *string `json:"targetUID"`
 	// Correlation Configuration
 	Config *CorrelationConfigUpdateDTO `json:"config"`
+	// Whether the correlation is enabled
+	// example: false
+	Enabled *bool `json:"enabled"`
+	// Tags of the correlation, replacing its current tags. An empty list removes all of them.
+	// example: ["payments"]
+	Tags *[]string `json:"tags"`
+	// Optional version of the correlation the update is based on. The update fails if the correlation has been
+	// changed since. Can also be given as the If-Match header of the request.
+	// example: 3
+	Version *int64 `json:"version"`
 }
 
 func (c UpdateCorrelationCommand) Validate() error {
+	if c.Version != nil && *c.Version < 1 {
+		return ErrInvalidCorrelationVersion
+	}
+
+	if c.Label != nil {
+		if err := validateLabel(*c.Label); err != nil {
+			return err
+		}
+	}
+
+	if c.Notes != nil {
+		if err := validateNotes(*c.Notes); err != nil {
+			return err
+		}
+	}
+
+	if c.Tags != nil {
+		if err := validateTags(*c.Tags); err != nil {
+			return err
+		}
+	}
+
 	if c.Config != nil {
 		if err := c.Config.Validate(); err != nil {
 			return err
 		}
 	}
 
-	if c.Label == nil && c.Description == nil && (c.Config == nil || (c.Config.Field == nil && c.Config.Type == nil && c.Config.Target == nil)) {
+	if c.Label == nil && c.Description == nil && c.Notes == nil && c.TargetUID == nil && c.Enabled == nil && c.Tags == nil && (c.Config == nil || (c.Config.Field == nil && c.Config.Type == nil && c.Config.Target == nil && c.Config.Transformations == nil && c.Config.Mappings == nil && c.Config.OpenMode == nil && c.Config.TargetTimeoutMs == nil && c.Config.TargetVisualization == nil && c.Config.Condition == nil)) {
 		return ErrUpdateCorrelationEmptyParams
 	}
 
 	return nil
 }
 
+// UpdateCorrelationsCommand is the command for updating several correlations of an org at once
+type UpdateCorrelationsCommand struct {
+	OrgId   int64                      `json:"-"`
+	Updates []UpdateCorrelationCommand `json:"updates"`
+	// BestEffort applies every update that succeeds and reports the others as failed. By default, the updates
+	// are applied in a single transaction, and none is applied if any fails.
+	BestEffort bool `json:"bestEffort"`
+}
+
+// Validate validates every update, returning an UpdateCorrelationsError for the first invalid one.
+func (c UpdateCorrelationsCommand) Validate() error {
+	for i, update := range c.Updates {
+		if err := c.validateUpdate(update); err != nil {
+			return UpdateCorrelationsError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
+
+func (c UpdateCorrelationsCommand) validateUpdate(update UpdateCorrelationCommand) error {
+	if update.OrgId != 0 && update.OrgId != c.OrgId {
+		return fmt.Errorf("%w: update belongs to org %d", ErrUpdateCorrelationsOrgMismatch, update.OrgId)
+	}
+	return update.Validate()
+}
+
+// UpdateCorrelationsError reports which update of an UpdateCorrelationsCommand failed.
+type UpdateCorrelationsError struct {
+	// Index of the failed update
+	Index int
+	Err   error
+}
+
+func (e UpdateCorrelationsError) Error() string {
+	return fmt.Sprintf("update %d: %s", e.Index, e.Err)
+}
+
+func (e UpdateCorrelationsError) Unwrap() error {
+	return e.Err
+}
+
+// UpdateCorrelationResult is the outcome of a single update of an UpdateCorrelationsCommand
+type UpdateCorrelationResult struct {
+	// Index of the update in the command
+	Index int `json:"index"`
+	// The updated correlation, if the update succeeded
+	Correlation *Correlation `json:"correlation,omitempty"`
+	// Err is set if the update failed
+	Err error `json:"-"`
+}
+
+// PreviewCorrelationQuery is the query to preview the target a correlation links to, when followed from a row of
+// the source data source with the given field values
+type PreviewCorrelationQuery struct {
+	UID       string `json:"-"`
+	SourceUID string `json:"-"`
+	OrgId     int64  `json:"-"`
+	// Field values of the source row
+	// example: {"message": "level=error traceID=abc"}
+	Fields map[string]string `json:"fields"`
+	// Time range of the source query
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// ExpandCorrelationTargetQuery is the query to expand the target of a correlation, when followed from a row of the
+// source data source with the given field values
+type ExpandCorrelationTargetQuery struct {
+	OrgId       int64
+	Correlation Correlation
+	// Field values of the source row
+	Fields map[string]string
+	// Time range of the source query
+	From time.Time
+	To   time.Time
+}
+
+// CorrelationPreview is the result of a PreviewCorrelationQuery or an ExpandCorrelationTargetQuery
+type CorrelationPreview struct {
+	// Target query with all resolvable variables interpolated
+	Target map[string]interface{} `json:"target"`
+	// Names of the variables that could not be resolved, and are left as is in the target
+	Unresolved []string `json:"unresolved"`
+	// Whether the row matches the condition of the correlation, so that the link is shown on it. Regex conditions
+	// the server can't evaluate are assumed to match.
+	Matched bool `json:"matched"`
+}
+
+// TestTransformationsQuery is the query to apply a chain of transformations to a sample field value, without
+// saving a correlation
+// swagger:model
+type TestTransformationsQuery struct {
+	// Name of the field the value is from. Regexes without mapValue bind the value they extract to it.
+	// required:true
+	// example: message
+	Field string `json:"field"`
+	// Sample value of the field
+	// required:true
+	// example: level=error traceID=abc
+	Value string `json:"value"`
+	// Transformations to apply, in order. All are applied to the sample value, whichever field they're configured
+	// for.
+	// required:true
+	Transformations Transformations `json:"transformations"`
+}
+
+func (q TestTransformationsQuery) Validate() error {
+	if err := validateFieldName(q.Field); err != nil {
+		return err
+	}
+	if len(q.Transformations) == 0 {
+		return ErrNoTransformationsToTest
+	}
+	return q.Transformations.Validate()
+}
+
+// TestTransformationsResult is the result of a TestTransformationsQuery
+// swagger:model
+type TestTransformationsResult struct {
+	// Variables bound by the transformations
+	// example: {"traceID": "abc"}
+	Variables map[string]string `json:"variables"`
+	// Indices of the transformations that extracted nothing from the sample value
+	Unmatched []int `json:"unmatched"`
+}
+
 // GetCorrelationQuery is the query to retrieve a single correlation
 type GetCorrelationQuery struct {
 	// UID of the correlation
@@ -208,17 +1208,237 @@ type GetCorrelationQuery struct {
 type GetCorrelationsBySourceUIDQuery struct {
 	SourceUID string `json:"-"`
 	OrgId     int64  `json:"-"`
+	// EnabledOnly excludes disabled correlations
+	EnabledOnly bool `json:"-"`
+	// Tags only returns correlations that have all of these tags, if set
+	Tags []string `json:"-"`
+	// OrphanedOnly only returns correlations whose target data source was deleted
+	OrphanedOnly bool `json:"-"`
+	// Query only returns correlations whose label, description or config, e.g. the target query, contains it
+	Query string `json:"-"`
+	// SortBy sorts the correlations, if set
+	SortBy CorrelationsSort `json:"-"`
+	// SortDirection is the direction of the sort, ascending by default
+	SortDirection SortDirection `json:"-"`
+}
+
+// GetCorrelationsByTargetUIDQuery is the query to retrieve all correlations of an org targeting the given data source,
+// from any source data source
+type GetCorrelationsByTargetUIDQuery struct {
+	TargetUID string `json:"-"`
+	OrgId     int64  `json:"-"`
+	// EnabledOnly excludes disabled correlations
+	EnabledOnly bool `json:"-"`
+	// SignedInUser only returns the correlations originating from data sources whose correlations the user can read,
+	// if set
+	SignedInUser *user.SignedInUser `json:"-"`
 }
 
 // GetCorrelationsQuery is the query to retrieve all correlations
 type GetCorrelationsQuery struct {
 	OrgId int64 `json:"-"`
+	// EnabledOnly excludes disabled correlations
+	EnabledOnly bool `json:"-"`
+	// Limit is the maximum number of correlations to return, 0 returns all of them
+	Limit int64 `json:"-"`
+	// Page is the 1-based page of correlations to return when a limit is set
+	Page int64 `json:"-"`
+	// SourceUIDs only returns correlations originating from one of these data sources, if set
+	SourceUIDs []string `json:"-"`
+	// TargetUIDs only returns correlations targeting one of these data sources, if set
+	TargetUIDs []string `json:"-"`
+	// Label only returns correlations whose label contains it, before tokens are resolved
+	Label string `json:"-"`
+	// Tags only returns correlations that have all of these tags, if set
+	Tags []string `json:"-"`
+	// OrphanedOnly only returns correlations whose target data source was deleted
+	OrphanedOnly bool `json:"-"`
+	// Query only returns correlations whose label, description or config, e.g. the target query, contains it
+	Query string `json:"-"`
+	// SortBy sorts the correlations, if set
+	SortBy CorrelationsSort `json:"-"`
+	// SortDirection is the direction of the sort, ascending by default
+	SortDirection SortDirection `json:"-"`
+	// SignedInUser only returns the correlations originating from data sources whose correlations the user can read,
+	// if set
+	SignedInUser *user.SignedInUser `json:"-"`
+}
+
+// GetCorrelationsResponseBody is a page of the correlations of an org
+// swagger:model
+type GetCorrelationsResponseBody struct {
+	Correlations []CorrelationListItem `json:"correlations"`
+	// Total number of correlations matching the query, across all pages
+	// example: 250
+	TotalCount int64 `json:"totalCount"`
+	// example: 1
+	Page int64 `json:"page"`
+	// example: 100
+	Limit int64 `json:"limit"`
+}
+
+// SearchCorrelationsByConfigQuery is the query to retrieve the correlations whose target query contains a term
+type SearchCorrelationsByConfigQuery struct {
+	OrgId int64  `json:"-"`
+	Term  string `json:"-"`
+}
+
+// CorrelationsCountByDataSourceQuery is the query to count correlations per data source
+type CorrelationsCountByDataSourceQuery struct {
+	OrgId int64 `json:"-"`
+	// ByTarget counts correlations per target data source instead of per source data source
+	ByTarget bool `json:"-"`
+	// IncludeEmpty also returns data sources without any correlation, with a count of 0
+	IncludeEmpty bool `json:"-"`
+}
+
+// DataSourceCorrelationsCount is the number of correlations of a single data source
+type DataSourceCorrelationsCount struct {
+	// UID of the data source
+	// example: d0oxYRg4z
+	UID string `json:"uid" xorm:"uid"`
+	// Number of correlations originating from (or pointing to) the data source
+	// example: 3
+	Count int64 `json:"count" xorm:"count"`
+}
+
+// CorrelationTemplate is a correlation definition that is stored once and materialized as a correlation for
+// each of many, usually similar, source data sources
+// swagger:model
+type CorrelationTemplate struct {
+	// Unique identifier of the template
+	// example: 8xDdz3M4k
+	UID   string `json:"uid" xorm:"pk 'uid'"`
+	OrgId int64  `json:"-" xorm:"org_id"`
+	// UID of the data source the materialized correlations point to
+	// example:PE1C5CBDA0504A6A3
+	TargetUID *string `json:"targetUID" xorm:"target_uid"`
+	// Label of the materialized correlations
+	// example: My Label
+	Label string `json:"label" xorm:"label"`
+	// Description of the materialized correlations
+	// example: Logs to Traces
+	Description string `json:"description" xorm:"description"`
+	// Configuration of the materialized correlations
+	Config CorrelationConfig `json:"config" xorm:"jsonb config"`
+}
+
+func (CorrelationTemplate) TableName() string {
+	return "correlation_template"
+}
+
+// CreateCorrelationTemplateCommand is the command for creating a correlation template
+type CreateCorrelationTemplateCommand struct {
+	OrgId       int64             `json:"-"`
+	TargetUID   *string           `json:"targetUID"`
+	Label       string            `json:"label"`
+	Description string            `json:"description"`
+	Config      CorrelationConfig `json:"config" binding:"Required"`
+}
+
+func (c CreateCorrelationTemplateCommand) Validate() error {
+	if err := validateLabel(c.Label); err != nil {
+		return err
+	}
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+	return validateTargetUID(c.Config, c.TargetUID)
+}
+
+// UpdateCorrelationTemplateCommand is the command for updating a correlation template
+type UpdateCorrelationTemplateCommand struct {
+	UID   string `json:"-"`
+	OrgId int64  `json:"-"`
+
+	Label       *string            `json:"label"`
+	Description *string            `json:"description"`
+	Config      *CorrelationConfig `json:"config"`
+	// Resync also applies the changes to all correlations materialized from the template
+	Resync bool `json:"resync"`
+}
+
+func (c UpdateCorrelationTemplateCommand) Validate() error {
+	if c.Label != nil {
+		if err := validateLabel(*c.Label); err != nil {
+			return err
+		}
+	}
+	if c.Config != nil {
+		return c.Config.Validate()
+	}
+	return nil
+}
+
+// ApplyTemplateCommand is the command for materializing a correlation template for the given source data
+// sources. Correlations previously materialized for a source are updated rather than duplicated.
+type ApplyTemplateCommand struct {
+	TemplateID string   `json:"-"`
+	SourceUIDs []string `json:"sourceUIDs"`
+	OrgId      int64    `json:"-"`
+}
+
+// DiffMatchKey is the key used to match correlations of two sets when diffing them
+type DiffMatchKey string
+
+const (
+	// DiffMatchByUID matches correlations with the same UID
+	DiffMatchByUID DiffMatchKey = "uid"
+	// DiffMatchBySourceAndLabel matches correlations with the same source data source and label, which is
+	// useful to compare correlations created independently of each other, e.g. in different orgs
+	DiffMatchBySourceAndLabel DiffMatchKey = "source_label"
+)
+
+// CorrelationSet is one side of a correlations diff
+type CorrelationSet struct {
+	// OrgId of the organization whose correlations are compared. Ignored if Correlations is set.
+	OrgId int64
+	// Correlations to compare, e.g. read from an exported document
+	Correlations []Correlation
+}
+
+// DiffCorrelationsCommand is the command to compare two sets of correlations
+type DiffCorrelationsCommand struct {
+	Base    CorrelationSet
+	Compare CorrelationSet
+	// MatchBy defines how correlations of both sets are matched. Defaults to DiffMatchByUID.
+	MatchBy DiffMatchKey
+}
+
+// CorrelationsDiff is the result of a DiffCorrelationsCommand
+type CorrelationsDiff struct {
+	// Correlations only present in the compared set
+	Added []Correlation `json:"added"`
+	// Correlations only present in the base set
+	Removed []Correlation `json:"removed"`
+	// Correlations present in both sets, but with different properties
+	Changed []CorrelationChange `json:"changed"`
+}
+
+// CorrelationChange describes the differences of a correlation present in both sets of a diff
+type CorrelationChange struct {
+	Base    Correlation `json:"base"`
+	Compare Correlation `json:"compare"`
+	// Properties that differ, e.g. "label" or "config.transformations"
+	Fields []string `json:"fields"`
+}
+
+// CorrelationKey identifies a correlation, whose UID is unique within its source data source.
+type CorrelationKey struct {
+	UID       string
+	SourceUID string
 }
 
 type DeleteCorrelationsBySourceUIDCommand struct {
 	SourceUID string
+	// OrgId is the org of the source data source, which the deletions are reported in
+	OrgId int64
 }
 
 type DeleteCorrelationsByTargetUIDCommand struct {
 	TargetUID string
+	OrgId     int64
+	// Orphan keeps the correlations of the org targeting the data source, without a target, rather than deleting
+	// the correlations of all orgs
+	Orphan bool
 }
@@ -2,6 +2,8 @@ package correlations
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -12,7 +14,7 @@ func TestCorrelationModels(t *testing.T) {
 		t.Run("Successfully validates a correct create command", func(t *testing.T) {
 			targetUid := "targetUid"
 			config := &CorrelationConfig{
-				Field:  "field",
+				Field:  CorrelationFields{"field"},
 				Target: map[string]interface{}{},
 				Type:   ConfigTypeQuery,
 			}
@@ -28,7 +30,7 @@ func TestCorrelationModels(t *testing.T) {
 
 		t.Run("Fails if target UID is not set and config type = query", func(t *testing.T) {
 			config := &CorrelationConfig{
-				Field:  "field",
+				Field:  CorrelationFields{"field"},
 				Target: map[string]interface{}{},
 				Type:   ConfigTypeQuery,
 			}
@@ -43,7 +45,7 @@ func TestCorrelationModels(t *testing.T) {
 
 		t.Run("Fails if config type is unknown", func(t *testing.T) {
 			config := &CorrelationConfig{
-				Field:  "field",
+				Field:  CorrelationFields{"field"},
 				Target: map[string]interface{}{},
 				Type:   "unknown config type",
 			}
@@ -55,9 +57,116 @@ func TestCorrelationModels(t *testing.T) {
 
 			require.Error(t, cmd.Validate())
 		})
+
+		t.Run("Enforces the maximum notes length", func(t *testing.T) {
+			targetUid := "targetUid"
+			cmd := CreateCorrelationCommand{
+				SourceUID: "some-uid",
+				OrgId:     1,
+				TargetUID: &targetUid,
+				Config:    CorrelationConfig{Field: CorrelationFields{"field"}, Type: ConfigTypeQuery},
+				Notes:     strings.Repeat("ü", MaxNotesLength),
+			}
+			require.NoError(t, cmd.Validate())
+
+			cmd.Notes += "!"
+			require.ErrorIs(t, cmd.Validate(), ErrCorrelationNotesTooLong)
+		})
+	})
+
+	t.Run("ValidateCorrelationUID", func(t *testing.T) {
+		type test struct {
+			uid       string
+			assertion require.ErrorAssertionFunc
+		}
+
+		tests := []test{
+			{uid: "50xhMlg9k", assertion: require.NoError},
+			{uid: "logs-to-traces_v2", assertion: require.NoError},
+			{uid: strings.Repeat("a", 40), assertion: require.NoError},
+			{uid: "", assertion: require.Error},
+			{uid: strings.Repeat("a", 41), assertion: require.Error},
+			{uid: "logs to traces", assertion: require.Error},
+			{uid: "logs/traces", assertion: require.Error},
+			{uid: "lögs", assertion: require.Error},
+		}
+
+		for _, tc := range tests {
+			tc.assertion(t, ValidateCorrelationUID(tc.uid), tc.uid)
+		}
+
+		require.ErrorIs(t, ValidateCorrelationUID("a.b"), ErrInvalidCorrelationUID)
+
+		targetUid := "targetUid"
+		cmd := CreateCorrelationCommand{
+			TargetUID: &targetUid,
+			Config:    CorrelationConfig{Field: CorrelationFields{"field"}, Type: ConfigTypeQuery},
+			UID:       "not/valid",
+		}
+		require.ErrorIs(t, cmd.Validate(), ErrInvalidCorrelationUID)
+	})
+
+	t.Run("UpdateCorrelationCommand Validate notes", func(t *testing.T) {
+		notes := "see the runbook"
+		require.NoError(t, UpdateCorrelationCommand{Notes: &notes}.Validate())
+
+		notes = ""
+		require.NoError(t, UpdateCorrelationCommand{Notes: &notes}.Validate())
+
+		notes = strings.Repeat("a", MaxNotesLength+1)
+		require.ErrorIs(t, UpdateCorrelationCommand{Notes: &notes}.Validate(), ErrCorrelationNotesTooLong)
+	})
+
+	t.Run("Validates correlation settings", func(t *testing.T) {
+		require.NoError(t, UpdateCorrelationSettingsCommand{OnTargetDeleted: TargetDeletionOrphan}.Validate())
+		require.NoError(t, UpdateCorrelationSettingsCommand{OnTargetDeleted: TargetDeletionDelete}.Validate())
+		require.ErrorIs(t, UpdateCorrelationSettingsCommand{OnTargetDeleted: "keep"}.Validate(), ErrInvalidCorrelationSettings)
+	})
+
+	t.Run("Accepts updates only changing the target", func(t *testing.T) {
+		targetUID := "tempo"
+		require.NoError(t, UpdateCorrelationCommand{TargetUID: &targetUID}.Validate())
 	})
 
-	t.Run("CorrelationConfigType Validate", func(t *testing.T) {
+	t.Run("Validates the version an update is based on", func(t *testing.T) {
+		label := "label"
+		version := int64(3)
+		require.NoError(t, UpdateCorrelationCommand{Label: &label, Version: &version}.Validate())
+
+		version = 0
+		require.ErrorIs(t, UpdateCorrelationCommand{Label: &label, Version: &version}.Validate(), ErrInvalidCorrelationVersion)
+
+		version = 3
+		require.ErrorIs(t, UpdateCorrelationCommand{Version: &version}.Validate(), ErrUpdateCorrelationEmptyParams)
+	})
+
+	t.Run("Validates tags", func(t *testing.T) {
+		targetUid := "targetUid"
+		cmd := CreateCorrelationCommand{
+			TargetUID: &targetUid,
+			Config:    CorrelationConfig{Field: CorrelationFields{"field"}, Type: ConfigTypeQuery},
+			Tags:      []string{"payments", " infra ", "payments"},
+		}
+		require.NoError(t, cmd.Validate())
+		require.Equal(t, []string{"payments", "infra"}, normalizeTags(cmd.Tags))
+
+		cmd.Tags = []string{"payments", " "}
+		require.ErrorIs(t, cmd.Validate(), ErrInvalidCorrelationTag)
+
+		cmd.Tags = []string{strings.Repeat("ü", MaxTagLength+1)}
+		require.ErrorIs(t, cmd.Validate(), ErrInvalidCorrelationTag)
+
+		tags := make([]string, 0, MaxTags+1)
+		for i := 0; i <= MaxTags; i++ {
+			tags = append(tags, fmt.Sprintf("tag-%d", i))
+		}
+		require.ErrorIs(t, UpdateCorrelationCommand{Tags: &tags}.Validate(), ErrInvalidCorrelationTag)
+
+		tags = []string{}
+		require.NoError(t, UpdateCorrelationCommand{Tags: &tags}.Validate())
+	})
+
+	t.Run("CorrelationConfig Validate type", func(t *testing.T) {
 		t.Run("Successfully validates a correct type", func(t *testing.T) {
 			type test struct {
 				input     CorrelationConfigType
@@ -70,22 +179,532 @@ func TestCorrelationModels(t *testing.T) {
 			}
 
 			for _, tc := range tests {
-				tc.assertion(t, tc.input.Validate())
+				tc.assertion(t, CorrelationConfig{Type: tc.input, Field: CorrelationFields{"message"}}.Validate())
 			}
 		})
 	})
 
+	t.Run("UpdateCorrelationsCommand Validate", func(t *testing.T) {
+		label := "label"
+
+		t.Run("Reports the index of the invalid update", func(t *testing.T) {
+			err := UpdateCorrelationsCommand{OrgId: 1, Updates: []UpdateCorrelationCommand{{Label: &label}, {}}}.Validate()
+			require.ErrorIs(t, err, ErrUpdateCorrelationEmptyParams)
+			var batchErr UpdateCorrelationsError
+			require.ErrorAs(t, err, &batchErr)
+			require.Equal(t, 1, batchErr.Index)
+		})
+
+		t.Run("Fails if updates belong to different orgs", func(t *testing.T) {
+			err := UpdateCorrelationsCommand{OrgId: 1, Updates: []UpdateCorrelationCommand{{Label: &label, OrgId: 2}}}.Validate()
+			require.ErrorIs(t, err, ErrUpdateCorrelationsOrgMismatch)
+		})
+	})
+
+	t.Run("PatchCorrelationsCommand Validate", func(t *testing.T) {
+		target := "tempo-uid"
+		require.NoError(t, PatchCorrelationsCommand{UIDs: []string{"a", "b"}, TargetUID: &target}.Validate())
+		require.ErrorIs(t, PatchCorrelationsCommand{TargetUID: &target}.Validate(), ErrNoCorrelationsToPatch)
+		require.ErrorIs(t, PatchCorrelationsCommand{UIDs: []string{"a"}}.Validate(), ErrUpdateCorrelationEmptyParams)
+	})
+
 	t.Run("CorrelationConfig JSON Marshaling", func(t *testing.T) {
 		t.Run("Applies a default empty object if target is not defined", func(t *testing.T) {
 			config := CorrelationConfig{
-				Field: "field",
+				Field: CorrelationFields{"field"},
 				Type:  ConfigTypeQuery,
 			}
 
 			data, err := json.Marshal(config)
 			require.NoError(t, err)
 
-			require.Equal(t, `{"type":"query","field":"field","target":{}}`, string(data))
+			require.Equal(t, `{"type":"query","field":"field","target":{},"openMode":"explore"}`, string(data))
+		})
+	})
+
+	t.Run("CorrelationConfig Validate", func(t *testing.T) {
+		t.Run("Validates open modes", func(t *testing.T) {
+			type test struct {
+				mode      CorrelationOpenMode
+				assertion require.ErrorAssertionFunc
+			}
+
+			tests := []test{
+				{mode: "", assertion: require.NoError},
+				{mode: OpenModeExplore, assertion: require.NoError},
+				{mode: OpenModeSplit, assertion: require.NoError},
+				{mode: OpenModeNewTab, assertion: require.NoError},
+				{mode: "popup", assertion: require.Error},
+				{mode: "Explore", assertion: require.Error},
+			}
+
+			for _, tc := range tests {
+				config := CorrelationConfig{Field: CorrelationFields{"message"}, Type: ConfigTypeQuery, Target: map[string]interface{}{}, OpenMode: tc.mode}
+				err := config.Validate()
+				tc.assertion(t, err, tc.mode)
+				if err != nil {
+					require.ErrorIs(t, err, ErrInvalidOpenMode)
+				}
+			}
+		})
+
+		t.Run("Validates the open mode of updates", func(t *testing.T) {
+			mode := CorrelationOpenMode("popup")
+			require.ErrorIs(t, CorrelationConfigUpdateDTO{OpenMode: &mode}.Validate(), ErrInvalidOpenMode)
+
+			mode = OpenModeSplit
+			require.NoError(t, UpdateCorrelationCommand{Config: &CorrelationConfigUpdateDTO{OpenMode: &mode}}.Validate())
+		})
+
+		t.Run("Accepts updates that only toggle the correlation", func(t *testing.T) {
+			enabled := false
+			require.NoError(t, UpdateCorrelationCommand{Enabled: &enabled}.Validate())
+			require.ErrorIs(t, UpdateCorrelationCommand{}.Validate(), ErrUpdateCorrelationEmptyParams)
+		})
+
+		t.Run("Accepts updates that only change the mappings", func(t *testing.T) {
+			require.NoError(t, UpdateCorrelationCommand{Config: &CorrelationConfigUpdateDTO{Mappings: Mappings{{Source: "trace_id", Target: "traceId"}}}}.Validate())
+			require.NoError(t, UpdateCorrelationCommand{Config: &CorrelationConfigUpdateDTO{Mappings: Mappings{}}}.Validate())
+		})
+
+		t.Run("Validates field names", func(t *testing.T) {
+			type test struct {
+				field     string
+				assertion require.ErrorAssertionFunc
+			}
+
+			tests := []test{
+				{field: "message", assertion: require.NoError},
+				{field: "trace id", assertion: require.NoError},
+				{field: "Time", assertion: require.NoError},
+				{field: "", assertion: require.Error},
+				{field: "   ", assertion: require.Error},
+				{field: " message", assertion: require.Error},
+				{field: "message\n", assertion: require.Error},
+				{field: "mess\x00age", assertion: require.Error},
+				{field: "message\u007f", assertion: require.Error},
+			}
+
+			for _, tc := range tests {
+				config := CorrelationConfig{Field: CorrelationFields{tc.field}, Type: ConfigTypeQuery, Target: map[string]interface{}{}}
+				err := config.Validate()
+				tc.assertion(t, err, tc.field)
+				if err != nil {
+					require.ErrorIs(t, err, ErrInvalidCorrelationField)
+				}
+			}
+		})
+	})
+
+	t.Run("Transformation Split", func(t *testing.T) {
+		type test struct {
+			index    int
+			expected string
+			ok       bool
+		}
+
+		tests := []test{
+			{index: 0, expected: "", ok: true},
+			{index: 2, expected: "v1", ok: true},
+			{index: 3, expected: "users", ok: true},
+			{index: -1, expected: "42", ok: true},
+			{index: -3, expected: "v1", ok: true},
+			{index: 5, expected: "", ok: false},
+			{index: -6, expected: "", ok: false},
+		}
+
+		for _, tc := range tests {
+			transformation := Transformation{Type: TransformationSplit, Delimiter: "/", Index: tc.index, MapValue: "segment"}
+			part, ok := transformation.Split("/api/v1/users/42")
+			require.Equal(t, tc.ok, ok, tc.index)
+			require.Equal(t, tc.expected, part, tc.index)
+		}
+	})
+
+	t.Run("CorrelationConfig JSON Marshaling includes split transformations", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeQuery,
+			Field:           CorrelationFields{"path"},
+			Transformations: Transformations{{Type: TransformationSplit, Delimiter: "/", Index: -1, MapValue: "segment"}},
+		}
+		data, err := json.Marshal(config)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"type":"query","field":"path","target":{},"transformations":[{"type":"split","delimiter":"/","index":-1,"mapValue":"segment"}],"openMode":"explore"}`, string(data))
+	})
+
+	t.Run("CorrelationConfig JSON Marshaling round-trips open modes", func(t *testing.T) {
+		for _, mode := range []CorrelationOpenMode{OpenModeExplore, OpenModeSplit, OpenModeNewTab} {
+			config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Target: map[string]interface{}{}, OpenMode: mode}
+			data, err := json.Marshal(config)
+			require.NoError(t, err)
+
+			var decoded CorrelationConfig
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			require.Equal(t, config, decoded)
+		}
+
+		data, err := json.Marshal(CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}})
+		require.NoError(t, err)
+		var decoded CorrelationConfig
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, OpenModeExplore, decoded.OpenMode)
+	})
+
+	t.Run("CorrelationConfig Validate target timeout", func(t *testing.T) {
+		type test struct {
+			timeout   int
+			assertion require.ErrorAssertionFunc
+		}
+
+		tests := []test{
+			{timeout: 0, assertion: require.NoError},
+			{timeout: 30000, assertion: require.NoError},
+			{timeout: MaxTargetTimeoutMs, assertion: require.NoError},
+			{timeout: MaxTargetTimeoutMs + 1, assertion: require.Error},
+			{timeout: -1, assertion: require.Error},
+		}
+
+		for _, tc := range tests {
+			config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, TargetTimeoutMs: tc.timeout}
+			tc.assertion(t, config.Validate(), tc.timeout)
+		}
+
+		negative := -1
+		err := UpdateCorrelationCommand{Config: &CorrelationConfigUpdateDTO{TargetTimeoutMs: &negative}}.Validate()
+		require.ErrorIs(t, err, ErrInvalidTargetTimeout)
+
+		tooLong := MaxTargetTimeoutMs + 1
+		err = UpdateCorrelationCommand{Config: &CorrelationConfigUpdateDTO{TargetTimeoutMs: &tooLong}}.Validate()
+		require.ErrorIs(t, err, ErrInvalidTargetTimeout)
+	})
+
+	t.Run("CorrelationConfig Validate applies a changed target timeout limit", func(t *testing.T) {
+		previous := MaxTargetTimeoutMs
+		t.Cleanup(func() { MaxTargetTimeoutMs = previous })
+		MaxTargetTimeoutMs = 1000
+
+		config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, TargetTimeoutMs: 1000}
+		require.NoError(t, config.Validate())
+		config.TargetTimeoutMs = 1001
+		require.ErrorIs(t, config.Validate(), ErrInvalidTargetTimeout)
+	})
+
+	t.Run("CorrelationConfig JSON Marshaling round-trips the target timeout", func(t *testing.T) {
+		data, err := json.Marshal(CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, TargetTimeoutMs: 30000})
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"targetTimeoutMs":30000`)
+
+		var decoded CorrelationConfig
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, 30000, decoded.TargetTimeoutMs)
+
+		data, err = json.Marshal(CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}})
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "targetTimeoutMs")
+	})
+
+	t.Run("CorrelationConfig Validate target visualization", func(t *testing.T) {
+		for _, v := range []CorrelationVisualization{"", VisualizationLogs, VisualizationTable, VisualizationGraph, VisualizationTraces} {
+			config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, TargetVisualization: v}
+			require.NoError(t, config.Validate(), v)
+
+			update := UpdateCorrelationCommand{Config: &CorrelationConfigUpdateDTO{TargetVisualization: &v}}
+			require.NoError(t, update.Validate(), v)
+		}
+
+		config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, TargetVisualization: "heatmap"}
+		require.ErrorIs(t, config.Validate(), ErrInvalidTargetVisualization)
+
+		invalid := CorrelationVisualization("Logs")
+		err := UpdateCorrelationCommand{Config: &CorrelationConfigUpdateDTO{TargetVisualization: &invalid}}.Validate()
+		require.ErrorIs(t, err, ErrInvalidTargetVisualization)
+	})
+
+	t.Run("CorrelationConfig JSON Marshaling round-trips the target visualization", func(t *testing.T) {
+		data, err := json.Marshal(CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, TargetVisualization: VisualizationTraces})
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"targetVisualization":"traces"`)
+
+		var decoded CorrelationConfig
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, VisualizationTraces, decoded.TargetVisualization)
+
+		data, err = json.Marshal(CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}})
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "targetVisualization")
+	})
+
+	t.Run("CorrelationConfig Validate transformation capabilities", func(t *testing.T) {
+		// Trace correlations generate their target from the field, so they can't use any transformation.
+		const linkType = ConfigTypeTrace
+
+		transformations := Transformations{{Type: TransformationRegex, Expression: "(\\w+)"}, {Type: TransformationLogfmt}}
+
+		t.Run("accepts transformations supported by the type", func(t *testing.T) {
+			config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Transformations: transformations}
+			require.NoError(t, config.Validate())
 		})
+
+		t.Run("rejects transformations the type can't use", func(t *testing.T) {
+			require.NoError(t, CorrelationConfig{Type: linkType, Field: CorrelationFields{"message"}}.Validate())
+
+			config := CorrelationConfig{Type: linkType, Field: CorrelationFields{"message"}, Transformations: transformations}
+			require.ErrorIs(t, config.Validate(), ErrUnsupportedTransformation)
+
+			configType := linkType
+			update := UpdateCorrelationCommand{Config: &CorrelationConfigUpdateDTO{Type: &configType, Transformations: transformations}}
+			require.ErrorIs(t, update.Validate(), ErrUnsupportedTransformation)
+		})
+	})
+
+	t.Run("CorrelationConfig Validate external targets", func(t *testing.T) {
+		external := func(url interface{}) CorrelationConfig {
+			return CorrelationConfig{
+				Type:   ConfigTypeExternal,
+				Field:  CorrelationFields{"message"},
+				Target: map[string]interface{}{ExternalTargetURL: url},
+			}
+		}
+
+		testCases := []struct {
+			name   string
+			config CorrelationConfig
+			err    error
+		}{
+			{
+				name:   "accepts absolute URLs",
+				config: external("https://search.example.com/?q=${message}"),
+			},
+			{
+				name:   "rejects missing URLs",
+				config: CorrelationConfig{Type: ConfigTypeExternal, Field: CorrelationFields{"message"}, Target: map[string]interface{}{}},
+				err:    ErrInvalidExternalTarget,
+			},
+			{
+				name:   "rejects URLs that aren't strings",
+				config: external(42),
+				err:    ErrInvalidExternalTarget,
+			},
+			{
+				name:   "rejects query keys next to the URL",
+				config: CorrelationConfig{Type: ConfigTypeExternal, Field: CorrelationFields{"message"}, Target: map[string]interface{}{ExternalTargetURL: "https://example.com", "expr": "up"}},
+				err:    ErrInvalidExternalTarget,
+			},
+			{
+				name:   "rejects relative URLs",
+				config: external("/browse/${message}"),
+				err:    ErrInvalidExternalTarget,
+			},
+			{
+				name:   "rejects URLs with other schemes",
+				config: external("javascript:alert(${message})"),
+				err:    ErrInvalidExternalTarget,
+			},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.config.Validate()
+				if tc.err == nil {
+					require.NoError(t, err)
+					return
+				}
+				require.ErrorIs(t, err, tc.err)
+			})
+		}
+	})
+
+	t.Run("CreateCorrelationCommand Validate external target UID", func(t *testing.T) {
+		targetUID := "targetUid"
+		cmd := CreateCorrelationCommand{
+			SourceUID: "some-uid",
+			OrgId:     1,
+			Config: CorrelationConfig{
+				Type:   ConfigTypeExternal,
+				Field:  CorrelationFields{"message"},
+				Target: map[string]interface{}{ExternalTargetURL: "https://example.com/${message}"},
+			},
+		}
+		require.NoError(t, cmd.Validate())
+
+		cmd.TargetUID = &targetUID
+		require.ErrorIs(t, cmd.Validate(), ErrInvalidTargetUID)
+	})
+
+	t.Run("CorrelationConfig JSON Marshaling keeps the external type", func(t *testing.T) {
+		data, err := json.Marshal(CorrelationConfig{
+			Type:   ConfigTypeExternal,
+			Field:  CorrelationFields{"message"},
+			Target: map[string]interface{}{ExternalTargetURL: "https://example.com/${message}"},
+		})
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"type":"external"`)
+
+		data, err = json.Marshal(CorrelationConfig{Field: CorrelationFields{"message"}})
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"type":"query"`)
+	})
+
+	t.Run("CorrelationConfig Migrate", func(t *testing.T) {
+		t.Run("Is a no-op for configs of the current version", func(t *testing.T) {
+			config := CorrelationConfig{
+				Type:            ConfigTypeQuery,
+				Field:           CorrelationFields{"message"},
+				Target:          map[string]interface{}{"expr": "job=app"},
+				Transformations: Transformations{{Type: TransformationLogfmt}},
+			}
+			require.False(t, config.NeedsMigration())
+
+			migrated := config
+			require.NoError(t, migrated.Migrate())
+			require.Equal(t, config, migrated)
+		})
+
+		t.Run("Upgrades configs stored before types were introduced", func(t *testing.T) {
+			var config CorrelationConfig
+			require.NoError(t, json.Unmarshal([]byte(`{"field":"message","target":{"expr":"job=app"}}`), &config))
+			require.True(t, config.NeedsMigration())
+
+			require.NoError(t, config.Migrate())
+			require.False(t, config.NeedsMigration())
+			require.Equal(t, CorrelationConfig{
+				Type:   ConfigTypeQuery,
+				Field:  CorrelationFields{"message"},
+				Target: map[string]interface{}{"expr": "job=app"},
+			}, config)
+		})
+
+		t.Run("Fails for configs of a newer version", func(t *testing.T) {
+			var config CorrelationConfig
+			require.NoError(t, json.Unmarshal([]byte(`{"type":"query","field":"message","version":99}`), &config))
+			require.False(t, config.NeedsMigration())
+			require.ErrorIs(t, config.Migrate(), ErrUnsupportedConfigVersion)
+			require.Equal(t, 99, config.Version)
+		})
+	})
+
+	t.Run("Transformations Validate", func(t *testing.T) {
+		t.Run("Fails if type is unknown", func(t *testing.T) {
+			err := Transformations{{Type: "jq", Expression: "."}}.Validate()
+			require.ErrorIs(t, err, ErrInvalidTransformationType)
+		})
+
+		t.Run("Fails if a regex transformation has no expression", func(t *testing.T) {
+			err := Transformations{{Type: TransformationRegex}}.Validate()
+			require.ErrorIs(t, err, ErrTransformationRegexReqExp)
+		})
+
+		t.Run("Enforces the maximum expression length", func(t *testing.T) {
+			atLimit := strings.Repeat("a", MaxTransformationExpressionLength)
+			require.NoError(t, Transformations{{Type: TransformationRegex, Expression: atLimit}}.Validate())
+
+			err := Transformations{
+				{Type: TransformationLogfmt},
+				{Type: TransformationRegex, Expression: atLimit + "a"},
+			}.Validate()
+			require.ErrorIs(t, err, ErrTransformationExpressionTooLong)
+			require.Contains(t, err.Error(), "transformation 1")
+		})
+
+		t.Run("Counts the expression length in characters", func(t *testing.T) {
+			atLimit := strings.Repeat("é", MaxTransformationExpressionLength)
+			require.NoError(t, Transformations{{Type: TransformationRegex, Expression: atLimit}}.Validate())
+
+			err := Transformations{{Type: TransformationRegex, Expression: atLimit + "é"}}.Validate()
+			require.ErrorIs(t, err, ErrTransformationExpressionTooLong)
+		})
+
+		t.Run("Applies a changed maximum expression length", func(t *testing.T) {
+			previous := MaxTransformationExpressionLength
+			t.Cleanup(func() { MaxTransformationExpressionLength = previous })
+			MaxTransformationExpressionLength = 8
+
+			require.NoError(t, Transformations{{Type: TransformationRegex, Expression: "trace=.*"}}.Validate())
+			err := CorrelationConfig{
+				Type:            ConfigTypeQuery,
+				Field:           CorrelationFields{"message"},
+				Transformations: Transformations{{Type: TransformationRegex, Expression: "traceID=(\\w+)"}},
+			}.Validate()
+			require.ErrorIs(t, err, ErrTransformationExpressionTooLong)
+		})
+
+		t.Run("Fails if a split transformation has no delimiter or variable", func(t *testing.T) {
+			err := Transformations{{Type: TransformationSplit, MapValue: "segment"}}.Validate()
+			require.ErrorIs(t, err, ErrTransformationSplitReqDelimiter)
+
+			err = Transformations{{Type: TransformationSplit, Delimiter: "/"}}.Validate()
+			require.ErrorIs(t, err, ErrTransformationSplitReqMapValue)
+
+			require.NoError(t, Transformations{{Type: TransformationSplit, Delimiter: "/", Index: -1, MapValue: "segment"}}.Validate())
+		})
+
+		t.Run("Fails if a jsonpath transformation has no valid path or variable", func(t *testing.T) {
+			err := Transformations{{Type: TransformationJSONPath, MapValue: "id"}}.Validate()
+			require.ErrorIs(t, err, ErrTransformationJSONPathReqExp)
+
+			err = Transformations{{Type: TransformationJSONPath, Expression: "$.user.id"}}.Validate()
+			require.ErrorIs(t, err, ErrTransformationJSONPathReqMapValue)
+
+			err = Transformations{{Type: TransformationJSONPath, Expression: "$..id", MapValue: "id"}}.Validate()
+			require.ErrorIs(t, err, ErrInvalidJSONPath)
+
+			require.NoError(t, Transformations{{Type: TransformationJSONPath, Expression: "$.user.id", MapValue: "id"}}.Validate())
+		})
+
+		t.Run("Rejects regexes with nested unbounded quantifiers", func(t *testing.T) {
+			type test struct {
+				expression string
+				assertion  require.ErrorAssertionFunc
+			}
+
+			tests := []test{
+				{expression: `(\w+)+$`, assertion: require.Error},
+				{expression: `(a*b?)*`, assertion: require.Error},
+				{expression: `((ab){2,})+`, assertion: require.Error},
+				{expression: `(a+){1,3}`, assertion: require.NoError},
+				{expression: `traceID=(\w+)`, assertion: require.NoError},
+				{expression: `(?<=id=)\w+`, assertion: require.NoError},
+			}
+
+			for _, tc := range tests {
+				tc.assertion(t, Transformations{{Type: TransformationRegex, Expression: tc.expression}}.Validate(), tc.expression)
+			}
+		})
+	})
+
+	t.Run("Correlation Kind", func(t *testing.T) {
+		type test struct {
+			name            string
+			transformations Transformations
+			mappings        Mappings
+			kind            CorrelationKind
+		}
+
+		tests := []test{
+			{name: "no transformations", kind: KindLink},
+			{name: "single regex", transformations: Transformations{{Type: TransformationRegex, Expression: "(Superman|Batman)", MapValue: "hero"}}, kind: KindExtract},
+			{name: "single split", transformations: Transformations{{Type: TransformationSplit, Delimiter: "/", Index: 2, MapValue: "path"}}, kind: KindExtract},
+			{name: "logfmt", transformations: Transformations{{Type: TransformationLogfmt}}, kind: KindMapped},
+			{name: "logfmt and regex", transformations: Transformations{{Type: TransformationRegex, Expression: "id=(\\w+)"}, {Type: TransformationLogfmt}}, kind: KindMapped},
+			{name: "regex and split", transformations: Transformations{{Type: TransformationRegex, Expression: "id=(\\w+)"}, {Type: TransformationSplit, Delimiter: "-", MapValue: "id"}}, kind: KindTransform},
+			{name: "mappings", mappings: Mappings{{Source: "traceID", Target: "traceId"}}, kind: KindMapped},
+			{name: "regex and mappings", transformations: Transformations{{Type: TransformationRegex, Expression: "id=(\\w+)", MapValue: "id"}}, mappings: Mappings{{Source: "id", Target: "traceId"}}, kind: KindMapped},
+		}
+
+		for _, tc := range tests {
+			c := Correlation{Config: CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Transformations: tc.transformations, Mappings: tc.mappings}}
+			require.Equal(t, tc.kind, c.Kind(), tc.name)
+		}
+	})
+
+	t.Run("CorrelationListItem JSON Marshaling includes the kind", func(t *testing.T) {
+		items := newCorrelationListItems([]Correlation{{UID: "uid", SourceUID: "source", Config: CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Transformations: Transformations{{Type: TransformationLogfmt}}}}})
+
+		data, err := json.Marshal(items)
+		require.NoError(t, err)
+
+		var decoded []map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, "mapped", decoded[0]["kind"])
+		require.Equal(t, "uid", decoded[0]["uid"])
 	})
 }
@@ -0,0 +1,134 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// TargetDeletionBehavior is what happens to the correlations of an org when their target data source is deleted.
+type TargetDeletionBehavior string
+
+const (
+	// TargetDeletionDelete deletes the correlations along with their target data source.
+	TargetDeletionDelete TargetDeletionBehavior = "delete"
+	// TargetDeletionOrphan keeps the correlations without a target and marks them as orphaned, so that they can be
+	// pointed at another data source.
+	TargetDeletionOrphan TargetDeletionBehavior = "orphan"
+)
+
+// CorrelationSettings are the settings of correlations applying to a whole org
+// swagger:model
+type CorrelationSettings struct {
+	// What happens to correlations when their target data source is deleted
+	// example: orphan
+	OnTargetDeleted TargetDeletionBehavior `json:"onTargetDeleted"`
+}
+
+// defaultCorrelationSettings are the settings of orgs that never changed them.
+var defaultCorrelationSettings = CorrelationSettings{OnTargetDeleted: TargetDeletionDelete}
+
+// correlationOrgSettings is a row of the correlation_org_settings table.
+type correlationOrgSettings struct {
+	ID              int64                  `xorm:"pk autoincr 'id'"`
+	OrgID           int64                  `xorm:"org_id"`
+	OnTargetDeleted TargetDeletionBehavior `xorm:"on_target_deleted"`
+	Updated         int64                  `xorm:"updated"`
+}
+
+func (s correlationOrgSettings) TableName() string {
+	return "correlation_org_settings"
+}
+
+// UpdateCorrelationSettingsCommand is the command to change the correlation settings of an org
+// swagger:model
+type UpdateCorrelationSettingsCommand struct {
+	OrgId int64 `json:"-"`
+	// What happens to correlations when their target data source is deleted, either "delete" or "orphan"
+	// example: orphan
+	OnTargetDeleted TargetDeletionBehavior `json:"onTargetDeleted" binding:"Required"`
+}
+
+func (c UpdateCorrelationSettingsCommand) Validate() error {
+	if c.OnTargetDeleted != TargetDeletionDelete && c.OnTargetDeleted != TargetDeletionOrphan {
+		return fmt.Errorf("%w: unknown onTargetDeleted %q", ErrInvalidCorrelationSettings, c.OnTargetDeleted)
+	}
+	return nil
+}
+
+func (s CorrelationsService) getSettings(ctx context.Context, orgID int64) (CorrelationSettings, error) {
+	settings := defaultCorrelationSettings
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		row := correlationOrgSettings{}
+		found, err := session.Where("org_id = ?", orgID).Get(&row)
+		if err != nil || !found {
+			return err
+		}
+		settings.OnTargetDeleted = row.OnTargetDeleted
+		return nil
+	})
+	return settings, err
+}
+
+func (s CorrelationsService) updateSettings(ctx context.Context, cmd UpdateCorrelationSettingsCommand) (CorrelationSettings, error) {
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		row := correlationOrgSettings{}
+		found, err := session.Where("org_id = ?", cmd.OrgId).Get(&row)
+		if err != nil {
+			return err
+		}
+		row.OrgID = cmd.OrgId
+		row.OnTargetDeleted = cmd.OnTargetDeleted
+		row.Updated = time.Now().Unix()
+		if !found {
+			_, err = session.Insert(&row)
+			return err
+		}
+		_, err = session.ID(row.ID).Update(&row)
+		return err
+	})
+	if err != nil {
+		return CorrelationSettings{}, err
+	}
+	return CorrelationSettings{OnTargetDeleted: cmd.OnTargetDeleted}, nil
+}
+
+// orphanCorrelationsByTargetUID removes the target of the correlations of an org targeting a data source, and marks
+// them as orphaned. As any other change, this makes a new version of each correlation.
+func (s CorrelationsService) orphanCorrelationsByTargetUID(ctx context.Context, cmd DeleteCorrelationsByTargetUIDCommand) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		targeting := make([]Correlation, 0)
+		if err := session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).
+			Where("correlation.target_uid = ?", cmd.TargetUID).Find(&targeting); err != nil {
+			return err
+		}
+		if err := loadTags(session, targeting); err != nil {
+			return err
+		}
+
+		for _, correlation := range targeting {
+			before := correlation
+			correlation.TargetUID = nil
+			correlation.Orphaned = true
+			correlation.Version++
+			if _, err := session.Where("uid = ? AND source_uid = ?", correlation.UID, correlation.SourceUID).
+				AllCols().Update(&correlation); err != nil {
+				return err
+			}
+			if err := saveVersion(session, cmd.OrgId, correlation); err != nil {
+				return err
+			}
+			// Deleted correlations are orphaned too, so that they aren't restored with a missing target, but their
+			// history ends with their deletion.
+			if correlation.DeletedAt != 0 {
+				continue
+			}
+			if err := recordHistory(ctx, session, cmd.OrgId, HistoryActionUpdated, &before, &correlation); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,201 @@
+package correlations
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Built-in variables that can be used in the target of a correlation, in addition to the fields of the source
+// row and the variables bound by transformations. They take precedence over fields and variables of the same name.
+const (
+	// VariableSourceUID is the UID of the source data source.
+	VariableSourceUID = "__sourceUID"
+	// VariableSourceName is the name of the source data source.
+	VariableSourceName = "__sourceName"
+	// VariableFrom is the start of the time range of the source query, in Unix milliseconds.
+	VariableFrom = "__from"
+	// VariableTo is the end of the time range of the source query, in Unix milliseconds.
+	VariableTo = "__to"
+)
+
+// BuiltInVariables are the names of all built-in variables.
+var BuiltInVariables = []string{VariableSourceUID, VariableSourceName, VariableFrom, VariableTo}
+
+// IsBuiltInVariable reports whether name is the name of a built-in variable.
+func IsBuiltInVariable(name string) bool {
+	for _, v := range BuiltInVariables {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+var variablePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// logfmtPattern matches the key/value pairs of a logfmt line, with optionally quoted values.
+var logfmtPattern = regexp.MustCompile(`([^\s=]+)=("(?:[^"\\]|\\.)*"|\S*)`)
+
+// resolveVariables returns the variables available to the target of a correlation when it is followed from a row
+// with the given field values: the fields themselves, the variables bound by the transformations and mappings, and
+// the built-in variables. The link of correlations attached to several fields is followed from the first of them the
+// row has, and its value is available under the names of all of them.
+func resolveVariables(config CorrelationConfig, fields map[string]string, builtIns map[string]string) map[string]string {
+	vars := make(map[string]string, len(fields)+len(builtIns))
+	for k, v := range fields {
+		vars[k] = v
+	}
+	correlationValue, found := config.Field.value(fields)
+	if found {
+		for _, name := range config.Field {
+			if _, ok := vars[name]; !ok {
+				vars[name] = correlationValue
+			}
+		}
+	}
+
+	for _, t := range config.Transformations {
+		field, value, ok := t.Field, correlationValue, found
+		if field == "" {
+			field = config.Field.Primary()
+		} else {
+			value, ok = fields[field]
+		}
+		if !ok {
+			continue
+		}
+
+		for name, v := range t.extract(field, value) {
+			vars[name] = v
+		}
+	}
+
+	for _, m := range config.Mappings {
+		if value, ok := vars[m.Source]; ok {
+			vars[m.Target] = value
+		}
+	}
+
+	for k, v := range builtIns {
+		vars[k] = v
+	}
+	return vars
+}
+
+// extract returns the variables a transformation binds when applied to the value of the given field, or nil if it
+// extracts nothing.
+func (t Transformation) extract(field, value string) map[string]string {
+	switch t.Type {
+	case TransformationRegex:
+		// The frontend evaluates expressions as JavaScript regexes. Expressions Go can't compile bind nothing here.
+		re, err := compileRegex(t.Expression)
+		if err != nil {
+			return nil
+		}
+		if names := captureGroupNames(t.Expression); len(names) > 0 {
+			match := re.FindStringSubmatchIndex(value)
+			if match == nil {
+				return nil
+			}
+			vars := make(map[string]string, len(names))
+			for i, name := range re.SubexpNames() {
+				if name != "" && match[2*i] >= 0 {
+					vars[name] = value[match[2*i]:match[2*i+1]]
+				}
+			}
+			return vars
+		}
+		if match := re.FindStringSubmatch(value); len(match) > 1 {
+			name := t.MapValue
+			if name == "" {
+				name = field
+			}
+			return map[string]string{name: match[1]}
+		}
+	case TransformationLogfmt:
+		matches := logfmtPattern.FindAllStringSubmatch(value, -1)
+		if len(matches) == 0 {
+			return nil
+		}
+		vars := make(map[string]string, len(matches))
+		for _, match := range matches {
+			v := match[2]
+			if unquoted, err := strconv.Unquote(v); err == nil {
+				v = unquoted
+			}
+			vars[match[1]] = v
+		}
+		return vars
+	case TransformationSplit:
+		if part, ok := t.Split(value); ok {
+			return map[string]string{t.MapValue: part}
+		}
+	case TransformationGrok:
+		if values, ok := t.Grok(value); ok {
+			return values
+		}
+	case TransformationJSONPath:
+		if selected, ok := t.JSONPath(value); ok {
+			return map[string]string{t.MapValue: selected}
+		}
+	}
+	return nil
+}
+
+// interpolateTarget returns a copy of target with every ${name} reference in its strings replaced by the value of
+// the variable, along with the sorted names of the variables that could not be resolved. Unresolved references are
+// left as is.
+func interpolateTarget(target map[string]interface{}, vars map[string]string) (map[string]interface{}, []string) {
+	unresolved := make(map[string]struct{})
+	var interpolate func(v interface{}) interface{}
+	interpolate = func(v interface{}) interface{} {
+		switch value := v.(type) {
+		case string:
+			return variablePattern.ReplaceAllStringFunc(value, func(ref string) string {
+				name := ref[2 : len(ref)-1]
+				if resolved, ok := vars[name]; ok {
+					return resolved
+				}
+				unresolved[name] = struct{}{}
+				return ref
+			})
+		case map[string]interface{}:
+			result := make(map[string]interface{}, len(value))
+			for k, item := range value {
+				result[k] = interpolate(item)
+			}
+			return result
+		case []interface{}:
+			result := make([]interface{}, len(value))
+			for i, item := range value {
+				result[i] = interpolate(item)
+			}
+			return result
+		default:
+			return v
+		}
+	}
+
+	result := make(map[string]interface{}, len(target))
+	for k, v := range target {
+		result[k] = interpolate(v)
+	}
+
+	names := make([]string, 0, len(unresolved))
+	for name := range unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return result, names
+}
+
+func builtInVariables(sourceUID, sourceName string, from, to time.Time) map[string]string {
+	return map[string]string{
+		VariableSourceUID:  sourceUID,
+		VariableSourceName: sourceName,
+		VariableFrom:       strconv.FormatInt(from.UnixMilli(), 10),
+		VariableTo:         strconv.FormatInt(to.UnixMilli(), 10),
+	}
+}
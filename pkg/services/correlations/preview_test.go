@@ -0,0 +1,156 @@
+package correlations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelationPreview(t *testing.T) {
+	from := time.UnixMilli(1672531200000)
+	to := from.Add(time.Hour)
+	builtIns := builtInVariables("loki-uid", "Loki", from, to)
+
+	t.Run("Resolves built-in variables", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:  ConfigTypeQuery,
+			Field: CorrelationFields{"message"},
+			Target: map[string]interface{}{
+				"expr":  `{source="${__sourceName}", uid="${__sourceUID}"}`,
+				"range": map[string]interface{}{"from": "${__from}", "to": "${__to}"},
+			},
+		}
+		require.NoError(t, config.Validate())
+
+		target, unresolved := interpolateTarget(config.Target, resolveVariables(config, nil, builtIns))
+		require.Empty(t, unresolved)
+		require.Equal(t, map[string]interface{}{
+			"expr":  `{source="Loki", uid="loki-uid"}`,
+			"range": map[string]interface{}{"from": "1672531200000", "to": "1672534800000"},
+		}, target)
+	})
+
+	t.Run("Built-in variables take precedence over fields", func(t *testing.T) {
+		config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Target: map[string]interface{}{"query": "${__sourceName}"}}
+		target, _ := interpolateTarget(config.Target, resolveVariables(config, map[string]string{"__sourceName": "spoofed"}, builtIns))
+		require.Equal(t, "Loki", target["query"])
+	})
+
+	t.Run("Resolves fields and transformation variables", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:  ConfigTypeQuery,
+			Field: CorrelationFields{"message"},
+			Target: map[string]interface{}{
+				"queries": []interface{}{"${traceID}", "${level} ${service}", "${path} ${host}", "${missing}"},
+			},
+			Transformations: Transformations{
+				{Type: TransformationLogfmt},
+				{Type: TransformationRegex, Expression: `service=(\w+)`, MapValue: "service"},
+				{Type: TransformationSplit, Field: "url", Delimiter: "/", Index: -1, MapValue: "path"},
+			},
+		}
+		fields := map[string]string{
+			"message": `level=error traceID=abc123 service=checkout msg="payment failed"`,
+			"url":     "/api/orders",
+			"host":    "web-1",
+		}
+
+		target, unresolved := interpolateTarget(config.Target, resolveVariables(config, fields, builtIns))
+		require.Equal(t, []interface{}{"abc123", "error checkout", "orders web-1", "${missing}"}, target["queries"])
+		require.Equal(t, []string{"missing"}, unresolved)
+	})
+
+	t.Run("Resolves every field of a correlation attached to several fields", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:   ConfigTypeQuery,
+			Field:  CorrelationFields{"traceID", "trace_id"},
+			Target: map[string]interface{}{"query": "${traceID} ${trace_id} ${id}"},
+			Transformations: Transformations{
+				{Type: TransformationRegex, Expression: `(\w+)-\d+`, MapValue: "id"},
+			},
+		}
+		require.NoError(t, config.Validate())
+
+		target, unresolved := interpolateTarget(config.Target, resolveVariables(config, map[string]string{"trace_id": "abc-123"}, builtIns))
+		require.Empty(t, unresolved)
+		require.Equal(t, "abc-123 abc-123 abc", target["query"])
+	})
+
+	t.Run("Unquotes logfmt values", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeQuery,
+			Field:           CorrelationFields{"message"},
+			Target:          map[string]interface{}{"query": "${msg}"},
+			Transformations: Transformations{{Type: TransformationLogfmt}},
+		}
+		target, _ := interpolateTarget(config.Target, resolveVariables(config, map[string]string{"message": `msg="payment \"failed\""`}, builtIns))
+		require.Equal(t, `payment "failed"`, target["query"])
+	})
+
+	t.Run("Resolves mapped variables", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeQuery,
+			Field:           CorrelationFields{"message"},
+			Target:          map[string]interface{}{"query": "${traceId} ${svc} ${unmapped}"},
+			Transformations: Transformations{{Type: TransformationRegex, Expression: `service=(\w+)`, MapValue: "service"}},
+			Mappings:        Mappings{{Source: "traceID", Target: "traceId"}, {Source: "service", Target: "svc"}, {Source: "missing", Target: "unmapped"}},
+		}
+		require.NoError(t, config.Validate())
+
+		fields := map[string]string{"message": "service=checkout", "traceID": "abc123"}
+		target, unresolved := interpolateTarget(config.Target, resolveVariables(config, fields, builtIns))
+		require.Equal(t, "abc123 checkout ${unmapped}", target["query"])
+		require.Equal(t, []string{"unmapped"}, unresolved)
+	})
+
+	t.Run("Knows all built-in variables", func(t *testing.T) {
+		for name := range builtIns {
+			require.True(t, IsBuiltInVariable(name), name)
+		}
+		require.False(t, IsBuiltInVariable("__value"))
+	})
+}
+
+func TestTransformationsDryRun(t *testing.T) {
+	s := CorrelationsService{}
+
+	t.Run("Returns the variables bound by each transformation", func(t *testing.T) {
+		result := s.TestTransformations(TestTransformationsQuery{
+			Field: "message",
+			Value: `level=error traceID=abc123 path=/api/orders?id=7`,
+			Transformations: Transformations{
+				{Type: TransformationRegex, Expression: `traceID=(\w+)`},
+				{Type: TransformationRegex, Expression: `path=(?<path>[^?\s]+)`},
+				{Type: TransformationRegex, Expression: `spanID=(\w+)`, MapValue: "spanId"},
+				{Type: TransformationLogfmt},
+			},
+		})
+		require.Equal(t, map[string]string{
+			"message": "abc123",
+			"path":    "/api/orders?id=7",
+			"level":   "error",
+			"traceID": "abc123",
+		}, result.Variables)
+		require.Equal(t, []int{2}, result.Unmatched)
+	})
+
+	t.Run("Applies every transformation to the sample value", func(t *testing.T) {
+		result := s.TestTransformations(TestTransformationsQuery{
+			Field:           "message",
+			Value:           `{"user": {"id": 42}}`,
+			Transformations: Transformations{{Type: TransformationJSONPath, Field: "body", Expression: "$.user.id", MapValue: "userId"}},
+		})
+		require.Equal(t, map[string]string{"userId": "42"}, result.Variables)
+		require.Empty(t, result.Unmatched)
+	})
+
+	t.Run("Validates the query", func(t *testing.T) {
+		require.ErrorIs(t, TestTransformationsQuery{Value: "a"}.Validate(), ErrInvalidCorrelationField)
+		require.ErrorIs(t, TestTransformationsQuery{Field: "message"}.Validate(), ErrNoTransformationsToTest)
+		require.ErrorIs(t, TestTransformationsQuery{
+			Field:           "message",
+			Transformations: Transformations{{Type: TransformationSplit}},
+		}.Validate(), ErrTransformationSplitReqDelimiter)
+	})
+}
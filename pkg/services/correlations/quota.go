@@ -0,0 +1,126 @@
+package correlations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/quota"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	QuotaTargetSrv quota.TargetSrv = "correlations"
+	QuotaTarget    quota.Target    = "correlations"
+)
+
+// readQuotaConfig returns the default global and per-org limits of correlations.
+func readQuotaConfig(cfg *setting.Cfg) (*quota.Map, error) {
+	limits := &quota.Map{}
+
+	if cfg == nil {
+		return limits, nil
+	}
+
+	globalQuotaTag, err := quota.NewTag(QuotaTargetSrv, QuotaTarget, quota.GlobalScope)
+	if err != nil {
+		return limits, err
+	}
+	orgQuotaTag, err := quota.NewTag(QuotaTargetSrv, QuotaTarget, quota.OrgScope)
+	if err != nil {
+		return limits, err
+	}
+
+	limits.Set(globalQuotaTag, cfg.Quota.Global.Correlations)
+	limits.Set(orgQuotaTag, cfg.Quota.Org.Correlations)
+	return limits, nil
+}
+
+// Usage counts the correlations of all orgs and, if the scope has one, of a single org. Correlations belong to the
+// org of their source data source.
+func (s CorrelationsService) Usage(ctx context.Context, scopeParams *quota.ScopeParameters) (*quota.Map, error) {
+	u := &quota.Map{}
+
+	var total int64
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		var err error
+		total, err = session.Table("correlation").Where(notDeleted).Count()
+		return err
+	})
+	if err != nil {
+		return u, err
+	}
+	tag, err := quota.NewTag(QuotaTargetSrv, QuotaTarget, quota.GlobalScope)
+	if err != nil {
+		return u, err
+	}
+	u.Set(tag, total)
+
+	if scopeParams != nil && scopeParams.OrgID != 0 {
+		var count int64
+		err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+			var err error
+			count, err = session.Table("correlation").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", scopeParams.OrgID).Where(notDeleted).Count()
+			return err
+		})
+		if err != nil {
+			return u, err
+		}
+		tag, err := quota.NewTag(QuotaTargetSrv, QuotaTarget, quota.OrgScope)
+		if err != nil {
+			return u, err
+		}
+		u.Set(tag, count)
+	}
+
+	return u, nil
+}
+
+// checkQuota returns ErrQuotaReached if the org can't have any more correlations.
+func (s CorrelationsService) checkQuota(ctx context.Context, orgID int64) error {
+	reached, err := s.QuotaService.CheckQuotaReached(ctx, QuotaTargetSrv, &quota.ScopeParameters{OrgID: orgID})
+	if err != nil {
+		return fmt.Errorf("failed to check correlations quota: %w", err)
+	}
+	if reached {
+		return ErrQuotaReached
+	}
+	return nil
+}
+
+// checkQuotaFor returns ErrQuotaReached if the org can't have count more correlations, e.g. when a template is
+// applied to several data sources at once.
+func (s CorrelationsService) checkQuotaFor(ctx context.Context, orgID int64, count int64) error {
+	if count <= 0 {
+		return nil
+	}
+	if err := s.checkQuota(ctx, orgID); err != nil {
+		return err
+	}
+	if count == 1 {
+		return nil
+	}
+
+	for _, scope := range []struct {
+		scope quota.Scope
+		id    int64
+	}{{quota.GlobalScope, 0}, {quota.OrgScope, orgID}} {
+		quotas, err := s.QuotaService.GetQuotasByScope(ctx, scope.scope, scope.id)
+		if err != nil {
+			if errors.Is(err, quota.ErrDisabled) {
+				return nil
+			}
+			return fmt.Errorf("failed to check correlations quota: %w", err)
+		}
+		for _, q := range quotas {
+			if q.Service != string(QuotaTargetSrv) || q.Target != string(QuotaTarget) || q.Limit < 0 {
+				continue
+			}
+			if q.Used+count > q.Limit {
+				return ErrQuotaReached
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package correlations
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/quota"
+	"github.com/grafana/grafana/pkg/services/quota/quotatest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// limitedQuotaService reports the usage and limits of correlations, and whether the quota is reached accordingly.
+type limitedQuotaService struct {
+	*quotatest.FakeQuotaService
+	quotas map[quota.Scope]quota.QuotaDTO
+}
+
+func (f limitedQuotaService) GetQuotasByScope(_ context.Context, scope quota.Scope, _ int64) ([]quota.QuotaDTO, error) {
+	if q, ok := f.quotas[scope]; ok {
+		return []quota.QuotaDTO{q}, nil
+	}
+	return []quota.QuotaDTO{}, nil
+}
+
+func (f limitedQuotaService) CheckQuotaReached(_ context.Context, _ quota.TargetSrv, _ *quota.ScopeParameters) (bool, error) {
+	for _, q := range f.quotas {
+		if q.Limit >= 0 && q.Used >= q.Limit {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newLimitedQuotaService(scope quota.Scope, used, limit int64) limitedQuotaService {
+	return limitedQuotaService{
+		FakeQuotaService: quotatest.New(false, nil),
+		quotas: map[quota.Scope]quota.QuotaDTO{
+			scope: {Service: string(QuotaTargetSrv), Target: string(QuotaTarget), Scope: string(scope), Used: used, Limit: limit},
+		},
+	}
+}
+
+func TestCorrelationsQuota(t *testing.T) {
+	t.Run("rejects correlations once the quota is reached", func(t *testing.T) {
+		s := CorrelationsService{QuotaService: quotatest.New(true, nil)}
+
+		_, err := s.CreateCorrelation(context.Background(), CreateCorrelationCommand{OrgId: 1, SourceUID: "loki"})
+		require.ErrorIs(t, err, ErrQuotaReached)
+
+		_, err = s.CreateCorrelations(context.Background(), []CreateCorrelationCommand{{OrgId: 1, SourceUID: "loki"}})
+		require.ErrorIs(t, err, ErrQuotaReached)
+	})
+
+	t.Run("fails if the quota can't be checked", func(t *testing.T) {
+		checkErr := errors.New("unavailable")
+		s := CorrelationsService{QuotaService: quotatest.New(false, checkErr)}
+
+		_, err := s.CreateCorrelation(context.Background(), CreateCorrelationCommand{OrgId: 1, SourceUID: "loki"})
+		require.ErrorIs(t, err, checkErr)
+		require.NotErrorIs(t, err, ErrQuotaReached)
+	})
+
+	t.Run("reads the default limits from the configuration", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.Quota.Org.Correlations = 5
+		cfg.Quota.Global.Correlations = -1
+
+		limits, err := readQuotaConfig(cfg)
+		require.NoError(t, err)
+		orgTag, err := quota.NewTag(QuotaTargetSrv, QuotaTarget, quota.OrgScope)
+		require.NoError(t, err)
+		limit, ok := limits.Get(orgTag)
+		require.True(t, ok)
+		require.Equal(t, int64(5), limit)
+
+		globalTag, err := quota.NewTag(QuotaTargetSrv, QuotaTarget, quota.GlobalScope)
+		require.NoError(t, err)
+		limit, ok = limits.Get(globalTag)
+		require.True(t, ok)
+		require.Equal(t, int64(-1), limit)
+	})
+	t.Run("rejects adding several correlations beyond the quota at once", func(t *testing.T) {
+		s := CorrelationsService{QuotaService: newLimitedQuotaService(quota.OrgScope, 2, 3)}
+
+		require.NoError(t, s.checkQuotaFor(context.Background(), 1, 1))
+		require.ErrorIs(t, s.checkQuotaFor(context.Background(), 1, 2), ErrQuotaReached)
+
+		s = CorrelationsService{QuotaService: newLimitedQuotaService(quota.GlobalScope, 2, 3)}
+		require.ErrorIs(t, s.checkQuotaFor(context.Background(), 1, 2), ErrQuotaReached)
+	})
+
+	t.Run("rejects creating several correlations that would go beyond the quota", func(t *testing.T) {
+		s := CorrelationsService{QuotaService: newLimitedQuotaService(quota.OrgScope, 2, 3)}
+
+		_, err := s.CreateCorrelations(context.Background(), []CreateCorrelationCommand{
+			{OrgId: 1, SourceUID: "loki"},
+			{OrgId: 1, SourceUID: "prometheus"},
+		})
+		require.ErrorIs(t, err, ErrQuotaReached)
+	})
+
+	t.Run("allows updating correlations once the quota is reached", func(t *testing.T) {
+		s := CorrelationsService{QuotaService: newLimitedQuotaService(quota.OrgScope, 3, 3)}
+
+		require.NoError(t, s.checkQuotaFor(context.Background(), 1, 0))
+	})
+
+	t.Run("ignores unlimited quotas", func(t *testing.T) {
+		s := CorrelationsService{QuotaService: newLimitedQuotaService(quota.OrgScope, 100, -1)}
+
+		require.NoError(t, s.checkQuotaFor(context.Background(), 1, 50))
+	})
+}
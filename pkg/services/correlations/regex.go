@@ -0,0 +1,52 @@
+package correlations
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// jsNamedGroupPattern matches the opening of a named capture group in the JavaScript syntax, (?<name>, but not the
+// lookbehinds (?<= and (?<! that share its prefix.
+var jsNamedGroupPattern = regexp.MustCompile(`\(\?<([^=!>][^>]*)>`)
+
+// namedGroupPattern matches the opening of a named capture group in either the JavaScript or the Go syntax. It's
+// only used for expressions Go can't parse.
+var namedGroupPattern = regexp.MustCompile(`\(\?P?<([^=!>][^>]*)>`)
+
+// variableNamePattern matches the names capture groups may bind, which must be usable as ${name} in targets.
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// toGoRegex rewrites the named capture groups of a regex given in the JavaScript syntax, which the frontend uses,
+// into the Go syntax.
+func toGoRegex(expr string) string {
+	return jsNamedGroupPattern.ReplaceAllString(expr, "(?P<$1>")
+}
+
+// compileRegex compiles the expression of a regex transformation with Go's regex engine.
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile(toGoRegex(expr))
+}
+
+// captureGroupNames returns the names of the named capture groups of a regex, in order. Expressions Go can't parse,
+// e.g. because of lookarounds, are scanned for named groups instead, skipping escaped parentheses.
+func captureGroupNames(expr string) []string {
+	names := make([]string, 0)
+	if re, err := syntax.Parse(toGoRegex(expr), syntax.Perl); err == nil {
+		for _, name := range re.CapNames() {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	for _, match := range namedGroupPattern.FindAllStringSubmatchIndex(expr, -1) {
+		backslashes := len(expr[:match[0]]) - len(strings.TrimRight(expr[:match[0]], `\`))
+		if backslashes%2 == 1 {
+			continue
+		}
+		names = append(names, expr[match[2]:match[3]])
+	}
+	return names
+}
@@ -0,0 +1,110 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexCaptureGroups(t *testing.T) {
+	t.Run("Finds named capture groups in both syntaxes", func(t *testing.T) {
+		require.Equal(t, []string{"host", "user"}, captureGroupNames(`(?P<host>[\w.]+) (?<user>\w+)`))
+		require.Equal(t, []string{}, captureGroupNames(`id=(\w+)`))
+		// Lookbehinds are not named groups, and Go can't parse them.
+		require.Equal(t, []string{"id"}, captureGroupNames(`(?<=id=)(?<id>\w+)(?<!x)\(?<escaped>\)`))
+	})
+
+	t.Run("Binds every named capture group", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeQuery,
+			Field:           CorrelationFields{"message"},
+			Target:          map[string]interface{}{"query": "${host} ${user} ${port}"},
+			Transformations: Transformations{{Type: TransformationRegex, Expression: `(?<user>\w+)@(?P<host>[\w.-]+)(?::(?<port>\d+))?`}},
+		}
+		require.NoError(t, config.Validate())
+
+		target, unresolved := interpolateTarget(config.Target, resolveVariables(config, map[string]string{"message": "login deploy@web-1.example.com"}, nil))
+		require.Equal(t, "web-1.example.com deploy ${port}", target["query"])
+		require.Equal(t, []string{"port"}, unresolved)
+	})
+
+	t.Run("Named capture groups provide variables to external correlations", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeExternal,
+			Field:           CorrelationFields{"message"},
+			Target:          map[string]interface{}{ExternalTargetURL: "https://hosts.example.com/${host}/${user}"},
+			Transformations: Transformations{{Type: TransformationRegex, Expression: `(?<user>\w+)@(?<host>[\w.]+)`}},
+		}
+		require.NoError(t, config.Validate())
+	})
+
+	t.Run("Validates named capture groups", func(t *testing.T) {
+		testCases := []struct {
+			name            string
+			transformations Transformations
+			err             error
+		}{
+			{
+				name:            "invalid variable name",
+				transformations: Transformations{{Type: TransformationRegex, Expression: `(?<user-name>\w+)`}},
+				err:             ErrInvalidCaptureGroupName,
+			},
+			{
+				name:            "built-in variable",
+				transformations: Transformations{{Type: TransformationRegex, Expression: `(?<__from>\d+)`}},
+				err:             ErrInvalidCaptureGroupName,
+			},
+			{
+				name:            "groups and mapValue",
+				transformations: Transformations{{Type: TransformationRegex, Expression: `(?<user>\w+)`, MapValue: "user"}},
+				err:             ErrTransformationRegexGroupsMapValue,
+			},
+			{
+				name:            "duplicate group",
+				transformations: Transformations{{Type: TransformationRegex, Expression: `(?<id>\w+)-(?<id>\w+)`}},
+				err:             ErrTransformationVariableCollision,
+			},
+			{
+				name: "group bound by another regex",
+				transformations: Transformations{
+					{Type: TransformationRegex, Expression: `user=(\w+)`, MapValue: "user"},
+					{Type: TransformationRegex, Expression: `(?<user>\w+)@(?<host>\w+)`},
+				},
+				err: ErrTransformationVariableCollision,
+			},
+			{
+				name: "group bound by a split",
+				transformations: Transformations{
+					{Type: TransformationRegex, Expression: `(?<path>/\S+)`},
+					{Type: TransformationSplit, Field: "url", Delimiter: "?", MapValue: "path"},
+				},
+				err: ErrTransformationVariableCollision,
+			},
+			{
+				name: "group bound by grok",
+				transformations: Transformations{
+					{Type: TransformationGrok, Expression: `%{IP:client}`},
+					{Type: TransformationRegex, Expression: `(?<client>\S+)`},
+				},
+				err: ErrTransformationVariableCollision,
+			},
+			{
+				name: "distinct variables",
+				transformations: Transformations{
+					{Type: TransformationRegex, Expression: `(?<user>\w+)@(?<host>\w+)`},
+					{Type: TransformationSplit, Field: "url", Delimiter: "/", MapValue: "path"},
+				},
+			},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.transformations.Validate()
+				if tc.err == nil {
+					require.NoError(t, err)
+				} else {
+					require.ErrorIs(t, err, tc.err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cuelang.org/go/cue"
+	cueerrors "cuelang.org/go/cue/errors"
+	"github.com/grafana/thema"
+
+	"github.com/grafana/grafana/pkg/registry/corekind"
+)
+
+var (
+	configSchemaOnce sync.Once
+	// configSchemaMu serializes validations, as CUE values can't be evaluated concurrently.
+	configSchemaMu sync.Mutex
+	configSchema   cue.Value
+)
+
+// correlationConfigSchema returns the #Config definition of the current schema of the correlation kind, declared in
+// kinds/correlation.
+func correlationConfigSchema() cue.Value {
+	configSchemaOnce.Do(func() {
+		kind := corekind.NewBase(nil).Correlation()
+		schema := thema.SchemaP(kind.Lineage(), kind.Decl().Properties.CurrentVersion)
+		configSchema = schema.Underlying().LookupPath(cue.MakePath(cue.Def("#Config")))
+	})
+	return configSchema
+}
+
+// ConfigFieldError is a field of a correlation config that doesn't fit the schema of the correlation kind.
+// swagger:model
+type ConfigFieldError struct {
+	// Path of the field in the correlation
+	// example: config.transformations[0].type
+	Field string `json:"field"`
+	// example: "sed" is not one of "grok", "jsonpath", "logfmt", "regex", "split"
+	Message string `json:"message"`
+}
+
+// ConfigSchemaError lists every field of a correlation config that doesn't fit the schema of the correlation kind.
+// Besides ErrInvalidCorrelationConfig, it matches the error of each of its fields, e.g. ErrInvalidOpenMode for the
+// openMode field, so that callers can tell what's wrong without parsing paths.
+type ConfigSchemaError struct {
+	Fields []ConfigFieldError
+}
+
+func (e ConfigSchemaError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", f.Field, f.Message))
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidCorrelationConfig, strings.Join(fields, "; "))
+}
+
+func (e ConfigSchemaError) Is(target error) bool {
+	if target == ErrInvalidCorrelationConfig {
+		return true
+	}
+	for _, f := range e.Fields {
+		if fieldError(f.Field) == target {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldError returns the error reported for the config field at path.
+func fieldError(path string) error {
+	switch {
+	case path == "config.type":
+		return ErrInvalidConfigType
+	case path == "config.field" || strings.HasPrefix(path, "config.field["):
+		return ErrInvalidCorrelationField
+	case path == "config.openMode":
+		return ErrInvalidOpenMode
+	case path == "config.targetTimeoutMs":
+		return ErrInvalidTargetTimeout
+	case path == "config.targetVisualization":
+		return ErrInvalidTargetVisualization
+	case strings.HasPrefix(path, "config.condition"):
+		return ErrInvalidCorrelationCondition
+	case strings.HasPrefix(path, "config.mappings"):
+		return ErrInvalidCorrelationMapping
+	case strings.HasPrefix(path, "config.transformations") && strings.HasSuffix(path, ".type"):
+		return ErrInvalidTransformationType
+	}
+	return ErrInvalidCorrelationConfig
+}
+
+// validateConfigSchema checks the JSON representation of config against the schema of the correlation kind. Required
+// fields that are missing are only reported if the config is complete, rather than the part of it an update sets.
+func validateConfigSchema(config interface{}, complete bool) error {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	configSchemaMu.Lock()
+	defer configSchemaMu.Unlock()
+
+	schema := correlationConfigSchema()
+	value := schema.Context().CompileBytes(raw)
+	if err := value.Err(); err != nil {
+		return err
+	}
+	if err := schema.Unify(value).Validate(cue.All(), cue.Concrete(complete)); err != nil {
+		return ConfigSchemaError{Fields: configFieldErrors(err)}
+	}
+	return nil
+}
+
+// configFieldErrors turns the errors of a CUE validation into one error per field. A value that matches none of the
+// allowed values is reported as a single error listing them, rather than an error per allowed value.
+func configFieldErrors(err error) []ConfigFieldError {
+	var fields []ConfigFieldError
+	allowed := map[string][]string{}
+	given := map[string]string{}
+	for _, e := range cueerrors.Errors(err) {
+		path := configFieldPath(e.Path())
+		format, args := e.Msg()
+		if format == "conflicting values %s and %s" && len(args) == 2 {
+			allowed[path] = append(allowed[path], fmt.Sprint(args[0]))
+			given[path] = fmt.Sprint(args[1])
+			continue
+		}
+		if strings.HasSuffix(format, "errors in empty disjunction:") {
+			continue
+		}
+		if format == "incomplete value %v" {
+			fields = append(fields, ConfigFieldError{Field: path, Message: "is required"})
+			continue
+		}
+		fields = append(fields, ConfigFieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for path, values := range allowed {
+		fields = append(fields, ConfigFieldError{Field: path, Message: fmt.Sprintf("%s is not one of %s", given[path], strings.Join(values, ", "))})
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// configFieldPath returns the path of a field of the #Config definition relative to the correlation, e.g.
+// config.transformations[0].type.
+func configFieldPath(path []string) string {
+	for i, selector := range path {
+		if selector == "#Config" {
+			path = path[i+1:]
+			break
+		}
+	}
+	var b strings.Builder
+	b.WriteString("config")
+	for _, selector := range path {
+		if _, err := strconv.Atoi(selector); err == nil {
+			b.WriteString("[" + selector + "]")
+			continue
+		}
+		b.WriteString("." + selector)
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package correlations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfigSchema(t *testing.T) {
+	t.Run("accepts configs that fit the schema", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:                ConfigTypeQuery,
+			Field:               CorrelationFields{"traceID", "trace_id"},
+			Target:              map[string]interface{}{"expr": "${traceID}"},
+			Transformations:     Transformations{{Type: TransformationSplit, Delimiter: "/", Index: -1, MapValue: "id"}},
+			Mappings:            Mappings{{Source: "traceID", Target: "traceId"}},
+			OpenMode:            OpenModeSplit,
+			TargetTimeoutMs:     MaxTargetTimeoutMs,
+			TargetVisualization: VisualizationTraces,
+			Condition:           &CorrelationCondition{Type: ConditionEquals, Value: "x"},
+		}
+		require.NoError(t, validateConfigSchema(config.schemaValue(), true))
+	})
+
+	t.Run("leaves the tunable limits to the config validation", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            ConfigTypeQuery,
+			Field:           CorrelationFields{"message"},
+			Transformations: Transformations{{Type: TransformationRegex, Expression: strings.Repeat("a", MaxTransformationExpressionLength+1)}},
+			TargetTimeoutMs: MaxTargetTimeoutMs + 1,
+		}
+		require.NoError(t, validateConfigSchema(config.schemaValue(), true))
+	})
+
+	t.Run("reports every field that doesn't fit", func(t *testing.T) {
+		config := CorrelationConfig{
+			Type:            "link",
+			Field:           CorrelationFields{"message"},
+			Transformations: Transformations{{Type: TransformationLogfmt}, {Type: "sed"}},
+			Mappings:        Mappings{{Source: "", Target: "traceId"}},
+			OpenMode:        "popup",
+			TargetTimeoutMs: -1,
+		}
+		err := validateConfigSchema(config.schemaValue(), true)
+
+		var invalid ConfigSchemaError
+		require.ErrorAs(t, err, &invalid)
+		fields := make([]string, 0, len(invalid.Fields))
+		for _, f := range invalid.Fields {
+			fields = append(fields, f.Field)
+		}
+		require.Equal(t, []string{
+			"config.mappings[0].source",
+			"config.openMode",
+			"config.targetTimeoutMs",
+			"config.transformations[1].type",
+			"config.type",
+		}, fields)
+		require.Equal(t, ConfigFieldError{Field: "config.type", Message: `"link" is not one of "dashboard", "external", "query", "trace"`}, invalid.Fields[4])
+	})
+
+	t.Run("reports missing required fields", func(t *testing.T) {
+		err := validateConfigSchema(CorrelationConfig{}.schemaValue(), true)
+
+		var invalid ConfigSchemaError
+		require.ErrorAs(t, err, &invalid)
+		require.Equal(t, []ConfigFieldError{
+			{Field: "config.field", Message: "is required"},
+			{Field: "config.type", Message: "is required"},
+		}, invalid.Fields)
+	})
+
+	t.Run("matches the error of each field", func(t *testing.T) {
+		err := validateConfigSchema(CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, OpenMode: "popup"}.schemaValue(), true)
+		require.ErrorIs(t, err, ErrInvalidCorrelationConfig)
+		require.ErrorIs(t, err, ErrInvalidOpenMode)
+		require.NotErrorIs(t, err, ErrInvalidConfigType)
+	})
+
+	t.Run("only checks the parts of the config an update sets", func(t *testing.T) {
+		empty := CorrelationFields{}
+		require.NoError(t, validateConfigSchema(CorrelationConfigUpdateDTO{Field: &empty, Condition: &CorrelationCondition{}}.schemaValue(), false))
+
+		visualization := CorrelationVisualization("heatmap")
+		err := validateConfigSchema(CorrelationConfigUpdateDTO{TargetVisualization: &visualization}.schemaValue(), false)
+		require.ErrorIs(t, err, ErrInvalidTargetVisualization)
+	})
+}
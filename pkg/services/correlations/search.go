@@ -0,0 +1,94 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// searchCorrelationsByConfig finds the correlations whose target query contains the term. The config column only
+// allows a coarse LIKE filter on the serialized config, which also hits keys and other config fields, so the rows
+// found are matched precisely against the values of the target query afterwards. An empty term matches every
+// correlation.
+func (s CorrelationsService) searchCorrelationsByConfig(ctx context.Context, cmd SearchCorrelationsByConfigQuery) ([]Correlation, error) {
+	candidates := make([]Correlation, 0)
+
+	err := s.SQLStore.WithDbSession(ctx, func(session *db.Session) error {
+		q := session.Select("correlation.*").Join("", "data_source AS dss", "correlation.source_uid = dss.uid and dss.org_id = ?", cmd.OrgId).Join("LEFT", "data_source AS dst", "correlation.target_uid = dst.uid and dst.org_id = ?", cmd.OrgId).Where(targetExists).And(notDeleted)
+		if pattern, ok := configLikePattern(cmd.Term); ok {
+			// The config is stored as text rather than jsonb, so it can be compared without a cast.
+			q = q.And(s.containsCond("correlation.config"), pattern)
+		}
+		return q.Find(&candidates)
+	})
+	if err != nil {
+		return []Correlation{}, err
+	}
+
+	correlations := make([]Correlation, 0, len(candidates))
+	for i := range candidates {
+		s.migrateConfig(&candidates[i])
+		if cmd.Term == "" || targetContains(candidates[i].Config.Target, cmd.Term) {
+			correlations = append(correlations, candidates[i])
+		}
+	}
+	return correlations, nil
+}
+
+// filterByText restricts a session to the correlations whose label, description or config contains the text. Unlike
+// searchCorrelationsByConfig, the config is only filtered in the database, so that lists can still be paginated: the
+// text may match a key of the config rather than a value of the target query.
+func (s CorrelationsService) filterByText(q *xorm.Session, text string) *xorm.Session {
+	if text == "" {
+		return q
+	}
+	label, description := s.containsCond("correlation.label"), s.containsCond("correlation.description")
+	pattern := containsPattern(text)
+	if configPattern, ok := configLikePattern(text); ok {
+		return q.And("("+label+" OR "+description+" OR "+s.containsCond("correlation.config")+")", pattern, pattern, configPattern)
+	}
+	return q.And("("+label+" OR "+description+")", pattern, pattern)
+}
+
+// configLikePattern returns the pattern of containsCond matching serialized configs that contain the term. Terms
+// that are escaped in JSON, e.g. because of quotes, are not filtered in the database at all, as the backslashes of
+// their serialized form are escape characters of LIKE on some databases.
+func configLikePattern(term string) (string, bool) {
+	if term == "" {
+		return "", false
+	}
+	encoded, err := json.Marshal(term)
+	if err != nil {
+		return "", false
+	}
+	escaped := string(encoded[1 : len(encoded)-1])
+	if strings.Contains(escaped, `\`) {
+		return "", false
+	}
+	return containsPattern(escaped), true
+}
+
+// targetContains reports whether any string in the target query, e.g. an expr or rawSql, contains the term.
+func targetContains(target interface{}, term string) bool {
+	switch v := target.(type) {
+	case string:
+		return strings.Contains(v, term)
+	case map[string]interface{}:
+		for _, value := range v {
+			if targetContains(value, term) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if targetContains(value, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigLikePattern(t *testing.T) {
+	testCases := []struct {
+		name     string
+		term     string
+		pattern  string
+		filtered bool
+	}{
+		{name: "wraps plain terms in wildcards", term: "job=", pattern: "%job=%", filtered: true},
+		{name: "escapes wildcards", term: "100%_!", pattern: "%100!%!_!!%", filtered: true},
+		{name: "does not filter on empty terms", term: ""},
+		{name: "does not filter on terms escaped in JSON", term: `{job="app"}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pattern, ok := configLikePattern(tc.term)
+			require.Equal(t, tc.filtered, ok)
+			require.Equal(t, tc.pattern, pattern)
+		})
+	}
+}
+
+func TestTargetContains(t *testing.T) {
+	target := map[string]interface{}{
+		"expr":    `rate({job="app"}[5m])`,
+		"refId":   "A",
+		"hide":    false,
+		"filters": []interface{}{map[string]interface{}{"key": "namespace", "value": "prod"}},
+	}
+
+	t.Run("matches substrings of query values", func(t *testing.T) {
+		require.True(t, targetContains(target, `job="app"`))
+	})
+
+	t.Run("matches values nested in lists", func(t *testing.T) {
+		require.True(t, targetContains(target, "prod"))
+	})
+
+	t.Run("does not match keys", func(t *testing.T) {
+		require.False(t, targetContains(target, "expr"))
+	})
+
+	t.Run("matches case-sensitively", func(t *testing.T) {
+		require.False(t, targetContains(target, "RATE"))
+	})
+}
@@ -0,0 +1,63 @@
+package correlations
+
+import (
+	"fmt"
+
+	"xorm.io/xorm"
+)
+
+// CorrelationsSort is what lists of correlations can be sorted by.
+type CorrelationsSort string
+
+const (
+	SortByLabel   CorrelationsSort = "label"
+	SortByCreated CorrelationsSort = "created"
+	SortByUpdated CorrelationsSort = "updated"
+)
+
+// sortColumns maps each sort to the column correlations are ordered by.
+var sortColumns = map[CorrelationsSort]string{
+	SortByLabel:   "correlation.label",
+	SortByCreated: "correlation.created",
+	SortByUpdated: "correlation.updated",
+}
+
+// SortDirection is the order of a sorted list of correlations.
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "asc"
+	SortDescending SortDirection = "desc"
+)
+
+// ParseCorrelationsSort reads the sort of a list of correlations and its direction. Both are optional: lists without a
+// sort keep their default order, and sorts without a direction are ascending.
+func ParseCorrelationsSort(sort, direction string) (CorrelationsSort, SortDirection, error) {
+	by := CorrelationsSort(sort)
+	if _, ok := sortColumns[by]; !ok && by != "" {
+		return "", "", fmt.Errorf("%w: unknown sort %q", ErrInvalidCorrelationsSort, sort)
+	}
+
+	dir := SortDirection(direction)
+	switch dir {
+	case "":
+		dir = SortAscending
+	case SortAscending, SortDescending:
+	default:
+		return "", "", fmt.Errorf("%w: unknown sort direction %q", ErrInvalidCorrelationsSort, direction)
+	}
+	return by, dir, nil
+}
+
+// sortCorrelations orders a session of correlations as requested. Correlations that compare equal stay in the order
+// of their keys, so that pages of a sorted list don't overlap.
+func sortCorrelations(q *xorm.Session, by CorrelationsSort, direction SortDirection) *xorm.Session {
+	column, ok := sortColumns[by]
+	if !ok {
+		return q
+	}
+	if direction == SortDescending {
+		column += " DESC"
+	}
+	return q.OrderBy(column + ", correlation.source_uid, correlation.uid")
+}
@@ -0,0 +1,38 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCorrelationsSort(t *testing.T) {
+	t.Run("sorts are optional and ascending by default", func(t *testing.T) {
+		by, direction, err := ParseCorrelationsSort("", "")
+		require.NoError(t, err)
+		require.Equal(t, CorrelationsSort(""), by)
+		require.Equal(t, SortAscending, direction)
+
+		by, direction, err = ParseCorrelationsSort("created", "")
+		require.NoError(t, err)
+		require.Equal(t, SortByCreated, by)
+		require.Equal(t, SortAscending, direction)
+	})
+
+	t.Run("every sort can be reversed", func(t *testing.T) {
+		for _, sort := range []CorrelationsSort{SortByLabel, SortByCreated, SortByUpdated} {
+			by, direction, err := ParseCorrelationsSort(string(sort), "desc")
+			require.NoError(t, err)
+			require.Equal(t, sort, by)
+			require.Equal(t, SortDescending, direction)
+		}
+	})
+
+	t.Run("unknown sorts and directions are rejected", func(t *testing.T) {
+		_, _, err := ParseCorrelationsSort("uid", "")
+		require.ErrorIs(t, err, ErrInvalidCorrelationsSort)
+
+		_, _, err = ParseCorrelationsSort("label", "up")
+		require.ErrorIs(t, err, ErrInvalidCorrelationsSort)
+	})
+}
@@ -0,0 +1,135 @@
+package correlations
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// MaxTagLength is the maximum length, in characters, of a correlation tag.
+var MaxTagLength = 50
+
+// MaxTags is the maximum number of tags of a correlation.
+var MaxTags = 20
+
+// correlationTag is a row of the correlation_tag table. Tags are kept in a table of their own, rather than a column
+// of the correlation table, so that correlations can be filtered by tag in SQL.
+type correlationTag struct {
+	ID             int64  `xorm:"pk autoincr 'id'"`
+	CorrelationUID string `xorm:"correlation_uid"`
+	SourceUID      string `xorm:"source_uid"`
+	Term           string `xorm:"term"`
+}
+
+func (t correlationTag) TableName() string {
+	return "correlation_tag"
+}
+
+// normalizeTags trims the tags of a correlation and drops duplicates, keeping the order they were given in.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func validateTags(tags []string) error {
+	tags = normalizeTags(tags)
+	if len(tags) > MaxTags {
+		return fmt.Errorf("%w: %d tags, the maximum is %d", ErrInvalidCorrelationTag, len(tags), MaxTags)
+	}
+	for _, tag := range tags {
+		if tag == "" {
+			return fmt.Errorf("%w: must not be empty", ErrInvalidCorrelationTag)
+		}
+		if n := utf8.RuneCountInString(tag); n > MaxTagLength {
+			return fmt.Errorf("%w: %q is %d characters long, the maximum is %d", ErrInvalidCorrelationTag, tag, n, MaxTagLength)
+		}
+	}
+	return nil
+}
+
+// saveTags replaces the tags of a correlation.
+func saveTags(session *db.Session, correlation Correlation) error {
+	if err := deleteTags(session, correlation.UID, correlation.SourceUID); err != nil {
+		return err
+	}
+	for _, tag := range correlation.Tags {
+		if _, err := session.Insert(&correlationTag{CorrelationUID: correlation.UID, SourceUID: correlation.SourceUID, Term: tag}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTags drops all tags of a correlation.
+func deleteTags(session *db.Session, uid, sourceUID string) error {
+	_, err := session.Where("source_uid = ? AND correlation_uid = ?", sourceUID, uid).Delete(&correlationTag{})
+	return err
+}
+
+// loadTags sets the tags of correlations read from the correlation table, in the order they were saved in.
+// Correlations without tags get an empty list.
+func loadTags(session *db.Session, correlations []Correlation) error {
+	if len(correlations) == 0 {
+		return nil
+	}
+	uids := make([]string, 0, len(correlations))
+	for _, correlation := range correlations {
+		uids = append(uids, correlation.UID)
+	}
+
+	rows := make([]correlationTag, 0)
+	if err := session.In("correlation_uid", uids).OrderBy("id").Find(&rows); err != nil {
+		return err
+	}
+	tags := make(map[[2]string][]string, len(correlations))
+	for _, row := range rows {
+		key := [2]string{row.CorrelationUID, row.SourceUID}
+		tags[key] = append(tags[key], row.Term)
+	}
+	for i := range correlations {
+		correlations[i].Tags = tags[[2]string{correlations[i].UID, correlations[i].SourceUID}]
+		if correlations[i].Tags == nil {
+			correlations[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// loadCorrelationTags sets the tags of a single correlation read from the correlation table.
+func loadCorrelationTags(session *db.Session, correlation *Correlation) error {
+	correlations := []Correlation{*correlation}
+	if err := loadTags(session, correlations); err != nil {
+		return err
+	}
+	correlation.Tags = correlations[0].Tags
+	return nil
+}
+
+// filterByTags restricts a session reading the correlation table to the correlations that have every given tag.
+func filterByTags(q *xorm.Session, tags []string) *xorm.Session {
+	args := make([]interface{}, 0, len(tags)+1)
+	for _, tag := range normalizeTags(tags) {
+		if tag != "" {
+			args = append(args, tag)
+		}
+	}
+	if len(args) == 0 {
+		return q
+	}
+	n := len(args)
+	args = append(args, n)
+	return q.And("(SELECT COUNT(DISTINCT ct.term) FROM correlation_tag AS ct WHERE ct.correlation_uid = correlation.uid AND ct.source_uid = correlation.source_uid AND ct.term IN (?"+strings.Repeat(",?", n-1)+")) = ?", args...)
+}
@@ -0,0 +1,165 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// TargetQueryWarning describes a problem of the target query of a correlation, which would likely make the query
+// fail once the correlation is followed. Warnings don't keep correlations from being saved.
+type TargetQueryWarning struct {
+	// Key of the target query the warning is about
+	// example: expr
+	Key string `json:"key"`
+	// example: unclosed "(" in expression
+	Message string `json:"message"`
+}
+
+// delimiters describes the quoting rules of a query language, as far as needed to tell whether brackets in a query
+// are balanced.
+type delimiters struct {
+	quotes string
+	// backslashEscapes is set if quoted characters can be escaped with a backslash.
+	backslashEscapes bool
+}
+
+var (
+	promQLDelimiters   = delimiters{quotes: "\"'`", backslashEscapes: true}
+	sqlDelimiters      = delimiters{quotes: "'\"`"}
+	graphiteDelimiters = delimiters{quotes: "'\"", backslashEscapes: true}
+)
+
+// targetQueryChecks maps data source types to shallow checks of the target queries of their correlations. The
+// checks only look at the shape of a query, queries are never run.
+var targetQueryChecks = map[string]func(target map[string]interface{}) []TargetQueryWarning{
+	datasources.DS_PROMETHEUS:     expressionCheck("expr", promQLDelimiters),
+	datasources.DS_LOKI:           expressionCheck("expr", promQLDelimiters),
+	datasources.DS_MYSQL:          expressionCheck("rawSql", sqlDelimiters),
+	datasources.DS_POSTGRES:       expressionCheck("rawSql", sqlDelimiters),
+	datasources.DS_MSSQL:          expressionCheck("rawSql", sqlDelimiters),
+	datasources.DS_GRAPHITE:       expressionCheck("target", graphiteDelimiters),
+	datasources.DS_ES:             checkElasticsearchQuery,
+	datasources.DS_ES_OPEN_DISTRO: checkElasticsearchQuery,
+	datasources.DS_ES_OPENSEARCH:  checkElasticsearchQuery,
+}
+
+// checkTargetQuery checks the target query of a query correlation against the type of its target data source.
+// Types without a check only get the checks common to all queries.
+func checkTargetQuery(config CorrelationConfig, targetType string) []TargetQueryWarning {
+	warnings := make([]TargetQueryWarning, 0)
+	if config.Type != ConfigTypeQuery {
+		return warnings
+	}
+
+	if refID, ok := config.Target["refId"]; ok {
+		if _, ok := refID.(string); !ok {
+			warnings = append(warnings, TargetQueryWarning{Key: "refId", Message: "must be a string"})
+		}
+	}
+	if hide, ok := config.Target["hide"].(bool); ok && hide {
+		warnings = append(warnings, TargetQueryWarning{Key: "hide", Message: "hidden queries return no data"})
+	}
+	if check, ok := targetQueryChecks[targetType]; ok {
+		warnings = append(warnings, check(config.Target)...)
+	}
+	return warnings
+}
+
+// expressionCheck returns a check of queries written in a query language, which requires the expression under key
+// to be a non-empty string with balanced brackets and quotes.
+func expressionCheck(key string, d delimiters) func(target map[string]interface{}) []TargetQueryWarning {
+	return func(target map[string]interface{}) []TargetQueryWarning {
+		raw, ok := target[key]
+		if !ok {
+			return []TargetQueryWarning{{Key: key, Message: "the query has no expression"}}
+		}
+		expr, ok := raw.(string)
+		if !ok {
+			return []TargetQueryWarning{{Key: key, Message: "must be a string"}}
+		}
+		if expr == "" {
+			return []TargetQueryWarning{{Key: key, Message: "the query has no expression"}}
+		}
+		if msg := d.check(expr); msg != "" {
+			return []TargetQueryWarning{{Key: key, Message: msg}}
+		}
+		return nil
+	}
+}
+
+// checkElasticsearchQuery checks the types of the parts of an Elasticsearch query.
+func checkElasticsearchQuery(target map[string]interface{}) []TargetQueryWarning {
+	var warnings []TargetQueryWarning
+	if query, ok := target["query"]; ok {
+		if _, ok := query.(string); !ok {
+			warnings = append(warnings, TargetQueryWarning{Key: "query", Message: "must be a string"})
+		}
+	}
+
+	for _, key := range []string{"bucketAggs", "metrics"} {
+		if raw, ok := target[key]; ok {
+			if _, ok := raw.([]interface{}); !ok {
+				warnings = append(warnings, TargetQueryWarning{Key: key, Message: "must be a list"})
+			}
+		}
+	}
+	return warnings
+}
+
+// check returns what's wrong with the brackets and quotes of expr, or an empty string if nothing is.
+func (d delimiters) check(expr string) string {
+	closing := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var open []rune
+	var quote rune
+	escaped := false
+	for _, r := range expr {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && d.backslashEscapes:
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case strings.ContainsRune(d.quotes, r):
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			open = append(open, r)
+		case closing[r] != 0:
+			if len(open) == 0 || open[len(open)-1] != closing[r] {
+				return fmt.Sprintf("unexpected %q in expression", r)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+
+	if quote != 0 {
+		return fmt.Sprintf("unclosed %q in expression", quote)
+	}
+	if len(open) > 0 {
+		return fmt.Sprintf("unclosed %q in expression", open[len(open)-1])
+	}
+	return ""
+}
+
+// validateTargetQuery runs checkTargetQuery against the target data source of a correlation. Correlations without
+// a target data source have no target query to check.
+func (s CorrelationsService) validateTargetQuery(ctx context.Context, orgID int64, correlation Correlation) ([]TargetQueryWarning, error) {
+	if correlation.TargetUID == nil || correlation.Config.Type != ConfigTypeQuery {
+		return make([]TargetQueryWarning, 0), nil
+	}
+
+	query := &datasources.GetDataSourceQuery{OrgId: orgID, Uid: *correlation.TargetUID}
+	if err := s.DataSourceService.GetDataSource(ctx, query); err != nil {
+		return nil, ErrTargetDataSourceDoesNotExists
+	}
+	return checkTargetQuery(correlation.Config, query.Result.Type), nil
+}
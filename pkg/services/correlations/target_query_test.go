@@ -0,0 +1,84 @@
+package correlations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+)
+
+func TestCheckTargetQuery(t *testing.T) {
+	query := func(target map[string]interface{}) CorrelationConfig {
+		return CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Target: target}
+	}
+
+	t.Run("accepts well-formed queries", func(t *testing.T) {
+		require.Empty(t, checkTargetQuery(query(map[string]interface{}{"expr": `sum(rate({job="${job}"} |= "error" [5m]))`}), datasources.DS_LOKI))
+		require.Empty(t, checkTargetQuery(query(map[string]interface{}{"expr": `up{instance="a\"(b"}`}), datasources.DS_PROMETHEUS))
+		require.Empty(t, checkTargetQuery(query(map[string]interface{}{"rawSql": `SELECT * FROM logs WHERE msg = 'it''s (' AND id IN (1, 2)`}), datasources.DS_POSTGRES))
+		require.Empty(t, checkTargetQuery(query(map[string]interface{}{"query": "level:error", "metrics": []interface{}{}}), datasources.DS_ES))
+	})
+
+	t.Run("reports malformed expressions", func(t *testing.T) {
+		cases := []struct {
+			target   map[string]interface{}
+			dsType   string
+			key      string
+			expected string
+		}{
+			{map[string]interface{}{"expr": `sum(rate(up[5m])`}, datasources.DS_PROMETHEUS, "expr", `unclosed '(' in expression`},
+			{map[string]interface{}{"expr": `up{job="api"]`}, datasources.DS_PROMETHEUS, "expr", `unexpected ']' in expression`},
+			{map[string]interface{}{"expr": `{app="api}`}, datasources.DS_LOKI, "expr", `unclosed '"' in expression`},
+			{map[string]interface{}{"expr": ""}, datasources.DS_LOKI, "expr", "the query has no expression"},
+			{map[string]interface{}{"expr": 1.0}, datasources.DS_LOKI, "expr", "must be a string"},
+			{map[string]interface{}{"rawSql": `SELECT 'a`}, datasources.DS_MYSQL, "rawSql", `unclosed '\'' in expression`},
+			{map[string]interface{}{"format": "table"}, datasources.DS_MSSQL, "rawSql", "the query has no expression"},
+			{map[string]interface{}{"target": "sumSeries(a.*"}, datasources.DS_GRAPHITE, "target", `unclosed '(' in expression`},
+			{map[string]interface{}{"bucketAggs": "date_histogram"}, datasources.DS_ES_OPENSEARCH, "bucketAggs", "must be a list"},
+		}
+		for _, tc := range cases {
+			warnings := checkTargetQuery(query(tc.target), tc.dsType)
+			require.Equal(t, []TargetQueryWarning{{Key: tc.key, Message: tc.expected}}, warnings, tc.target)
+		}
+	})
+
+	t.Run("reports problems common to all queries", func(t *testing.T) {
+		warnings := checkTargetQuery(query(map[string]interface{}{"refId": 1.0, "hide": true}), "tempo")
+		require.Equal(t, []TargetQueryWarning{
+			{Key: "refId", Message: "must be a string"},
+			{Key: "hide", Message: "hidden queries return no data"},
+		}, warnings)
+	})
+
+	t.Run("only checks query correlations", func(t *testing.T) {
+		config := CorrelationConfig{Type: ConfigTypeExternal, Field: CorrelationFields{"message"}, Target: map[string]interface{}{"url": "https://example.com"}}
+		require.Empty(t, checkTargetQuery(config, datasources.DS_PROMETHEUS))
+	})
+}
+
+func TestValidateTargetQuery(t *testing.T) {
+	s := CorrelationsService{
+		DataSourceService: &fakeDatasources.FakeDataSourceService{
+			DataSources: []*datasources.DataSource{
+				{Uid: "prometheus", Type: datasources.DS_PROMETHEUS, OrgId: 1},
+			},
+		},
+	}
+	targetUID := "prometheus"
+	correlation := Correlation{
+		TargetUID: &targetUID,
+		Config:    CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Target: map[string]interface{}{"expr": "up{"}},
+	}
+
+	warnings, err := s.ValidateTargetQuery(context.Background(), 1, correlation)
+	require.NoError(t, err)
+	require.Equal(t, []TargetQueryWarning{{Key: "expr", Message: `unclosed '{' in expression`}}, warnings)
+
+	missingUID := "mimir"
+	correlation.TargetUID = &missingUID
+	_, err = s.ValidateTargetQuery(context.Background(), 1, correlation)
+	require.ErrorIs(t, err, ErrTargetDataSourceDoesNotExists)
+}
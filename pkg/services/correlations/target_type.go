@@ -0,0 +1,65 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// targetQueryKeys maps keys of target queries that are specific to some data source types to these types. Keys
+// used by many types, such as "query" or "queryType", tell nothing about the type and are not listed.
+var targetQueryKeys = map[string][]string{
+	"expr":       {datasources.DS_PROMETHEUS, datasources.DS_LOKI},
+	"rawSql":     {datasources.DS_MYSQL, datasources.DS_POSTGRES, datasources.DS_MSSQL},
+	"bucketAggs": {datasources.DS_ES, datasources.DS_ES_OPEN_DISTRO, datasources.DS_ES_OPENSEARCH},
+	"target":     {datasources.DS_GRAPHITE},
+}
+
+// checkTargetType checks that the target query of a query correlation fits the type of its target data source,
+// judged by the keys of the query. Queries without type-specific keys fit any data source.
+func checkTargetType(config CorrelationConfig, targetType string) error {
+	if config.Type != ConfigTypeQuery {
+		return nil
+	}
+
+	keys := make([]string, 0, len(config.Target))
+	for key := range config.Target {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		types, ok := targetQueryKeys[key]
+		if !ok {
+			continue
+		}
+		compatible := false
+		for _, t := range types {
+			if t == targetType {
+				compatible = true
+				break
+			}
+		}
+		if !compatible {
+			return fmt.Errorf("%w: target query key %q is used by %s, not %s", ErrIncompatibleTargetDataSource, key, strings.Join(types, ", "), targetType)
+		}
+	}
+	return nil
+}
+
+// verifyTargetType runs checkTargetType against the target data source of a correlation. Mismatches are only
+// logged, unless StrictTargetTypeCheck is enabled.
+func (s CorrelationsService) verifyTargetType(ctx context.Context, orgID int64, correlation Correlation, target *datasources.DataSource) error {
+	err := checkTargetType(correlation.Config, target.Type)
+	if err == nil {
+		return nil
+	}
+	if s.StrictTargetTypeCheck {
+		return err
+	}
+	s.log.FromContext(ctx).Warn("Correlation target query does not fit the target data source", "orgId", orgID, "uid", correlation.UID, "sourceUID", correlation.SourceUID, "targetUID", target.Uid, "error", err)
+	return nil
+}
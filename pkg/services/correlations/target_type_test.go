@@ -0,0 +1,43 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+func TestCheckTargetType(t *testing.T) {
+	config := func(target map[string]interface{}) CorrelationConfig {
+		return CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"traceID"}, Target: target}
+	}
+
+	type test struct {
+		name       string
+		config     CorrelationConfig
+		targetType string
+		assertion  require.ErrorAssertionFunc
+	}
+
+	tests := []test{
+		{name: "matching query key", config: config(map[string]interface{}{"expr": "{job=\"app\"}"}), targetType: datasources.DS_LOKI, assertion: require.NoError},
+		{name: "key shared by several types", config: config(map[string]interface{}{"expr": "up"}), targetType: datasources.DS_PROMETHEUS, assertion: require.NoError},
+		{name: "no type-specific keys", config: config(map[string]interface{}{"query": "${traceID}"}), targetType: datasources.DS_TEMPO, assertion: require.NoError},
+		{name: "empty target", config: config(nil), targetType: datasources.DS_MYSQL, assertion: require.NoError},
+		{name: "mismatching query key", config: config(map[string]interface{}{"expr": "{job=\"app\"}"}), targetType: datasources.DS_MYSQL, assertion: require.Error},
+		{name: "one of several keys mismatching", config: config(map[string]interface{}{"query": "x", "rawSql": "SELECT 1"}), targetType: datasources.DS_LOKI, assertion: require.Error},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.assertion(t, checkTargetType(tc.config, tc.targetType))
+		})
+	}
+
+	t.Run("reports the offending key", func(t *testing.T) {
+		err := checkTargetType(config(map[string]interface{}{"rawSql": "SELECT 1"}), datasources.DS_LOKI)
+		require.ErrorIs(t, err, ErrIncompatibleTargetDataSource)
+		require.ErrorContains(t, err, `"rawSql"`)
+	})
+}
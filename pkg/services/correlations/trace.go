@@ -0,0 +1,49 @@
+package correlations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// dsXRay is the plugin ID of the AWS X-Ray data source, which has no constant in the datasources package.
+const dsXRay = "grafana-x-ray-datasource"
+
+// traceQueries build the query looking up a trace by its ID for every type of tracing data source trace correlations
+// can target.
+var traceQueries = map[string]func(traceID string) map[string]interface{}{
+	datasources.DS_TEMPO: func(traceID string) map[string]interface{} {
+		return map[string]interface{}{"queryType": "traceql", "query": traceID}
+	},
+	datasources.DS_JAEGER: func(traceID string) map[string]interface{} {
+		return map[string]interface{}{"query": traceID}
+	},
+	datasources.DS_ZIPKIN: func(traceID string) map[string]interface{} {
+		return map[string]interface{}{"queryType": "traceID", "query": traceID}
+	},
+	dsXRay: func(traceID string) map[string]interface{} {
+		return map[string]interface{}{"queryType": "getTrace", "query": traceID}
+	},
+}
+
+// generateTraceTarget sets the target of a trace correlation to the query looking up the trace whose ID is the value of
+// the correlation field, referenced by the name of its first field, in the shape the type of the target data source
+// expects. Any target the config had is replaced. Configs of other types are left untouched.
+func generateTraceTarget(config *CorrelationConfig, targetType string) error {
+	if config.Type != ConfigTypeTrace {
+		return nil
+	}
+	query, ok := traceQueries[targetType]
+	if !ok {
+		supported := make([]string, 0, len(traceQueries))
+		for t := range traceQueries {
+			supported = append(supported, t)
+		}
+		sort.Strings(supported)
+		return fmt.Errorf("%w: trace correlations can't target %s data sources, only %s", ErrIncompatibleTargetDataSource, targetType, strings.Join(supported, ", "))
+	}
+	config.Target = query("${" + config.Field.Primary() + "}")
+	return nil
+}
@@ -0,0 +1,43 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+func TestGenerateTraceTarget(t *testing.T) {
+	t.Run("the trace query is shaped for the target data source", func(t *testing.T) {
+		tests := map[string]map[string]interface{}{
+			datasources.DS_TEMPO:  {"queryType": "traceql", "query": "${traceId}"},
+			datasources.DS_JAEGER: {"query": "${traceId}"},
+			datasources.DS_ZIPKIN: {"queryType": "traceID", "query": "${traceId}"},
+			dsXRay:                {"queryType": "getTrace", "query": "${traceId}"},
+		}
+		for targetType, expected := range tests {
+			config := CorrelationConfig{Type: ConfigTypeTrace, Field: CorrelationFields{"traceId"}}
+			require.NoError(t, generateTraceTarget(&config, targetType))
+			require.Equal(t, expected, config.Target, targetType)
+		}
+	})
+
+	t.Run("a given target is replaced", func(t *testing.T) {
+		config := CorrelationConfig{Type: ConfigTypeTrace, Field: CorrelationFields{"traceID"}, Target: map[string]interface{}{"expr": "up"}}
+		require.NoError(t, generateTraceTarget(&config, datasources.DS_JAEGER))
+		require.Equal(t, map[string]interface{}{"query": "${traceID}"}, config.Target)
+	})
+
+	t.Run("non-tracing data sources are rejected", func(t *testing.T) {
+		config := CorrelationConfig{Type: ConfigTypeTrace, Field: CorrelationFields{"traceId"}}
+		err := generateTraceTarget(&config, datasources.DS_LOKI)
+		require.ErrorIs(t, err, ErrIncompatibleTargetDataSource)
+	})
+
+	t.Run("other config types are left untouched", func(t *testing.T) {
+		config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"traceId"}, Target: map[string]interface{}{"expr": "up"}}
+		require.NoError(t, generateTraceTarget(&config, datasources.DS_TEMPO))
+		require.Equal(t, map[string]interface{}{"expr": "up"}, config.Target)
+	})
+}
@@ -0,0 +1,114 @@
+package correlations
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const (
+	// usageDebounceInterval is the minimum time between two recorded uses of the same correlation.
+	usageDebounceInterval = 5 * time.Minute
+	// usageFlushInterval is how long uses are collected before they are written to the database.
+	usageFlushInterval = 10 * time.Second
+)
+
+type usageKey struct {
+	uid       string
+	sourceUID string
+	orgID     int64
+}
+
+// usageTracker records when correlations were last used, off the request path. Uses are collected in memory and
+// written in batches, and repeated uses of the same correlation within the debounce interval are dropped, so that
+// clicking a correlation link doesn't cause a database write per click.
+type usageTracker struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	debounce  time.Duration
+	flushWait time.Duration
+	// recorded holds when each correlation was last recorded as used, to debounce repeated uses.
+	recorded map[usageKey]time.Time
+	// pending holds the uses that have not been written yet.
+	pending   map[usageKey]time.Time
+	scheduled bool
+	write     func(ctx context.Context, uses map[usageKey]time.Time) error
+	log       log.Logger
+}
+
+func newUsageTracker(clk clock.Clock, write func(ctx context.Context, uses map[usageKey]time.Time) error, logger log.Logger) *usageTracker {
+	return &usageTracker{
+		clock:     clk,
+		debounce:  usageDebounceInterval,
+		flushWait: usageFlushInterval,
+		recorded:  make(map[usageKey]time.Time),
+		pending:   make(map[usageKey]time.Time),
+		write:     write,
+		log:       logger,
+	}
+}
+
+// mark records a use of a correlation, unless it was already recorded within the debounce interval.
+// The use is written with the next flush, which is scheduled if needed.
+func (t *usageTracker) mark(key usageKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	if last, ok := t.recorded[key]; ok && now.Sub(last) < t.debounce {
+		return
+	}
+	t.recorded[key] = now
+	t.pending[key] = now
+
+	if !t.scheduled {
+		t.scheduled = true
+		t.clock.AfterFunc(t.flushWait, func() {
+			if err := t.flush(context.Background()); err != nil {
+				t.log.Warn("Failed to record correlation usage", "error", err)
+			}
+		})
+	}
+}
+
+// flush writes all pending uses.
+func (t *usageTracker) flush(ctx context.Context) error {
+	t.mu.Lock()
+	uses := t.pending
+	t.pending = make(map[usageKey]time.Time)
+	t.scheduled = false
+	// Entries older than the debounce interval no longer suppress anything.
+	now := t.clock.Now()
+	for key, last := range t.recorded {
+		if now.Sub(last) >= t.debounce {
+			delete(t.recorded, key)
+		}
+	}
+	t.mu.Unlock()
+
+	if len(uses) == 0 {
+		return nil
+	}
+	return t.write(ctx, uses)
+}
+
+// writeUsage sets the last_used column of the given correlations. Disabled correlations, and correlations whose
+// source data source does not belong to the org the use was recorded in, are left untouched.
+func (s CorrelationsService) writeUsage(ctx context.Context, uses map[usageKey]time.Time) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		for key, usedAt := range uses {
+			if _, err := session.Exec(
+				"UPDATE correlation SET last_used = ? WHERE uid = ? AND source_uid = ? AND enabled = ? AND source_uid IN (SELECT uid FROM data_source WHERE org_id = ?)",
+				usedAt.Unix(), key.uid, key.sourceUID, true, key.orgID,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+package correlations
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+func TestUsageTracker(t *testing.T) {
+	setup := func() (*usageTracker, *clock.Mock, *[]map[usageKey]time.Time) {
+		clk := clock.NewMock()
+		var mu sync.Mutex
+		written := make([]map[usageKey]time.Time, 0)
+		tracker := newUsageTracker(clk, func(_ context.Context, uses map[usageKey]time.Time) error {
+			mu.Lock()
+			defer mu.Unlock()
+			written = append(written, uses)
+			return nil
+		}, log.NewNopLogger())
+		return tracker, clk, &written
+	}
+
+	a := usageKey{uid: "a", sourceUID: "loki", orgID: 1}
+	b := usageKey{uid: "b", sourceUID: "loki", orgID: 1}
+
+	t.Run("uses are written in one batch after the flush interval", func(t *testing.T) {
+		tracker, clk, written := setup()
+
+		tracker.mark(a)
+		tracker.mark(b)
+		require.Empty(t, *written)
+
+		clk.Add(usageFlushInterval)
+		require.Len(t, *written, 1)
+		require.Equal(t, map[usageKey]time.Time{a: clk.Now().Add(-usageFlushInterval), b: clk.Now().Add(-usageFlushInterval)}, (*written)[0])
+	})
+
+	t.Run("repeated uses within the debounce interval are recorded once", func(t *testing.T) {
+		tracker, clk, written := setup()
+		first := clk.Now()
+
+		for i := 0; i < 100; i++ {
+			tracker.mark(a)
+		}
+		clk.Add(usageFlushInterval)
+		tracker.mark(a)
+		clk.Add(usageFlushInterval)
+
+		require.Len(t, *written, 1)
+		require.Equal(t, map[usageKey]time.Time{a: first}, (*written)[0])
+	})
+
+	t.Run("uses after the debounce interval are recorded again", func(t *testing.T) {
+		tracker, clk, written := setup()
+
+		tracker.mark(a)
+		clk.Add(usageDebounceInterval)
+		tracker.mark(a)
+		clk.Add(usageFlushInterval)
+
+		require.Len(t, *written, 2)
+		require.Equal(t, map[usageKey]time.Time{a: clk.Now().Add(-usageFlushInterval)}, (*written)[1])
+	})
+
+	t.Run("flushing writes pending uses immediately", func(t *testing.T) {
+		tracker, _, written := setup()
+
+		require.NoError(t, tracker.flush(context.Background()))
+		require.Empty(t, *written)
+
+		tracker.mark(a)
+		require.NoError(t, tracker.flush(context.Background()))
+		require.Len(t, *written, 1)
+	})
+}
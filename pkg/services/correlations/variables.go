@@ -0,0 +1,203 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validateReferencedVariables checks that every variable the templates of the target reference is provided by the
+// correlation. Macros are always provided. All the variables that aren't are reported at once, as an UnresolvedVariablesError.
+func (c CorrelationConfig) validateReferencedVariables(templates ...string) error {
+	provided, open := c.providedVariables()
+	if open {
+		return nil
+	}
+	missing := map[string]struct{}{}
+	for _, template := range templates {
+		for _, match := range variablePattern.FindAllStringSubmatch(template, -1) {
+			if _, ok := provided[match[1]]; !ok && !IsMacro(match[1]) {
+				missing[match[1]] = struct{}{}
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return UnresolvedVariablesError{Variables: names}
+}
+
+// UnresolvedVariablesError lists the variables the target of a correlation references, but that no field,
+// transformation or mapping of the correlation provides.
+type UnresolvedVariablesError struct {
+	// Sorted names of the missing variables
+	Variables []string
+}
+
+func (e UnresolvedVariablesError) Error() string {
+	quoted := make([]string, 0, len(e.Variables))
+	for _, name := range e.Variables {
+		quoted = append(quoted, strconv.Quote(name))
+	}
+	return fmt.Sprintf("%s: %s", ErrUnresolvableTargetVariable, strings.Join(quoted, ", "))
+}
+
+func (e UnresolvedVariablesError) Unwrap() error {
+	return ErrUnresolvableTargetVariable
+}
+
+// targetTemplates returns the templates of the target that reference variables of the correlation: the URL of
+// external correlations, the variable values of dashboard correlations and every string of the query of query
+// correlations, however deeply it's nested.
+func (c CorrelationConfig) targetTemplates() []string {
+	switch c.Type {
+	case ConfigTypeQuery:
+		return queryTemplates(c.Target, nil)
+	case ConfigTypeExternal:
+		raw, _ := c.Target[ExternalTargetURL].(string)
+		return []string{raw}
+	case ConfigTypeDashboard:
+		variables, _ := c.dashboardVariables()
+		templates := make([]string, 0, len(variables))
+		for _, value := range variables {
+			templates = append(templates, value)
+		}
+		return templates
+	}
+	return nil
+}
+
+// queryTemplates appends the strings found in value, a target query or a part of it, to templates.
+func queryTemplates(value interface{}, templates []string) []string {
+	switch v := value.(type) {
+	case string:
+		templates = append(templates, v)
+	case map[string]interface{}:
+		for _, nested := range v {
+			templates = queryTemplates(nested, templates)
+		}
+	case []interface{}:
+		for _, nested := range v {
+			templates = queryTemplates(nested, templates)
+		}
+	}
+	return templates
+}
+
+// providedVariables returns the names of the variables the target of a correlation can rely on: the built-in
+// variables, the fields the correlation reads and the variables bound by its transformations and mappings. Logfmt
+// transformations bind a variable for every key of the source data, which is only known when the correlation is
+// followed, so any variable may be provided if there is one. This is reported as open.
+func (c CorrelationConfig) providedVariables() (map[string]struct{}, bool) {
+	provided := make(map[string]struct{})
+	for _, name := range c.Field {
+		provided[name] = struct{}{}
+	}
+	for _, name := range BuiltInVariables {
+		provided[name] = struct{}{}
+	}
+	for _, t := range c.Transformations {
+		if t.Type == TransformationLogfmt {
+			return nil, true
+		}
+		if t.Field != "" {
+			provided[t.Field] = struct{}{}
+		}
+		for _, name := range t.boundVariables(c.Field.Primary()) {
+			provided[name] = struct{}{}
+		}
+	}
+	for _, m := range c.Mappings {
+		provided[m.Target] = struct{}{}
+	}
+	return provided, false
+}
+
+// boundVariables returns the names of the variables a transformation binds, if they're known before the
+// correlation is followed. Logfmt transformations bind variables named by the source data, so they return none.
+func (t Transformation) boundVariables(correlationField string) []string {
+	switch t.Type {
+	case TransformationRegex:
+		if names := captureGroupNames(t.Expression); len(names) > 0 {
+			return names
+		}
+	case TransformationGrok:
+		return t.grokFields()
+	}
+	if name := t.boundVariable(correlationField); name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+// boundVariable returns the name of the single variable a regex, split or jsonpath transformation binds its result
+// to. Regex transformations without mapValue bind to the name of the field they're applied to. Other
+// transformations, and regex transformations with named capture groups, bind no single variable and return an
+// empty name.
+func (t Transformation) boundVariable(correlationField string) string {
+	switch t.Type {
+	case TransformationRegex:
+		if len(captureGroupNames(t.Expression)) > 0 {
+			return ""
+		}
+		if t.MapValue != "" {
+			return t.MapValue
+		}
+		if t.Field != "" {
+			return t.Field
+		}
+		return correlationField
+	case TransformationSplit, TransformationJSONPath:
+		return t.MapValue
+	}
+	return ""
+}
+
+// unusedVariables returns the sorted names of the variables bound by transformations of an external or dashboard
+// correlation that its target never references. Such transformations do nothing, which usually points at a typo in
+// either.
+func (c CorrelationConfig) unusedVariables() []string {
+	if c.Type != ConfigTypeExternal && c.Type != ConfigTypeDashboard {
+		return nil
+	}
+	referenced := map[string]struct{}{}
+	for _, template := range c.targetTemplates() {
+		for _, match := range variablePattern.FindAllStringSubmatch(template, -1) {
+			referenced[match[1]] = struct{}{}
+		}
+	}
+
+	unused := map[string]struct{}{}
+	for _, t := range c.Transformations {
+		name := t.boundVariable(c.Field.Primary())
+		if name == "" {
+			continue
+		}
+		if _, ok := referenced[name]; !ok {
+			unused[name] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(unused))
+	for name := range unused {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// warnUnusedVariables logs the variables of an external or dashboard correlation that are bound, but never used.
+// They're not rejected, as the target may be completed in a later update.
+func (s CorrelationsService) warnUnusedVariables(ctx context.Context, orgID int64, correlation Correlation) {
+	if unused := correlation.Config.unusedVariables(); len(unused) > 0 {
+		s.log.FromContext(ctx).Warn("Correlation binds variables its target does not use", "type", correlation.Config.Type, "orgId", orgID, "uid", correlation.UID, "sourceUID", correlation.SourceUID, "variables", unused)
+	}
+}
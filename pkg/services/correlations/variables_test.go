@@ -0,0 +1,116 @@
+package correlations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateExternalURLVariables(t *testing.T) {
+	external := func(url string, transformations ...Transformation) CorrelationConfig {
+		return CorrelationConfig{
+			Type:            ConfigTypeExternal,
+			Field:           CorrelationFields{"message"},
+			Target:          map[string]interface{}{ExternalTargetURL: url},
+			Transformations: transformations,
+		}
+	}
+	ticket := Transformation{Type: TransformationRegex, Expression: `(TICKET-\d+)`, MapValue: "ticket"}
+
+	t.Run("accepts URLs using variables bound by transformations", func(t *testing.T) {
+		require.NoError(t, external("https://tickets.example.com/browse/${ticket}?from=${__from}", ticket).Validate())
+	})
+
+	t.Run("accepts URLs using the correlation field", func(t *testing.T) {
+		require.NoError(t, external("https://search.example.com/?q=${message}").Validate())
+	})
+
+	t.Run("accepts any variable with logfmt transformations", func(t *testing.T) {
+		require.NoError(t, external("https://runbooks.example.com/${service}", Transformation{Type: TransformationLogfmt}).Validate())
+	})
+
+	t.Run("rejects URLs referencing variables nothing provides", func(t *testing.T) {
+		err := external("https://tickets.example.com/browse/${issue}", ticket).Validate()
+		require.ErrorIs(t, err, ErrUnresolvableTargetVariable)
+		require.ErrorContains(t, err, `"issue"`)
+	})
+
+	t.Run("accepts transformation variables the URL does not use", func(t *testing.T) {
+		config := external("https://tickets.example.com/browse/${message}", ticket)
+		require.NoError(t, config.Validate())
+		require.Equal(t, []string{"ticket"}, config.unusedVariables())
+	})
+}
+
+func TestUnusedVariables(t *testing.T) {
+	external := func(url string, transformations ...Transformation) CorrelationConfig {
+		return CorrelationConfig{
+			Type:            ConfigTypeExternal,
+			Field:           CorrelationFields{"message"},
+			Target:          map[string]interface{}{ExternalTargetURL: url},
+			Transformations: transformations,
+		}
+	}
+	ticket := Transformation{Type: TransformationRegex, Expression: `(TICKET-\d+)`, MapValue: "ticket"}
+	team := Transformation{Type: TransformationSplit, Delimiter: "/", MapValue: "team"}
+
+	t.Run("reports variables the URL does not reference", func(t *testing.T) {
+		config := external("https://tickets.example.com/browse/${ticket}", ticket, team)
+		require.Equal(t, []string{"team"}, config.unusedVariables())
+	})
+
+	t.Run("reports regex transformations without mapValue by field name", func(t *testing.T) {
+		config := external("https://tickets.example.com/", Transformation{Type: TransformationRegex, Expression: `(\d+)`, Field: "id"})
+		require.Equal(t, []string{"id"}, config.unusedVariables())
+	})
+
+	t.Run("reports nothing if all variables are used", func(t *testing.T) {
+		config := external("https://tickets.example.com/${team}/${ticket}", ticket, team, Transformation{Type: TransformationLogfmt})
+		require.Empty(t, config.unusedVariables())
+	})
+
+	t.Run("ignores query correlations", func(t *testing.T) {
+		config := CorrelationConfig{Type: ConfigTypeQuery, Field: CorrelationFields{"message"}, Target: map[string]interface{}{"expr": "up"}, Transformations: Transformations{team}}
+		require.Empty(t, config.unusedVariables())
+	})
+}
+
+func TestValidateQueryTargetVariables(t *testing.T) {
+	query := func(target map[string]interface{}, transformations ...Transformation) CorrelationConfig {
+		return CorrelationConfig{
+			Type:            ConfigTypeQuery,
+			Field:           CorrelationFields{"message"},
+			Target:          target,
+			Transformations: transformations,
+			Mappings:        Mappings{{Source: "message", Target: "msg"}},
+		}
+	}
+	traceID := Transformation{Type: TransformationRegex, Expression: `traceID=(\w+)`, MapValue: "traceId"}
+
+	t.Run("accepts queries using fields, built-ins, transformations and mappings", func(t *testing.T) {
+		config := query(map[string]interface{}{
+			"expr":  `{source="${__sourceName}"} |= "${traceId}" |= "${msg}" |= "${message}"`,
+			"range": map[string]interface{}{"from": "${__from}", "to": "${__to}"},
+		}, traceID)
+		require.NoError(t, config.Validate())
+	})
+
+	t.Run("reports every variable nothing provides, however deeply nested", func(t *testing.T) {
+		config := query(map[string]interface{}{
+			"expr":    `{job="${job}"} |= "${traceId}"`,
+			"queries": []interface{}{"${span}", map[string]interface{}{"expr": "${job} ${host}"}},
+		}, traceID)
+		err := config.Validate()
+		require.ErrorIs(t, err, ErrUnresolvableTargetVariable)
+
+		var unresolved UnresolvedVariablesError
+		require.ErrorAs(t, err, &unresolved)
+		require.Equal(t, []string{"host", "job", "span"}, unresolved.Variables)
+		require.ErrorContains(t, err, `"host", "job", "span"`)
+	})
+
+	t.Run("accepts any variable with logfmt transformations", func(t *testing.T) {
+		config := query(map[string]interface{}{"expr": "${service}"}, Transformation{Type: TransformationLogfmt})
+		require.NoError(t, config.Validate())
+	})
+}
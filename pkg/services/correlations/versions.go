@@ -0,0 +1,158 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+// MaxCorrelationVersions is the number of versions kept for every correlation. Older versions can't be restored.
+const MaxCorrelationVersions = 20
+
+// correlationVersion is a row of the correlation_version table, holding a correlation as it was at a version.
+type correlationVersion struct {
+	ID             int64  `xorm:"pk autoincr 'id'"`
+	OrgID          int64  `xorm:"org_id"`
+	CorrelationUID string `xorm:"correlation_uid"`
+	SourceUID      string `xorm:"source_uid"`
+	Version        int64  `xorm:"'version'"`
+	Created        int64  `xorm:"created"`
+	Correlation    string `xorm:"correlation"`
+}
+
+func (v correlationVersion) TableName() string {
+	return "correlation_version"
+}
+
+// RestoreCorrelationCommand is the command to restore a correlation to one of its earlier versions
+type RestoreCorrelationCommand struct {
+	UID       string `json:"-"`
+	SourceUID string `json:"-"`
+	OrgId     int64  `json:"-"`
+	Version   int64  `json:"-"`
+}
+
+// saveVersion stores the correlation at its current version, and drops the versions that are no longer kept.
+func saveVersion(session *db.Session, orgID int64, correlation Correlation) error {
+	encoded, err := json.Marshal(correlation)
+	if err != nil {
+		return err
+	}
+	if _, err := session.Insert(&correlationVersion{
+		OrgID:          orgID,
+		CorrelationUID: correlation.UID,
+		SourceUID:      correlation.SourceUID,
+		Version:        correlation.Version,
+		Created:        time.Now().Unix(),
+		Correlation:    string(encoded),
+	}); err != nil {
+		return err
+	}
+
+	_, err = session.Where("source_uid = ? AND correlation_uid = ? AND version <= ?",
+		correlation.SourceUID, correlation.UID, correlation.Version-MaxCorrelationVersions).Delete(&correlationVersion{})
+	return err
+}
+
+// deleteVersions drops all versions of a correlation. Like correlations themselves, versions are identified by the
+// UIDs of the correlation and its source data source.
+func deleteVersions(session *db.Session, uid, sourceUID string) error {
+	_, err := session.Where("source_uid = ? AND correlation_uid = ?", sourceUID, uid).Delete(&correlationVersion{})
+	return err
+}
+
+// restoreCorrelation sets the label, description, notes, tags, target, config and enabled state of a correlation
+// back to those of an earlier version. Restoring doesn't rewrite the versions in between, the restored correlation
+// is saved as a new version instead.
+func (s CorrelationsService) restoreCorrelation(ctx context.Context, cmd RestoreCorrelationCommand) (Correlation, error) {
+	correlation := Correlation{UID: cmd.UID, SourceUID: cmd.SourceUID}
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(session *db.Session) error {
+		query := &datasources.GetDataSourceQuery{OrgId: cmd.OrgId, Uid: cmd.SourceUID}
+		if err := s.DataSourceService.GetDataSource(ctx, query); err != nil {
+			return ErrSourceDataSourceDoesNotExists
+		}
+		if query.Result.ReadOnly {
+			return ErrSourceDataSourceReadOnly
+		}
+
+		found, err := session.Where(notDeleted).Get(&correlation)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrCorrelationNotFound
+		}
+		if correlation.Provisioned {
+			return ErrCorrelationReadOnly
+		}
+		s.migrateConfig(&correlation)
+		if err := loadCorrelationTags(session, &correlation); err != nil {
+			return err
+		}
+		before := correlation
+
+		version := correlationVersion{}
+		found, err = session.Where("org_id = ? AND source_uid = ? AND correlation_uid = ? AND version = ?",
+			cmd.OrgId, cmd.SourceUID, cmd.UID, cmd.Version).Get(&version)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("%w: %d", ErrCorrelationVersionNotFound, cmd.Version)
+		}
+		var restored Correlation
+		if err := json.Unmarshal([]byte(version.Correlation), &restored); err != nil {
+			return err
+		}
+		s.migrateConfig(&restored)
+
+		if restored.TargetUID != nil {
+			targetQuery := &datasources.GetDataSourceQuery{OrgId: cmd.OrgId, Uid: *restored.TargetUID}
+			if err := s.DataSourceService.GetDataSource(ctx, targetQuery); err != nil {
+				return ErrTargetDataSourceDoesNotExists
+			}
+			if err := generateTraceTarget(&restored.Config, targetQuery.Result.Type); err != nil {
+				return err
+			}
+			if err := s.verifyTargetType(ctx, cmd.OrgId, restored, targetQuery.Result); err != nil {
+				return err
+			}
+		}
+
+		if err := verifyTargetDashboard(session, cmd.OrgId, restored.Config); err != nil {
+			return err
+		}
+
+		correlation.Label = restored.Label
+		correlation.Description = restored.Description
+		correlation.Notes = restored.Notes
+		correlation.TargetUID = restored.TargetUID
+		correlation.Orphaned = restored.Orphaned
+		correlation.Config = restored.Config
+		correlation.Enabled = restored.Enabled
+		correlation.Tags = normalizeTags(restored.Tags)
+		correlation.Version++
+
+		if _, err := session.Where("uid = ? AND source_uid = ?", correlation.UID, correlation.SourceUID).
+			AllCols().Update(&correlation); err != nil {
+			return err
+		}
+		if err := saveTags(session, correlation); err != nil {
+			return err
+		}
+		if err := saveVersion(session, cmd.OrgId, correlation); err != nil {
+			return err
+		}
+		return recordHistory(ctx, session, cmd.OrgId, HistoryActionUpdated, &before, &correlation)
+	})
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	return correlation, nil
+}
@@ -9,4 +9,77 @@ type HistoryQuery struct {
 	Labels  map[string]string
 	From    time.Time
 	To      time.Time
+	// NamespaceUID and RuleGroup, if set, restrict the query to rules in the given folder and group.
+	NamespaceUID string
+	RuleGroup    string
+	// Tags, if set, restricts the query to transitions recorded with all of the given tags.
+	Tags map[string]string
+	// Partial makes backends skip entries that cannot be read, rather than failing the whole query.
+	// Skipped entries are reported as a warning notice on the returned frame.
+	Partial bool
+	// MaxDataPoints, if set, is the maximum number of transitions to return. Backends may downsample the result
+	// to respect it, but never drop a change of state to do so.
+	MaxDataPoints int
+	// Fields, if set, restricts the result to the named fields, e.g. just "time" and "current" for a state
+	// timeline. All fields are returned if not set.
+	Fields []string
+	// LabelsAsString adds the `labelsString` field, holding the labels of every transition in Prometheus notation,
+	// e.g. `{env="prod", team="x"}`, for display as is.
+	LabelsAsString bool
+	// Values, if set, restricts the query to transitions whose evaluation values satisfy all of the filters.
+	Values []ValueFilter
+	// MergeTolerance, if set, merges transitions of an alert instance that follow each other in less than the
+	// given duration, e.g. because the instance flapped, into a single transition with their net state change.
+	// It only affects the returned result, not the stored history.
+	MergeTolerance time.Duration
+}
+
+// ValueOperator is the comparison a ValueFilter applies.
+type ValueOperator string
+
+const (
+	ValueGreaterThan    ValueOperator = ">"
+	ValueGreaterOrEqual ValueOperator = ">="
+	ValueLessThan       ValueOperator = "<"
+	ValueLessOrEqual    ValueOperator = "<="
+	ValueEqual          ValueOperator = "=="
+)
+
+// ValueFilter compares an evaluation value of a transition with a threshold, e.g. to find the transitions where
+// the value exceeded 90.
+//
+// The compared value is the value of RefID. If RefID is empty, the primary value of the alert instance is compared,
+// which is the value of the first reference ID in alphabetical order. For rules that reduce a query and apply a
+// threshold to the result, this is the reduced value. Transitions without the compared value, such as errors and
+// transitions into NoData, never match.
+type ValueFilter struct {
+	RefID     string
+	Operator  ValueOperator
+	Threshold float64
+}
+
+// Valid reports whether the operator of the filter is known.
+func (f ValueFilter) Valid() bool {
+	switch f.Operator {
+	case ValueGreaterThan, ValueGreaterOrEqual, ValueLessThan, ValueLessOrEqual, ValueEqual:
+		return true
+	}
+	return false
+}
+
+// Matches reports whether value satisfies the filter.
+func (f ValueFilter) Matches(value float64) bool {
+	switch f.Operator {
+	case ValueGreaterThan:
+		return value > f.Threshold
+	case ValueGreaterOrEqual:
+		return value >= f.Threshold
+	case ValueLessThan:
+		return value < f.Threshold
+	case ValueLessOrEqual:
+		return value <= f.Threshold
+	case ValueEqual:
+		return value == f.Threshold
+	}
+	return false
 }
@@ -205,7 +205,7 @@ func (ng *AlertNG) init() error {
 		Tracer:               ng.tracer,
 	}
 
-	history, err := configureHistorianBackend(ng.Cfg.UnifiedAlerting.StateHistory, ng.annotationsRepo, ng.dashboardService, ng.store)
+	history, err := configureHistorianBackend(ng.Cfg.UnifiedAlerting.StateHistory, ng.annotationsRepo, ng.dashboardService, ng.store, ng.SQLStore)
 	if err != nil {
 		return err
 	}
@@ -378,33 +378,51 @@ func readQuotaConfig(cfg *setting.Cfg) (*quota.Map, error) {
 	return limits, nil
 }
 
-func configureHistorianBackend(cfg setting.UnifiedAlertingStateHistorySettings, ar annotations.Repository, ds dashboards.DashboardService, rs historian.RuleStore) (state.Historian, error) {
+func configureHistorianBackend(cfg setting.UnifiedAlertingStateHistorySettings, ar annotations.Repository, ds dashboards.DashboardService, rs historian.RuleStore, sqlStore db.DB) (state.Historian, error) {
 	if !cfg.Enabled {
 		return historian.NewNopHistorian(), nil
 	}
 
-	if cfg.Backend == "annotations" {
-		return historian.NewAnnotationBackend(ar, ds, rs), nil
+	deps, err := historianBackendDeps(cfg, ar, ds, rs, sqlStore)
+	if err != nil {
+		return nil, err
+	}
+	return historian.NewBackend(cfg.Backend, deps)
+}
+
+// historianBackendDeps translates the state history settings into the dependencies of the configured backend.
+func historianBackendDeps(cfg setting.UnifiedAlertingStateHistorySettings, ar annotations.Repository, ds dashboards.DashboardService, rs historian.RuleStore, sqlStore db.DB) (historian.BackendDeps, error) {
+	deps := historian.BackendDeps{
+		Annotations: ar,
+		Dashboards:  ds,
+		Rules:       rs,
+		SQLStore:    sqlStore,
 	}
-	if cfg.Backend == "loki" {
+	if cfg.Backend == historian.BackendTypeLoki {
 		baseURL, err := url.Parse(cfg.LokiRemoteURL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse remote loki URL: %w", err)
+			return historian.BackendDeps{}, fmt.Errorf("failed to parse remote loki URL: %w", err)
 		}
-		backend := historian.NewRemoteLokiBackend(historian.LokiConfig{
+		deps.Loki = historian.LokiConfig{
 			Url:               baseURL,
 			BasicAuthUser:     cfg.LokiBasicAuthUsername,
 			BasicAuthPassword: cfg.LokiBasicAuthPassword,
 			TenantID:          cfg.LokiTenantID,
-		})
-		if err := backend.TestConnection(); err != nil {
-			return nil, fmt.Errorf("failed to ping the remote loki historian: %w", err)
 		}
-		return backend, nil
 	}
-	if cfg.Backend == "sql" {
-		return historian.NewSqlBackend(), nil
+	if cfg.Backend == historian.BackendTypeSQL {
+		deps.SQL = historian.SqlConfig{
+			MaxValuesSize: cfg.SQLMaxValuesSize,
+			CacheTTL:      cfg.SQLCacheTTL,
+			CacheMaxBytes: cfg.SQLCacheMaxBytes,
+			Sampling: historian.SamplingConfig{
+				Every:       cfg.SQLSamplingEvery,
+				EveryByRule: cfg.SQLSamplingEveryByRule,
+			},
+			DeadlockRetries: cfg.SQLDeadlockRetries,
+			DeadlockBackoff: cfg.SQLDeadlockBackoff,
+			FlushInterval:   cfg.SQLFlushInterval,
+		}
 	}
-
-	return nil, fmt.Errorf("unrecognized state history backend: %s", cfg.Backend)
+	return deps, nil
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/events"
@@ -16,7 +17,9 @@ import (
 	"github.com/grafana/grafana/pkg/services/folder"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
+	"github.com/grafana/grafana/pkg/services/ngalert/state/historian"
 	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 )
 
@@ -72,3 +75,54 @@ func Test_subscribeToFolderChanges(t *testing.T) {
 		scheduler.AssertCalled(t, "UpdateAlertRule", rule.GetKey(), rule.Version)
 	}
 }
+
+func TestConfigureHistorianBackend(t *testing.T) {
+	t.Run("passes the sql settings to the sql backend", func(t *testing.T) {
+		f, err := ini.Load([]byte(`
+[unified_alerting.state_history]
+enabled = true
+backend = sql
+sql_max_values_size = 1024
+sql_cache_ttl = 30s
+sql_cache_max_bytes = 2048
+sql_sampling_every = 10
+sql_sampling_every_by_rule = rule-a:5, rule-b:20
+sql_deadlock_retries = -1
+sql_deadlock_backoff = 100ms
+sql_flush_interval = 2s
+`))
+		require.NoError(t, err)
+		cfg := setting.NewCfg()
+		cfg.IsFeatureToggleEnabled = func(key string) bool { return false }
+		require.NoError(t, cfg.ReadUnifiedAlertingSettings(f))
+
+		deps, err := historianBackendDeps(cfg.UnifiedAlerting.StateHistory, nil, nil, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, historian.SqlConfig{
+			MaxValuesSize: 1024,
+			CacheTTL:      30 * time.Second,
+			CacheMaxBytes: 2048,
+			Sampling: historian.SamplingConfig{
+				Every:       10,
+				EveryByRule: map[string]int{"rule-a": 5, "rule-b": 20},
+			},
+			DeadlockRetries: -1,
+			DeadlockBackoff: 100 * time.Millisecond,
+			FlushInterval:   2 * time.Second,
+		}, deps.SQL)
+
+		backend, err := configureHistorianBackend(cfg.UnifiedAlerting.StateHistory, nil, nil, nil, nil)
+		require.NoError(t, err)
+		require.IsType(t, &historian.SqlBackend{}, backend)
+	})
+
+	t.Run("leaves the sql settings empty for other backends", func(t *testing.T) {
+		deps, err := historianBackendDeps(setting.UnifiedAlertingStateHistorySettings{
+			Enabled:          true,
+			Backend:          historian.BackendTypeAnnotations,
+			SQLMaxValuesSize: 1024,
+		}, nil, nil, nil, nil)
+		require.NoError(t, err)
+		require.Zero(t, deps.SQL)
+	})
+}
@@ -0,0 +1,53 @@
+package historian
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/annotations"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+// Backend is a state history backend, that can both record and query state history.
+type Backend interface {
+	state.Historian
+	Querier
+}
+
+const (
+	BackendTypeNoop        = "noop"
+	BackendTypeAnnotations = "annotations"
+	BackendTypeLoki        = "loki"
+	BackendTypeSQL         = "sql"
+)
+
+// BackendDeps holds everything needed to construct any of the backends. Only the dependencies of the selected
+// backend need to be set.
+type BackendDeps struct {
+	Annotations annotations.Repository
+	Dashboards  dashboards.DashboardService
+	Rules       RuleStore
+	SQLStore    db.DB
+	Loki        LokiConfig
+	SQL         SqlConfig
+}
+
+// NewBackend returns the state history backend of the given kind.
+func NewBackend(kind string, deps BackendDeps) (Backend, error) {
+	switch kind {
+	case BackendTypeNoop:
+		return NewNoopBackend(), nil
+	case BackendTypeAnnotations:
+		return NewAnnotationBackend(deps.Annotations, deps.Dashboards, deps.Rules), nil
+	case BackendTypeLoki:
+		backend := NewRemoteLokiBackend(deps.Loki)
+		if err := backend.TestConnection(); err != nil {
+			return nil, fmt.Errorf("failed to ping the remote loki historian: %w", err)
+		}
+		return backend, nil
+	case BackendTypeSQL:
+		return NewSqlBackend(deps.SQL, deps.SQLStore), nil
+	}
+	return nil, fmt.Errorf("unrecognized state history backend: %s", kind)
+}
@@ -0,0 +1,87 @@
+package historian
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+func TestNewBackend(t *testing.T) {
+	t.Run("returns the backend of the given kind", func(t *testing.T) {
+		b, err := NewBackend(BackendTypeNoop, BackendDeps{})
+		require.NoError(t, err)
+		require.IsType(t, &NoopBackend{}, b)
+
+		b, err = NewBackend(BackendTypeAnnotations, BackendDeps{})
+		require.NoError(t, err)
+		require.IsType(t, &AnnotationBackend{}, b)
+
+		b, err = NewBackend(BackendTypeSQL, BackendDeps{})
+		require.NoError(t, err)
+		require.IsType(t, &SqlBackend{}, b)
+	})
+
+	t.Run("fails for unknown kinds", func(t *testing.T) {
+		_, err := NewBackend("cassandra", BackendDeps{})
+		require.ErrorContains(t, err, "unrecognized state history backend")
+	})
+}
+
+func TestNoopBackend(t *testing.T) {
+	b := NewNoopBackend()
+	b.RecordStatesAsync(context.Background(), createTestRule(), []state.StateTransition{createTransition(eval.Normal, eval.Alerting, nil, time.Now())})
+
+	frame, err := b.QueryStates(context.Background(), models.HistoryQuery{OrgID: 1})
+	require.NoError(t, err)
+	require.Zero(t, frame.Rows())
+}
+
+func TestMultiBackend(t *testing.T) {
+	primary := &fakeBackend{frame: data.NewFrame("primary")}
+	secondary := &fakeBackend{frame: data.NewFrame("secondary")}
+	other := &fakeBackend{frame: data.NewFrame("other")}
+	b := NewMultiBackend(primary, secondary, other)
+
+	rule := createTestRule()
+	transitions := []state.StateTransition{createTransition(eval.Normal, eval.Alerting, nil, time.Now())}
+
+	t.Run("writes to all backends", func(t *testing.T) {
+		b.RecordStatesAsync(context.Background(), rule, transitions)
+
+		for _, fake := range []*fakeBackend{primary, secondary, other} {
+			require.Len(t, fake.recorded, 1)
+			require.Equal(t, transitions, fake.recorded[0])
+		}
+	})
+
+	t.Run("reads from the primary backend", func(t *testing.T) {
+		frame, err := b.QueryStates(context.Background(), models.HistoryQuery{OrgID: 1})
+		require.NoError(t, err)
+		require.Equal(t, "primary", frame.Name)
+		require.Equal(t, 1, primary.queries)
+		require.Zero(t, secondary.queries)
+		require.Zero(t, other.queries)
+	})
+}
+
+type fakeBackend struct {
+	recorded [][]state.StateTransition
+	queries  int
+	frame    *data.Frame
+}
+
+func (f *fakeBackend) RecordStatesAsync(_ context.Context, _ *models.AlertRule, states []state.StateTransition) {
+	f.recorded = append(f.recorded, states)
+}
+
+func (f *fakeBackend) QueryStates(_ context.Context, _ models.HistoryQuery) (*data.Frame, error) {
+	f.queries++
+	return f.frame, nil
+}
@@ -0,0 +1,34 @@
+package historian
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+// MultiBackend writes state history to several backends at once, and reads it from a single primary backend.
+// It is useful when moving between backends: the new backend is filled while the old one keeps serving reads.
+type MultiBackend struct {
+	primary     Backend
+	secondaries []Backend
+}
+
+func NewMultiBackend(primary Backend, secondaries ...Backend) *MultiBackend {
+	return &MultiBackend{
+		primary:     primary,
+		secondaries: secondaries,
+	}
+}
+
+func (h *MultiBackend) RecordStatesAsync(ctx context.Context, rule *models.AlertRule, states []state.StateTransition) {
+	h.primary.RecordStatesAsync(ctx, rule, states)
+	for _, b := range h.secondaries {
+		b.RecordStatesAsync(ctx, rule, states)
+	}
+}
+
+func (h *MultiBackend) QueryStates(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
+	return h.primary.QueryStates(ctx, query)
+}
@@ -3,6 +3,7 @@ package historian
 import (
 	"context"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/state"
 )
@@ -16,3 +17,18 @@ func NewNopHistorian() *NoOpHistorian {
 
 func (f *NoOpHistorian) RecordStatesAsync(ctx context.Context, _ *models.AlertRule, _ []state.StateTransition) {
 }
+
+// NoopBackend is a state history backend for deployments that intentionally have state history turned off.
+// It discards all writes and answers every query with an empty frame.
+type NoopBackend struct{}
+
+func NewNoopBackend() *NoopBackend {
+	return &NoopBackend{}
+}
+
+func (b *NoopBackend) RecordStatesAsync(_ context.Context, _ *models.AlertRule, _ []state.StateTransition) {
+}
+
+func (b *NoopBackend) QueryStates(_ context.Context, _ models.HistoryQuery) (*data.Frame, error) {
+	return data.NewFrame("states"), nil
+}
@@ -2,26 +2,1087 @@ package historian
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/state"
 )
 
+const (
+	// defaultMaxValuesSize is the default maximum size of the serialized values of a single transition.
+	defaultMaxValuesSize = 64 * 1024
+	// defaultCacheMaxBytes is the default maximum size of the query cache, if enabled.
+	defaultCacheMaxBytes = 16 * 1024 * 1024
+	// purgeBatchSize is the maximum number of entries deleted by a single statement when purging history.
+	purgeBatchSize = 1000
+	// backfillBatchSize is the maximum number of entries inserted by a single statement when backfilling history.
+	backfillBatchSize = 100
+	// maxInsertParams is the maximum number of parameters of a single insert statement. It's the lowest limit of
+	// the supported databases, that of SQLite.
+	maxInsertParams = 32766
+)
+
+const (
+	// maxTagKeyLength is the maximum length of the key of a tag recorded with transitions.
+	maxTagKeyLength = 128
+	// maxTagValueLength is the maximum length of the value of a tag recorded with transitions.
+	maxTagValueLength = 1024
+)
+
+var (
+	// ErrInvalidTags is returned when the tags passed to RecordStatesWithTagsAsync are invalid.
+	ErrInvalidTags = errors.New("invalid state history tags")
+	// ErrInvalidHistoryQuery is returned when a state history query cannot be run, e.g. because it requests unknown fields.
+	ErrInvalidHistoryQuery = errors.New("invalid state history query")
+)
+
+// SqlConfig holds the configuration of the SQL state history backend.
+type SqlConfig struct {
+	// MaxValuesSize is the maximum size, in bytes, of the serialized evaluation values stored for a single transition.
+	// Larger values are truncated. Defaults to 64KiB if not set.
+	MaxValuesSize int
+	// CacheTTL enables caching of QueryStates results for the given duration. Repeated queries within the TTL,
+	// e.g. by auto-refreshing dashboards, return the cached result. Caching is disabled if not set.
+	CacheTTL time.Duration
+	// CacheMaxBytes is the approximate maximum memory used by cached results. Defaults to 16MiB if not set.
+	CacheMaxBytes int
+	// Sampling thins out the transitions recorded for frequently evaluated rules. Disabled by default.
+	Sampling SamplingConfig
+	// DeadlockRetries is how often a write or cleanup transaction is retried after the database aborted it due to
+	// a deadlock or serialization failure. Defaults to 3 if not set. Set it to a negative value to disable retries.
+	DeadlockRetries int
+	// DeadlockBackoff is the time to wait before the first retry, doubling with every further retry.
+	// Defaults to 50ms if not set.
+	DeadlockBackoff time.Duration
+	// FlushInterval, if set, buffers asynchronously recorded transitions and writes them at most once per interval,
+	// rather than right away.
+	FlushInterval time.Duration
+	// Clock is used for all time reads and timers of the backend, e.g. cache expiry, flushes and retry backoff.
+	// Defaults to the system clock if not set.
+	Clock clock.Clock
+}
+
+// SqlBackend is an implementation of state.Historian that uses the Grafana database as the backing datastore.
 type SqlBackend struct {
-	log log.Logger
+	db            db.DB
+	maxValuesSize int
+	clock         clock.Clock
+	cache         *queryCache
+	sampler       *sampler
+	// deadlockRetries and deadlockBackoff control retries of transactions aborted by the database.
+	deadlockRetries int
+	deadlockBackoff time.Duration
+	buffer          writeBuffer
+	log             log.Logger
+}
+
+func NewSqlBackend(cfg SqlConfig, db db.DB) *SqlBackend {
+	maxValuesSize := cfg.MaxValuesSize
+	if maxValuesSize <= 0 {
+		maxValuesSize = defaultMaxValuesSize
+	}
+	deadlockRetries := cfg.DeadlockRetries
+	if deadlockRetries == 0 {
+		deadlockRetries = defaultDeadlockRetries
+	}
+	deadlockBackoff := cfg.DeadlockBackoff
+	if deadlockBackoff <= 0 {
+		deadlockBackoff = defaultDeadlockBackoff
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.New()
+	}
+	h := &SqlBackend{
+		db:              db,
+		maxValuesSize:   maxValuesSize,
+		clock:           clk,
+		sampler:         newSampler(cfg.Sampling),
+		deadlockRetries: deadlockRetries,
+		deadlockBackoff: deadlockBackoff,
+		buffer:          writeBuffer{interval: cfg.FlushInterval},
+		log:             log.New("ngalert.state.historian", "backend", "sql"),
+	}
+	if cfg.CacheTTL > 0 {
+		cacheMaxBytes := cfg.CacheMaxBytes
+		if cacheMaxBytes <= 0 {
+			cacheMaxBytes = defaultCacheMaxBytes
+		}
+		h.cache = newQueryCache(h.clock, cfg.CacheTTL, cacheMaxBytes)
+	}
+	return h
 }
 
-func NewSqlBackend() *SqlBackend {
-	return &SqlBackend{
-		log: log.New("ngalert.state.historian"),
+// stateHistoryRow is a single state transition, as stored in the alert_state_history table.
+type stateHistoryRow struct {
+	ID            int64  `xorm:"pk autoincr 'id'"`
+	OrgID         int64  `xorm:"org_id"`
+	RuleUID       string `xorm:"rule_uid"`
+	NamespaceUID  string `xorm:"namespace_uid"`
+	RuleGroup     string `xorm:"rule_group"`
+	Labels        string `xorm:"labels"`
+	PreviousState string `xorm:"previous_state"`
+	CurrentState  string `xorm:"current_state"`
+	Values        string `xorm:"state_values"`
+	// ValuesTruncated is set if Values was too large to be stored and only contains part of the evaluation values.
+	ValuesTruncated bool `xorm:"values_truncated"`
+	// EvaluatedAt is the evaluation time of the transition, in Unix milliseconds.
+	EvaluatedAt int64 `xorm:"evaluated_at"`
+	// TraceID is the ID of the trace active while the rule was evaluated. It is nil if there was none.
+	TraceID *string `xorm:"trace_id"`
+	// Tags is a JSON object of the custom tags the transition was recorded with. It is nil if there were none.
+	Tags *string `xorm:"tags"`
+	// EvalDurationMs is how long the evaluation that caused the transition took, in milliseconds. It is nil if
+	// the duration is unknown.
+	EvalDurationMs *int64 `xorm:"eval_duration_ms"`
+	// InstanceCount is how many instances of the rule were firing after the evaluation that caused the transition.
+	// It is nil if the count is unknown, e.g. for backfilled transitions.
+	InstanceCount *int64 `xorm:"instance_count"`
+}
+
+func (stateHistoryRow) TableName() string {
+	return "alert_state_history"
+}
+
+// validate checks that the JSON columns of the entry can be decoded. Only the given columns are checked,
+// or all of them if cols is nil.
+func (r stateHistoryRow) validate(cols []string) error {
+	loaded := func(col string) bool {
+		if cols == nil {
+			return true
+		}
+		for _, c := range cols {
+			if c == col {
+				return true
+			}
+		}
+		return false
+	}
+	if loaded("labels") && !json.Valid([]byte(r.Labels)) {
+		return fmt.Errorf("state history entry %d has malformed labels", r.ID)
+	}
+	if loaded("state_values") && !json.Valid([]byte(r.Values)) {
+		return fmt.Errorf("state history entry %d has malformed values", r.ID)
 	}
+	if loaded("tags") && r.Tags != nil && !json.Valid([]byte(*r.Tags)) {
+		return fmt.Errorf("state history entry %d has malformed tags", r.ID)
+	}
+	return nil
+}
+
+// RecordStatesAsync records the transitions of a rule evaluation. The states of all instances of the rule should be
+// passed, including unchanged ones: only changes are recorded, but every entry also records how many instances were
+// firing at the time.
+func (h *SqlBackend) RecordStatesAsync(ctx context.Context, rule *models.AlertRule, states []state.StateTransition) {
+	logger := h.log.FromContext(ctx)
+	// Build rows before starting goroutine, to make sure all data is copied and won't mutate underneath us.
+	rows := h.buildRows(rule, states, tracing.TraceIDFromContext(ctx, false), logger)
+	h.recordAsync(ctx, rows, logger)
 }
 
-func (h *SqlBackend) RecordStatesAsync(ctx context.Context, _ *models.AlertRule, _ []state.StateTransition) {
+// RecordStatesWithTagsAsync behaves like RecordStatesAsync, but records the given tags with every transition.
+// Tags are arbitrary key/value pairs, e.g. the deployed version, that can be used to filter the history later on.
+// It returns an error, and records nothing, if a tag key or value exceeds the allowed length.
+func (h *SqlBackend) RecordStatesWithTagsAsync(ctx context.Context, rule *models.AlertRule, states []state.StateTransition, tags map[string]string) error {
+	encoded, err := encodeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	logger := h.log.FromContext(ctx)
+	rows := h.buildRows(rule, states, tracing.TraceIDFromContext(ctx, false), logger)
+	for i := range rows {
+		rows[i].Tags = encoded
+	}
+	h.recordAsync(ctx, rows, logger)
+	return nil
 }
 
 func (h *SqlBackend) QueryStates(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
-	return data.NewFrame("states"), nil
+	if h.cache == nil {
+		frame, _, err := h.queryStates(ctx, query)
+		return frame, err
+	}
+
+	key, err := h.cache.key(query)
+	if err != nil {
+		return nil, err
+	}
+	if frame, ok := h.cache.get(key); ok {
+		return frame, nil
+	}
+	frame, size, err := h.queryStates(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	h.cache.set(key, frame, size)
+	return frame, nil
+}
+
+// queryStates runs a state history query, returning the resulting frame along with its approximate size in bytes.
+func (h *SqlBackend) queryStates(ctx context.Context, query models.HistoryQuery) (*data.Frame, int, error) {
+	fields, err := projectFields(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows := make([]stateHistoryRow, 0)
+	skipped, err := h.iterateStates(ctx, query, requiredColumns(query, fields), func(row stateHistoryRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if query.MergeTolerance > 0 {
+		rows = mergeTransitions(rows, query.MergeTolerance)
+	}
+	if query.MaxDataPoints > 0 {
+		rows = downsample(rows, query.MaxDataPoints)
+	}
+
+	frame := data.NewFrame("states")
+	meta := &data.FrameMeta{}
+	for _, f := range fields {
+		frame.Fields = append(frame.Fields, f.build(rows))
+		if f.name == "evalDurationMs" {
+			if avg, ok := averageEvalDuration(rows); ok {
+				meta.Stats = append(meta.Stats, data.QueryStat{
+					FieldConfig: data.FieldConfig{DisplayName: "Average evaluation duration", Unit: "ms"},
+					Value:       avg,
+				})
+			}
+		}
+	}
+	if skipped > 0 {
+		meta.Notices = append(meta.Notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Skipped state history entries that could not be read: %d", skipped),
+		})
+	}
+	if len(meta.Stats) > 0 || len(meta.Notices) > 0 {
+		frame.SetMeta(meta)
+	}
+	return frame, estimateSize(rows), nil
+}
+
+// averageEvalDuration returns the average evaluation duration of the rows that have one, in milliseconds.
+func averageEvalDuration(rows []stateHistoryRow) (float64, bool) {
+	var sum, n int64
+	for _, row := range rows {
+		if row.EvalDurationMs != nil {
+			sum += *row.EvalDurationMs
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return float64(sum) / float64(n), true
+}
+
+// stateHistoryField is a field of the frames returned by QueryStates, along with the column it is read from.
+type stateHistoryField struct {
+	name   string
+	column string
+	build  func(rows []stateHistoryRow) *data.Field
+	// optional fields are only returned if requested explicitly.
+	optional bool
+}
+
+// We represent state history as eleven vectors:
+//  1. `time` - when the transition happened
+//  2. `ruleUID` - the UID of the rule that transitioned
+//  3. `labels` - a JSON object containing the labels of the alert instance
+//  4. `previous` - the previous state and reason
+//  5. `current` - the current state and reason
+//  6. `values` - a JSON object containing the evaluation values, or the error
+//  7. `traceID` - the trace active during evaluation, or null if there was none
+//  8. `valuesTruncated` - whether `values` was truncated because it was too large to be stored
+//  9. `tags` - a JSON object containing the custom tags of the transition, or null if there were none
+//  10. `evalDurationMs` - how long the evaluation took in milliseconds, or null if unknown
+//  11. `instanceCount` - how many instances of the rule were firing after the evaluation, or null if unknown
+var stateHistoryFields = []stateHistoryField{
+	{name: "time", column: "evaluated_at", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "time", func(r stateHistoryRow) time.Time { return time.UnixMilli(r.EvaluatedAt) })
+	}},
+	{name: "ruleUID", column: "rule_uid", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "ruleUID", func(r stateHistoryRow) string { return r.RuleUID })
+	}},
+	{name: "labels", column: "labels", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "labels", func(r stateHistoryRow) string { return r.Labels })
+	}},
+	{name: "previous", column: "previous_state", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "previous", func(r stateHistoryRow) string { return r.PreviousState })
+	}},
+	{name: "current", column: "current_state", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "current", func(r stateHistoryRow) string { return r.CurrentState })
+	}},
+	{name: "values", column: "state_values", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "values", func(r stateHistoryRow) string { return r.Values })
+	}},
+	{name: "traceID", column: "trace_id", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "traceID", func(r stateHistoryRow) *string { return r.TraceID })
+	}},
+	{name: "valuesTruncated", column: "values_truncated", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "valuesTruncated", func(r stateHistoryRow) bool { return r.ValuesTruncated })
+	}},
+	{name: "tags", column: "tags", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "tags", func(r stateHistoryRow) *string { return r.Tags })
+	}},
+	{name: "evalDurationMs", column: "eval_duration_ms", build: func(rows []stateHistoryRow) *data.Field {
+		field := buildField(rows, "evalDurationMs", func(r stateHistoryRow) *int64 { return r.EvalDurationMs })
+		field.Config = &data.FieldConfig{Unit: "ms"}
+		return field
+	}},
+	{name: "instanceCount", column: "instance_count", build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "instanceCount", func(r stateHistoryRow) *int64 { return r.InstanceCount })
+	}},
+	// `labelsString` - the labels of the alert instance in Prometheus notation, e.g. `{env="prod", team="x"}`
+	{name: "labelsString", column: "labels", optional: true, build: func(rows []stateHistoryRow) *data.Field {
+		return buildField(rows, "labelsString", func(r stateHistoryRow) string { return formatLabels(r.Labels) })
+	}},
+}
+
+func buildField[T any](rows []stateHistoryRow, name string, get func(stateHistoryRow) T) *data.Field {
+	values := make([]T, 0, len(rows))
+	for _, row := range rows {
+		values = append(values, get(row))
+	}
+	return data.NewField(name, nil, values)
+}
+
+// projectFields returns the frame fields requested by the query, in the order of the full frame. All fields
+// that are not optional are returned if the query doesn't name any.
+func projectFields(query models.HistoryQuery) ([]stateHistoryField, error) {
+	if len(query.Fields) == 0 {
+		fields := make([]stateHistoryField, 0, len(stateHistoryFields))
+		for _, f := range stateHistoryFields {
+			if !f.optional || f.name == "labelsString" && query.LabelsAsString {
+				fields = append(fields, f)
+			}
+		}
+		return fields, nil
+	}
+	requested := make(map[string]bool, len(query.Fields))
+	for _, name := range query.Fields {
+		requested[name] = true
+	}
+	if query.LabelsAsString {
+		requested["labelsString"] = true
+	}
+	fields := make([]stateHistoryField, 0, len(requested))
+	for _, f := range stateHistoryFields {
+		if requested[f.name] {
+			fields = append(fields, f)
+			delete(requested, f.name)
+		}
+	}
+	for name := range requested {
+		return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidHistoryQuery, name)
+	}
+	return fields, nil
+}
+
+// formatLabels formats labels, given as a JSON object, in Prometheus notation. Labels are sorted by name and values
+// are quoted and escaped, so that the same label set always results in the same string. Labels that cannot be
+// decoded are returned as is.
+func formatLabels(raw string) string {
+	var labels map[string]string
+	if err := json.Unmarshal([]byte(raw), &labels); err != nil {
+		return raw
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// requiredColumns returns the columns that need to be read to build the given fields, and to filter and
+// downsample the rows as requested by the query.
+func requiredColumns(query models.HistoryQuery, fields []stateHistoryField) []string {
+	cols := []string{"id", "evaluated_at"}
+	add := func(col string) {
+		for _, c := range cols {
+			if c == col {
+				return
+			}
+		}
+		cols = append(cols, col)
+	}
+	for _, f := range fields {
+		add(f.column)
+	}
+	for _, col := range (historyQueryBuilder{query: query}).postFilterColumns() {
+		add(col)
+	}
+	if query.MaxDataPoints > 0 || query.MergeTolerance > 0 {
+		add("previous_state")
+		add("current_state")
+	}
+	if query.MergeTolerance > 0 {
+		add("rule_uid")
+		add("labels")
+	}
+	return cols
+}
+
+// ExportCSV writes the state transitions matching the query to w as CSV, in chronological order.
+// Rows are streamed from the database, so memory use does not depend on the size of the history.
+func (h *SqlBackend) ExportCSV(ctx context.Context, query models.HistoryQuery, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"time", "rule_uid", "previous_state", "current_state", "labels", "values"}); err != nil {
+		return err
+	}
+
+	_, err := h.iterateStates(ctx, query, nil, func(row stateHistoryRow) error {
+		var labels data.Labels
+		if err := json.Unmarshal([]byte(row.Labels), &labels); err != nil {
+			return fmt.Errorf("failed to parse labels of state history entry %d: %w", row.ID, err)
+		}
+		// Values are quoted, so that commas and quotes inside them can't be confused with the separators.
+		return writer.Write([]string{
+			time.UnixMilli(row.EvaluatedAt).UTC().Format(time.RFC3339Nano),
+			row.RuleUID,
+			row.PreviousState,
+			row.CurrentState,
+			formatLabels(row.Labels),
+			row.Values,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// CurrentStates returns the latest recorded transition of every alert instance in the organization, that is,
+// of every combination of rule and label set. The resulting frame has the fields `time`, `ruleUID`, `labels`
+// and `current`, with the same meaning as in QueryStates, and is ordered by rule UID and labels.
+func (h *SqlBackend) CurrentStates(ctx context.Context, orgID int64) (*data.Frame, error) {
+	builder := historyQueryBuilder{query: models.HistoryQuery{OrgID: orgID}}
+	cond, args := builder.where()
+	outerCond, outerArgs := builder.whereOn("h")
+	args = append(args, outerArgs...)
+	rows := make([]stateHistoryRow, 0)
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		// Window functions aren't available on every supported database, e.g. MySQL 5.7, so the latest transitions
+		// are found by joining the history with the latest evaluation time of every instance.
+		rawSQL := `SELECT h.id, h.rule_uid, h.labels, h.current_state, h.evaluated_at
+		FROM alert_state_history AS h
+		INNER JOIN (
+			SELECT rule_uid, labels, MAX(evaluated_at) AS latest_at
+			FROM alert_state_history
+			WHERE ` + cond + `
+			GROUP BY rule_uid, labels
+		) latest ON h.rule_uid = latest.rule_uid AND h.labels = latest.labels AND h.evaluated_at = latest.latest_at
+		WHERE ` + outerCond + `
+		ORDER BY h.rule_uid, h.labels, h.id DESC`
+		return sess.SQL(rawSQL, args...).Find(&rows)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query current states: %w", err)
+	}
+	rows = latestPerKey(rows, func(row stateHistoryRow) string { return row.RuleUID + "\x00" + row.Labels })
+
+	times := make([]time.Time, 0, len(rows))
+	ruleUIDs := make([]string, 0, len(rows))
+	labels := make([]string, 0, len(rows))
+	current := make([]string, 0, len(rows))
+	for _, row := range rows {
+		times = append(times, time.UnixMilli(row.EvaluatedAt))
+		ruleUIDs = append(ruleUIDs, row.RuleUID)
+		labels = append(labels, row.Labels)
+		current = append(current, row.CurrentState)
+	}
+
+	return data.NewFrame("states",
+		data.NewField("time", nil, times),
+		data.NewField("ruleUID", nil, ruleUIDs),
+		data.NewField("labels", nil, labels),
+		data.NewField("current", nil, current),
+	), nil
+}
+
+// latestPerKey keeps, of the rows with the same key, the one with the highest ID, which is the latest of
+// transitions evaluated at the same time. The order of the rows is otherwise preserved.
+func latestPerKey(rows []stateHistoryRow, key func(stateHistoryRow) string) []stateHistoryRow {
+	kept := make(map[string]int, len(rows))
+	result := make([]stateHistoryRow, 0, len(rows))
+	for _, row := range rows {
+		k := key(row)
+		if i, ok := kept[k]; ok {
+			if row.ID > result[i].ID {
+				result[i] = row
+			}
+			continue
+		}
+		kept[k] = len(result)
+		result = append(result, row)
+	}
+	return result
+}
+
+// RuleChange is the latest state change of a rule, across all of its alert instances.
+type RuleChange struct {
+	RuleUID string
+	// State is the state the instance that changed last went into, e.g. "Alerting" or "Normal (MissingSeries)".
+	State string
+	// ChangedAt is the evaluation time of the change.
+	ChangedAt time.Time
+}
+
+// RecentlyChangedRules returns the rules of the organization with state changes since the given time, ordered by
+// their latest change, most recent first. At most limit rules are returned, or all of them if limit is not positive.
+func (h *SqlBackend) RecentlyChangedRules(ctx context.Context, orgID int64, since time.Time, limit int) ([]RuleChange, error) {
+	builder := historyQueryBuilder{query: models.HistoryQuery{OrgID: orgID, From: since}}
+	cond, args := builder.where()
+	outerCond, outerArgs := builder.whereOn("h")
+	args = append(args, outerArgs...)
+	rows := make([]stateHistoryRow, 0)
+	err := h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		// As in CurrentStates, the latest changes are found without window functions.
+		rawSQL := `SELECT h.id, h.rule_uid, h.current_state, h.evaluated_at
+		FROM alert_state_history AS h
+		INNER JOIN (
+			SELECT rule_uid, MAX(evaluated_at) AS latest_at
+			FROM alert_state_history
+			WHERE ` + cond + `
+			GROUP BY rule_uid
+		) latest ON h.rule_uid = latest.rule_uid AND h.evaluated_at = latest.latest_at
+		WHERE ` + outerCond + `
+		ORDER BY h.evaluated_at DESC, h.id DESC`
+		return sess.SQL(rawSQL, args...).Find(&rows)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recently changed rules: %w", err)
+	}
+	// Several instances of a rule can change at its latest evaluation, so the limit only applies once there is a
+	// single row per rule.
+	rows = latestPerKey(rows, func(row stateHistoryRow) string { return row.RuleUID })
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	changes := make([]RuleChange, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, RuleChange{
+			RuleUID:   row.RuleUID,
+			State:     row.CurrentState,
+			ChangedAt: time.UnixMilli(row.EvaluatedAt),
+		})
+	}
+	return changes, nil
+}
+
+// DistinctStates returns the distinct states, e.g. "Alerting" or "Normal (MissingSeries)", that transitions
+// matching the query went into, in alphabetical order. Options of the query that shape the result rather than
+// select entries, such as MaxDataPoints, are ignored.
+func (h *SqlBackend) DistinctStates(ctx context.Context, query models.HistoryQuery) ([]string, error) {
+	builder, err := newHistoryQueryBuilder(query)
+	if err != nil {
+		return nil, err
+	}
+	cond, args := builder.where()
+
+	var states []string
+	err = h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		if !builder.postFiltered() {
+			states = make([]string, 0)
+			return sess.Table(stateHistoryRow{}).Where(cond, args...).Distinct("current_state").Asc("current_state").Find(&states)
+		}
+
+		// Labels, tags and values are only matched after decoding, so their distinct combinations with the state
+		// are fetched and filtered here.
+		cols := append([]string{"current_state"}, builder.postFilterColumns()...)
+		rows := make([]stateHistoryRow, 0)
+		if err := sess.Table(stateHistoryRow{}).Where(cond, args...).Distinct(cols...).Find(&rows); err != nil {
+			return err
+		}
+		seen := make(map[string]struct{})
+		for _, row := range rows {
+			matches, err := builder.matches(row)
+			if err != nil {
+				return fmt.Errorf("failed to match state history entry: %w", err)
+			}
+			if !matches {
+				continue
+			}
+			seen[row.CurrentState] = struct{}{}
+		}
+		states = make([]string, 0, len(seen))
+		for state := range seen {
+			states = append(states, state)
+		}
+		sort.Strings(states)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distinct states: %w", err)
+	}
+	return states, nil
+}
+
+// PurgeRuleHistory deletes the complete state history of a rule in the given organization, e.g. after the rule was
+// permanently deleted. Entries are deleted in batches to avoid holding long-running locks on the table.
+// It returns the number of deleted entries.
+func (h *SqlBackend) PurgeRuleHistory(ctx context.Context, orgID int64, ruleUID string) (int64, error) {
+	cond, args := historyQueryBuilder{query: models.HistoryQuery{OrgID: orgID, RuleUID: ruleUID}}.where()
+	var total int64
+	for {
+		var deleted int64
+		err := h.withDeadlockRetry(ctx, func() error {
+			return h.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+				ids := make([]int64, 0, purgeBatchSize)
+				if err := sess.Table(stateHistoryRow{}).Where(cond, args...).Cols("id").Limit(purgeBatchSize).Find(&ids); err != nil {
+					return err
+				}
+				if len(ids) == 0 {
+					return nil
+				}
+				var err error
+				deleted, err = sess.Where("org_id = ?", orgID).In("id", ids).Delete(stateHistoryRow{})
+				return err
+			})
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to purge state history of rule %s: %w", ruleUID, err)
+		}
+		total += deleted
+		if deleted < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// iterateStates streams the state history entries matching the query to fn, in chronological order.
+// Only the given columns are read, or all of them if cols is nil.
+// If the query allows partial results, malformed entries are skipped rather than failing the query,
+// and the number of skipped entries is returned.
+func (h *SqlBackend) iterateStates(ctx context.Context, query models.HistoryQuery, cols []string, fn func(stateHistoryRow) error) (int, error) {
+	builder, err := newHistoryQueryBuilder(query)
+	if err != nil {
+		return 0, err
+	}
+	skipped := 0
+	err = h.db.WithDbSession(ctx, func(sess *db.Session) error {
+		cond, args := builder.where()
+		q := sess.Where(cond, args...)
+		if cols != nil {
+			q = q.Cols(cols...)
+		}
+		rows, err := q.Asc("evaluated_at", "id").Rows(new(stateHistoryRow))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+
+		for rows.Next() {
+			var row stateHistoryRow
+			if err := rows.Scan(&row); err != nil {
+				return err
+			}
+			if err := row.validate(cols); err != nil {
+				if !query.Partial {
+					return err
+				}
+				skipped++
+				continue
+			}
+			matches, err := builder.matches(row)
+			if err != nil {
+				return fmt.Errorf("failed to match state history entry %d: %w", row.ID, err)
+			}
+			if !matches {
+				continue
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query state history: %w", err)
+	}
+	if skipped > 0 {
+		h.log.FromContext(ctx).Warn("Skipped malformed state history entries", "count", skipped)
+	}
+	return skipped, nil
+}
+
+func (h *SqlBackend) buildRows(rule *models.AlertRule, states []state.StateTransition, traceID string, logger log.Logger) []stateHistoryRow {
+	var trace *string
+	if traceID != "" {
+		trace = &traceID
+	}
+
+	// The caller passes the states of all instances of the rule, not only the changed ones, so the firing instances
+	// are counted before unchanged states are filtered out.
+	var firing int64
+	for _, state := range states {
+		if state.State.State == eval.Alerting {
+			firing++
+		}
+	}
+
+	rows := make([]stateHistoryRow, 0, len(states))
+	for _, state := range states {
+		if !shouldRecord(state) || !h.sampler.keep(rule.UID, state) {
+			continue
+		}
+		row, ok := h.buildRow(state, logger)
+		if !ok {
+			continue
+		}
+		row.OrgID = rule.OrgID
+		row.RuleUID = rule.UID
+		row.NamespaceUID = rule.NamespaceUID
+		row.RuleGroup = rule.RuleGroup
+		row.TraceID = trace
+		row.InstanceCount = &firing
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// buildRow converts a transition to a row, leaving the rule it belongs to unset. It returns false if the transition
+// cannot be stored.
+func (h *SqlBackend) buildRow(transition state.StateTransition, logger log.Logger) (stateHistoryRow, bool) {
+	labels, err := json.Marshal(removePrivateLabels(transition.State.Labels))
+	if err != nil {
+		logger.Error("Failed to marshal labels of state, skipping", "error", err)
+		return stateHistoryRow{}, false
+	}
+	values, truncated, err := serializeValues(transition.State, h.maxValuesSize)
+	if err != nil {
+		logger.Error("Failed to marshal values of state, skipping", "error", err)
+		return stateHistoryRow{}, false
+	}
+	if truncated {
+		logger.Warn("Values of state are too large to be stored, truncating", "maxSize", h.maxValuesSize)
+	}
+
+	var evalDuration *int64
+	if d := transition.State.EvaluationDuration; d > 0 {
+		ms := d.Milliseconds()
+		evalDuration = &ms
+	}
+
+	return stateHistoryRow{
+		Labels:          string(labels),
+		PreviousState:   transition.PreviousFormatted(),
+		CurrentState:    transition.Formatted(),
+		Values:          values,
+		ValuesTruncated: truncated,
+		EvaluatedAt:     transition.State.LastEvaluationTime.UnixMilli(),
+		EvalDurationMs:  evalDuration,
+	}, true
+}
+
+// recordRows stores the rows with one multi-row insert, so that they're written atomically and with a single
+// round trip. The rows may belong to any number of rules and evaluations.
+func (h *SqlBackend) recordRows(ctx context.Context, rows []stateHistoryRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return h.withDeadlockRetry(ctx, func() error {
+		return h.db.WithDbSession(ctx, func(sess *db.Session) error {
+			_, err := sess.Insert(rows)
+			return err
+		})
+	})
+}
+
+// BackfillStates writes historical transitions, e.g. exported from another state history backend, with their
+// original evaluation times. Unlike RecordStatesAsync, every given transition is written, without filtering or
+// sampling, and the write is synchronous. Transitions are attributed to the org and rule of their state.
+//
+// A transition is identified by its rule, labels and evaluation time. Transitions that are already stored are
+// skipped, so that an interrupted backfill can simply be re-run. It returns the number of written transitions.
+func (h *SqlBackend) BackfillStates(ctx context.Context, transitions []state.StateTransition) (int, error) {
+	logger := h.log.FromContext(ctx)
+	rows := make([]stateHistoryRow, 0, len(transitions))
+	for _, transition := range transitions {
+		row, ok := h.buildRow(transition, logger)
+		if !ok {
+			continue
+		}
+		row.OrgID = transition.State.OrgID
+		row.RuleUID = transition.State.AlertRuleUID
+		rows = append(rows, row)
+	}
+
+	written := 0
+	for start := 0; start < len(rows); start += backfillBatchSize {
+		end := start + backfillBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		var batch []stateHistoryRow
+		err := h.withDeadlockRetry(ctx, func() error {
+			return h.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+				var err error
+				batch, err = excludeStored(sess, rows[start:end])
+				if err != nil {
+					return err
+				}
+				if len(batch) == 0 {
+					return nil
+				}
+				_, err = sess.Insert(batch)
+				return err
+			})
+		})
+		if err != nil {
+			return written, fmt.Errorf("failed to backfill state history: %w", err)
+		}
+		written += len(batch)
+	}
+	return written, nil
+}
+
+// backfillKey is the identity of a transition, used to make backfills idempotent.
+type backfillKey struct {
+	orgID       int64
+	ruleUID     string
+	labels      string
+	evaluatedAt int64
+}
+
+// excludeStored returns the rows that are not stored yet, dropping duplicates within rows as well.
+func excludeStored(sess *db.Session, rows []stateHistoryRow) ([]stateHistoryRow, error) {
+	type ruleKey struct {
+		orgID   int64
+		ruleUID string
+	}
+	// Look up the stored transitions of every rule within the time range covered by its rows.
+	ranges := make(map[ruleKey][2]int64)
+	for _, row := range rows {
+		key := ruleKey{row.OrgID, row.RuleUID}
+		r, ok := ranges[key]
+		if !ok {
+			r = [2]int64{row.EvaluatedAt, row.EvaluatedAt}
+		}
+		if row.EvaluatedAt < r[0] {
+			r[0] = row.EvaluatedAt
+		}
+		if row.EvaluatedAt > r[1] {
+			r[1] = row.EvaluatedAt
+		}
+		ranges[key] = r
+	}
+
+	seen := make(map[backfillKey]struct{})
+	for key, r := range ranges {
+		cond, args := historyQueryBuilder{query: models.HistoryQuery{
+			OrgID:   key.orgID,
+			RuleUID: key.ruleUID,
+			From:    time.UnixMilli(r[0]),
+			To:      time.UnixMilli(r[1]),
+		}}.where()
+		stored := make([]stateHistoryRow, 0)
+		err := sess.Table(stateHistoryRow{}).
+			Where(cond, args...).
+			Cols("labels", "evaluated_at").
+			Find(&stored)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stored {
+			seen[backfillKey{key.orgID, key.ruleUID, s.Labels, s.EvaluatedAt}] = struct{}{}
+		}
+	}
+
+	result := make([]stateHistoryRow, 0, len(rows))
+	for _, row := range rows {
+		key := backfillKey{row.OrgID, row.RuleUID, row.Labels, row.EvaluatedAt}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// downsample reduces rows to at most max entries, where possible.
+//
+// Rows in which the state changed are always kept, as hiding a change would misrepresent the history.
+// The remaining budget, if any, is spread evenly across the rows in which the state stayed the same,
+// thinning out stable runs. If there are more changes than max, all changes are returned and the result
+// exceeds max. The chronological order of rows is preserved.
+func downsample(rows []stateHistoryRow, max int) []stateHistoryRow {
+	if len(rows) <= max {
+		return rows
+	}
+
+	keep := make([]bool, len(rows))
+	stable := make([]int, 0, len(rows))
+	for i, row := range rows {
+		if row.PreviousState == row.CurrentState {
+			stable = append(stable, i)
+		} else {
+			keep[i] = true
+		}
+	}
+	if budget := max - (len(rows) - len(stable)); budget > 0 {
+		for k := 0; k < budget; k++ {
+			keep[stable[k*len(stable)/budget]] = true
+		}
+	}
+
+	result := make([]stateHistoryRow, 0, max)
+	for i, row := range rows {
+		if keep[i] {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// serializeValues returns the JSON representation of the evaluation values of a state, truncated to at most maxSize bytes.
+// Values are truncated by dropping entries, in reverse key order, and error messages by cutting them short,
+// so that the result is always valid JSON. The second return value reports whether truncation happened.
+func serializeValues(s *state.State, maxSize int) (string, bool, error) {
+	b, err := valuesAsDataBlob(s).MarshalJSON()
+	if err != nil || len(b) <= maxSize {
+		return string(b), false, err
+	}
+
+	jsonData := simplejson.New()
+	switch {
+	case s.State == eval.Error && s.Error != nil:
+		msg := []rune(s.Error.Error())
+		// Binary search the longest prefix of the message that fits.
+		n := sort.Search(len(msg)+1, func(i int) bool {
+			b, err := json.Marshal(map[string]string{"error": string(msg[:i])})
+			return err != nil || len(b) > maxSize
+		}) - 1
+		if n > 0 {
+			jsonData.Set("error", string(msg[:n]))
+		}
+	case len(s.Values) > 0:
+		keys := make([]string, 0, len(s.Values))
+		for k := range s.Values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		subset := func(n int) map[string]float64 {
+			values := make(map[string]float64, n)
+			for _, k := range keys[:n] {
+				values[k] = s.Values[k]
+			}
+			return values
+		}
+		// Binary search the largest number of values that fits.
+		n := sort.Search(len(keys)+1, func(i int) bool {
+			b, err := json.Marshal(map[string]interface{}{"values": subset(i)})
+			return err != nil || len(b) > maxSize
+		}) - 1
+		if n > 0 {
+			jsonData.Set("values", subset(n))
+		}
+	}
+
+	b, err = jsonData.MarshalJSON()
+	return string(b), true, err
+}
+
+// encodeTags validates tags and returns their JSON representation, or nil if there are none.
+func encodeTags(tags map[string]string) (*string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	for k, v := range tags {
+		if k == "" {
+			return nil, fmt.Errorf("%w: tag keys must not be empty", ErrInvalidTags)
+		}
+		if len(k) > maxTagKeyLength {
+			return nil, fmt.Errorf("%w: tag key %q exceeds the maximum length of %d", ErrInvalidTags, k, maxTagKeyLength)
+		}
+		if len(v) > maxTagValueLength {
+			return nil, fmt.Errorf("%w: value of tag %q exceeds the maximum length of %d", ErrInvalidTags, k, maxTagValueLength)
+		}
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+	encoded := string(b)
+	return &encoded, nil
+}
+
+// labelsMatch returns whether the JSON-encoded labels contain all of the given matchers.
+func labelsMatch(encoded string, matchers map[string]string) (bool, error) {
+	var labels map[string]string
+	if err := json.Unmarshal([]byte(encoded), &labels); err != nil {
+		return false, err
+	}
+	for k, v := range matchers {
+		if labels[k] != v {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// valuesMatch returns whether the JSON-encoded evaluation values, as written by serializeValues, satisfy all of
+// the given filters. See models.ValueFilter for which value a filter compares.
+func valuesMatch(encoded string, filters []models.ValueFilter) (bool, error) {
+	var decoded struct {
+		Values map[string]float64 `json:"values"`
+	}
+	if err := json.Unmarshal([]byte(encoded), &decoded); err != nil {
+		return false, err
+	}
+	if len(decoded.Values) == 0 {
+		return false, nil
+	}
+	for _, f := range filters {
+		refID := f.RefID
+		if refID == "" {
+			for k := range decoded.Values {
+				if refID == "" || k < refID {
+					refID = k
+				}
+			}
+		}
+		value, ok := decoded.Values[refID]
+		if !ok || !f.Matches(value) {
+			return false, nil
+		}
+	}
+	return true, nil
 }
@@ -0,0 +1,135 @@
+package historian
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// queryCache caches the results of state history queries for a short time, so that dashboards refreshing the
+// same query on a timer do not hit the database every time. Cached frames are shared between callers and
+// must not be modified.
+type queryCache struct {
+	mtx      sync.Mutex
+	clock    clock.Clock
+	ttl      time.Duration
+	maxBytes int
+	size     int
+	entries  map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	frame   *data.Frame
+	size    int
+	expires time.Time
+}
+
+func newQueryCache(clk clock.Clock, ttl time.Duration, maxBytes int) *queryCache {
+	return &queryCache{
+		clock:    clk,
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		entries:  make(map[string]cacheEntry),
+	}
+}
+
+// key returns the fingerprint of a query. The time range is rounded to the TTL, so that queries
+// relative to the current time that are repeated within the TTL map to the same key.
+func (c *queryCache) key(query models.HistoryQuery) (string, error) {
+	fingerprint := struct {
+		models.HistoryQuery
+		From int64
+		To   int64
+	}{
+		HistoryQuery: query,
+		From:         roundedUnix(query.From, c.ttl),
+		To:           roundedUnix(query.To, c.ttl),
+	}
+	// Maps are marshaled with sorted keys, so the key doesn't depend on the order of the filters.
+	b, err := json.Marshal(fingerprint)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func roundedUnix(t time.Time, to time.Duration) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Truncate(to).UnixMilli()
+}
+
+func (c *queryCache) get(key string) (*data.Frame, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.clock.Now().Before(entry.expires) {
+		c.remove(key)
+		return nil, false
+	}
+	return entry.frame, true
+}
+
+// set adds a frame of approximately size bytes to the cache, evicting expired entries and, if the cache is
+// still too large, the entries closest to expiry. Frames larger than the cache are not cached.
+func (c *queryCache) set(key string, frame *data.Frame, size int) {
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.clock.Now()
+	c.remove(key)
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			c.remove(k)
+		}
+	}
+	for c.size+size > c.maxBytes {
+		oldest := ""
+		for k, entry := range c.entries {
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		c.remove(oldest)
+	}
+
+	c.entries[key] = cacheEntry{frame: frame, size: size, expires: now.Add(c.ttl)}
+	c.size += size
+}
+
+func (c *queryCache) remove(key string) {
+	if entry, ok := c.entries[key]; ok {
+		c.size -= entry.size
+		delete(c.entries, key)
+	}
+}
+
+// estimateSize returns the approximate number of bytes the frame built from rows occupies in memory.
+func estimateSize(rows []stateHistoryRow) int {
+	// Fixed-size values, like times and booleans, and string headers.
+	const perRow = 128
+	size := 0
+	for _, row := range rows {
+		size += perRow + len(row.RuleUID) + len(row.Labels) + len(row.PreviousState) + len(row.CurrentState) + len(row.Values)
+		if row.TraceID != nil {
+			size += len(*row.TraceID)
+		}
+		if row.Tags != nil {
+			size += len(*row.Tags)
+		}
+	}
+	return size
+}
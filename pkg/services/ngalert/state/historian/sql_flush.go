@@ -0,0 +1,135 @@
+package historian
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+// writeBuffer holds the batches of asynchronously recorded transitions that are yet to be written.
+type writeBuffer struct {
+	// interval is the time batches are buffered for before they're written. Zero writes them right away.
+	interval time.Duration
+
+	mu      sync.Mutex
+	batches [][]stateHistoryRow
+	// scheduled is set while a flush of the buffered batches is pending. timer is the timer of the pending
+	// flush, if it's delayed by the interval.
+	scheduled bool
+	timer     *clock.Timer
+
+	// flushing serializes flushes, so that a flush only returns after all earlier ones completed.
+	flushing sync.Mutex
+}
+
+// recordAsync buffers the rows of a rule evaluation and makes sure a flush is scheduled to write them.
+func (h *SqlBackend) recordAsync(_ context.Context, rows []stateHistoryRow, logger log.Logger) {
+	if len(rows) == 0 {
+		return
+	}
+
+	b := &h.buffer
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, rows)
+	if b.scheduled {
+		return
+	}
+	b.scheduled = true
+
+	flush := func() {
+		// The batches outlive the evaluations that recorded them, so they're not written with their context.
+		if err := h.flush(context.Background()); err != nil {
+			logger.Error("Failed to save alert state history batch", "error", err)
+			return
+		}
+		logger.Debug("Done saving alert state history batch")
+	}
+	if b.interval <= 0 {
+		go flush()
+		return
+	}
+	b.timer = h.clock.AfterFunc(b.interval, flush)
+}
+
+// FlushNow immediately writes all transitions recorded asynchronously so far, without waiting for the flush
+// interval. It returns once they, as well as those of any flush already in progress, are stored.
+// It's safe to call concurrently with scheduled flushes; every transition is written once.
+func (h *SqlBackend) FlushNow(ctx context.Context) error {
+	return h.flush(ctx)
+}
+
+// flush takes all buffered batches and writes them with as few inserts as possible, combining the transitions of
+// all rules. Transitions whose insert fails are dropped.
+func (h *SqlBackend) flush(ctx context.Context) error {
+	b := &h.buffer
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+
+	b.mu.Lock()
+	batches := b.batches
+	b.batches = nil
+	b.scheduled = false
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+
+	statements := packStatements(batches, maxInsertRows)
+	var firstErr error
+	failed := 0
+	for _, rows := range statements {
+		if err := h.recordRows(ctx, rows); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed += len(rows)
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("failed to save %d of %d transitions: %w", failed, countRows(batches), firstErr)
+	}
+	return nil
+}
+
+// maxInsertRows is the maximum number of rows written by a single insert, so that its parameters, one per column
+// other than the auto-incremented ID, stay within maxInsertParams.
+var maxInsertRows = maxInsertParams / (reflect.TypeOf(stateHistoryRow{}).NumField() - 1)
+
+// packStatements packs the buffered batches, regardless of their rule, into as few inserts of at most maxRows rows
+// as possible. A batch is only split across inserts if it doesn't fit into a single one on its own, so that the
+// transitions of an evaluation are otherwise written together.
+func packStatements(batches [][]stateHistoryRow, maxRows int) [][]stateHistoryRow {
+	var statements [][]stateHistoryRow
+	var current []stateHistoryRow
+	for _, rows := range batches {
+		if len(current)+len(rows) > maxRows && len(current) > 0 {
+			statements = append(statements, current)
+			current = nil
+		}
+		for len(rows) > maxRows {
+			statements = append(statements, rows[:maxRows])
+			rows = rows[maxRows:]
+		}
+		current = append(current, rows...)
+	}
+	if len(current) > 0 {
+		statements = append(statements, current)
+	}
+	return statements
+}
+
+func countRows(batches [][]stateHistoryRow) int {
+	n := 0
+	for _, rows := range batches {
+		n += len(rows)
+	}
+	return n
+}
@@ -0,0 +1,153 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// HistoryFormat is a format WriteStates can render the state history in.
+type HistoryFormat string
+
+const (
+	// HistoryFormatCSV is the CSV written by ExportCSV.
+	HistoryFormatCSV HistoryFormat = "csv"
+	// HistoryFormatPrometheusMatrix is the JSON response of the Prometheus range query API, see newPrometheusMatrix.
+	HistoryFormatPrometheusMatrix HistoryFormat = "prometheus_matrix"
+)
+
+// WriteStates writes the state transitions matching the query to w in the given format.
+func (h *SqlBackend) WriteStates(ctx context.Context, query models.HistoryQuery, format HistoryFormat, w io.Writer) error {
+	switch format {
+	case HistoryFormatCSV:
+		return h.ExportCSV(ctx, query, w)
+	case HistoryFormatPrometheusMatrix:
+		query.Fields = []string{"time", "ruleUID", "labels", "previous", "current"}
+		query.LabelsAsString = false
+		frame, err := h.QueryStates(ctx, query)
+		if err != nil {
+			return err
+		}
+		matrix, err := newPrometheusMatrix(frame)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(matrix)
+	}
+	return fmt.Errorf("%w: unknown format %q", ErrInvalidHistoryQuery, format)
+}
+
+// prometheusMatrix is the response of the Prometheus range query API for a matrix result.
+type prometheusMatrix struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string             `json:"resultType"`
+		Result     []prometheusSeries `json:"result"`
+	} `json:"data"`
+}
+
+type prometheusSeries struct {
+	Metric map[string]string `json:"metric"`
+	// Values are pairs of a Unix timestamp in seconds and the sample value, formatted as a string.
+	Values [][2]interface{} `json:"values"`
+}
+
+// newPrometheusMatrix renders the state transitions of a QueryStates frame as an `ALERTS` range vector, so that
+// panels built for Prometheus can read it. The frame must have the fields `time`, `ruleUID`, `labels`, `previous`
+// and `current`. The result looks like this:
+//
+//	{
+//	  "status": "success",
+//	  "data": {
+//	    "resultType": "matrix",
+//	    "result": [
+//	      {
+//	        "metric": {"__name__": "ALERTS", "alertstate": "firing", "grafana_rule_uid": "my-rule", "team": "x"},
+//	        "values": [[1672628645, "1"], [1672628705, "0"]]
+//	      }
+//	    ]
+//	  }
+//	}
+//
+// There is a series for every combination of instance labels and state the instance was in. The state is the
+// `alertstate` label, which is "firing" for Alerting, "pending" for Pending, and the lower case name of the state
+// otherwise, without the reason. Every transition adds a sample with the value "1" to the series of the state it
+// went into and, if the state changed, a sample with the value "0" to the series of the state it left.
+// Timestamps are in seconds, with millisecond precision. Series are sorted by their labels and samples by time.
+func newPrometheusMatrix(frame *data.Frame) (prometheusMatrix, error) {
+	fields := make(map[string]*data.Field, 5)
+	for _, name := range []string{"time", "ruleUID", "labels", "previous", "current"} {
+		field, idx := frame.FieldByName(name)
+		if idx < 0 {
+			return prometheusMatrix{}, fmt.Errorf("state history frame has no field %q", name)
+		}
+		fields[name] = field
+	}
+
+	series := make(map[string]*prometheusSeries)
+	add := func(ruleUID string, labels data.Labels, alertstate string, at time.Time, value string) {
+		metric := make(map[string]string, len(labels)+3)
+		for k, v := range labels {
+			metric[k] = v
+		}
+		metric["__name__"] = "ALERTS"
+		metric["alertstate"] = alertstate
+		metric["grafana_rule_uid"] = ruleUID
+		key := data.Labels(metric).String()
+		s, ok := series[key]
+		if !ok {
+			s = &prometheusSeries{Metric: metric, Values: make([][2]interface{}, 0, 1)}
+			series[key] = s
+		}
+		s.Values = append(s.Values, [2]interface{}{float64(at.UnixMilli()) / 1000, value})
+	}
+
+	for i := 0; i < frame.Rows(); i++ {
+		var labels data.Labels
+		if err := json.Unmarshal([]byte(fields["labels"].At(i).(string)), &labels); err != nil {
+			return prometheusMatrix{}, fmt.Errorf("failed to parse labels of transition %d: %w", i, err)
+		}
+		at := fields["time"].At(i).(time.Time)
+		ruleUID := fields["ruleUID"].At(i).(string)
+		previous, current := alertState(fields["previous"].At(i).(string)), alertState(fields["current"].At(i).(string))
+		if previous != current {
+			add(ruleUID, labels, previous, at, "0")
+		}
+		add(ruleUID, labels, current, at, "1")
+	}
+
+	keys := make([]string, 0, len(series))
+	for k := range series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var matrix prometheusMatrix
+	matrix.Status = "success"
+	matrix.Data.ResultType = "matrix"
+	matrix.Data.Result = make([]prometheusSeries, 0, len(keys))
+	for _, k := range keys {
+		matrix.Data.Result = append(matrix.Data.Result, *series[k])
+	}
+	return matrix, nil
+}
+
+// alertState returns the `alertstate` label value of a formatted state, e.g. "firing" for "Alerting (Error)".
+func alertState(formatted string) string {
+	state, _, _ := strings.Cut(formatted, " (")
+	switch state {
+	case "Alerting":
+		return "firing"
+	case "Pending":
+		return "pending"
+	}
+	return strings.ToLower(state)
+}
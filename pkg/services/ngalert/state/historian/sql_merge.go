@@ -0,0 +1,59 @@
+package historian
+
+import (
+	"sort"
+	"time"
+)
+
+// mergeTransitions collapses transitions of the same alert instance that follow each other in less than the
+// tolerance, e.g. when an instance flaps, into a single transition. This only shapes the result of a query; the
+// stored history is not changed.
+//
+// Each run of closely spaced transitions is replaced by its last transition, i.e. the state the instance settled
+// in, with the previous state of the first transition of the run, so that the merged transition represents the
+// net change. Runs in which the instance ends up in the state it started in have no net change and are dropped.
+// The chronological order of rows is preserved, with merged transitions placed at the time of their last member.
+func mergeTransitions(rows []stateHistoryRow, tolerance time.Duration) []stateHistoryRow {
+	type run struct {
+		first, last int
+	}
+	type entry struct {
+		index int
+		row   stateHistoryRow
+	}
+
+	result := make([]entry, 0, len(rows))
+	closeRun := func(r run) {
+		row := rows[r.last]
+		row.PreviousState = rows[r.first].PreviousState
+		if r.first != r.last && row.PreviousState == row.CurrentState {
+			return
+		}
+		result = append(result, entry{index: r.last, row: row})
+	}
+
+	open := make(map[string]run)
+	for i, row := range rows {
+		key := row.RuleUID + "\x00" + row.Labels
+		r, ok := open[key]
+		if ok && row.EvaluatedAt-rows[r.last].EvaluatedAt < tolerance.Milliseconds() {
+			r.last = i
+			open[key] = r
+			continue
+		}
+		if ok {
+			closeRun(r)
+		}
+		open[key] = run{first: i, last: i}
+	}
+	for _, r := range open {
+		closeRun(r)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].index < result[j].index })
+	merged := make([]stateHistoryRow, 0, len(result))
+	for _, e := range result {
+		merged = append(merged, e.row)
+	}
+	return merged
+}
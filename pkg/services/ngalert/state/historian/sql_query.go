@@ -0,0 +1,121 @@
+package historian
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// historyQueryBuilder translates a models.HistoryQuery into the conditions selecting the matching state history
+// entries. Filters on plain columns become part of the SQL WHERE clause, while labels, tags and values are stored
+// as JSON and can only be matched after decoding an entry. Every read path builds its conditions with it, so that
+// all of them apply the same filter semantics.
+type historyQueryBuilder struct {
+	query models.HistoryQuery
+}
+
+// newHistoryQueryBuilder returns a builder for the query, or an error wrapping ErrInvalidHistoryQuery if the query
+// has filters that can't be applied.
+func newHistoryQueryBuilder(query models.HistoryQuery) (historyQueryBuilder, error) {
+	for _, f := range query.Values {
+		if !f.Valid() {
+			return historyQueryBuilder{}, fmt.Errorf("%w: unknown value operator %q", ErrInvalidHistoryQuery, f.Operator)
+		}
+	}
+	return historyQueryBuilder{query: query}, nil
+}
+
+// where returns the SQL condition, without the WHERE keyword, and its arguments.
+func (b historyQueryBuilder) where() (string, []interface{}) {
+	return b.whereOn("")
+}
+
+// whereOn is like where, with the columns qualified by the given table name or alias, for queries joining the
+// state history with itself.
+func (b historyQueryBuilder) whereOn(table string) (string, []interface{}) {
+	col := func(name string) string {
+		if table == "" {
+			return name
+		}
+		return table + "." + name
+	}
+	conds := []string{col("org_id") + " = ?"}
+	args := []interface{}{b.query.OrgID}
+	add := func(cond string, arg interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if b.query.RuleUID != "" {
+		add(col("rule_uid")+" = ?", b.query.RuleUID)
+	}
+	if b.query.NamespaceUID != "" {
+		add(col("namespace_uid")+" = ?", b.query.NamespaceUID)
+	}
+	if b.query.RuleGroup != "" {
+		add(col("rule_group")+" = ?", b.query.RuleGroup)
+	}
+	if !b.query.From.IsZero() {
+		add(col("evaluated_at")+" >= ?", b.query.From.UnixMilli())
+	}
+	if !b.query.To.IsZero() {
+		add(col("evaluated_at")+" <= ?", b.query.To.UnixMilli())
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// postFiltered reports whether the query has filters that are applied by matches rather than in SQL.
+func (b historyQueryBuilder) postFiltered() bool {
+	return len(b.query.Labels) > 0 || len(b.query.Tags) > 0 || len(b.query.Values) > 0
+}
+
+// postFilterColumns returns the columns matches needs to be loaded.
+func (b historyQueryBuilder) postFilterColumns() []string {
+	var cols []string
+	if len(b.query.Labels) > 0 {
+		cols = append(cols, "labels")
+	}
+	if len(b.query.Tags) > 0 {
+		cols = append(cols, "tags")
+	}
+	if len(b.query.Values) > 0 {
+		cols = append(cols, "state_values")
+	}
+	return cols
+}
+
+// matches reports whether an entry that was selected by the SQL condition also satisfies the label, tag and value
+// filters of the query.
+func (b historyQueryBuilder) matches(row stateHistoryRow) (bool, error) {
+	if len(b.query.Labels) > 0 {
+		matches, err := labelsMatch(row.Labels, b.query.Labels)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse labels: %w", err)
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	if len(b.query.Tags) > 0 {
+		if row.Tags == nil {
+			return false, nil
+		}
+		matches, err := labelsMatch(*row.Tags, b.query.Tags)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse tags: %w", err)
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	if len(b.query.Values) > 0 {
+		matches, err := valuesMatch(row.Values, b.query.Values)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse values: %w", err)
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	return true, nil
+}
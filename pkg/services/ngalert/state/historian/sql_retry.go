@@ -0,0 +1,64 @@
+package historian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/VividCortex/mysqlerr"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+const (
+	// defaultDeadlockRetries is the default number of times a transaction is retried after a deadlock.
+	defaultDeadlockRetries = 3
+	// defaultDeadlockBackoff is the default time to wait before the first retry. It doubles with every retry.
+	defaultDeadlockBackoff = 50 * time.Millisecond
+)
+
+// Postgres SQL states of transactions that were aborted to resolve a conflict, and succeed when retried.
+const (
+	pqSerializationFailure = "40001"
+	pqDeadlockDetected     = "40P01"
+)
+
+// isTransientTxError reports whether err means that the transaction was aborted by the database to resolve
+// a conflict with a concurrent transaction, e.g. a deadlock between writes and retention deletes.
+// Retrying the whole transaction is expected to succeed.
+func isTransientTxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlerr.ER_LOCK_DEADLOCK
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == pqSerializationFailure || pqErr.Code == pqDeadlockDetected
+	}
+	return false
+}
+
+// withDeadlockRetry runs tx, which must execute a complete transaction, and runs it again with exponential
+// backoff if the database aborted it due to a deadlock or serialization failure. Other errors are returned
+// immediately.
+func (h *SqlBackend) withDeadlockRetry(ctx context.Context, tx func() error) error {
+	backoff := h.deadlockBackoff
+	for attempt := 0; ; attempt++ {
+		err := tx()
+		if err == nil || !isTransientTxError(err) {
+			return err
+		}
+		if attempt >= h.deadlockRetries {
+			return fmt.Errorf("transaction failed after %d retries: %w", attempt, err)
+		}
+
+		h.log.FromContext(ctx).Debug("Transaction aborted by the database, retrying", "error", err, "attempt", attempt+1, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-h.clock.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,49 @@
+package historian
+
+import (
+	"sync"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+// SamplingConfig configures sampling of recorded transitions, for rules that are evaluated so frequently that
+// recording every transition is excessive. Only transitions that keep the state, e.g. because only the reason
+// changed, are sampled. Transitions to a different state are always recorded.
+type SamplingConfig struct {
+	// Every records only every Nth sampled transition of a rule. Values of 0 and 1 record every transition.
+	Every int
+	// EveryByRule overrides Every for the rules with the given UIDs.
+	EveryByRule map[string]int
+}
+
+type sampler struct {
+	cfg SamplingConfig
+
+	mtx    sync.Mutex
+	counts map[string]int
+}
+
+func newSampler(cfg SamplingConfig) *sampler {
+	return &sampler{cfg: cfg, counts: make(map[string]int)}
+}
+
+// keep reports whether a transition of the given rule should be recorded. Sampling is deterministic: of the
+// transitions subject to sampling, the first and then every Nth one of each rule is kept.
+func (s *sampler) keep(ruleUID string, transition state.StateTransition) bool {
+	if transition.PreviousState != transition.State.State {
+		return true
+	}
+	every := s.cfg.Every
+	if n, ok := s.cfg.EveryByRule[ruleUID]; ok {
+		every = n
+	}
+	if every <= 1 {
+		return true
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	n := s.counts[ruleUID]
+	s.counts[ruleUID] = (n + 1) % every
+	return n == 0
+}
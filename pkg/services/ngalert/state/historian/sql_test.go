@@ -0,0 +1,1478 @@
+package historian
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VividCortex/mysqlerr"
+	"github.com/benbjohnson/clock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationSqlBackend(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	t.Run("recorded transitions are queryable", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now().Truncate(time.Millisecond)
+		transitions := []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+			createTransition(eval.Normal, eval.Normal, data.Labels{"a": "c"}, now),
+		}
+
+		recordSync(t, sql, rule, transitions, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, now, frame.Fields[0].At(0))
+		require.Equal(t, rule.UID, frame.Fields[1].At(0))
+		require.JSONEq(t, `{"a":"b"}`, frame.Fields[2].At(0).(string))
+		require.Equal(t, "Normal", frame.Fields[3].At(0))
+		require.Equal(t, "Alerting", frame.Fields[4].At(0))
+	})
+
+	t.Run("query filters by labels and time range", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now()
+		transitions := []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-2*time.Hour)),
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "c"}, now),
+		}
+
+		recordSync(t, sql, rule, transitions, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{
+			OrgID:  rule.OrgID,
+			Labels: map[string]string{"a": "b"},
+			From:   now.Add(-time.Hour),
+			To:     now.Add(time.Hour),
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.JSONEq(t, `{"a":"b"}`, frame.Fields[2].At(0).(string))
+	})
+
+	t.Run("query filters by namespace and group", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		now := time.Now()
+		payments := models.AlertRuleGen(withOrgID(1), withUID("payments"), withNamespaceUID("payments-folder"), withGroup("api"))()
+		other := models.AlertRuleGen(withOrgID(1), withUID("other"), withNamespaceUID("other-folder"), withGroup("api"))()
+
+		recordSync(t, sql, payments, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now)}, "")
+		recordSync(t, sql, other, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now)}, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: 1, NamespaceUID: "payments-folder"})
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, payments.UID, frame.Fields[1].At(0))
+
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: 1, NamespaceUID: "payments-folder", RuleGroup: "other-group"})
+		require.NoError(t, err)
+		require.Equal(t, 0, frame.Rows())
+
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: 1, RuleGroup: "api"})
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+	})
+
+	t.Run("current states return the latest transition of each instance", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		now := time.Now().Truncate(time.Millisecond)
+		first := models.AlertRuleGen(withOrgID(1), withUID("first"))()
+		second := models.AlertRuleGen(withOrgID(1), withUID("second"))()
+		otherOrg := models.AlertRuleGen(withOrgID(2), withUID("other-org"))()
+
+		recordSync(t, sql, first, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-2*time.Minute)),
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now),
+			createTransition(eval.Normal, eval.Pending, data.Labels{"a": "c"}, now.Add(-time.Minute)),
+		}, "")
+		recordSync(t, sql, second, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-time.Minute)),
+		}, "")
+		recordSync(t, sql, otherOrg, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+		}, "")
+
+		frame, err := sql.CurrentStates(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, 3, frame.Rows())
+
+		require.Equal(t, "first", frame.Fields[1].At(0))
+		require.JSONEq(t, `{"a":"b"}`, frame.Fields[2].At(0).(string))
+		require.Equal(t, "Normal", frame.Fields[3].At(0))
+		require.Equal(t, now, frame.Fields[0].At(0))
+
+		require.Equal(t, "first", frame.Fields[1].At(1))
+		require.JSONEq(t, `{"a":"c"}`, frame.Fields[2].At(1).(string))
+		require.Equal(t, "Pending", frame.Fields[3].At(1))
+
+		require.Equal(t, "second", frame.Fields[1].At(2))
+		require.Equal(t, "Alerting", frame.Fields[3].At(2))
+	})
+
+	t.Run("current states keep the last recorded of transitions evaluated at the same time", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		now := time.Now().Truncate(time.Millisecond)
+		rule := models.AlertRuleGen(withOrgID(1), withUID("rule"))()
+
+		recordSync(t, sql, rule, []state.StateTransition{
+			createTransition(eval.Normal, eval.Pending, data.Labels{"a": "b"}, now),
+		}, "")
+		recordSync(t, sql, rule, []state.StateTransition{
+			createTransition(eval.Pending, eval.Alerting, data.Labels{"a": "b"}, now),
+		}, "")
+
+		frame, err := sql.CurrentStates(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, "Alerting", frame.Fields[3].At(0))
+	})
+
+	t.Run("recently changed rules are ordered by their latest change", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		now := time.Now().Truncate(time.Millisecond)
+		first := models.AlertRuleGen(withOrgID(1), withUID("first"))()
+		second := models.AlertRuleGen(withOrgID(1), withUID("second"))()
+		third := models.AlertRuleGen(withOrgID(1), withUID("third"))()
+		stale := models.AlertRuleGen(withOrgID(1), withUID("stale"))()
+		otherOrg := models.AlertRuleGen(withOrgID(2), withUID("other-org"))()
+
+		recordSync(t, sql, first, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-3*time.Minute)),
+			createTransition(eval.Normal, eval.Pending, data.Labels{"a": "c"}, now.Add(-time.Minute)),
+		}, "")
+		recordSync(t, sql, second, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-4*time.Minute)),
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now),
+		}, "")
+		recordSync(t, sql, third, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-2*time.Minute)),
+		}, "")
+		recordSync(t, sql, stale, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-time.Hour)),
+		}, "")
+		recordSync(t, sql, otherOrg, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+		}, "")
+
+		changes, err := sql.RecentlyChangedRules(context.Background(), 1, now.Add(-10*time.Minute), 0)
+		require.NoError(t, err)
+		require.Equal(t, []RuleChange{
+			{RuleUID: "second", State: "Normal", ChangedAt: now},
+			{RuleUID: "first", State: "Pending", ChangedAt: now.Add(-time.Minute)},
+			{RuleUID: "third", State: "Alerting", ChangedAt: now.Add(-2 * time.Minute)},
+		}, changes)
+
+		changes, err = sql.RecentlyChangedRules(context.Background(), 1, now.Add(-10*time.Minute), 2)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		require.Equal(t, "second", changes[0].RuleUID)
+		require.Equal(t, "first", changes[1].RuleUID)
+
+		changes, err = sql.RecentlyChangedRules(context.Background(), 1, now.Add(time.Minute), 0)
+		require.NoError(t, err)
+		require.Empty(t, changes)
+	})
+
+	t.Run("recently changed rules count a rule once when several of its instances changed last", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		now := time.Now().Truncate(time.Millisecond)
+		first := models.AlertRuleGen(withOrgID(1), withUID("first"))()
+		second := models.AlertRuleGen(withOrgID(1), withUID("second"))()
+
+		recordSync(t, sql, first, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+			createTransition(eval.Normal, eval.Pending, data.Labels{"a": "c"}, now),
+		}, "")
+		recordSync(t, sql, second, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-time.Minute)),
+		}, "")
+
+		changes, err := sql.RecentlyChangedRules(context.Background(), 1, now.Add(-10*time.Minute), 2)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		require.Equal(t, "first", changes[0].RuleUID)
+		require.Equal(t, now, changes[0].ChangedAt)
+		require.Equal(t, "second", changes[1].RuleUID)
+	})
+
+	t.Run("tagged transitions are filterable by tag", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now()
+
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(-time.Minute))}, "")
+		err := sql.RecordStatesWithTagsAsync(context.Background(), rule, []state.StateTransition{
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now),
+		}, map[string]string{"version": "1.2.3", "oncall": "alice"})
+		require.NoError(t, err)
+
+		query := models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Tags: map[string]string{"version": "1.2.3"}}
+		var frame *data.Frame
+		require.Eventually(t, func() bool {
+			frame, err = sql.QueryStates(context.Background(), query)
+			return err == nil && frame.Rows() == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, "Normal", frame.Fields[4].At(0))
+		require.JSONEq(t, `{"version":"1.2.3","oncall":"alice"}`, *frame.Fields[8].At(0).(*string))
+
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Tags: map[string]string{"version": "1.2.4"}})
+		require.NoError(t, err)
+		require.Equal(t, 0, frame.Rows())
+
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Nil(t, frame.Fields[8].At(0))
+	})
+
+	t.Run("oversized tags are rejected", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		transitions := []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, time.Now())}
+
+		err := sql.RecordStatesWithTagsAsync(context.Background(), rule, transitions, map[string]string{strings.Repeat("k", maxTagKeyLength+1): "v"})
+		require.ErrorIs(t, err, ErrInvalidTags)
+		err = sql.RecordStatesWithTagsAsync(context.Background(), rule, transitions, map[string]string{"k": strings.Repeat("v", maxTagValueLength+1)})
+		require.ErrorIs(t, err, ErrInvalidTags)
+		err = sql.RecordStatesWithTagsAsync(context.Background(), rule, transitions, map[string]string{"": "v"})
+		require.ErrorIs(t, err, ErrInvalidTags)
+	})
+
+	t.Run("malformed entries are skipped for partial queries", func(t *testing.T) {
+		store := db.InitTestDB(t)
+		sql := NewSqlBackend(SqlConfig{}, store)
+		rule := createTestRule()
+		now := time.Now()
+
+		recordSync(t, sql, rule, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now.Add(time.Second)),
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(2*time.Second)),
+		}, "")
+		err := store.WithDbSession(context.Background(), func(sess *db.Session) error {
+			_, err := sess.Exec("UPDATE alert_state_history SET state_values = ? WHERE evaluated_at = ?", `{"values":`, now.Add(time.Second).UnixMilli())
+			return err
+		})
+		require.NoError(t, err)
+
+		_, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.Error(t, err)
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Partial: true})
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "Alerting", frame.Fields[4].At(0))
+		require.Equal(t, "Alerting", frame.Fields[4].At(1))
+		require.NotNil(t, frame.Meta)
+		require.Len(t, frame.Meta.Notices, 1)
+		require.Equal(t, data.NoticeSeverityWarning, frame.Meta.Notices[0].Severity)
+		require.Equal(t, "Skipped state history entries that could not be read: 1", frame.Meta.Notices[0].Text)
+	})
+
+	t.Run("purging a rule deletes only its history", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		now := time.Now()
+		purged := models.AlertRuleGen(withOrgID(1), withUID("purged"))()
+		kept := models.AlertRuleGen(withOrgID(1), withUID("kept"))()
+		otherOrg := models.AlertRuleGen(withOrgID(2), withUID("purged"))()
+
+		transitions := make([]state.StateTransition, 0, 5)
+		for i := 0; i < 5; i++ {
+			transitions = append(transitions, createTransition(eval.Normal, eval.Alerting, data.Labels{"i": fmt.Sprint(i)}, now))
+		}
+		recordSync(t, sql, purged, transitions, "")
+		recordSync(t, sql, kept, transitions[:2], "")
+		recordSync(t, sql, otherOrg, transitions[:3], "")
+
+		deleted, err := sql.PurgeRuleHistory(context.Background(), 1, "purged")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), deleted)
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: 1, RuleUID: "purged"})
+		require.NoError(t, err)
+		require.Equal(t, 0, frame.Rows())
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: 1, RuleUID: "kept"})
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: 2, RuleUID: "purged"})
+		require.NoError(t, err)
+		require.Equal(t, 3, frame.Rows())
+
+		deleted, err = sql.PurgeRuleHistory(context.Background(), 1, "purged")
+		require.NoError(t, err)
+		require.Equal(t, int64(0), deleted)
+	})
+
+	t.Run("transitions of many instances are recorded together", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now()
+
+		const instances = 100
+		transitions := make([]state.StateTransition, 0, instances)
+		for i := 0; i < instances; i++ {
+			transition := createTransition(eval.Normal, eval.Alerting, data.Labels{"instance": fmt.Sprint(i)}, now)
+			transition.Values = map[string]float64{"A": float64(i)}
+			transitions = append(transitions, transition)
+		}
+		recordSync(t, sql, rule, transitions, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Equal(t, instances, frame.Rows())
+		for i := 0; i < instances; i++ {
+			require.JSONEq(t, fmt.Sprintf(`{"instance":"%d"}`, i), frame.Fields[2].At(i).(string))
+			require.JSONEq(t, fmt.Sprintf(`{"values":{"A":%d}}`, i), frame.Fields[5].At(i).(string))
+		}
+	})
+
+	t.Run("trace IDs are stored when present", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now()
+
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now)}, "")
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now.Add(time.Second))}, "4bf92f3577b34da6a3ce929d0e0e4736")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Nil(t, frame.Fields[6].At(0))
+		traceID := frame.Fields[6].At(1).(*string)
+		require.NotNil(t, traceID)
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", *traceID)
+	})
+
+	t.Run("query returns only the requested fields", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now().Truncate(time.Millisecond)
+
+		recordSync(t, sql, rule, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "c"}, now.Add(time.Second)),
+		}, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Fields: []string{"current", "time"}})
+		require.NoError(t, err)
+		require.Len(t, frame.Fields, 2)
+		require.Equal(t, "time", frame.Fields[0].Name)
+		require.Equal(t, "current", frame.Fields[1].Name)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, now, frame.Fields[0].At(0))
+		require.Equal(t, "Alerting", frame.Fields[1].At(0))
+		require.Equal(t, "Normal", frame.Fields[1].At(1))
+
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Labels: map[string]string{"a": "c"}, Fields: []string{"current"}})
+		require.NoError(t, err)
+		require.Len(t, frame.Fields, 1)
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, "Normal", frame.Fields[0].At(0))
+
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Len(t, frame.Fields, 11)
+
+		_, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Fields: []string{"time", "severity"}})
+		require.ErrorIs(t, err, ErrInvalidHistoryQuery)
+	})
+
+	t.Run("backfilled transitions are queryable at their original times", func(t *testing.T) {
+		store := db.InitTestDB(t)
+		sql := NewSqlBackend(SqlConfig{Sampling: SamplingConfig{Every: 10}}, store)
+		rule := createTestRule()
+		past := time.Now().Add(-30 * 24 * time.Hour).Truncate(time.Millisecond)
+
+		transitions := make([]state.StateTransition, 0, 2*backfillBatchSize)
+		for i := 0; i < 2*backfillBatchSize; i++ {
+			// Same-state transitions would be dropped or sampled when recording, but must be kept by backfills.
+			transition := createTransition(eval.Normal, eval.Normal, data.Labels{"a": "b"}, past.Add(time.Duration(i)*time.Minute))
+			transition.State.OrgID = rule.OrgID
+			transition.State.AlertRuleUID = rule.UID
+			transitions = append(transitions, transition)
+		}
+
+		written, err := sql.BackfillStates(context.Background(), transitions)
+		require.NoError(t, err)
+		require.Equal(t, len(transitions), written)
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, From: past, To: past.Add(time.Hour)})
+		require.NoError(t, err)
+		require.Equal(t, 61, frame.Rows())
+		require.Equal(t, past, frame.Fields[0].At(0))
+		require.Equal(t, past.Add(time.Hour), frame.Fields[0].At(60))
+	})
+
+	t.Run("backfills can be re-run", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		past := time.Now().Add(-24 * time.Hour).Truncate(time.Millisecond)
+
+		transitions := []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, past),
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "c"}, past),
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, past.Add(time.Minute)),
+		}
+		for i := range transitions {
+			transitions[i].State.OrgID = rule.OrgID
+			transitions[i].State.AlertRuleUID = rule.UID
+		}
+
+		written, err := sql.BackfillStates(context.Background(), transitions[:2])
+		require.NoError(t, err)
+		require.Equal(t, 2, written)
+
+		written, err = sql.BackfillStates(context.Background(), append(transitions, transitions[2]))
+		require.NoError(t, err)
+		require.Equal(t, 1, written)
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Equal(t, 3, frame.Rows())
+	})
+
+	t.Run("distinct states are deduplicated and filtered", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now().Truncate(time.Millisecond)
+
+		recordSync(t, sql, rule, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now.Add(time.Second)),
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(2*time.Second)),
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "c"}, now.Add(3*time.Second)),
+			createTransition(eval.Alerting, eval.Pending, data.Labels{"a": "c"}, now.Add(4*time.Second)),
+			createTransition(eval.Pending, eval.Error, data.Labels{"a": "c"}, now.Add(5*time.Second)),
+		}, "")
+
+		states, err := sql.DistinctStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Alerting", "Error", "Normal", "Pending"}, states)
+
+		states, err = sql.DistinctStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, From: now, To: now.Add(3 * time.Second)})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Alerting", "Normal"}, states)
+
+		states, err = sql.DistinctStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Labels: map[string]string{"a": "c"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Alerting", "Error", "Pending"}, states)
+
+		states, err = sql.DistinctStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: "other"})
+		require.NoError(t, err)
+		require.Empty(t, states)
+	})
+
+	t.Run("labels can be returned as a string", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now()
+
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"team": "x", "env": "prod"}, now)}, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, LabelsAsString: true})
+		require.NoError(t, err)
+		require.Len(t, frame.Fields, 12)
+		require.Equal(t, "labelsString", frame.Fields[11].Name)
+		require.Equal(t, `{env="prod", team="x"}`, frame.Fields[11].At(0))
+
+		frame, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Fields: []string{"time", "labelsString"}})
+		require.NoError(t, err)
+		require.Len(t, frame.Fields, 2)
+		require.Equal(t, `{env="prod", team="x"}`, frame.Fields[1].At(0))
+	})
+
+	t.Run("transitions can be filtered by value", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now().Truncate(time.Millisecond)
+		withValues := func(labels data.Labels, at time.Time, values map[string]float64) state.StateTransition {
+			transition := createTransition(eval.Normal, eval.Alerting, labels, at)
+			transition.State.Values = values
+			return transition
+		}
+		noData := createTransition(eval.Normal, eval.NoData, data.Labels{"instance": "4"}, now)
+		recordSync(t, sql, rule, []state.StateTransition{
+			withValues(data.Labels{"instance": "1"}, now, map[string]float64{"B": 95, "C": 1}),
+			withValues(data.Labels{"instance": "2"}, now.Add(time.Second), map[string]float64{"B": 90, "C": 0}),
+			withValues(data.Labels{"instance": "3"}, now.Add(2*time.Second), map[string]float64{"B": 42, "C": 0}),
+			noData,
+		}, "")
+
+		instances := func(filters ...models.ValueFilter) []string {
+			t.Helper()
+			frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Values: filters})
+			require.NoError(t, err)
+			result := make([]string, 0, frame.Rows())
+			for i := 0; i < frame.Rows(); i++ {
+				var labels data.Labels
+				require.NoError(t, json.Unmarshal([]byte(frame.Fields[2].At(i).(string)), &labels))
+				result = append(result, labels["instance"])
+			}
+			return result
+		}
+
+		require.Equal(t, []string{"1"}, instances(models.ValueFilter{Operator: models.ValueGreaterThan, Threshold: 90}))
+		require.Equal(t, []string{"1", "2"}, instances(models.ValueFilter{Operator: models.ValueGreaterOrEqual, Threshold: 90}))
+		require.Equal(t, []string{"3"}, instances(models.ValueFilter{Operator: models.ValueLessThan, Threshold: 90}))
+		require.Equal(t, []string{"2", "3"}, instances(models.ValueFilter{RefID: "C", Operator: models.ValueEqual, Threshold: 0}))
+		require.Equal(t, []string{"2"}, instances(
+			models.ValueFilter{Operator: models.ValueGreaterOrEqual, Threshold: 50},
+			models.ValueFilter{RefID: "C", Operator: models.ValueEqual, Threshold: 0},
+		))
+		require.Empty(t, instances(models.ValueFilter{RefID: "X", Operator: models.ValueGreaterThan, Threshold: 0}))
+
+		states, err := sql.DistinctStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Values: []models.ValueFilter{{Operator: models.ValueGreaterThan, Threshold: 0}}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Alerting"}, states)
+
+		_, err = sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Values: []models.ValueFilter{{Operator: "~", Threshold: 1}}})
+		require.ErrorIs(t, err, ErrInvalidHistoryQuery)
+	})
+
+	t.Run("evaluation durations are recorded", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now().Truncate(time.Millisecond)
+		slow := createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now)
+		slow.State.EvaluationDuration = 1500 * time.Millisecond
+		fast := createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now.Add(time.Minute))
+		fast.State.EvaluationDuration = 500 * time.Millisecond
+		unknown := createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(2*time.Minute))
+
+		recordSync(t, sql, rule, []state.StateTransition{slow, fast, unknown}, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Fields: []string{"time", "evalDurationMs"}})
+		require.NoError(t, err)
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, int64(1500), *frame.Fields[1].At(0).(*int64))
+		require.Equal(t, int64(500), *frame.Fields[1].At(1).(*int64))
+		require.Nil(t, frame.Fields[1].At(2))
+		require.Equal(t, "ms", frame.Fields[1].Config.Unit)
+
+		require.NotNil(t, frame.Meta)
+		require.Len(t, frame.Meta.Stats, 1)
+		require.Equal(t, 1000.0, frame.Meta.Stats[0].Value)
+	})
+
+	t.Run("no average evaluation duration is reported if none is known", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, time.Now())}, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Nil(t, frame.Fields[9].At(0))
+		require.Nil(t, frame.Meta)
+	})
+
+	t.Run("the number of firing instances is recorded with every transition", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now().Truncate(time.Millisecond)
+
+		recordSync(t, sql, rule, []state.StateTransition{
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now),
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "c"}, now),
+			// unchanged, not recorded, but counted
+			createTransition(eval.Alerting, eval.Alerting, data.Labels{"a": "d"}, now),
+			createTransition(eval.Normal, eval.Pending, data.Labels{"a": "e"}, now),
+		}, "")
+		recordSync(t, sql, rule, []state.StateTransition{
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now.Add(time.Minute)),
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "c"}, now.Add(time.Minute)),
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "d"}, now.Add(time.Minute)),
+		}, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Fields: []string{"time", "current", "instanceCount"}})
+		require.NoError(t, err)
+		require.Equal(t, 6, frame.Rows())
+		for i := 0; i < 3; i++ {
+			require.Equal(t, int64(3), *frame.Fields[2].At(i).(*int64))
+		}
+		for i := 3; i < 6; i++ {
+			require.Equal(t, "Normal", frame.Fields[1].At(i))
+			require.Equal(t, int64(0), *frame.Fields[2].At(i).(*int64))
+		}
+	})
+
+	t.Run("the number of firing instances of backfilled transitions is unknown", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		transition := createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, time.Now())
+		transition.State.OrgID = rule.OrgID
+		transition.State.AlertRuleUID = rule.UID
+
+		_, err := sql.BackfillStates(context.Background(), []state.StateTransition{transition})
+		require.NoError(t, err)
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Fields: []string{"instanceCount"}})
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.Nil(t, frame.Fields[0].At(0))
+	})
+
+	t.Run("closely spaced transitions are merged at query time", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Now().Truncate(time.Second)
+
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now)}, "")
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now.Add(2*time.Second))}, "")
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now.Add(4*time.Second))}, "")
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now.Add(time.Minute))}, "")
+
+		query := models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, Fields: []string{"time", "previous", "current"}}
+		frame, err := sql.QueryStates(context.Background(), query)
+		require.NoError(t, err)
+		require.Equal(t, 4, frame.Rows())
+
+		query.MergeTolerance = 5 * time.Second
+		frame, err = sql.QueryStates(context.Background(), query)
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, now.Add(4*time.Second), frame.Fields[0].At(0))
+		require.Equal(t, "Normal", frame.Fields[1].At(0))
+		require.Equal(t, "Alerting", frame.Fields[2].At(0))
+		require.Equal(t, now.Add(time.Minute), frame.Fields[0].At(1))
+		require.Equal(t, "Normal", frame.Fields[2].At(1))
+	})
+
+	t.Run("transitions are exportable as CSV", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		transitions := []state.StateTransition{
+			createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b", "msg": "hello, \"world\""}, now.Add(time.Minute)),
+			createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b", "msg": "hello, \"world\""}, now),
+		}
+
+		recordSync(t, sql, rule, transitions, "")
+
+		buf := new(bytes.Buffer)
+		require.NoError(t, sql.ExportCSV(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID}, buf))
+
+		records, err := csv.NewReader(buf).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, [][]string{
+			{"time", "rule_uid", "previous_state", "current_state", "labels", "values"},
+			{"2023-01-02T03:04:05Z", rule.UID, "Normal", "Alerting", `{a="b", msg="hello, \"world\""}`, `{"values":{"A":1}}`},
+			{"2023-01-02T03:05:05Z", rule.UID, "Alerting", "Normal", `{a="b", msg="hello, \"world\""}`, `{"values":{"A":1}}`},
+		}, records)
+	})
+
+	t.Run("transitions can be written as a Prometheus matrix", func(t *testing.T) {
+		sql := createTestSqlBackendSut(t)
+		rule := createTestRule()
+		now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		pending := createTransition(eval.Normal, eval.Pending, data.Labels{"alertname": "high-cpu", "team": "x"}, now)
+		firing := createTransition(eval.Pending, eval.Alerting, data.Labels{"alertname": "high-cpu", "team": "x"}, now.Add(time.Minute))
+		resolved := createTransition(eval.Alerting, eval.Normal, data.Labels{"alertname": "high-cpu", "team": "x"}, now.Add(3*time.Minute+500*time.Millisecond))
+		resolved.State.StateReason = eval.NoData.String()
+
+		recordSync(t, sql, rule, []state.StateTransition{pending, firing, resolved}, "")
+
+		buf := new(bytes.Buffer)
+		require.NoError(t, sql.WriteStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID}, HistoryFormatPrometheusMatrix, buf))
+		require.JSONEq(t, `{
+			"status": "success",
+			"data": {
+				"resultType": "matrix",
+				"result": [
+					{
+						"metric": {"__name__": "ALERTS", "alertname": "high-cpu", "alertstate": "firing", "grafana_rule_uid": "my-rule", "team": "x"},
+						"values": [[1672628705, "1"], [1672628825.5, "0"]]
+					},
+					{
+						"metric": {"__name__": "ALERTS", "alertname": "high-cpu", "alertstate": "normal", "grafana_rule_uid": "my-rule", "team": "x"},
+						"values": [[1672628645, "0"], [1672628825.5, "1"]]
+					},
+					{
+						"metric": {"__name__": "ALERTS", "alertname": "high-cpu", "alertstate": "pending", "grafana_rule_uid": "my-rule", "team": "x"},
+						"values": [[1672628645, "1"], [1672628705, "0"]]
+					}
+				]
+			}
+		}`, buf.String())
+
+		err := sql.WriteStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID}, "xml", buf)
+		require.ErrorIs(t, err, ErrInvalidHistoryQuery)
+	})
+
+	t.Run("oversized values are stored truncated", func(t *testing.T) {
+		sql := NewSqlBackend(SqlConfig{MaxValuesSize: 24}, db.InitTestDB(t))
+		rule := createTestRule()
+		now := time.Now()
+		fits := createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now)
+		fits.Values = map[string]float64{"A": 1, "B": 2}
+		tooLarge := createTransition(eval.Alerting, eval.Normal, data.Labels{"a": "b"}, now.Add(time.Second))
+		tooLarge.Values = map[string]float64{"A": 1, "B": 2, "C": 3}
+
+		recordSync(t, sql, rule, []state.StateTransition{fits, tooLarge}, "")
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+		require.JSONEq(t, `{"values":{"A":1,"B":2}}`, frame.Fields[5].At(0).(string))
+		require.False(t, frame.Fields[7].At(0).(bool))
+		require.JSONEq(t, `{"values":{"A":1,"B":2}}`, frame.Fields[5].At(1).(string))
+		require.True(t, frame.Fields[7].At(1).(bool))
+	})
+}
+
+func TestIntegrationSqlBackendCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	const ttl = time.Minute
+	createSut := func(t *testing.T) (*SqlBackend, *clock.Mock) {
+		mock := clock.NewMock()
+		return NewSqlBackend(SqlConfig{CacheTTL: ttl, Clock: mock}, db.InitTestDB(t)), mock
+	}
+	rule := createTestRule()
+	base := time.Date(2023, 1, 2, 3, 4, 0, 0, time.UTC)
+	record := func(t *testing.T, sql *SqlBackend, labels data.Labels) {
+		recordSync(t, sql, rule, []state.StateTransition{createTransition(eval.Normal, eval.Alerting, labels, base)}, "")
+	}
+	query := func(from time.Time) models.HistoryQuery {
+		return models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID, From: from, To: from.Add(time.Hour)}
+	}
+
+	t.Run("repeated queries within the time bucket hit the cache", func(t *testing.T) {
+		sql, _ := createSut(t)
+		record(t, sql, data.Labels{"a": "b"})
+
+		frame, err := sql.QueryStates(context.Background(), query(base.Add(-time.Hour+time.Second)))
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+
+		record(t, sql, data.Labels{"a": "c"})
+
+		frame, err = sql.QueryStates(context.Background(), query(base.Add(-time.Hour+2*time.Second)))
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+	})
+
+	t.Run("cached results expire after the TTL", func(t *testing.T) {
+		sql, mock := createSut(t)
+		record(t, sql, data.Labels{"a": "b"})
+
+		frame, err := sql.QueryStates(context.Background(), query(base.Add(-time.Hour)))
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+
+		record(t, sql, data.Labels{"a": "c"})
+		mock.Add(ttl)
+
+		frame, err = sql.QueryStates(context.Background(), query(base.Add(-time.Hour)))
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+	})
+
+	t.Run("queries with different filters miss the cache", func(t *testing.T) {
+		sql, _ := createSut(t)
+		record(t, sql, data.Labels{"a": "b"})
+
+		frame, err := sql.QueryStates(context.Background(), query(base.Add(-time.Hour)))
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+
+		record(t, sql, data.Labels{"a": "c"})
+
+		filtered := query(base.Add(-time.Hour))
+		filtered.Labels = map[string]string{"a": "c"}
+		frame, err = sql.QueryStates(context.Background(), filtered)
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+		require.JSONEq(t, `{"a":"c"}`, frame.Fields[2].At(0).(string))
+
+		frame, err = sql.QueryStates(context.Background(), query(base.Add(-30*time.Minute)))
+		require.NoError(t, err)
+		require.Equal(t, 2, frame.Rows())
+	})
+}
+
+func TestIntegrationSqlBackendDeadlockRetry(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	deadlock := &mysql.MySQLError{Number: mysqlerr.ER_LOCK_DEADLOCK, Message: "Deadlock found when trying to get lock"}
+	createSut := func(t *testing.T, failures int, err error) (*SqlBackend, *flakyDB) {
+		store := &flakyDB{DB: db.InitTestDB(t), failures: failures, err: err}
+		return NewSqlBackend(SqlConfig{DeadlockRetries: 2, DeadlockBackoff: time.Millisecond}, store), store
+	}
+	rule := createTestRule()
+	rows := func(sql *SqlBackend) []stateHistoryRow {
+		transitions := []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, time.Now())}
+		return sql.buildRows(rule, transitions, "", log.NewNopLogger())
+	}
+
+	t.Run("write is retried after a deadlock", func(t *testing.T) {
+		sql, store := createSut(t, 1, deadlock)
+
+		require.NoError(t, sql.recordRows(context.Background(), rows(sql)))
+		require.Equal(t, 2, store.calls)
+
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		require.Equal(t, 1, frame.Rows())
+	})
+
+	t.Run("cleanup is retried after a serialization failure", func(t *testing.T) {
+		sql, store := createSut(t, 0, nil)
+		require.NoError(t, sql.recordRows(context.Background(), rows(sql)))
+		store.failures, store.err, store.calls = 1, &pq.Error{Code: pqSerializationFailure}, 0
+
+		deleted, err := sql.PurgeRuleHistory(context.Background(), rule.OrgID, rule.UID)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, deleted)
+	})
+
+	t.Run("error is returned when retries are exhausted", func(t *testing.T) {
+		sql, store := createSut(t, 5, deadlock)
+
+		err := sql.recordRows(context.Background(), rows(sql))
+		require.ErrorIs(t, err, deadlock)
+		require.ErrorContains(t, err, "after 2 retries")
+		require.Equal(t, 3, store.calls)
+	})
+
+	t.Run("backoff waits on the configured clock", func(t *testing.T) {
+		mock := clock.NewMock()
+		store := &flakyDB{DB: db.InitTestDB(t), failures: 2, err: deadlock}
+		sql := NewSqlBackend(SqlConfig{DeadlockRetries: 2, DeadlockBackoff: time.Hour, Clock: mock}, store)
+		start := mock.Now()
+
+		done := make(chan error)
+		go func() {
+			done <- sql.recordRows(context.Background(), rows(sql))
+		}()
+		for {
+			select {
+			case err := <-done:
+				require.NoError(t, err)
+				// The retries waited one and two hours, without any of that time passing for real.
+				require.GreaterOrEqual(t, mock.Now().Sub(start), 3*time.Hour)
+				return
+			case <-time.After(time.Millisecond):
+				mock.Add(30 * time.Minute)
+			}
+		}
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		sql, store := createSut(t, 1, errors.New("disk full"))
+
+		err := sql.recordRows(context.Background(), rows(sql))
+		require.EqualError(t, err, "disk full")
+		require.Equal(t, 1, store.calls)
+	})
+}
+
+// flakyDB fails the first sessions it opens with the configured error.
+type flakyDB struct {
+	db.DB
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyDB) fail() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyDB) WithDbSession(ctx context.Context, callback sqlstore.DBTransactionFunc) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	return f.DB.WithDbSession(ctx, callback)
+}
+
+func (f *flakyDB) WithTransactionalDbSession(ctx context.Context, callback sqlstore.DBTransactionFunc) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	return f.DB.WithTransactionalDbSession(ctx, callback)
+}
+
+func TestIntegrationSqlBackendFlush(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	const interval = time.Minute
+	createSut := func(t *testing.T) (*SqlBackend, *clock.Mock) {
+		mock := clock.NewMock()
+		return NewSqlBackend(SqlConfig{FlushInterval: interval, Clock: mock}, db.InitTestDB(t)), mock
+	}
+	rule := createTestRule()
+	count := func(t *testing.T, sql *SqlBackend) int {
+		t.Helper()
+		frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: rule.OrgID, RuleUID: rule.UID})
+		require.NoError(t, err)
+		return frame.Rows()
+	}
+	transitions := func(instance int) []state.StateTransition {
+		return []state.StateTransition{createTransition(eval.Normal, eval.Alerting, data.Labels{"instance": fmt.Sprint(instance)}, time.Now())}
+	}
+
+	t.Run("FlushNow stores buffered transitions right away", func(t *testing.T) {
+		sql, _ := createSut(t)
+		sql.RecordStatesAsync(context.Background(), rule, transitions(1))
+		sql.RecordStatesAsync(context.Background(), rule, transitions(2))
+		require.Equal(t, 0, count(t, sql))
+
+		require.NoError(t, sql.FlushNow(context.Background()))
+		require.Equal(t, 2, count(t, sql))
+
+		require.NoError(t, sql.FlushNow(context.Background()))
+		require.Equal(t, 2, count(t, sql))
+	})
+
+	t.Run("buffered transitions are stored after the flush interval", func(t *testing.T) {
+		sql, mock := createSut(t)
+		sql.RecordStatesAsync(context.Background(), rule, transitions(1))
+
+		mock.Add(interval)
+		require.Eventually(t, func() bool { return count(t, sql) == 1 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("concurrent flushes store every transition once", func(t *testing.T) {
+		sql, mock := createSut(t)
+		const writers, records = 4, 10
+		done := make(chan struct{})
+		for w := 0; w < writers; w++ {
+			go func(w int) {
+				defer func() { done <- struct{}{} }()
+				for i := 0; i < records; i++ {
+					sql.RecordStatesAsync(context.Background(), rule, transitions(w*records+i))
+					if i%3 == 0 {
+						assert.NoError(t, sql.FlushNow(context.Background()))
+					}
+				}
+			}(w)
+		}
+		for w := 0; w < writers; w++ {
+			<-done
+		}
+		mock.Add(interval)
+
+		require.NoError(t, sql.FlushNow(context.Background()))
+		require.Equal(t, writers*records, count(t, sql))
+	})
+
+	t.Run("transitions of several rules are written with a single insert", func(t *testing.T) {
+		store := &flakyDB{DB: db.InitTestDB(t)}
+		sql := NewSqlBackend(SqlConfig{FlushInterval: interval, Clock: clock.NewMock()}, store)
+		rules := []*models.AlertRule{
+			models.AlertRuleGen(withOrgID(1), withUID("first"))(),
+			models.AlertRuleGen(withOrgID(1), withUID("second"))(),
+			models.AlertRuleGen(withOrgID(1), withUID("third"))(),
+		}
+		for i, r := range rules {
+			sql.RecordStatesAsync(context.Background(), r, []state.StateTransition{
+				createTransition(eval.Normal, eval.Alerting, data.Labels{"rule": r.UID}, time.Now()),
+				createTransition(eval.Alerting, eval.Normal, data.Labels{"instance": fmt.Sprint(i)}, time.Now()),
+			})
+		}
+
+		require.NoError(t, sql.FlushNow(context.Background()))
+		require.Equal(t, 1, store.calls)
+
+		for i, r := range rules {
+			frame, err := sql.QueryStates(context.Background(), models.HistoryQuery{OrgID: 1, RuleUID: r.UID, Fields: []string{"current", "labelsString"}})
+			require.NoError(t, err)
+			require.Equal(t, 2, frame.Rows())
+			rows := map[string]string{}
+			for row := 0; row < frame.Rows(); row++ {
+				rows[frame.Fields[1].At(row).(string)] = frame.Fields[0].At(row).(string)
+			}
+			require.Equal(t, map[string]string{
+				fmt.Sprintf(`{rule=%q}`, r.UID):   "Alerting",
+				fmt.Sprintf(`{instance="%d"}`, i): "Normal",
+			}, rows)
+		}
+	})
+
+	t.Run("oversized flushes are split into several inserts", func(t *testing.T) {
+		store := &flakyDB{DB: db.InitTestDB(t)}
+		sql := NewSqlBackend(SqlConfig{FlushInterval: interval, Clock: clock.NewMock()}, store)
+		transitions := make([]state.StateTransition, 0, maxInsertRows+1)
+		for i := 0; i <= maxInsertRows; i++ {
+			transitions = append(transitions, createTransition(eval.Normal, eval.Alerting, data.Labels{"instance": fmt.Sprint(i)}, time.Now()))
+		}
+		sql.RecordStatesAsync(context.Background(), rule, transitions)
+
+		require.NoError(t, sql.FlushNow(context.Background()))
+		require.Equal(t, 2, store.calls)
+		require.Equal(t, maxInsertRows+1, count(t, sql))
+	})
+}
+
+func TestPackStatements(t *testing.T) {
+	batch := func(ruleUID string, n int) []stateHistoryRow {
+		rows := make([]stateHistoryRow, n)
+		for i := range rows {
+			rows[i] = stateHistoryRow{RuleUID: ruleUID, Labels: fmt.Sprintf(`{"instance":"%d"}`, i)}
+		}
+		return rows
+	}
+	sizes := func(statements [][]stateHistoryRow) []int {
+		result := make([]int, 0, len(statements))
+		for _, rows := range statements {
+			result = append(result, len(rows))
+		}
+		return result
+	}
+
+	t.Run("combines batches of several rules", func(t *testing.T) {
+		statements := packStatements([][]stateHistoryRow{batch("a", 2), batch("b", 3), batch("c", 1)}, 10)
+		require.Equal(t, []int{6}, sizes(statements))
+		require.Equal(t, "a", statements[0][0].RuleUID)
+		require.Equal(t, "b", statements[0][2].RuleUID)
+		require.Equal(t, `{"instance":"2"}`, statements[0][4].Labels)
+		require.Equal(t, "c", statements[0][5].RuleUID)
+	})
+
+	t.Run("does not split batches that fit into an insert", func(t *testing.T) {
+		statements := packStatements([][]stateHistoryRow{batch("a", 3), batch("b", 3), batch("c", 2)}, 5)
+		require.Equal(t, []int{3, 5}, sizes(statements))
+	})
+
+	t.Run("splits batches that are larger than an insert", func(t *testing.T) {
+		statements := packStatements([][]stateHistoryRow{batch("a", 1), batch("b", 7), batch("c", 1)}, 3)
+		require.Equal(t, []int{1, 3, 3, 2}, sizes(statements))
+		require.Equal(t, "b", statements[3][0].RuleUID)
+		require.Equal(t, "c", statements[3][1].RuleUID)
+	})
+
+	t.Run("returns nothing for empty buffers", func(t *testing.T) {
+		require.Empty(t, packStatements(nil, 3))
+	})
+}
+
+func TestHistoryQueryBuilder(t *testing.T) {
+	from := time.UnixMilli(1000)
+	to := time.UnixMilli(2000)
+
+	t.Run("builds the SQL condition of each filter", func(t *testing.T) {
+		type test struct {
+			name  string
+			query models.HistoryQuery
+			cond  string
+			args  []interface{}
+		}
+
+		tests := []test{
+			{name: "org only", query: models.HistoryQuery{OrgID: 1}, cond: "org_id = ?", args: []interface{}{int64(1)}},
+			{name: "rule", query: models.HistoryQuery{OrgID: 1, RuleUID: "r"}, cond: "org_id = ? AND rule_uid = ?", args: []interface{}{int64(1), "r"}},
+			{name: "namespace", query: models.HistoryQuery{OrgID: 1, NamespaceUID: "ns"}, cond: "org_id = ? AND namespace_uid = ?", args: []interface{}{int64(1), "ns"}},
+			{name: "group", query: models.HistoryQuery{OrgID: 1, RuleGroup: "g"}, cond: "org_id = ? AND rule_group = ?", args: []interface{}{int64(1), "g"}},
+			{name: "from", query: models.HistoryQuery{OrgID: 1, From: from}, cond: "org_id = ? AND evaluated_at >= ?", args: []interface{}{int64(1), int64(1000)}},
+			{name: "to", query: models.HistoryQuery{OrgID: 1, To: to}, cond: "org_id = ? AND evaluated_at <= ?", args: []interface{}{int64(1), int64(2000)}},
+			{
+				name:  "all",
+				query: models.HistoryQuery{OrgID: 1, RuleUID: "r", NamespaceUID: "ns", RuleGroup: "g", From: from, To: to},
+				cond:  "org_id = ? AND rule_uid = ? AND namespace_uid = ? AND rule_group = ? AND evaluated_at >= ? AND evaluated_at <= ?",
+				args:  []interface{}{int64(1), "r", "ns", "g", int64(1000), int64(2000)},
+			},
+			{
+				name:  "post-filters are not part of the condition",
+				query: models.HistoryQuery{OrgID: 1, Labels: map[string]string{"a": "b"}, Tags: map[string]string{"t": "v"}, Values: []models.ValueFilter{{Operator: models.ValueGreaterThan}}},
+				cond:  "org_id = ?",
+				args:  []interface{}{int64(1)},
+			},
+		}
+
+		for _, tc := range tests {
+			builder, err := newHistoryQueryBuilder(tc.query)
+			require.NoError(t, err, tc.name)
+			cond, args := builder.where()
+			require.Equal(t, tc.cond, cond, tc.name)
+			require.Equal(t, tc.args, args, tc.name)
+		}
+	})
+
+	t.Run("qualifies the columns of the SQL condition with a table", func(t *testing.T) {
+		builder, err := newHistoryQueryBuilder(models.HistoryQuery{OrgID: 1, RuleUID: "r", NamespaceUID: "ns", RuleGroup: "g", From: from, To: to})
+		require.NoError(t, err)
+		cond, args := builder.whereOn("h")
+		require.Equal(t, "h.org_id = ? AND h.rule_uid = ? AND h.namespace_uid = ? AND h.rule_group = ? AND h.evaluated_at >= ? AND h.evaluated_at <= ?", cond)
+		require.Equal(t, []interface{}{int64(1), "r", "ns", "g", int64(1000), int64(2000)}, args)
+	})
+
+	t.Run("matches decoded entries against labels, tags and values", func(t *testing.T) {
+		tags := `{"t":"v"}`
+		row := stateHistoryRow{Labels: `{"a":"b","c":"d"}`, Tags: &tags, Values: `{"values":{"B":95}}`}
+		untagged := stateHistoryRow{Labels: `{"a":"b"}`, Values: `{"values":{"B":95}}`}
+
+		type test struct {
+			name    string
+			query   models.HistoryQuery
+			row     stateHistoryRow
+			matches bool
+			cols    []string
+		}
+
+		tests := []test{
+			{name: "no filters", row: row, matches: true},
+			{name: "labels", query: models.HistoryQuery{Labels: map[string]string{"a": "b"}}, row: row, matches: true, cols: []string{"labels"}},
+			{name: "labels mismatch", query: models.HistoryQuery{Labels: map[string]string{"a": "x"}}, row: row, cols: []string{"labels"}},
+			{name: "tags", query: models.HistoryQuery{Tags: map[string]string{"t": "v"}}, row: row, matches: true, cols: []string{"tags"}},
+			{name: "tags of untagged entry", query: models.HistoryQuery{Tags: map[string]string{"t": "v"}}, row: untagged, cols: []string{"tags"}},
+			{name: "values", query: models.HistoryQuery{Values: []models.ValueFilter{{Operator: models.ValueGreaterThan, Threshold: 90}}}, row: row, matches: true, cols: []string{"state_values"}},
+			{name: "values mismatch", query: models.HistoryQuery{Values: []models.ValueFilter{{Operator: models.ValueLessThan, Threshold: 90}}}, row: row, cols: []string{"state_values"}},
+			{
+				name:    "combined",
+				query:   models.HistoryQuery{Labels: map[string]string{"c": "d"}, Tags: map[string]string{"t": "v"}, Values: []models.ValueFilter{{Operator: models.ValueEqual, Threshold: 95}}},
+				row:     row,
+				matches: true,
+				cols:    []string{"labels", "tags", "state_values"},
+			},
+			{
+				name:  "combined with one mismatch",
+				query: models.HistoryQuery{Labels: map[string]string{"c": "d"}, Tags: map[string]string{"t": "x"}, Values: []models.ValueFilter{{Operator: models.ValueEqual, Threshold: 95}}},
+				row:   row,
+				cols:  []string{"labels", "tags", "state_values"},
+			},
+		}
+
+		for _, tc := range tests {
+			builder, err := newHistoryQueryBuilder(tc.query)
+			require.NoError(t, err, tc.name)
+			require.Equal(t, len(tc.cols) > 0, builder.postFiltered(), tc.name)
+			require.Equal(t, tc.cols, builder.postFilterColumns(), tc.name)
+			matches, err := builder.matches(tc.row)
+			require.NoError(t, err, tc.name)
+			require.Equal(t, tc.matches, matches, tc.name)
+		}
+	})
+
+	t.Run("rejects unknown value operators", func(t *testing.T) {
+		_, err := newHistoryQueryBuilder(models.HistoryQuery{Values: []models.ValueFilter{{Operator: "~"}}})
+		require.ErrorIs(t, err, ErrInvalidHistoryQuery)
+	})
+
+	t.Run("fails on malformed labels", func(t *testing.T) {
+		builder, err := newHistoryQueryBuilder(models.HistoryQuery{Labels: map[string]string{"a": "b"}})
+		require.NoError(t, err)
+		_, err = builder.matches(stateHistoryRow{Labels: "{"})
+		require.Error(t, err)
+	})
+}
+
+func TestSampling(t *testing.T) {
+	rule := createTestRule()
+	now := time.Now()
+	reasonChange := func() state.StateTransition {
+		transition := createTransition(eval.Alerting, eval.Alerting, data.Labels{"a": "b"}, now)
+		transition.PreviousStateReason = ""
+		transition.State.StateReason = models.StateReasonError
+		return transition
+	}
+	stateChange := func() state.StateTransition {
+		return createTransition(eval.Normal, eval.Alerting, data.Labels{"a": "b"}, now)
+	}
+	record := func(sql *SqlBackend, ruleUID string, transitions []state.StateTransition) int {
+		r := *rule
+		r.UID = ruleUID
+		return len(sql.buildRows(&r, transitions, "", log.NewNopLogger()))
+	}
+
+	t.Run("transitions are not sampled by default", func(t *testing.T) {
+		sql := NewSqlBackend(SqlConfig{}, nil)
+		require.Equal(t, 6, record(sql, rule.UID, []state.StateTransition{reasonChange(), reasonChange(), reasonChange(), reasonChange(), reasonChange(), reasonChange()}))
+	})
+
+	t.Run("same-state transitions are thinned at the configured rate", func(t *testing.T) {
+		sql := NewSqlBackend(SqlConfig{Sampling: SamplingConfig{Every: 3}}, nil)
+		transitions := make([]state.StateTransition, 0, 9)
+		for i := 0; i < 9; i++ {
+			transitions = append(transitions, reasonChange())
+		}
+		require.Equal(t, 3, record(sql, rule.UID, transitions))
+		// Counting continues across calls.
+		require.Equal(t, 1, record(sql, rule.UID, transitions[:2]))
+		require.Equal(t, 0, record(sql, rule.UID, transitions[:1]))
+	})
+
+	t.Run("state changes are always recorded", func(t *testing.T) {
+		sql := NewSqlBackend(SqlConfig{Sampling: SamplingConfig{Every: 100}}, nil)
+		transitions := []state.StateTransition{stateChange(), reasonChange(), stateChange(), reasonChange(), stateChange()}
+		require.Equal(t, 4, record(sql, rule.UID, transitions))
+	})
+
+	t.Run("rates can be overridden per rule", func(t *testing.T) {
+		sql := NewSqlBackend(SqlConfig{Sampling: SamplingConfig{Every: 2, EveryByRule: map[string]int{"frequent": 4, "exact": 1}}}, nil)
+		transitions := make([]state.StateTransition, 0, 8)
+		for i := 0; i < 8; i++ {
+			transitions = append(transitions, reasonChange())
+		}
+		require.Equal(t, 4, record(sql, "other", transitions))
+		require.Equal(t, 2, record(sql, "frequent", transitions))
+		require.Equal(t, 8, record(sql, "exact", transitions))
+	})
+}
+
+func TestQueryCache(t *testing.T) {
+	t.Run("entries are evicted to respect the size cap", func(t *testing.T) {
+		mock := clock.NewMock()
+		cache := newQueryCache(mock, time.Minute, 100)
+
+		cache.set("a", data.NewFrame("a"), 60)
+		mock.Add(time.Second)
+		cache.set("b", data.NewFrame("b"), 30)
+		cache.set("c", data.NewFrame("c"), 30)
+
+		_, ok := cache.get("a")
+		require.False(t, ok)
+		_, ok = cache.get("b")
+		require.True(t, ok)
+		_, ok = cache.get("c")
+		require.True(t, ok)
+		require.Equal(t, 60, cache.size)
+	})
+
+	t.Run("entries larger than the cache are not cached", func(t *testing.T) {
+		cache := newQueryCache(clock.NewMock(), time.Minute, 100)
+		cache.set("a", data.NewFrame("a"), 101)
+		_, ok := cache.get("a")
+		require.False(t, ok)
+		require.Equal(t, 0, cache.size)
+	})
+
+	t.Run("keys do not depend on the order of filters", func(t *testing.T) {
+		cache := newQueryCache(clock.NewMock(), time.Minute, 100)
+		a, err := cache.key(models.HistoryQuery{OrgID: 1, Labels: map[string]string{"a": "1", "b": "2"}})
+		require.NoError(t, err)
+		b, err := cache.key(models.HistoryQuery{OrgID: 1, Labels: map[string]string{"b": "2", "a": "1"}})
+		require.NoError(t, err)
+		require.Equal(t, a, b)
+	})
+}
+
+func TestMergeTransitions(t *testing.T) {
+	row := func(labels string, at int64, prev, cur string) stateHistoryRow {
+		return stateHistoryRow{RuleUID: "my-rule", Labels: labels, EvaluatedAt: at, PreviousState: prev, CurrentState: cur}
+	}
+	const a, b = `{"a":"b"}`, `{"a":"c"}`
+
+	t.Run("well-separated transitions are kept", func(t *testing.T) {
+		rows := []stateHistoryRow{
+			row(a, 0, "Normal", "Alerting"),
+			row(a, 10_000, "Alerting", "Normal"),
+			row(a, 20_000, "Normal", "Alerting"),
+		}
+		require.Equal(t, rows, mergeTransitions(rows, 5*time.Second))
+	})
+
+	t.Run("closely spaced transitions are merged into their net change", func(t *testing.T) {
+		rows := []stateHistoryRow{
+			row(a, 0, "Normal", "Pending"),
+			row(a, 1_000, "Pending", "Alerting"),
+			row(a, 2_000, "Alerting", "Error"),
+			row(a, 3_000, "Error", "Alerting"),
+			row(a, 60_000, "Alerting", "Normal"),
+		}
+		require.Equal(t, []stateHistoryRow{
+			row(a, 3_000, "Normal", "Alerting"),
+			row(a, 60_000, "Alerting", "Normal"),
+		}, mergeTransitions(rows, 5*time.Second))
+	})
+
+	t.Run("runs without a net change are dropped", func(t *testing.T) {
+		rows := []stateHistoryRow{
+			row(a, 0, "Normal", "Alerting"),
+			row(a, 1_000, "Alerting", "Normal"),
+			row(a, 2_000, "Normal", "Alerting"),
+			row(a, 3_000, "Alerting", "Normal"),
+		}
+		require.Empty(t, mergeTransitions(rows, 5*time.Second))
+	})
+
+	t.Run("transitions exactly the tolerance apart are not merged", func(t *testing.T) {
+		rows := []stateHistoryRow{
+			row(a, 0, "Normal", "Alerting"),
+			row(a, 5_000, "Alerting", "Normal"),
+		}
+		require.Equal(t, rows, mergeTransitions(rows, 5*time.Second))
+	})
+
+	t.Run("instances are merged independently and keep their order", func(t *testing.T) {
+		rows := []stateHistoryRow{
+			row(a, 0, "Normal", "Pending"),
+			row(b, 500, "Normal", "Alerting"),
+			row(a, 1_000, "Pending", "Alerting"),
+			row(b, 30_000, "Alerting", "Normal"),
+		}
+		require.Equal(t, []stateHistoryRow{
+			row(b, 500, "Normal", "Alerting"),
+			row(a, 1_000, "Normal", "Alerting"),
+			row(b, 30_000, "Alerting", "Normal"),
+		}, mergeTransitions(rows, 5*time.Second))
+	})
+}
+
+func TestDownsample(t *testing.T) {
+	row := func(at int64, prev, cur string) stateHistoryRow {
+		return stateHistoryRow{EvaluatedAt: at, PreviousState: prev, CurrentState: cur}
+	}
+	times := func(rows []stateHistoryRow) []int64 {
+		result := make([]int64, 0, len(rows))
+		for _, r := range rows {
+			result = append(result, r.EvaluatedAt)
+		}
+		return result
+	}
+
+	t.Run("rows under the cap are returned as is", func(t *testing.T) {
+		rows := []stateHistoryRow{row(1, "Normal", "Alerting"), row(2, "Alerting", "Alerting")}
+		require.Equal(t, rows, downsample(rows, 2))
+	})
+
+	t.Run("stable runs are thinned to respect the cap", func(t *testing.T) {
+		rows := []stateHistoryRow{row(1, "Normal", "Alerting")}
+		for i := int64(2); i <= 9; i++ {
+			rows = append(rows, row(i, "Alerting", "Alerting"))
+		}
+		rows = append(rows, row(10, "Alerting", "Normal"))
+
+		result := downsample(rows, 6)
+		require.Equal(t, []int64{1, 2, 4, 6, 8, 10}, times(result))
+	})
+
+	t.Run("state changes are never dropped", func(t *testing.T) {
+		rows := []stateHistoryRow{
+			row(1, "Normal", "Alerting"),
+			row(2, "Alerting", "Alerting"),
+			row(3, "Alerting", "Normal"),
+			row(4, "Normal", "Normal"),
+			row(5, "Normal", "Pending"),
+			row(6, "Pending", "Alerting"),
+		}
+
+		result := downsample(rows, 2)
+		require.Equal(t, []int64{1, 3, 5, 6}, times(result))
+	})
+}
+
+func TestFormatLabels(t *testing.T) {
+	t.Run("labels are sorted regardless of insertion order", func(t *testing.T) {
+		a, err := json.Marshal(map[string]string{"b": "2", "a": "1", "c": "3"})
+		require.NoError(t, err)
+		require.Equal(t, `{a="1", b="2", c="3"}`, formatLabels(string(a)))
+		require.Equal(t, `{a="1", b="2", c="3"}`, formatLabels(`{"c":"3","b":"2","a":"1"}`))
+	})
+
+	t.Run("values are escaped", func(t *testing.T) {
+		require.Equal(t, `{msg="say \"hi\"\n", path="C:\\tmp"}`, formatLabels(`{"path":"C:\\tmp","msg":"say \"hi\"\n"}`))
+	})
+
+	t.Run("empty label sets are formatted as braces", func(t *testing.T) {
+		require.Equal(t, `{}`, formatLabels(`{}`))
+	})
+}
+
+func TestSerializeValues(t *testing.T) {
+	values := &state.State{State: eval.Alerting, Values: map[string]float64{"A": 1, "B": 2}}
+	full := `{"values":{"A":1,"B":2}}`
+
+	t.Run("values under the limit are stored fully", func(t *testing.T) {
+		s, truncated, err := serializeValues(values, len(full))
+		require.NoError(t, err)
+		require.False(t, truncated)
+		require.Equal(t, full, s)
+	})
+
+	t.Run("values over the limit drop entries", func(t *testing.T) {
+		s, truncated, err := serializeValues(values, len(full)-1)
+		require.NoError(t, err)
+		require.True(t, truncated)
+		require.Equal(t, `{"values":{"A":1}}`, s)
+	})
+
+	t.Run("values are dropped entirely if none fit", func(t *testing.T) {
+		s, truncated, err := serializeValues(values, 10)
+		require.NoError(t, err)
+		require.True(t, truncated)
+		require.Equal(t, `{}`, s)
+	})
+
+	t.Run("error messages over the limit are cut short", func(t *testing.T) {
+		errState := &state.State{State: eval.Error, Error: errors.New("something went wrong")}
+		s, truncated, err := serializeValues(errState, 21)
+		require.NoError(t, err)
+		require.True(t, truncated)
+		require.Equal(t, `{"error":"something"}`, s)
+	})
+}
+
+func createTestSqlBackendSut(t *testing.T) *SqlBackend {
+	t.Helper()
+	return NewSqlBackend(SqlConfig{}, db.InitTestDB(t))
+}
+
+func createTestRule() *models.AlertRule {
+	return models.AlertRuleGen(withOrgID(1), withUID("my-rule"))()
+}
+
+func createTransition(from, to eval.State, labels data.Labels, at time.Time) state.StateTransition {
+	return state.StateTransition{
+		PreviousState: from,
+		State: &state.State{
+			State:              to,
+			Labels:             labels,
+			Values:             map[string]float64{"A": 1},
+			LastEvaluationTime: at,
+		},
+	}
+}
+
+func recordSync(t *testing.T, sql *SqlBackend, rule *models.AlertRule, transitions []state.StateTransition, traceID string) {
+	t.Helper()
+	rows := sql.buildRows(rule, transitions, traceID, log.NewNopLogger())
+	require.NoError(t, sql.recordRows(context.Background(), rows))
+}
+
+func withNamespaceUID(uid string) func(rule *models.AlertRule) {
+	return func(rule *models.AlertRule) {
+		rule.NamespaceUID = uid
+	}
+}
+
+func withGroup(group string) func(rule *models.AlertRule) {
+	return func(rule *models.AlertRule) {
+		rule.RuleGroup = group
+	}
+}
@@ -0,0 +1,130 @@
+package correlations
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/correlations"
+)
+
+type configReader struct {
+	log log.Logger
+}
+
+// readConfig reads all provisioning files of a directory and returns the commands to provision their correlations.
+// A missing directory provisions nothing.
+func (cr *configReader) readConfig(path string) ([]correlations.CreateCorrelationCommand, error) {
+	cr.log.Debug("Looking for correlation provisioning files", "path", path)
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Can't read correlation provisioning files from directory", "path", path, "error", err)
+		return nil, nil
+	}
+
+	var cmds []correlations.CreateCorrelationCommand
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".yaml") && !strings.HasSuffix(file.Name(), ".yml") {
+			continue
+		}
+		cr.log.Debug("Parsing correlation provisioning file", "path", path, "file.Name", file.Name())
+		cfg, err := cr.parseConfig(path, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name(), err)
+		}
+
+		for i, correlation := range cfg.Correlations {
+			cmd, err := makeCreateCorrelationCommand(correlation)
+			if err != nil {
+				return nil, fmt.Errorf("%s: correlation %d: %w", file.Name(), i+1, err)
+			}
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	return cmds, nil
+}
+
+func (cr *configReader) parseConfig(path string, file fs.DirEntry) (*configs, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiVersion *configVersion
+	if err := yaml.Unmarshal(yamlFile, &apiVersion); err != nil {
+		return nil, err
+	}
+	if apiVersion == nil {
+		return &configs{}, nil
+	}
+	if apiVersion.APIVersion != 1 {
+		return nil, fmt.Errorf("unsupported apiVersion %d", apiVersion.APIVersion)
+	}
+
+	var v1 *configsV1
+	if err := yaml.Unmarshal(yamlFile, &v1); err != nil {
+		return nil, err
+	}
+	return v1.mapToCorrelationsFromConfig(), nil
+}
+
+// makeCreateCorrelationCommand checks a correlation read from a provisioning file and returns the command creating
+// it. Provisioned correlations must have a UID, so that provisioning them again updates them rather than adding
+// duplicates.
+func makeCreateCorrelationCommand(correlation *upsertCorrelationFromConfig) (correlations.CreateCorrelationCommand, error) {
+	if correlation.UID == "" {
+		return correlations.CreateCorrelationCommand{}, fmt.Errorf("required field uid is missing")
+	}
+	if correlation.SourceUID == "" {
+		return correlations.CreateCorrelationCommand{}, fmt.Errorf("required field sourceUID is missing")
+	}
+	if correlation.Config == nil {
+		return correlations.CreateCorrelationCommand{}, fmt.Errorf("required field config is missing")
+	}
+
+	orgID := correlation.OrgID
+	if orgID < 1 {
+		orgID = 1
+	}
+	cmd := correlations.CreateCorrelationCommand{
+		UID:               correlation.UID,
+		SourceUID:         correlation.SourceUID,
+		OrgId:             orgID,
+		Label:             correlation.Label,
+		Description:       correlation.Description,
+		SkipReadOnlyCheck: true,
+	}
+	if correlation.TargetUID != "" {
+		targetUID := correlation.TargetUID
+		cmd.TargetUID = &targetUID
+	}
+
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	body, err := json.Marshal(correlation.Config)
+	if err != nil {
+		return correlations.CreateCorrelationCommand{}, err
+	}
+	if err := json.Unmarshal(body, &cmd.Config); err != nil {
+		return correlations.CreateCorrelationCommand{}, err
+	}
+
+	if err := cmd.Validate(); err != nil {
+		return correlations.CreateCorrelationCommand{}, err
+	}
+	return cmd, nil
+}
@@ -0,0 +1,128 @@
+package correlations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/correlations"
+)
+
+const (
+	correctProperties  = "./testdata/test-configs/correct-properties"
+	missingFields      = "./testdata/test-configs/missing-fields"
+	brokenYaml         = "./testdata/test-configs/broken-yaml"
+	unsupportedVersion = "./testdata/test-configs/unsupported-version"
+	missingFolder      = "./testdata/test-configs/missing-folder"
+)
+
+func TestConfigReader(t *testing.T) {
+	reader := &configReader{log: log.New("test logger")}
+
+	t.Run("Can read correct properties", func(t *testing.T) {
+		cmds, err := reader.readConfig(correctProperties)
+		require.NoError(t, err)
+		require.Len(t, cmds, 2)
+
+		query := cmds[0]
+		require.Equal(t, "logs-to-traces", query.UID)
+		require.Equal(t, int64(2), query.OrgId)
+		require.Equal(t, "loki", query.SourceUID)
+		require.Equal(t, "tempo", *query.TargetUID)
+		require.Equal(t, "Logs to traces", query.Label)
+		require.Equal(t, "Opens the trace of a log line", query.Description)
+		require.Equal(t, correlations.ConfigTypeQuery, query.Config.Type)
+		require.Equal(t, correlations.CorrelationFields{"message"}, query.Config.Field)
+		require.Equal(t, map[string]interface{}{"query": "${traceId}"}, query.Config.Target)
+		require.Equal(t, correlations.Transformations{
+			{Type: correlations.TransformationRegex, Expression: `traceId=(\w+)`, MapValue: "traceId"},
+		}, query.Config.Transformations)
+
+		external := cmds[1]
+		require.Equal(t, "logs-to-tickets", external.UID)
+		require.Equal(t, int64(1), external.OrgId)
+		require.Nil(t, external.TargetUID)
+		require.Equal(t, correlations.ConfigTypeExternal, external.Config.Type)
+	})
+
+	t.Run("Correlations without a UID should return error", func(t *testing.T) {
+		_, err := reader.readConfig(missingFields)
+		require.EqualError(t, err, "correlations.yaml: correlation 1: required field uid is missing")
+	})
+
+	t.Run("Broken yaml should return error", func(t *testing.T) {
+		_, err := reader.readConfig(brokenYaml)
+		require.Error(t, err)
+	})
+
+	t.Run("Unsupported versions should return error", func(t *testing.T) {
+		_, err := reader.readConfig(unsupportedVersion)
+		require.EqualError(t, err, "correlations.yaml: unsupported apiVersion 2")
+	})
+
+	t.Run("Skip missing directory", func(t *testing.T) {
+		cmds, err := reader.readConfig(missingFolder)
+		require.NoError(t, err)
+		require.Empty(t, cmds)
+	})
+}
+
+func TestProvision(t *testing.T) {
+	t.Run("Provisions every correlation read", func(t *testing.T) {
+		store := &fakeStore{}
+		require.NoError(t, Provision(context.Background(), correctProperties, store))
+		require.Len(t, store.provisioned, 2)
+		require.Equal(t, "logs-to-traces", store.provisioned[0].UID)
+		require.Equal(t, "logs-to-tickets", store.provisioned[1].UID)
+	})
+
+	t.Run("Keeps the correlations read and deletes other provisioned ones", func(t *testing.T) {
+		store := &fakeStore{}
+		require.NoError(t, Provision(context.Background(), correctProperties, store))
+		require.Equal(t, [][]correlations.CorrelationKey{{
+			{UID: "logs-to-traces", SourceUID: "loki"},
+			{UID: "logs-to-tickets", SourceUID: "loki"},
+		}}, store.kept)
+	})
+
+	t.Run("Deletes every provisioned correlation if the files are removed", func(t *testing.T) {
+		store := &fakeStore{}
+		require.NoError(t, Provision(context.Background(), missingFolder, store))
+		require.Equal(t, [][]correlations.CorrelationKey{{}}, store.kept)
+	})
+
+	t.Run("Provisions nothing if a file is invalid", func(t *testing.T) {
+		store := &fakeStore{}
+		require.Error(t, Provision(context.Background(), missingFields, store))
+		require.Empty(t, store.provisioned)
+		require.Empty(t, store.kept)
+	})
+
+	t.Run("Returns errors of the store", func(t *testing.T) {
+		store := &fakeStore{err: correlations.ErrSourceDataSourceDoesNotExists}
+		err := Provision(context.Background(), correctProperties, store)
+		require.ErrorIs(t, err, correlations.ErrSourceDataSourceDoesNotExists)
+		require.ErrorContains(t, err, `"logs-to-traces"`)
+	})
+}
+
+type fakeStore struct {
+	provisioned []correlations.CreateCorrelationCommand
+	kept        [][]correlations.CorrelationKey
+	err         error
+}
+
+func (s *fakeStore) ProvisionCorrelation(_ context.Context, cmd correlations.CreateCorrelationCommand) (correlations.Correlation, error) {
+	if s.err != nil {
+		return correlations.Correlation{}, s.err
+	}
+	s.provisioned = append(s.provisioned, cmd)
+	return correlations.Correlation{UID: cmd.UID, SourceUID: cmd.SourceUID}, nil
+}
+
+func (s *fakeStore) DeleteStaleProvisionedCorrelations(_ context.Context, provisioned []correlations.CorrelationKey) ([]correlations.Correlation, error) {
+	s.kept = append(s.kept, provisioned)
+	return []correlations.Correlation{}, nil
+}
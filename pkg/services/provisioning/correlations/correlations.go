@@ -0,0 +1,47 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/correlations"
+)
+
+// Store is the part of the correlations service needed to provision correlations.
+type Store interface {
+	ProvisionCorrelation(ctx context.Context, cmd correlations.CreateCorrelationCommand) (correlations.Correlation, error)
+	DeleteStaleProvisionedCorrelations(ctx context.Context, provisioned []correlations.CorrelationKey) ([]correlations.Correlation, error)
+}
+
+// Provision scans a directory for provisioning config files and creates or updates the correlations in those files.
+// All files are read and checked before any correlation is written. Correlations provisioned before, but removed
+// from the files since, are deleted.
+func Provision(ctx context.Context, configDirectory string, store Store) error {
+	logger := log.New("provisioning.correlations")
+	cr := &configReader{log: logger}
+
+	cmds, err := cr.readConfig(configDirectory)
+	if err != nil {
+		return err
+	}
+
+	provisioned := make([]correlations.CorrelationKey, 0, len(cmds))
+	for _, cmd := range cmds {
+		logger.Info("Provisioning correlation from configuration", "uid", cmd.UID, "sourceUID", cmd.SourceUID, "orgId", cmd.OrgId)
+		if _, err := store.ProvisionCorrelation(ctx, cmd); err != nil {
+			return fmt.Errorf("failed to provision correlation %q: %w", cmd.UID, err)
+		}
+		provisioned = append(provisioned, correlations.CorrelationKey{UID: cmd.UID, SourceUID: cmd.SourceUID})
+	}
+
+	stale, err := store.DeleteStaleProvisionedCorrelations(ctx, provisioned)
+	if err != nil {
+		return fmt.Errorf("failed to delete correlations removed from the configuration: %w", err)
+	}
+	for _, correlation := range stale {
+		logger.Info("Deleted correlation removed from configuration", "uid", correlation.UID, "sourceUID", correlation.SourceUID)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package correlations
+
+import (
+	"github.com/grafana/grafana/pkg/services/provisioning/values"
+)
+
+// configVersion is used to figure out which API version a config uses.
+type configVersion struct {
+	APIVersion int64 `json:"apiVersion" yaml:"apiVersion"`
+}
+
+// configs is the normalized content of a correlations provisioning file. Every version of the file format is
+// mapped to it.
+type configs struct {
+	Correlations []*upsertCorrelationFromConfig
+}
+
+type upsertCorrelationFromConfig struct {
+	OrgID       int64
+	UID         string
+	SourceUID   string
+	TargetUID   string
+	Label       string
+	Description string
+	Config      map[string]interface{}
+}
+
+type configsV1 struct {
+	configVersion
+
+	Correlations []*upsertCorrelationFromConfigV1 `json:"correlations" yaml:"correlations"`
+}
+
+type upsertCorrelationFromConfigV1 struct {
+	OrgID       values.Int64Value  `json:"orgId" yaml:"orgId"`
+	UID         values.StringValue `json:"uid" yaml:"uid"`
+	SourceUID   values.StringValue `json:"sourceUID" yaml:"sourceUID"`
+	TargetUID   values.StringValue `json:"targetUID" yaml:"targetUID"`
+	Label       values.StringValue `json:"label" yaml:"label"`
+	Description values.StringValue `json:"description" yaml:"description"`
+	Config      values.JSONValue   `json:"config" yaml:"config"`
+}
+
+func (cfg *configsV1) mapToCorrelationsFromConfig() *configs {
+	r := &configs{}
+	if cfg == nil {
+		return r
+	}
+
+	for _, correlation := range cfg.Correlations {
+		r.Correlations = append(r.Correlations, &upsertCorrelationFromConfig{
+			OrgID:       correlation.OrgID.Value(),
+			UID:         correlation.UID.Value(),
+			SourceUID:   correlation.SourceUID.Value(),
+			TargetUID:   correlation.TargetUID.Value(),
+			Label:       correlation.Label.Value(),
+			Description: correlation.Description.Value(),
+			Config:      correlation.Config.Value(),
+		})
+	}
+
+	return r
+}
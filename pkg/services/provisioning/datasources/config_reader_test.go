@@ -284,6 +284,17 @@ func TestDatasourceAsConfig(t *testing.T) {
 			require.Equal(t, 1, len(correlationsStore.deletedBySourceUID))
 			require.Equal(t, 1, len(correlationsStore.deletedByTargetUID))
 		})
+
+		t.Run("Keeps the UID of provisioned correlations", func(t *testing.T) {
+			correlation := map[string]interface{}{"uid": "logs-to-traces", "targetUID": "graphite", "label": "a label", "description": ""}
+			cmd, err := makeCreateCorrelationCommand(correlation, "graphite", 1)
+			require.NoError(t, err)
+			require.Equal(t, "logs-to-traces", cmd.UID)
+
+			correlation["uid"] = "logs to traces"
+			_, err = makeCreateCorrelationCommand(correlation, "graphite", 1)
+			require.ErrorIs(t, err, correlations.ErrInvalidCorrelationUID)
+		})
 	})
 }
 
@@ -345,9 +356,9 @@ type mockCorrelationsStore struct {
 	items              []correlations.Correlation
 }
 
-func (m *mockCorrelationsStore) CreateCorrelation(c context.Context, cmd correlations.CreateCorrelationCommand) (correlations.Correlation, error) {
-	m.created = append(m.created, cmd)
-	return correlations.Correlation{}, nil
+func (m *mockCorrelationsStore) CreateCorrelations(c context.Context, cmds []correlations.CreateCorrelationCommand) ([]correlations.Correlation, error) {
+	m.created = append(m.created, cmds...)
+	return make([]correlations.Correlation, len(cmds)), nil
 }
 
 func (m *mockCorrelationsStore) DeleteCorrelationsBySourceUID(c context.Context, cmd correlations.DeleteCorrelationsBySourceUIDCommand) error {
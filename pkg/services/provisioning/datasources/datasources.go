@@ -23,7 +23,7 @@ type Store interface {
 type CorrelationsStore interface {
 	DeleteCorrelationsByTargetUID(ctx context.Context, cmd correlations.DeleteCorrelationsByTargetUIDCommand) error
 	DeleteCorrelationsBySourceUID(ctx context.Context, cmd correlations.DeleteCorrelationsBySourceUIDCommand) error
-	CreateCorrelation(ctx context.Context, cmd correlations.CreateCorrelationCommand) (correlations.Correlation, error)
+	CreateCorrelations(ctx context.Context, cmds []correlations.CreateCorrelationCommand) ([]correlations.Correlation, error)
 }
 
 var (
@@ -96,6 +96,7 @@ func (dc *DatasourceProvisioner) apply(ctx context.Context, cfg *configs) error
 			if len(ds.Correlations) > 0 {
 				if err := dc.correlationsStore.DeleteCorrelationsBySourceUID(ctx, correlations.DeleteCorrelationsBySourceUIDCommand{
 					SourceUID: cmd.Result.Uid,
+					OrgId:     cmd.Result.OrgId,
 				}); err != nil {
 					return err
 				}
@@ -112,9 +113,9 @@ func (dc *DatasourceProvisioner) apply(ctx context.Context, cfg *configs) error
 		}
 	}
 
-	for _, createCorrelationCmd := range correlationsToInsert {
-		if _, err := dc.correlationsStore.CreateCorrelation(ctx, createCorrelationCmd); err != nil {
-			return fmt.Errorf("err=%s source=%s", err.Error(), createCorrelationCmd.SourceUID)
+	if len(correlationsToInsert) > 0 {
+		if _, err := dc.correlationsStore.CreateCorrelations(ctx, correlationsToInsert); err != nil {
+			return fmt.Errorf("failed to create correlations: %w", err)
 		}
 	}
 
@@ -151,6 +152,13 @@ func makeCreateCorrelationCommand(correlation map[string]interface{}, SourceUID
 		createCommand.TargetUID = &targetUID
 	}
 
+	if uid, ok := correlation["uid"].(string); ok {
+		if err := correlations.ValidateCorrelationUID(uid); err != nil {
+			return correlations.CreateCorrelationCommand{}, err
+		}
+		createCommand.UID = uid
+	}
+
 	if correlation["config"] != nil {
 		jsonbody, err := json.Marshal(correlation["config"])
 		if err != nil {
@@ -163,14 +171,18 @@ func makeCreateCorrelationCommand(correlation map[string]interface{}, SourceUID
 		}
 
 		createCommand.Config = config
+		if err := createCommand.Validate(); err != nil {
+			return correlations.CreateCorrelationCommand{}, err
+		}
 	} else {
-		// when provisioning correlations without config we default to type="query"
+		// when provisioning correlations without config we default to type="query". There is no field to
+		// validate in that case, but a target is still required.
 		createCommand.Config = correlations.CorrelationConfig{
 			Type: correlations.ConfigTypeQuery,
 		}
-	}
-	if err := createCommand.Validate(); err != nil {
-		return correlations.CreateCorrelationCommand{}, err
+		if createCommand.TargetUID == nil {
+			return correlations.CreateCorrelationCommand{}, fmt.Errorf("correlations of type \"%s\" must have a targetUID", correlations.ConfigTypeQuery)
+		}
 	}
 
 	return createCommand, nil
@@ -191,12 +203,14 @@ func (dc *DatasourceProvisioner) deleteDatasources(ctx context.Context, dsToDele
 		if getDsQuery.Result != nil {
 			if err := dc.correlationsStore.DeleteCorrelationsBySourceUID(ctx, correlations.DeleteCorrelationsBySourceUIDCommand{
 				SourceUID: getDsQuery.Result.Uid,
+				OrgId:     getDsQuery.Result.OrgId,
 			}); err != nil {
 				return err
 			}
 
 			if err := dc.correlationsStore.DeleteCorrelationsByTargetUID(ctx, correlations.DeleteCorrelationsByTargetUIDCommand{
 				TargetUID: getDsQuery.Result.Uid,
+				OrgId:     getDsQuery.Result.OrgId,
 			}); err != nil {
 				return err
 			}
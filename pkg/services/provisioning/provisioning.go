@@ -23,6 +23,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/pluginsettings"
 	prov_alerting "github.com/grafana/grafana/pkg/services/provisioning/alerting"
+	prov_correlations "github.com/grafana/grafana/pkg/services/provisioning/correlations"
 	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
 	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
 	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
@@ -62,6 +63,7 @@ func ProvideService(
 		newDashboardProvisioner:      dashboards.New,
 		provisionNotifiers:           notifiers.Provision,
 		provisionDatasources:         datasources.Provision,
+		provisionCorrelations:        prov_correlations.Provision,
 		provisionPlugins:             plugins.Provision,
 		provisionAlerting:            prov_alerting.Provision,
 		dashboardProvisioningService: dashboardProvisioningService,
@@ -83,6 +85,7 @@ type ProvisioningService interface {
 	registry.BackgroundService
 	RunInitProvisioners(ctx context.Context) error
 	ProvisionDatasources(ctx context.Context) error
+	ProvisionCorrelations(ctx context.Context) error
 	ProvisionPlugins(ctx context.Context) error
 	ProvisionNotifications(ctx context.Context) error
 	ProvisionDashboards(ctx context.Context) error
@@ -99,6 +102,7 @@ func NewProvisioningServiceImpl() *ProvisioningServiceImpl {
 		newDashboardProvisioner: dashboards.New,
 		provisionNotifiers:      notifiers.Provision,
 		provisionDatasources:    datasources.Provision,
+		provisionCorrelations:   prov_correlations.Provision,
 		provisionPlugins:        plugins.Provision,
 	}
 }
@@ -133,6 +137,7 @@ type ProvisioningServiceImpl struct {
 	dashboardProvisioner         dashboards.DashboardProvisioner
 	provisionNotifiers           func(context.Context, string, notifiers.Manager, org.Service, encryption.Internal, *notifications.NotificationService) error
 	provisionDatasources         func(context.Context, string, datasources.Store, datasources.CorrelationsStore, org.Service) error
+	provisionCorrelations        func(context.Context, string, prov_correlations.Store) error
 	provisionPlugins             func(context.Context, string, plugifaces.Store, pluginsettings.Service, org.Service) error
 	provisionAlerting            func(context.Context, prov_alerting.ProvisionerConfig) error
 	mutex                        sync.Mutex
@@ -153,6 +158,12 @@ func (ps *ProvisioningServiceImpl) RunInitProvisioners(ctx context.Context) erro
 		return err
 	}
 
+	// Correlations refer to data sources, so they're provisioned once all data sources are.
+	err = ps.ProvisionCorrelations(ctx)
+	if err != nil {
+		return err
+	}
+
 	err = ps.ProvisionPlugins(ctx)
 	if err != nil {
 		return err
@@ -213,6 +224,16 @@ func (ps *ProvisioningServiceImpl) ProvisionDatasources(ctx context.Context) err
 	return nil
 }
 
+func (ps *ProvisioningServiceImpl) ProvisionCorrelations(ctx context.Context) error {
+	correlationsPath := filepath.Join(ps.Cfg.ProvisioningPath, "correlations")
+	if err := ps.provisionCorrelations(ctx, correlationsPath, ps.correlationsService); err != nil {
+		err = fmt.Errorf("%v: %w", "Correlation provisioning error", err)
+		ps.log.Error("Failed to provision correlations", "error", err)
+		return err
+	}
+	return nil
+}
+
 func (ps *ProvisioningServiceImpl) ProvisionPlugins(ctx context.Context) error {
 	appPath := filepath.Join(ps.Cfg.ProvisioningPath, "plugins")
 	if err := ps.provisionPlugins(ctx, appPath, ps.pluginStore, ps.pluginsSettings, ps.orgService); err != nil {
@@ -5,6 +5,7 @@ import "context"
 type Calls struct {
 	RunInitProvisioners                 []interface{}
 	ProvisionDatasources                []interface{}
+	ProvisionCorrelations               []interface{}
 	ProvisionPlugins                    []interface{}
 	ProvisionNotifications              []interface{}
 	ProvisionDashboards                 []interface{}
@@ -48,6 +49,11 @@ func (mock *ProvisioningServiceMock) ProvisionDatasources(ctx context.Context) e
 	return nil
 }
 
+func (mock *ProvisioningServiceMock) ProvisionCorrelations(ctx context.Context) error {
+	mock.Calls.ProvisionCorrelations = append(mock.Calls.ProvisionCorrelations, nil)
+	return nil
+}
+
 func (mock *ProvisioningServiceMock) ProvisionPlugins(ctx context.Context) error {
 	mock.Calls.ProvisionPlugins = append(mock.Calls.ProvisionPlugins, nil)
 	if mock.ProvisionPluginsFunc != nil {
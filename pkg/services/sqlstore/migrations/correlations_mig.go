@@ -1,6 +1,13 @@
 package migrations
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"xorm.io/xorm"
+
 	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
 )
 
@@ -29,4 +36,319 @@ func addCorrelationsMigrations(mg *Migrator) {
 	mg.AddMigration("add correlation config column", NewAddColumnMigration(correlationsV1, &Column{
 		Name: "config", Type: DB_Text, Nullable: true,
 	}))
+
+	correlationTemplatesV1 := Table{
+		Name: "correlation_template",
+		Columns: []*Column{
+			{Name: "uid", Type: DB_NVarchar, Length: 40, Nullable: false, IsPrimaryKey: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "target_uid", Type: DB_NVarchar, Length: 40, Nullable: true},
+			{Name: "label", Type: DB_Text, Nullable: false},
+			{Name: "description", Type: DB_Text, Nullable: false},
+			{Name: "config", Type: DB_Text, Nullable: true},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id"}},
+		},
+	}
+
+	mg.AddMigration("create correlation_template table v1", NewAddTableMigration(correlationTemplatesV1))
+	mg.AddMigration("add index correlation_template.org_id", NewAddIndexMigration(correlationTemplatesV1, correlationTemplatesV1.Indices[0]))
+
+	// Correlations materialized from a template reference it, so that they can be re-synced when it changes
+	mg.AddMigration("add correlation template_uid column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "template_uid", Type: DB_NVarchar, Length: 40, Nullable: true,
+	}))
+	mg.AddMigration("add index correlations.template_uid", NewAddIndexMigration(correlationsV1, &Index{
+		Cols: []string{"template_uid"},
+	}))
+
+	// Unix timestamp of the last use of a correlation, written in batches by the correlations service
+	mg.AddMigration("add correlation last_used column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "last_used", Type: DB_BigInt, Nullable: true,
+	}))
+
+	// Existing correlations stay enabled
+	mg.AddMigration("add correlation enabled column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "enabled", Type: DB_Bool, Nullable: false, Default: "1",
+	}))
+
+	// Free-form notes for editors, not shown when following a correlation
+	mg.AddMigration("add correlation notes column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "notes", Type: DB_Text, Nullable: true,
+	}))
+
+	// Provisioned correlations can only be changed through their provisioning files
+	mg.AddMigration("add correlation provisioned column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "provisioned", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("convert correlation config mappings to a list", &correlationMappingsMigration{batchSize: 1000})
+
+	// Audit trail of the changes to correlations. Entries are kept after the correlation is deleted.
+	correlationHistoryV1 := Table{
+		Name: "correlation_history",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "correlation_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "source_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "action", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+			{Name: "old_correlation", Type: DB_MediumText, Nullable: true},
+			{Name: "new_correlation", Type: DB_MediumText, Nullable: true},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "source_uid", "correlation_uid"}},
+		},
+	}
+
+	mg.AddMigration("create correlation_history table v1", NewAddTableMigration(correlationHistoryV1))
+	mg.AddMigration("add index correlation_history.org_id_source_uid_correlation_uid", NewAddIndexMigration(correlationHistoryV1, correlationHistoryV1.Indices[0]))
+
+	// Existing correlations start at their first version
+	mg.AddMigration("add correlation version column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "version", Type: DB_BigInt, Nullable: false, Default: "1",
+	}))
+
+	// Snapshots of the last versions of correlations, which they can be restored to
+	correlationVersionV1 := Table{
+		Name: "correlation_version",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "correlation_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "source_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "version", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+			{Name: "correlation", Type: DB_MediumText, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"source_uid", "correlation_uid", "version"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create correlation_version table v1", NewAddTableMigration(correlationVersionV1))
+	mg.AddMigration("add unique index correlation_version.source_uid_correlation_uid_version", NewAddIndexMigration(correlationVersionV1, correlationVersionV1.Indices[0]))
+
+	// Tags are kept in a table of their own to filter correlations by tag
+	correlationTagV1 := Table{
+		Name: "correlation_tag",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "correlation_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "source_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "term", Type: DB_NVarchar, Length: 50, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"source_uid", "correlation_uid"}},
+			{Cols: []string{"term"}},
+		},
+	}
+
+	mg.AddMigration("create correlation_tag table v1", NewAddTableMigration(correlationTagV1))
+	mg.AddMigration("add index correlation_tag.source_uid_correlation_uid", NewAddIndexMigration(correlationTagV1, correlationTagV1.Indices[0]))
+	mg.AddMigration("add index correlation_tag.term", NewAddIndexMigration(correlationTagV1, correlationTagV1.Indices[1]))
+
+	// Correlations whose target data source was deleted can be kept without a target
+	mg.AddMigration("add correlation orphaned column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "orphaned", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+
+	correlationOrgSettingsV1 := Table{
+		Name: "correlation_org_settings",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "on_target_deleted", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "updated", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create correlation_org_settings table v1", NewAddTableMigration(correlationOrgSettingsV1))
+	mg.AddMigration("add unique index correlation_org_settings.org_id", NewAddIndexMigration(correlationOrgSettingsV1, correlationOrgSettingsV1.Indices[0]))
+
+	// Correlations can be sorted by when they were created or last changed
+	mg.AddMigration("add correlation created column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "created", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+	mg.AddMigration("add correlation updated column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "updated", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+	// Existing correlations take the times of their first and latest versions
+	mg.AddMigration("set correlation created and updated from their versions", NewRawSQLMigration(`
+		UPDATE correlation SET
+			created = COALESCE((SELECT MIN(v.created) FROM correlation_version AS v WHERE v.correlation_uid = correlation.uid AND v.source_uid = correlation.source_uid), 0),
+			updated = COALESCE((SELECT MAX(v.created) FROM correlation_version AS v WHERE v.correlation_uid = correlation.uid AND v.source_uid = correlation.source_uid), 0)
+	`))
+
+	// How often the links of correlations are shown and followed
+	correlationStatsV1 := Table{
+		Name: "correlation_stats",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "correlation_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "source_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "rendered", Type: DB_BigInt, Nullable: false, Default: "0"},
+			{Name: "clicked", Type: DB_BigInt, Nullable: false, Default: "0"},
+			{Name: "last_rendered", Type: DB_BigInt, Nullable: false, Default: "0"},
+			{Name: "last_clicked", Type: DB_BigInt, Nullable: false, Default: "0"},
+		},
+		Indices: []*Index{
+			{Cols: []string{"source_uid", "correlation_uid"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create correlation_stats table v1", NewAddTableMigration(correlationStatsV1))
+	mg.AddMigration("add unique index correlation_stats.source_uid_correlation_uid", NewAddIndexMigration(correlationStatsV1, correlationStatsV1.Indices[0]))
+
+	// Correlations can apply to every data source of a type
+	mg.AddMigration("add correlation source_type column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "source_type", Type: DB_NVarchar, Length: 255, Nullable: false, Default: "''",
+	}))
+	mg.AddMigration("add index correlations.source_type", NewAddIndexMigration(correlationsV1, &Index{
+		Cols: []string{"source_type"},
+	}))
+
+	// Deleted correlations are kept until they're purged, so that they can be restored
+	mg.AddMigration("add correlation deleted_at column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "deleted_at", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+	mg.AddMigration("add index correlations.deleted_at", NewAddIndexMigration(correlationsV1, &Index{
+		Cols: []string{"deleted_at"},
+	}))
+
+	// Correlations record the user who created them
+	mg.AddMigration("add correlation created_by column", NewAddColumnMigration(correlationsV1, &Column{
+		Name: "created_by", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+	// Existing correlations take the user of their creation in their history, if it was recorded
+	mg.AddMigration("set correlation created_by from their history", NewRawSQLMigration(`
+		UPDATE correlation SET
+			created_by = COALESCE((SELECT MIN(h.user_id) FROM correlation_history AS h WHERE h.correlation_uid = correlation.uid AND h.source_uid = correlation.source_uid AND h.action = 'created'), 0)
+	`))
+}
+
+// correlationMappingsMigration rewrites the mappings of correlation and correlation template configs, which used to
+// be any JSON, as the list of source and target pairs they're read as now. Mappings given as an object of targets
+// keyed by source are converted and null mappings are removed. Mappings of any other shape can't be read, and would
+// make the whole config unreadable, so they're logged and removed. Rows are rewritten in batches, and only if their
+// mappings change, so running it again changes nothing.
+type correlationMappingsMigration struct {
+	MigrationBase
+	batchSize int
+}
+
+func (m *correlationMappingsMigration) SQL(dialect Dialect) string {
+	return "code migration"
+}
+
+func (m *correlationMappingsMigration) Exec(sess *xorm.Session, mg *Migrator) error {
+	if err := m.migrateTable(sess, mg, "correlation", "uid", "source_uid"); err != nil {
+		return err
+	}
+	return m.migrateTable(sess, mg, "correlation_template", "uid")
+}
+
+func (m *correlationMappingsMigration) migrateTable(sess *xorm.Session, mg *Migrator, table string, keys ...string) error {
+	// Rows are never added or removed here, so ordering by the primary key keeps the batches stable.
+	query := fmt.Sprintf("SELECT %s, config FROM %s WHERE config IS NOT NULL ORDER BY %s LIMIT ? OFFSET ?",
+		strings.Join(keys, ", "), table, strings.Join(keys, ", "))
+	update := fmt.Sprintf("UPDATE %s SET config = ? WHERE %s = ?", table, strings.Join(keys, " = ? AND "))
+
+	for offset := 0; ; offset += m.batchSize {
+		rows, err := sess.QueryString(query, m.batchSize, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			config, changed, err := convertCorrelationMappings(row["config"])
+			if err != nil {
+				mg.Logger.Warn("Leaving correlation mappings that can't be converted as they are", "table", table, "uid", row["uid"], "error", err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+
+			args := []interface{}{update, config}
+			for _, key := range keys {
+				args = append(args, row[key])
+			}
+			if _, err := sess.Exec(args...); err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < m.batchSize {
+			return nil
+		}
+	}
+}
+
+// convertCorrelationMappings returns the config with its mappings converted to a list of source and target pairs,
+// and whether that changed it. Configs that aren't JSON objects, and null mappings, are left as they are. Mappings
+// that can't be converted are left as well, with an error describing them, so that no config is lost.
+func convertCorrelationMappings(config string) (string, bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(config), &fields); err != nil || fields == nil {
+		return config, false, nil
+	}
+	raw, ok := fields["mappings"]
+	if !ok {
+		return config, false, nil
+	}
+
+	var list []storedCorrelationMapping
+	var bySource map[string]string
+	switch {
+	case string(raw) == "null":
+		return config, false, nil
+	case json.Unmarshal(raw, &list) == nil && isMappingsList(list):
+		return config, false, nil
+	case json.Unmarshal(raw, &bySource) == nil && bySource != nil:
+		sources := make([]string, 0, len(bySource))
+		for source := range bySource {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		mappings := make([]map[string]string, 0, len(sources))
+		for _, source := range sources {
+			mappings = append(mappings, map[string]string{"source": source, "target": bySource[source]})
+		}
+		converted, err := json.Marshal(mappings)
+		if err != nil {
+			return config, false, err
+		}
+		fields["mappings"] = converted
+	default:
+		return config, false, fmt.Errorf("mappings are neither a list of source and target pairs nor an object: %s", raw)
+	}
+
+	converted, err := json.Marshal(fields)
+	if err != nil {
+		return config, false, err
+	}
+	return string(converted), true, nil
+}
+
+// storedCorrelationMapping is a stored mapping, whose source or target may be missing.
+type storedCorrelationMapping struct {
+	Source *string `json:"source"`
+	Target *string `json:"target"`
+}
+
+func isMappingsList(list []storedCorrelationMapping) bool {
+	for _, m := range list {
+		if m.Source == nil || m.Target == nil {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"xorm.io/xorm"
+
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/services/sqlstore/sqlutil"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestConvertCorrelationMappings(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   string
+		expected string
+		changed  bool
+		err      bool
+	}{
+		{name: "no mappings", config: `{"field":"message"}`, expected: `{"field":"message"}`},
+		{name: "not an object", config: `["message"]`, expected: `["message"]`},
+		{name: "list of pairs", config: `{"mappings":[{"source":"traceID","target":"traceId"}]}`, expected: `{"mappings":[{"source":"traceID","target":"traceId"}]}`},
+		{name: "null mappings", config: `{"field":"message","mappings":null}`, expected: `{"field":"message","mappings":null}`},
+		{
+			name:     "object mappings",
+			config:   `{"field":"message","mappings":{"traceID":"traceId","service":"svc"}}`,
+			expected: `{"field":"message","mappings":[{"source":"service","target":"svc"},{"source":"traceID","target":"traceId"}]}`,
+			changed:  true,
+		},
+		{name: "object of other values", config: `{"field":"message","mappings":{"traceID":1}}`, expected: `{"field":"message","mappings":{"traceID":1}}`, err: true},
+		{name: "incomplete pairs", config: `{"field":"message","mappings":[{"source":"traceID"}]}`, expected: `{"field":"message","mappings":[{"source":"traceID"}]}`, err: true},
+		{name: "list of lists", config: `{"field":"message","mappings":[["traceID","traceId"]]}`, expected: `{"field":"message","mappings":[["traceID","traceId"]]}`, err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, changed, err := convertCorrelationMappings(tc.config)
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.changed, changed)
+			require.JSONEq(t, tc.expected, config)
+		})
+	}
+}
+
+func TestCorrelationMappingsMigration(t *testing.T) {
+	testDB := sqlutil.SQLite3TestDB()
+	x, err := xorm.NewEngine(testDB.DriverName, testDB.ConnStr)
+	require.NoError(t, err)
+	require.NoError(t, NewDialect(x).CleanDB())
+	// The in-memory test database is shared with the other tests of the package until it's closed.
+	t.Cleanup(func() {
+		require.NoError(t, x.Close())
+	})
+
+	mg := NewMigrator(x, &setting.Cfg{})
+	addMigrationLogMigrations(mg)
+	addCorrelationsMigrations(mg)
+	require.NoError(t, mg.Start(false, 0))
+
+	configs := map[string]string{
+		"object":  `{"field":"message","mappings":{"traceID":"traceId"}}`,
+		"null":    `{"field":"message","mappings":null}`,
+		"invalid": `{"field":"message","mappings":"traceID"}`,
+		"list":    `{"field":"message","mappings":[{"source":"traceID","target":"traceId"}]}`,
+		"none":    `{"field":"message"}`,
+	}
+	for uid, config := range configs {
+		_, err := x.Exec("INSERT INTO correlation (uid, source_uid, label, description, config) VALUES (?, 'source', '', '', ?)", uid, config)
+		require.NoError(t, err)
+	}
+	_, err = x.Exec("INSERT INTO correlation_template (uid, org_id, label, description, config) VALUES ('template', 1, '', '', ?)", configs["object"])
+	require.NoError(t, err)
+
+	expected := map[string]string{
+		"object":  `{"field":"message","mappings":[{"source":"traceID","target":"traceId"}]}`,
+		"null":    configs["null"],
+		"invalid": configs["invalid"],
+		"list":    configs["list"],
+		"none":    configs["none"],
+	}
+	// Running the migration again, e.g. after it was interrupted, changes nothing.
+	for i := 0; i < 2; i++ {
+		migration := &correlationMappingsMigration{batchSize: 2}
+		require.NoError(t, migration.Exec(x.NewSession(), mg))
+
+		rows, err := x.QueryString("SELECT uid, config FROM correlation")
+		require.NoError(t, err)
+		require.Len(t, rows, len(expected))
+		for _, row := range rows {
+			require.JSONEq(t, expected[row["uid"]], row["config"], row["uid"])
+		}
+
+		rows, err = x.QueryString("SELECT config FROM correlation_template")
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		require.JSONEq(t, expected["object"], rows[0]["config"])
+	}
+}
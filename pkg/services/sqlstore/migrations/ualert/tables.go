@@ -36,6 +36,9 @@ func AddTablesMigrations(mg *migrator.Migrator) {
 
 	AddAlertmanagerConfigHistoryMigrations(mg)
 	ExtractAlertmanagerConfigurationHistoryMigration(mg)
+
+	// Create state history table, used by the SQL state historian backend
+	AddStateHistoryMigrations(mg)
 }
 
 // AddAlertDefinitionMigrations should not be modified.
@@ -433,3 +436,47 @@ func AddAlertImageMigrations(mg *migrator.Migrator) {
 		Postgres("ALTER TABLE alert_image ALTER COLUMN url TYPE VARCHAR(2048);").
 		Mysql("ALTER TABLE alert_image MODIFY url VARCHAR(2048) NOT NULL;"))
 }
+
+func AddStateHistoryMigrations(mg *migrator.Migrator) {
+	stateHistory := migrator.Table{
+		Name: "alert_state_history",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "rule_uid", Type: migrator.DB_NVarchar, Length: UIDMaxLength, Nullable: false},
+			{Name: "labels", Type: migrator.DB_Text, Nullable: false},
+			{Name: "previous_state", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "current_state", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false},
+			{Name: "state_values", Type: migrator.DB_Text, Nullable: false},
+			{Name: "evaluated_at", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "trace_id", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: true},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "rule_uid", "evaluated_at"}, Type: migrator.IndexType},
+		},
+	}
+
+	mg.AddMigration("create alert_state_history table", migrator.NewAddTableMigration(stateHistory))
+	mg.AddMigration("add index in alert_state_history on org_id, rule_uid and evaluated_at columns", migrator.NewAddIndexMigration(stateHistory, stateHistory.Indices[0]))
+	mg.AddMigration("add column values_truncated in alert_state_history", migrator.NewAddColumnMigration(stateHistory, &migrator.Column{
+		Name: "values_truncated", Type: migrator.DB_Bool, Nullable: false, Default: "0",
+	}))
+	mg.AddMigration("add column namespace_uid in alert_state_history", migrator.NewAddColumnMigration(stateHistory, &migrator.Column{
+		Name: "namespace_uid", Type: migrator.DB_NVarchar, Length: UIDMaxLength, Nullable: false, Default: "''",
+	}))
+	mg.AddMigration("add column rule_group in alert_state_history", migrator.NewAddColumnMigration(stateHistory, &migrator.Column{
+		Name: "rule_group", Type: migrator.DB_NVarchar, Length: DefaultFieldMaxLength, Nullable: false, Default: "''",
+	}))
+	mg.AddMigration("add index in alert_state_history on org_id, namespace_uid and evaluated_at columns", migrator.NewAddIndexMigration(stateHistory, &migrator.Index{
+		Cols: []string{"org_id", "namespace_uid", "evaluated_at"}, Type: migrator.IndexType,
+	}))
+	mg.AddMigration("add column tags in alert_state_history", migrator.NewAddColumnMigration(stateHistory, &migrator.Column{
+		Name: "tags", Type: migrator.DB_Text, Nullable: true,
+	}))
+	mg.AddMigration("add column eval_duration_ms in alert_state_history", migrator.NewAddColumnMigration(stateHistory, &migrator.Column{
+		Name: "eval_duration_ms", Type: migrator.DB_BigInt, Nullable: true,
+	}))
+	mg.AddMigration("add column instance_count in alert_state_history", migrator.NewAddColumnMigration(stateHistory, &migrator.Column{
+		Name: "instance_count", Type: migrator.DB_BigInt, Nullable: true,
+	}))
+}
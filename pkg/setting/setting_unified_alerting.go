@@ -109,6 +109,15 @@ type UnifiedAlertingStateHistorySettings struct {
 	// if one of them is set.
 	LokiBasicAuthPassword string
 	LokiBasicAuthUsername string
+	// The SQL options only apply to the sql backend. See historian.SqlConfig for their meaning and defaults.
+	SQLMaxValuesSize       int
+	SQLCacheTTL            time.Duration
+	SQLCacheMaxBytes       int
+	SQLSamplingEvery       int
+	SQLSamplingEveryByRule map[string]int
+	SQLDeadlockRetries     int
+	SQLDeadlockBackoff     time.Duration
+	SQLFlushInterval       time.Duration
 }
 
 // IsEnabled returns true if UnifiedAlertingSettings.Enabled is either nil or true.
@@ -325,6 +334,25 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		LokiTenantID:          stateHistory.Key("loki_tenant_id").MustString(""),
 		LokiBasicAuthUsername: stateHistory.Key("loki_basic_auth_username").MustString(""),
 		LokiBasicAuthPassword: stateHistory.Key("loki_basic_auth_password").MustString(""),
+		SQLMaxValuesSize:      stateHistory.Key("sql_max_values_size").MustInt(0),
+		SQLCacheTTL:           stateHistory.Key("sql_cache_ttl").MustDuration(0),
+		SQLCacheMaxBytes:      stateHistory.Key("sql_cache_max_bytes").MustInt(0),
+		SQLSamplingEvery:      stateHistory.Key("sql_sampling_every").MustInt(0),
+		SQLDeadlockRetries:    stateHistory.Key("sql_deadlock_retries").MustInt(0),
+		SQLDeadlockBackoff:    stateHistory.Key("sql_deadlock_backoff").MustDuration(0),
+		SQLFlushInterval:      stateHistory.Key("sql_flush_interval").MustDuration(0),
+	}
+	samplingByRule := util.SplitString(stateHistory.Key("sql_sampling_every_by_rule").MustString(""))
+	if len(samplingByRule) > 0 {
+		uaCfgStateHistory.SQLSamplingEveryByRule = make(map[string]int, len(samplingByRule))
+	}
+	for _, entry := range samplingByRule {
+		ruleUID, every, found := strings.Cut(entry, ":")
+		n, err := strconv.Atoi(every)
+		if !found || ruleUID == "" || err != nil {
+			return fmt.Errorf("value of setting 'sql_sampling_every_by_rule' must be a list of rule_uid:every pairs, got %q", entry)
+		}
+		uaCfgStateHistory.SQLSamplingEveryByRule[ruleUID] = n
 	}
 	uaCfg.StateHistory = uaCfgStateHistory
 
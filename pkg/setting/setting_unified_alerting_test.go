@@ -266,3 +266,38 @@ func TestMinInterval(t *testing.T) {
 		})
 	}
 }
+
+func TestStateHistorySQLSamplingByRule(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    string
+		expected map[string]int
+		err      bool
+	}{
+		{desc: "empty", value: "", expected: nil},
+		{desc: "rule pairs", value: "rule-a:5, rule-b:20", expected: map[string]int{"rule-a": 5, "rule-b": 20}},
+		{desc: "missing rate", value: "rule-a", err: true},
+		{desc: "missing rule", value: ":5", err: true},
+		{desc: "invalid rate", value: "rule-a:often", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f := ini.Empty()
+			cfg := NewCfg()
+			cfg.IsFeatureToggleEnabled = func(key string) bool { return false }
+			stateHistorySec, err := f.NewSection("unified_alerting.state_history")
+			require.NoError(t, err)
+			_, err = stateHistorySec.NewKey("sql_sampling_every_by_rule", tc.value)
+			require.NoError(t, err)
+
+			err = cfg.ReadUnifiedAlertingSettings(f)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cfg.UnifiedAlerting.StateHistory.SQLSamplingEveryByRule)
+		})
+	}
+}
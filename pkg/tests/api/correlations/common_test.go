@@ -83,9 +83,10 @@ func (c TestContext) Post(params PostParams) *http.Response {
 }
 
 type PatchParams struct {
-	url  string
-	body string
-	user User
+	url     string
+	body    string
+	user    User
+	headers map[string]string
 }
 
 func (c TestContext) Patch(params PatchParams) *http.Response {
@@ -93,6 +94,9 @@ func (c TestContext) Patch(params PatchParams) *http.Response {
 
 	req, err := http.NewRequest(http.MethodPatch, c.getURL(params.url, params.user), bytes.NewBuffer([]byte(params.body)))
 	req.Header.Set("Content-Type", "application/json")
+	for k, v := range params.headers {
+		req.Header.Set(k, v)
+	}
 	require.NoError(c.t, err)
 	resp, err := http.DefaultClient.Do(req)
 	require.NoError(c.t, err)
@@ -101,6 +105,24 @@ func (c TestContext) Patch(params PatchParams) *http.Response {
 	return resp
 }
 
+type PutParams struct {
+	url  string
+	body string
+	user User
+}
+
+func (c TestContext) Put(params PutParams) *http.Response {
+	c.t.Helper()
+
+	req, err := http.NewRequest(http.MethodPut, c.getURL(params.url, params.user), bytes.NewBuffer([]byte(params.body)))
+	require.NoError(c.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+
+	return resp
+}
+
 type DeleteParams struct {
 	url  string
 	user User
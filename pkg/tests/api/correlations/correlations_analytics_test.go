@@ -0,0 +1,200 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationAnalytics(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	viewerUser := User{
+		username: "viewer",
+		password: "viewer",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleViewer),
+		Password:       viewerUser.password,
+		Login:          viewerUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	createCorrelation := func(label string) correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Label:     label,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"foo"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+	popular := createCorrelation("popular")
+	unused := createCorrelation("unused")
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+
+	report := func(user User, events ...string) *http.Response {
+		return ctx.Post(PostParams{
+			url:  "/api/datasources/correlations/analytics",
+			body: fmt.Sprintf(`{"events": [%s]}`, strings.Join(events, ",")),
+			user: user,
+		})
+	}
+	event := func(correlation correlations.Correlation, eventType correlations.AnalyticsEventType) string {
+		return fmt.Sprintf(`{"uid": "%s", "sourceUID": "%s", "type": "%s"}`, correlation.UID, correlation.SourceUID, eventType)
+	}
+	getStats := func(user User) *http.Response {
+		return ctx.Get(GetParams{
+			url:  "/api/datasources/correlations/stats",
+			user: user,
+		})
+	}
+	readStats := func() []correlations.CorrelationStats {
+		res := getStats(adminUser)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var stats []correlations.CorrelationStats
+		require.NoError(t, json.Unmarshal(responseBody, &stats))
+		return stats
+	}
+
+	t.Run("correlations without events are listed with zero counts", func(t *testing.T) {
+		stats := readStats()
+		require.Len(t, stats, 2)
+		for _, s := range stats {
+			require.Zero(t, s.Rendered)
+			require.Zero(t, s.Clicked)
+		}
+	})
+
+	t.Run("reported events are counted and the least used correlations listed first", func(t *testing.T) {
+		res := report(viewerUser,
+			event(popular, correlations.AnalyticsEventRendered),
+			event(popular, correlations.AnalyticsEventRendered),
+			event(popular, correlations.AnalyticsEventClicked),
+		)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.NoError(t, service.FlushCorrelationAnalytics(context.Background()))
+
+		res = report(viewerUser, event(popular, correlations.AnalyticsEventRendered))
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.NoError(t, service.FlushCorrelationAnalytics(context.Background()))
+
+		stats := readStats()
+		require.Len(t, stats, 2)
+		require.Equal(t, unused.UID, stats[0].UID)
+		require.Zero(t, stats[0].Rendered)
+		require.Equal(t, popular.UID, stats[1].UID)
+		require.Equal(t, "popular", stats[1].Label)
+		require.Equal(t, int64(3), stats[1].Rendered)
+		require.Equal(t, int64(1), stats[1].Clicked)
+		require.NotZero(t, stats[1].LastRendered)
+		require.NotZero(t, stats[1].LastClicked)
+	})
+
+	t.Run("clicks are recorded as uses", func(t *testing.T) {
+		require.NoError(t, service.FlushCorrelationUsage(context.Background()))
+
+		correlation, err := service.GetCorrelation(context.Background(), correlations.GetCorrelationQuery{
+			UID:       popular.UID,
+			SourceUID: dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+		})
+		require.NoError(t, err)
+		require.NotZero(t, correlation.LastUsed)
+	})
+
+	t.Run("events of other orgs are dropped", func(t *testing.T) {
+		service.ReportCorrelationAnalytics(correlations.ReportCorrelationAnalyticsCommand{
+			OrgId:  2,
+			Events: []correlations.CorrelationAnalyticsEvent{{UID: unused.UID, SourceUID: dataSource.Uid, Type: correlations.AnalyticsEventClicked}},
+		})
+		require.NoError(t, service.FlushCorrelationAnalytics(context.Background()))
+
+		require.Zero(t, readStats()[0].Clicked)
+	})
+
+	t.Run("invalid events are rejected", func(t *testing.T) {
+		res := report(viewerUser, fmt.Sprintf(`{"uid": "%s", "sourceUID": "%s", "type": "hovered"}`, popular.UID, popular.SourceUID))
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "bad request data", response.Message)
+	})
+
+	t.Run("stats can only be read by those who can delete correlations", func(t *testing.T) {
+		res := getStats(viewerUser)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("the stats of deleted correlations are hidden, and deleted when they're purged", func(t *testing.T) {
+		res := ctx.Delete(DeleteParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, popular.UID),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		stats := readStats()
+		require.Len(t, stats, 1)
+		require.Equal(t, unused.UID, stats[0].UID)
+
+		_, err := ctx.env.Server.HTTPServer.CorrelationsService.PurgeDeletedCorrelations(context.Background(), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		err = ctx.env.SQLStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+			count, err := sess.Table("correlation_stats").Where("correlation_uid = ?", popular.UID).Count()
+			require.Zero(t, count)
+			return err
+		})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,127 @@
+package correlations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+func TestIntegrationUpdateCorrelations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	createCorrelation := func(label string) correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Label:     label,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"foo"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+	first := createCorrelation("first")
+	second := createCorrelation("second")
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService
+
+	relabel := func(c correlations.Correlation, label string) correlations.UpdateCorrelationCommand {
+		return correlations.UpdateCorrelationCommand{
+			UID:       c.UID,
+			SourceUID: c.SourceUID,
+			Label:     &label,
+		}
+	}
+	labelOf := func(c correlations.Correlation) string {
+		correlation, err := service.(*correlations.CorrelationsService).GetCorrelation(context.Background(), correlations.GetCorrelationQuery{
+			UID:       c.UID,
+			SourceUID: c.SourceUID,
+			OrgId:     dataSource.OrgId,
+		})
+		require.NoError(t, err)
+		return correlation.Label
+	}
+
+	t.Run("applies all updates", func(t *testing.T) {
+		results, err := service.UpdateCorrelations(context.Background(), correlations.UpdateCorrelationsCommand{
+			OrgId:   dataSource.OrgId,
+			Updates: []correlations.UpdateCorrelationCommand{relabel(first, "first v2"), relabel(second, "second v2")},
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		for i, result := range results {
+			require.Equal(t, i, result.Index)
+			require.NoError(t, result.Err)
+		}
+		require.Equal(t, "first v2", results[0].Correlation.Label)
+		require.Equal(t, "first v2", labelOf(first))
+		require.Equal(t, "second v2", labelOf(second))
+	})
+
+	t.Run("reports invalid updates with their index", func(t *testing.T) {
+		_, err := service.UpdateCorrelations(context.Background(), correlations.UpdateCorrelationsCommand{
+			OrgId: dataSource.OrgId,
+			Updates: []correlations.UpdateCorrelationCommand{
+				relabel(first, "first v3"),
+				{UID: second.UID, SourceUID: second.SourceUID},
+			},
+		})
+		require.ErrorIs(t, err, correlations.ErrUpdateCorrelationEmptyParams)
+		var batchErr correlations.UpdateCorrelationsError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, 1, batchErr.Index)
+		require.Equal(t, "first v2", labelOf(first))
+	})
+
+	t.Run("rolls back all updates if one fails", func(t *testing.T) {
+		_, err := service.UpdateCorrelations(context.Background(), correlations.UpdateCorrelationsCommand{
+			OrgId: dataSource.OrgId,
+			Updates: []correlations.UpdateCorrelationCommand{
+				relabel(first, "first v3"),
+				relabel(correlations.Correlation{UID: "missing", SourceUID: dataSource.Uid}, "missing"),
+			},
+		})
+		require.ErrorIs(t, err, correlations.ErrCorrelationNotFound)
+		var batchErr correlations.UpdateCorrelationsError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, 1, batchErr.Index)
+		require.Equal(t, "first v2", labelOf(first))
+	})
+
+	t.Run("applies the updates that succeed in best-effort mode", func(t *testing.T) {
+		results, err := service.UpdateCorrelations(context.Background(), correlations.UpdateCorrelationsCommand{
+			OrgId:      dataSource.OrgId,
+			BestEffort: true,
+			Updates: []correlations.UpdateCorrelationCommand{
+				relabel(first, "first v3"),
+				relabel(correlations.Correlation{UID: "missing", SourceUID: dataSource.Uid}, "missing"),
+				{UID: second.UID, SourceUID: second.SourceUID},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[1].Err, correlations.ErrCorrelationNotFound)
+		require.Nil(t, results[1].Correlation)
+		require.ErrorIs(t, results[2].Err, correlations.ErrUpdateCorrelationEmptyParams)
+		require.Equal(t, "first v3", labelOf(first))
+		require.Equal(t, "second v2", labelOf(second))
+	})
+}
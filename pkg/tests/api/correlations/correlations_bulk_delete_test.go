@@ -0,0 +1,144 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationBulkDeleteCorrelations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	editorUser := User{
+		username: "editor",
+		password: "editor",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleEditor),
+		Password:       editorUser.password,
+		Login:          editorUser.username,
+	})
+
+	createDs := func(name string) *datasources.DataSource {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  "loki",
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result
+	}
+	loki := createDs("loki")
+	tempo := createDs("tempo")
+
+	create := func(source, target *datasources.DataSource) string {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: source.Uid,
+			TargetUID: &target.Uid,
+			OrgId:     1,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		}).UID
+	}
+
+	deleteCorrelations := func(body string, user User) *http.Response {
+		return ctx.Post(PostParams{
+			url:  "/api/datasources/correlations/delete",
+			body: body,
+			user: user,
+		})
+	}
+	count := func() int64 {
+		service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+		total, err := service.CountCorrelations(context.Background(), correlations.GetCorrelationsQuery{OrgId: 1})
+		require.NoError(t, err)
+		return total
+	}
+
+	t.Run("users without write access to all data sources can't delete in bulk", func(t *testing.T) {
+		uid := create(loki, tempo)
+		res := deleteCorrelations(fmt.Sprintf(`{"uids": ["%s"]}`, uid), editorUser)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.EqualValues(t, 1, count())
+
+		res = deleteCorrelations(fmt.Sprintf(`{"uids": ["%s"]}`, uid), adminUser)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("an empty list of UIDs is rejected", func(t *testing.T) {
+		res := deleteCorrelations(`{"uids": []}`, adminUser)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("correlations of several data sources are deleted and missing ones reported", func(t *testing.T) {
+		lokiToTempo := create(loki, tempo)
+		tempoToLoki := create(tempo, loki)
+		kept := create(loki, loki)
+
+		res := deleteCorrelations(fmt.Sprintf(`{"uids": ["%s", "%s", "missing", "%s"]}`, lokiToTempo, tempoToLoki, lokiToTempo), adminUser)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.DeleteCorrelationsResult
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, []string{lokiToTempo, tempoToLoki}, response.Deleted)
+		require.Equal(t, []string{"missing"}, response.NotFound)
+		require.EqualValues(t, 1, count())
+
+		res = deleteCorrelations(fmt.Sprintf(`{"uids": ["%s"]}`, kept), adminUser)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("nothing is deleted if one of the correlations is provisioned", func(t *testing.T) {
+		uid := create(loki, tempo)
+		service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+		_, err := service.ProvisionCorrelation(context.Background(), correlations.CreateCorrelationCommand{
+			UID:       "provisioned",
+			SourceUID: loki.Uid,
+			TargetUID: &tempo.Uid,
+			OrgId:     1,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		})
+		require.NoError(t, err)
+
+		res := deleteCorrelations(fmt.Sprintf(`{"uids": ["%s", "provisioned"]}`, uid), adminUser)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.EqualValues(t, 2, count())
+	})
+}
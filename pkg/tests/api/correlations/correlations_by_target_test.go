@@ -0,0 +1,100 @@
+package correlations
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationGetCorrelationsByTargetUID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	viewerUser := User{
+		username: "viewer",
+		password: "viewer",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleViewer),
+		Password:       viewerUser.password,
+		Login:          viewerUser.username,
+	})
+
+	createDs := func(name, typ string) *datasources.DataSource {
+		cmd := &datasources.AddDataSourceCommand{Name: name, Type: typ, OrgId: 1}
+		ctx.createDs(cmd)
+		return cmd.Result
+	}
+	loki := createDs("loki", "loki")
+	prometheus := createDs("prometheus", "prometheus")
+	tempo := createDs("tempo", "tempo")
+
+	createCorrelation := func(source, target *datasources.DataSource, label string, enabled bool) correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: source.Uid,
+			TargetUID: &target.Uid,
+			OrgId:     source.OrgId,
+			Label:     label,
+			Enabled:   &enabled,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"traceID"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+	fromLoki := createCorrelation(loki, tempo, "from loki", true)
+	fromPrometheus := createCorrelation(prometheus, tempo, "from prometheus", false)
+	createCorrelation(loki, prometheus, "to prometheus", true)
+
+	getByTarget := func(url string) []correlations.CorrelationListItem {
+		res := ctx.Get(GetParams{url: url, user: viewerUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response
+	}
+
+	t.Run("returns the correlations targeting the data source from every source", func(t *testing.T) {
+		response := getByTarget("/api/datasources/correlations/target/" + tempo.Uid)
+		require.Len(t, response, 2)
+		uids := []string{response[0].UID, response[1].UID}
+		require.ElementsMatch(t, []string{fromLoki.UID, fromPrometheus.UID}, uids)
+	})
+
+	t.Run("only returns enabled correlations if asked to", func(t *testing.T) {
+		response := getByTarget("/api/datasources/correlations/target/" + tempo.Uid + "?enabled=true")
+		require.Len(t, response, 1)
+		require.Equal(t, fromLoki.UID, response[0].UID)
+	})
+
+	t.Run("returns an empty list if no correlation targets the data source", func(t *testing.T) {
+		require.Empty(t, getByTarget("/api/datasources/correlations/target/"+loki.Uid))
+	})
+
+	t.Run("fails if the data source doesn't exist", func(t *testing.T) {
+		res := ctx.Get(GetParams{url: "/api/datasources/correlations/target/nonexistent", user: viewerUser})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Target data source not found", response.Message)
+	})
+}
@@ -0,0 +1,119 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationUpdateCorrelationVersionConflict(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+		SourceUID: dataSource.Uid,
+		TargetUID: &dataSource.Uid,
+		OrgId:     dataSource.OrgId,
+		Label:     "original",
+		Config: correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeQuery,
+			Field:  correlations.CorrelationFields{"traceID"},
+			Target: map[string]interface{}{"expr": "${traceID}"},
+		},
+	})
+	url := fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, correlation.UID)
+
+	readError := func(t *testing.T, res *http.Response) errorResponseBody {
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response
+	}
+
+	t.Run("returns the version of the correlation as its ETag", func(t *testing.T) {
+		res := ctx.Get(GetParams{url: url, user: adminUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, `"1"`, res.Header.Get("ETag"))
+	})
+
+	t.Run("updates the correlation if it wasn't changed since the given version", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{url: url, body: `{"label": "first", "version": 1}`, user: adminUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, `"2"`, res.Header.Get("ETag"))
+
+		res = ctx.Patch(PatchParams{url: url, body: `{"label": "second"}`, user: adminUser, headers: map[string]string{"If-Match": `"2"`}})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, `"3"`, res.Header.Get("ETag"))
+	})
+
+	t.Run("fails with a conflict if the correlation was changed since", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{url: url, body: `{"label": "stale", "version": 2}`, user: adminUser})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.Equal(t, "Correlation has been changed since the given version", readError(t, res).Message)
+
+		res = ctx.Patch(PatchParams{url: url, body: `{"label": "stale"}`, user: adminUser, headers: map[string]string{"If-Match": `"1"`}})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = ctx.Get(GetParams{url: url, user: adminUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		var current correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &current))
+		require.Equal(t, "second", current.Label)
+		require.Equal(t, int64(3), current.Version)
+	})
+
+	t.Run("rejects invalid versions", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{url: url, body: `{"label": "third"}`, user: adminUser, headers: map[string]string{"If-Match": `"abc"`}})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.Equal(t, "Invalid correlation version", readError(t, res).Message)
+
+		res = ctx.Patch(PatchParams{url: url, body: `{"label": "third", "version": 2}`, user: adminUser, headers: map[string]string{"If-Match": `"3"`}})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.Equal(t, "Version and If-Match header do not match", readError(t, res).Message)
+	})
+
+	t.Run("updates the correlation without a version", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{url: url, body: `{"label": "third"}`, user: adminUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
@@ -0,0 +1,110 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationConditions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	create := func(condition string) *http.Response {
+		return ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: fmt.Sprintf(`{
+					"targetUID": "%s",
+					"config": { "type": "query", "field": "traceID", "target": {}, "condition": %s }
+				}`, dataSource.Uid, condition),
+			user: adminUser,
+		})
+	}
+	preview := func(uid, fields string) correlations.CorrelationPreview {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s/preview", dataSource.Uid, uid),
+			body: fmt.Sprintf(`{"fields": %s}`, fields),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var preview correlations.CorrelationPreview
+		require.NoError(t, json.Unmarshal(responseBody, &preview))
+		return preview
+	}
+
+	var correlation correlations.Correlation
+	t.Run("correlations can have a condition", func(t *testing.T) {
+		res := create(`{"type": "regex", "value": "^[0-9a-f]{8,32}$"}`)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		correlation = response.Result
+		require.Equal(t, &correlations.CorrelationCondition{Type: correlations.ConditionRegex, Value: "^[0-9a-f]{8,32}$"}, correlation.Config.Condition)
+	})
+
+	t.Run("the condition is evaluated against the row when previewing", func(t *testing.T) {
+		require.True(t, preview(correlation.UID, `{"traceID": "0af7651916cd43dd"}`).Matched)
+		require.False(t, preview(correlation.UID, `{"traceID": "-"}`).Matched)
+		require.False(t, preview(correlation.UID, `{"message": "no trace"}`).Matched)
+	})
+
+	t.Run("an empty condition removes the condition", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, correlation.UID),
+			body: `{"config": {"condition": {}}}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.UpdateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Nil(t, response.Result.Config.Condition)
+		require.True(t, preview(correlation.UID, `{"message": "no trace"}`).Matched)
+	})
+
+	t.Run("invalid conditions are rejected", func(t *testing.T) {
+		res := create(`{"type": "contains", "value": "abc"}`)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
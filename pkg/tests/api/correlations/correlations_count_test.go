@@ -0,0 +1,86 @@
+package correlations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+func TestIntegrationCountCorrelationsByDataSource(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	createDs := func(name string) *datasources.DataSource {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  "loki",
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result
+	}
+
+	dsA := createDs("a")
+	dsB := createDs("b")
+	dsEmpty := createDs("empty")
+
+	createCorrelation := func(source, target *datasources.DataSource) {
+		ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: source.Uid,
+			TargetUID: &target.Uid,
+			OrgId:     1,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"foo"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+
+	createCorrelation(dsA, dsB)
+	createCorrelation(dsA, dsB)
+	createCorrelation(dsA, dsA)
+	createCorrelation(dsB, dsB)
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService
+
+	t.Run("counts correlations per source data source", func(t *testing.T) {
+		counts, err := service.CountCorrelationsByDataSource(context.Background(), correlations.CorrelationsCountByDataSourceQuery{OrgId: 1})
+		require.NoError(t, err)
+		require.Equal(t, []correlations.DataSourceCorrelationsCount{
+			{UID: dsA.Uid, Count: 3},
+			{UID: dsB.Uid, Count: 1},
+		}, counts)
+	})
+
+	t.Run("counts correlations per target data source", func(t *testing.T) {
+		counts, err := service.CountCorrelationsByDataSource(context.Background(), correlations.CorrelationsCountByDataSourceQuery{OrgId: 1, ByTarget: true})
+		require.NoError(t, err)
+		require.Equal(t, []correlations.DataSourceCorrelationsCount{
+			{UID: dsB.Uid, Count: 3},
+			{UID: dsA.Uid, Count: 1},
+		}, counts)
+	})
+
+	t.Run("includes data sources without correlations when requested", func(t *testing.T) {
+		counts, err := service.CountCorrelationsByDataSource(context.Background(), correlations.CorrelationsCountByDataSourceQuery{OrgId: 1, IncludeEmpty: true})
+		require.NoError(t, err)
+		require.Equal(t, []correlations.DataSourceCorrelationsCount{
+			{UID: dsA.Uid, Count: 3},
+			{UID: dsB.Uid, Count: 1},
+			{UID: dsEmpty.Uid, Count: 0},
+		}, counts)
+	})
+
+	t.Run("does not count data sources of other orgs", func(t *testing.T) {
+		counts, err := service.CountCorrelationsByDataSource(context.Background(), correlations.CorrelationsCountByDataSourceQuery{OrgId: 2, IncludeEmpty: true})
+		require.NoError(t, err)
+		require.Empty(t, counts)
+	})
+}
@@ -1,6 +1,7 @@
 package correlations
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -91,7 +92,7 @@ func TestIntegrationCreateCorrelation(t *testing.T) {
 		err = json.Unmarshal(responseBody, &response)
 		require.NoError(t, err)
 
-		require.Contains(t, response.Message, "Permissions needed: datasources:write")
+		require.Contains(t, response.Message, "Permissions needed: correlations:write")
 
 		require.NoError(t, res.Body.Close())
 	})
@@ -111,7 +112,7 @@ func TestIntegrationCreateCorrelation(t *testing.T) {
 		err = json.Unmarshal(responseBody, &response)
 		require.NoError(t, err)
 
-		require.Equal(t, "bad request data", response.Message)
+		require.Equal(t, "Invalid correlation config", response.Message)
 
 		require.NoError(t, res.Body.Close())
 	})
@@ -265,7 +266,7 @@ func TestIntegrationCreateCorrelation(t *testing.T) {
 		require.Equal(t, description, response.Result.Description)
 		require.Equal(t, label, response.Result.Label)
 		require.Equal(t, configType, response.Result.Config.Type)
-		require.Equal(t, fieldName, response.Result.Config.Field)
+		require.Equal(t, correlations.CorrelationFields{fieldName}, response.Result.Config.Field)
 		require.Equal(t, map[string]interface{}{"expr": "foo"}, response.Result.Config.Target)
 
 		require.NoError(t, res.Body.Close())
@@ -317,11 +318,15 @@ func TestIntegrationCreateCorrelation(t *testing.T) {
 		responseBody, err := io.ReadAll(res.Body)
 		require.NoError(t, err)
 
-		var response errorResponseBody
+		var response correlations.InvalidConfigResponseBody
 		err = json.Unmarshal(responseBody, &response)
 		require.NoError(t, err)
 
-		require.Contains(t, response.Message, "bad request data")
+		require.Equal(t, "Invalid correlation config", response.Message)
+		require.Equal(t, []correlations.ConfigFieldError{
+			{Field: "config.field", Message: "is required"},
+			{Field: "config.type", Message: "is required"},
+		}, response.Errors)
 
 		require.NoError(t, res.Body.Close())
 	})
@@ -347,14 +352,71 @@ func TestIntegrationCreateCorrelation(t *testing.T) {
 		responseBody, err := io.ReadAll(res.Body)
 		require.NoError(t, err)
 
-		var response errorResponseBody
+		var response correlations.InvalidConfigResponseBody
 		err = json.Unmarshal(responseBody, &response)
 		require.NoError(t, err)
 
-		require.Contains(t, response.Message, "bad request data")
-		require.Contains(t, response.Error, correlations.ErrInvalidConfigType.Error())
-		require.Contains(t, response.Error, configType)
+		require.Equal(t, "Invalid correlation config", response.Message)
+		require.Len(t, response.Errors, 1)
+		require.Equal(t, "config.type", response.Errors[0].Field)
+		require.Contains(t, response.Errors[0].Message, configType)
 
 		require.NoError(t, res.Body.Close())
 	})
+
+	t.Run("creating correlations in bulk checks all data sources before creating any", func(t *testing.T) {
+		service := ctx.env.Server.HTTPServer.CorrelationsService
+		config := correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeQuery,
+			Field:  correlations.CorrelationFields{"message"},
+			Target: map[string]interface{}{},
+		}
+		missing := "nonexistent-ds-uid"
+		cmd := func(sourceUID, targetUID, label string) correlations.CreateCorrelationCommand {
+			return correlations.CreateCorrelationCommand{
+				SourceUID: sourceUID,
+				TargetUID: &targetUID,
+				OrgId:     1,
+				Label:     label,
+				Config:    config,
+			}
+		}
+
+		_, err := service.CreateCorrelations(context.Background(), []correlations.CreateCorrelationCommand{
+			cmd(writableDs, readOnlyDS, "bulk"),
+			cmd(missing, writableDs, "bulk"),
+		})
+		require.ErrorIs(t, err, correlations.ErrSourceDataSourceDoesNotExists)
+		require.Contains(t, err.Error(), missing)
+
+		_, err = service.CreateCorrelations(context.Background(), []correlations.CreateCorrelationCommand{
+			cmd(writableDs, readOnlyDS, "bulk"),
+			cmd(writableDs, missing, "bulk"),
+		})
+		require.ErrorIs(t, err, correlations.ErrTargetDataSourceDoesNotExists)
+		require.Contains(t, err.Error(), missing)
+
+		_, err = service.CreateCorrelations(context.Background(), []correlations.CreateCorrelationCommand{
+			cmd(readOnlyDS, writableDs, "bulk"),
+		})
+		require.ErrorIs(t, err, correlations.ErrSourceDataSourceReadOnly)
+
+		stored, err := service.(*correlations.CorrelationsService).GetCorrelationsBySourceUID(context.Background(), correlations.GetCorrelationsBySourceUIDQuery{
+			SourceUID: writableDs,
+			OrgId:     1,
+		})
+		require.NoError(t, err)
+		for _, c := range stored {
+			require.NotEqual(t, "bulk", c.Label)
+		}
+
+		created, err := service.CreateCorrelations(context.Background(), []correlations.CreateCorrelationCommand{
+			cmd(writableDs, readOnlyDS, "bulk"),
+			cmd(writableDs, writableDs, "bulk"),
+		})
+		require.NoError(t, err)
+		require.Len(t, created, 2)
+		require.Equal(t, readOnlyDS, *created[0].TargetUID)
+		require.Equal(t, writableDs, *created[1].TargetUID)
+	})
 }
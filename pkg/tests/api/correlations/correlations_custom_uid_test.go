@@ -0,0 +1,165 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCreateCorrelationWithUID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	otherDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "prometheus",
+		Type:  "prometheus",
+		OrgId: 1,
+	}
+	ctx.createDs(otherDsCommand)
+	otherDataSource := otherDsCommand.Result
+
+	createFrom := func(source *datasources.DataSource, uid string) *http.Response {
+		return ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", source.Uid),
+			body: fmt.Sprintf(`{
+					"uid": "%s",
+					"targetUID": "%s",
+					"config": { "type": "query", "field": "traceID", "target": {} }
+				}`, uid, dataSource.Uid),
+			user: adminUser,
+		})
+	}
+	create := func(uid string) *http.Response {
+		return createFrom(dataSource, uid)
+	}
+	readError := func(res *http.Response) errorResponseBody {
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response
+	}
+
+	t.Run("correlations are created with the given UID", func(t *testing.T) {
+		res := create("logs-to-traces")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "logs-to-traces", response.Result.UID)
+	})
+
+	t.Run("creating a correlation with an existing UID fails with a conflict", func(t *testing.T) {
+		res := create("logs-to-traces")
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.Equal(t, "Correlation already exists", readError(res).Message)
+	})
+
+	t.Run("creating a correlation with the UID of a correlation of another data source fails with a conflict", func(t *testing.T) {
+		res := createFrom(otherDataSource, "logs-to-traces")
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.Equal(t, "Correlation already exists", readError(res).Message)
+	})
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+	command := func(source *datasources.DataSource, uid string) correlations.CreateCorrelationCommand {
+		return correlations.CreateCorrelationCommand{
+			UID:       uid,
+			SourceUID: source.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     1,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"traceID"},
+				Target: map[string]interface{}{},
+			},
+		}
+	}
+
+	t.Run("creating several correlations fails if a UID is taken by a correlation of another data source", func(t *testing.T) {
+		_, err := service.CreateCorrelations(context.Background(), []correlations.CreateCorrelationCommand{command(otherDataSource, "logs-to-traces")})
+		require.ErrorIs(t, err, correlations.ErrCorrelationUIDConflict)
+	})
+
+	t.Run("creating several correlations fails if they repeat a UID", func(t *testing.T) {
+		_, err := service.CreateCorrelations(context.Background(), []correlations.CreateCorrelationCommand{
+			command(dataSource, "repeated"),
+			command(otherDataSource, "repeated"),
+		})
+		require.ErrorIs(t, err, correlations.ErrCorrelationUIDConflict)
+
+		res := createFrom(dataSource, "repeated")
+		require.Equal(t, http.StatusOK, res.StatusCode, "nothing is created if a UID conflicts")
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("provisioning a correlation with the UID of a correlation of another data source fails", func(t *testing.T) {
+		_, err := service.ProvisionCorrelation(context.Background(), command(otherDataSource, "logs-to-traces"))
+		require.ErrorIs(t, err, correlations.ErrCorrelationUIDConflict)
+	})
+
+	t.Run("importing a correlation with the UID of a deleted correlation fails with a conflict", func(t *testing.T) {
+		res := createFrom(dataSource, "deleted")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		res = ctx.Delete(DeleteParams{url: fmt.Sprintf("/api/datasources/uid/%s/correlations/deleted", dataSource.Uid), user: adminUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		for _, source := range []string{"loki", "prometheus"} {
+			res = ctx.Post(PostParams{
+				url: "/api/datasources/correlations/import",
+				body: fmt.Sprintf(`{"bundle": {"version": %d, "correlations": [{
+						"uid": "deleted",
+						"source": {"name": "%s"},
+						"target": {"name": "loki"},
+						"config": { "type": "query", "field": "traceID", "target": {} }
+					}]}}`, correlations.CorrelationsExportVersion, source),
+				user: adminUser,
+			})
+			require.Equal(t, http.StatusConflict, res.StatusCode, source)
+			require.NoError(t, res.Body.Close())
+		}
+	})
+
+	t.Run("invalid UIDs are rejected", func(t *testing.T) {
+		res := create("logs to traces")
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
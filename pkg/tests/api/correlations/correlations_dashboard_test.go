@@ -0,0 +1,122 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationDashboardCorrelation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	res := ctx.Post(PostParams{
+		url:  "/api/dashboards/db",
+		body: `{"dashboard": {"uid": "service-overview", "title": "Service overview"}}`,
+		user: adminUser,
+	})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, res.Body.Close())
+
+	createBody := func(dashboardUID string) string {
+		return fmt.Sprintf(`{
+			"label": "Service overview",
+			"config": {
+				"type": "dashboard",
+				"field": "message",
+				"target": {"dashboardUID": "%s", "variables": {"service": "${service}"}},
+				"transformations": [{"type": "regex", "expression": "service=(\\w+)", "mapValue": "service"}]
+			}
+		}`, dashboardUID)
+	}
+
+	t.Run("creates a correlation opening a dashboard", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: createBody("service-overview"),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, correlations.ConfigTypeDashboard, response.Result.Config.Type)
+		require.Nil(t, response.Result.TargetUID)
+		require.Equal(t, "service-overview", response.Result.Config.Target[correlations.DashboardTargetUID])
+
+		res = ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s/preview", dataSource.Uid, response.Result.UID),
+			body: `{"fields": {"message": "level=error service=checkout"}}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var preview correlations.CorrelationPreview
+		require.NoError(t, json.Unmarshal(responseBody, &preview))
+		require.Equal(t, map[string]interface{}{"service": "checkout"}, preview.Target[correlations.DashboardTargetVariables])
+	})
+
+	t.Run("dashboard correlations can't have a target data source", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: fmt.Sprintf(`{
+				"targetUID": "%s",
+				"config": {"type": "dashboard", "field": "message", "target": {"dashboardUID": "service-overview"}}
+			}`, dataSource.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("the target dashboard must exist", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: createBody("missing"),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Target dashboard not found", response.Message)
+	})
+}
@@ -1,6 +1,7 @@
 package correlations
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -90,7 +91,7 @@ func TestIntegrationDeleteCorrelation(t *testing.T) {
 		err = json.Unmarshal(responseBody, &response)
 		require.NoError(t, err)
 
-		require.Contains(t, response.Message, "Permissions needed: datasources:write")
+		require.Contains(t, response.Message, "Permissions needed: correlations:delete")
 
 		require.NoError(t, res.Body.Close())
 	})
@@ -219,4 +220,32 @@ func TestIntegrationDeleteCorrelation(t *testing.T) {
 		require.NoError(t, res.Body.Close())
 		require.Equal(t, http.StatusNotFound, res.StatusCode)
 	})
+
+	t.Run("deleting with IgnoreNotFound should be idempotent", func(t *testing.T) {
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: writableDs,
+			TargetUID: &writableDs,
+			OrgId:     writableDsOrgId,
+		})
+		service := ctx.env.Server.HTTPServer.CorrelationsService
+		cmd := correlations.DeleteCorrelationCommand{
+			UID:            correlation.UID,
+			SourceUID:      correlation.SourceUID,
+			OrgId:          writableDsOrgId,
+			IgnoreNotFound: true,
+		}
+
+		deleted, err := service.DeleteCorrelation(context.Background(), cmd)
+		require.NoError(t, err)
+		require.True(t, deleted)
+
+		deleted, err = service.DeleteCorrelation(context.Background(), cmd)
+		require.NoError(t, err)
+		require.False(t, deleted)
+
+		cmd.IgnoreNotFound = false
+		deleted, err = service.DeleteCorrelation(context.Background(), cmd)
+		require.ErrorIs(t, err, correlations.ErrCorrelationNotFound)
+		require.False(t, deleted)
+	})
 }
@@ -0,0 +1,135 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationDuplicateCorrelation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDs := func(name string, readOnly bool) *datasources.DataSource {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:     name,
+			Type:     "loki",
+			OrgId:    1,
+			ReadOnly: readOnly,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result
+	}
+	lokiEU := createDs("loki-eu", false)
+	lokiUS := createDs("loki-us", false)
+	lokiReadOnly := createDs("loki-read-only", true)
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "tempo",
+		Type:  "tempo",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	tempo := createDsCommand.Result
+
+	original := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+		SourceUID:   lokiEU.Uid,
+		TargetUID:   &tempo.Uid,
+		OrgId:       1,
+		Label:       "Traces",
+		Description: "Logs to traces",
+		Tags:        []string{"tracing"},
+		Config: correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeQuery,
+			Field:  correlations.CorrelationFields{"traceId"},
+			Target: map[string]interface{}{"query": "${traceId}"},
+		},
+	})
+
+	duplicate := func(sourceUID string, body string) *http.Response {
+		return ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s/duplicate", sourceUID, original.UID),
+			body: body,
+			user: adminUser,
+		})
+	}
+	readCopy := func(res *http.Response) correlations.Correlation {
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Correlation duplicated", response.Message)
+		return response.Result
+	}
+
+	t.Run("copies a correlation of the same data source", func(t *testing.T) {
+		copied := readCopy(duplicate(lokiEU.Uid, `{}`))
+		require.NotEqual(t, original.UID, copied.UID)
+		require.Equal(t, lokiEU.Uid, copied.SourceUID)
+		require.Equal(t, "Traces (copy)", copied.Label)
+		require.Equal(t, original.Description, copied.Description)
+		require.Equal(t, original.TargetUID, copied.TargetUID)
+		require.Equal(t, original.Config, copied.Config)
+		require.Equal(t, []string{"tracing"}, copied.Tags)
+		require.Equal(t, int64(1), copied.Version)
+	})
+
+	t.Run("copies a correlation to another data source", func(t *testing.T) {
+		copied := readCopy(duplicate(lokiEU.Uid, fmt.Sprintf(`{"sourceUID": "%s"}`, lokiUS.Uid)))
+		require.Equal(t, lokiUS.Uid, copied.SourceUID)
+		require.Equal(t, "Traces (copy)", copied.Label)
+
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", lokiUS.Uid, copied.UID),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("copies can't be added to read-only data sources", func(t *testing.T) {
+		res := duplicate(lokiEU.Uid, fmt.Sprintf(`{"sourceUID": "%s"}`, lokiReadOnly.Uid))
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("copies need an existing data source and correlation", func(t *testing.T) {
+		res := duplicate(lokiEU.Uid, `{"sourceUID": "missing"}`)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = duplicate(lokiUS.Uid, `{}`)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Correlation not found", response.Message)
+	})
+}
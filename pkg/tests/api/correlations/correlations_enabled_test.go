@@ -0,0 +1,123 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationEnabled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	createCorrelation := func(enabled *bool) correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Enabled:   enabled,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"foo"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+
+	disabled := false
+	enabled := createCorrelation(nil)
+	maintenance := createCorrelation(&disabled)
+
+	listUIDs := func(url string) []string {
+		res := ctx.Get(GetParams{
+			url:  url,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		uids := make([]string, 0, len(response))
+		for _, c := range response {
+			uids = append(uids, c.UID)
+		}
+		return uids
+	}
+
+	t.Run("correlations are enabled by default", func(t *testing.T) {
+		require.True(t, enabled.Enabled)
+		require.False(t, maintenance.Enabled)
+	})
+
+	t.Run("disabled correlations are listed unless filtered out", func(t *testing.T) {
+		require.ElementsMatch(t, []string{enabled.UID, maintenance.UID}, listUIDs("/api/datasources/correlations"))
+		require.ElementsMatch(t, []string{enabled.UID}, listUIDs("/api/datasources/correlations?enabled=true"))
+		require.ElementsMatch(t, []string{enabled.UID}, listUIDs(fmt.Sprintf("/api/datasources/uid/%s/correlations?enabled=true", dataSource.Uid)))
+	})
+
+	t.Run("uses of disabled correlations are not recorded", func(t *testing.T) {
+		service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+		service.MarkCorrelationUsed(maintenance.UID, dataSource.Uid, dataSource.OrgId)
+		require.NoError(t, service.FlushCorrelationUsage(context.Background()))
+
+		correlation, err := service.GetCorrelation(context.Background(), correlations.GetCorrelationQuery{
+			UID:       maintenance.UID,
+			SourceUID: dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+		})
+		require.NoError(t, err)
+		require.Zero(t, correlation.LastUsed)
+	})
+
+	t.Run("correlations can be disabled and re-enabled", func(t *testing.T) {
+		update := func(uid string, enabled bool) {
+			res := ctx.Patch(PatchParams{
+				url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, uid),
+				body: fmt.Sprintf(`{"enabled": %t}`, enabled),
+				user: adminUser,
+			})
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			require.NoError(t, res.Body.Close())
+		}
+
+		update(maintenance.UID, true)
+		update(enabled.UID, false)
+		require.ElementsMatch(t, []string{maintenance.UID}, listUIDs("/api/datasources/correlations?enabled=true"))
+
+		update(enabled.UID, true)
+		require.ElementsMatch(t, []string{enabled.UID, maintenance.UID}, listUIDs("/api/datasources/correlations?enabled=true"))
+	})
+}
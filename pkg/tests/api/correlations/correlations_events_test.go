@@ -0,0 +1,143 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	loki := createDsCommand.Result
+
+	createDsCommand = &datasources.AddDataSourceCommand{
+		Name:  "tempo",
+		Type:  "tempo",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	tempo := createDsCommand.Result
+
+	// Events are published on the bus of the store making the changes.
+	var created []*events.CorrelationCreated
+	var updated []*events.CorrelationUpdated
+	var deleted []*events.CorrelationDeleted
+	bus := ctx.env.SQLStore.Bus()
+	bus.AddEventListener(func(_ context.Context, e *events.CorrelationCreated) error {
+		created = append(created, e)
+		return nil
+	})
+	bus.AddEventListener(func(_ context.Context, e *events.CorrelationUpdated) error {
+		updated = append(updated, e)
+		return nil
+	})
+	bus.AddEventListener(func(_ context.Context, e *events.CorrelationDeleted) error {
+		deleted = append(deleted, e)
+		return nil
+	})
+
+	correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+		SourceUID: loki.Uid,
+		TargetUID: &tempo.Uid,
+		OrgId:     1,
+		Label:     "Traces",
+		Config:    correlations.CorrelationConfig{Type: correlations.ConfigTypeQuery, Field: correlations.CorrelationFields{"traceId"}, Target: map[string]interface{}{}},
+	})
+
+	t.Run("creating a correlation publishes an event", func(t *testing.T) {
+		require.Len(t, created, 1)
+		require.Equal(t, correlation.UID, created[0].UID)
+		require.Equal(t, loki.Uid, created[0].SourceUID)
+		require.Equal(t, tempo.Uid, created[0].TargetUID)
+		require.Equal(t, int64(1), created[0].OrgID)
+	})
+
+	t.Run("updating a correlation publishes an event", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, correlation.UID),
+			body: `{"label": "Spans"}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.Len(t, updated, 1)
+		require.Equal(t, correlation.UID, updated[0].UID)
+		require.Equal(t, int64(1), updated[0].OrgID)
+	})
+
+	t.Run("failed changes publish no event", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, "missing"),
+			body: `{"label": "Spans"}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.Len(t, updated, 1)
+	})
+
+	t.Run("deleting a correlation publishes an event", func(t *testing.T) {
+		res := ctx.Delete(DeleteParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, correlation.UID),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.Len(t, deleted, 1)
+		require.Equal(t, correlation.UID, deleted[0].UID)
+		require.Equal(t, loki.Uid, deleted[0].SourceUID)
+		require.Equal(t, int64(1), deleted[0].OrgID)
+	})
+
+	t.Run("deleting the correlations of a data source publishes an event for each", func(t *testing.T) {
+		other := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: loki.Uid,
+			TargetUID: &tempo.Uid,
+			OrgId:     1,
+			Config:    correlations.CorrelationConfig{Type: correlations.ConfigTypeQuery, Field: correlations.CorrelationFields{"traceId"}, Target: map[string]interface{}{}},
+		})
+
+		err := ctx.env.Server.HTTPServer.CorrelationsService.DeleteCorrelationsByTargetUID(context.Background(), correlations.DeleteCorrelationsByTargetUIDCommand{
+			TargetUID: tempo.Uid,
+			OrgId:     1,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, deleted, 2)
+		require.Equal(t, other.UID, deleted[1].UID)
+		require.Equal(t, tempo.Uid, deleted[1].TargetUID)
+		require.Equal(t, int64(1), deleted[1].OrgID)
+	})
+}
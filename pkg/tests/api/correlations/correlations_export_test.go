@@ -0,0 +1,178 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationExportImportCorrelations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	editorUser := User{
+		username: "editor",
+		password: "editor",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleEditor),
+		Password:       editorUser.password,
+		Login:          editorUser.username,
+	})
+
+	createDs := func(name string, orgID int64) *datasources.DataSource {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  "loki",
+			OrgId: orgID,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result
+	}
+	logs := createDs("Logs (staging)", 1)
+	traces := createDs("Traces", 1)
+	prodLogs := createDs("Logs (production)", 2)
+	prodTraces := createDs("Traces", 2)
+
+	query := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+		SourceUID: logs.Uid,
+		TargetUID: &traces.Uid,
+		OrgId:     1,
+		Label:     "Logs to traces",
+		Config: correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeQuery,
+			Field:  correlations.CorrelationFields{"message"},
+			Target: map[string]interface{}{"expr": "${traceId}"},
+			Transformations: correlations.Transformations{
+				{Type: correlations.TransformationRegex, Expression: `traceId=(\w+)`, MapValue: "traceId"},
+			},
+		},
+	})
+	external := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+		SourceUID: traces.Uid,
+		OrgId:     1,
+		Label:     "Ticket",
+		Config: correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeExternal,
+			Field:  correlations.CorrelationFields{"message"},
+			Target: map[string]interface{}{"url": "https://tickets.example.com/${message}"},
+		},
+	})
+
+	var export correlations.CorrelationsExport
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+
+	importCorrelations := func(body string, user User) *http.Response {
+		return ctx.Post(PostParams{
+			url:  "/api/datasources/correlations/import",
+			body: body,
+			user: user,
+		})
+	}
+	importBody := func(options string) string {
+		bundle, err := json.Marshal(export)
+		require.NoError(t, err)
+		return fmt.Sprintf(`{"bundle": %s %s}`, bundle, options)
+	}
+
+	t.Run("exports correlations with data sources resolved to names", func(t *testing.T) {
+		res := ctx.Get(GetParams{
+			url:  "/api/datasources/correlations/export",
+			user: editorUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		require.NoError(t, json.Unmarshal(responseBody, &export))
+		require.Equal(t, correlations.CorrelationsExportVersion, export.Version)
+		require.Len(t, export.Correlations, 2)
+
+		require.Equal(t, query.UID, export.Correlations[0].UID)
+		require.Equal(t, correlations.ExportedDataSource{Name: "Logs (staging)", Type: "loki"}, export.Correlations[0].Source)
+		require.Equal(t, &correlations.ExportedDataSource{Name: "Traces", Type: "loki"}, export.Correlations[0].Target)
+		require.Equal(t, "Logs to traces", export.Correlations[0].Label)
+		require.Equal(t, query.Config.Transformations, export.Correlations[0].Config.Transformations)
+
+		require.Equal(t, external.UID, export.Correlations[1].UID)
+		require.Nil(t, export.Correlations[1].Target)
+	})
+
+	t.Run("users without write access to all data sources can't import", func(t *testing.T) {
+		res := importCorrelations(importBody(`, "uids": "generate"`), editorUser)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("importing fails if the UIDs are taken", func(t *testing.T) {
+		res := importCorrelations(importBody(""), adminUser)
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("importing fails if a data source can't be resolved", func(t *testing.T) {
+		res := importCorrelations(importBody(`, "uids": "generate", "dataSources": {"Traces": "missing"}`), adminUser)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		total, err := service.CountCorrelations(context.Background(), correlations.GetCorrelationsQuery{OrgId: 1})
+		require.NoError(t, err)
+		require.EqualValues(t, 2, total)
+	})
+
+	t.Run("imports correlations with generated UIDs", func(t *testing.T) {
+		res := importCorrelations(importBody(`, "uids": "generate"`), adminUser)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var result correlations.ImportCorrelationsResult
+		require.NoError(t, json.Unmarshal(responseBody, &result))
+		require.Len(t, result.Correlations, 2)
+		require.NotEqual(t, query.UID, result.Correlations[0].UID)
+		require.Equal(t, logs.Uid, result.Correlations[0].SourceUID)
+		require.Equal(t, traces.Uid, *result.Correlations[0].TargetUID)
+	})
+
+	t.Run("imports correlations into another org with remapped data sources", func(t *testing.T) {
+		result, err := service.ImportCorrelations(context.Background(), correlations.ImportCorrelationsCommand{
+			OrgId:       2,
+			Bundle:      export,
+			DataSources: map[string]string{"Logs (staging)": prodLogs.Uid},
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Correlations, 2)
+
+		imported, err := service.GetCorrelation(context.Background(), correlations.GetCorrelationQuery{UID: query.UID, SourceUID: prodLogs.Uid, OrgId: 2})
+		require.NoError(t, err)
+		require.Equal(t, prodTraces.Uid, *imported.TargetUID)
+		require.Equal(t, "Logs to traces", imported.Label)
+
+		imported, err = service.GetCorrelation(context.Background(), correlations.GetCorrelationQuery{UID: external.UID, SourceUID: prodTraces.Uid, OrgId: 2})
+		require.NoError(t, err)
+		require.Nil(t, imported.TargetUID)
+	})
+}
@@ -0,0 +1,146 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationExternalCorrelation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	create := func(body string) *http.Response {
+		return ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: body,
+			user: adminUser,
+		})
+	}
+	externalBody := func(url string) string {
+		return fmt.Sprintf(`{
+			"label": "Ticket",
+			"config": {
+				"type": "external",
+				"field": "message",
+				"target": { "url": "%s" },
+				"transformations": [{ "type": "regex", "expression": "(TICKET-\\d+)", "mapValue": "ticket" }]
+			}
+		}`, url)
+	}
+
+	var uid string
+
+	t.Run("external correlations are created without a target data source", func(t *testing.T) {
+		res := create(externalBody("https://tickets.example.com/browse/${ticket}"))
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Nil(t, response.Result.TargetUID)
+		require.Equal(t, correlations.ConfigTypeExternal, response.Result.Config.Type)
+		uid = response.Result.UID
+	})
+
+	t.Run("external correlations can be read", func(t *testing.T) {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, correlations.ConfigTypeExternal, response.Config.Type)
+		require.Equal(t, "https://tickets.example.com/browse/${ticket}", response.Config.Target[correlations.ExternalTargetURL])
+
+		res = ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var list []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &list))
+		require.Len(t, list, 1)
+	})
+
+	t.Run("invalid URL templates are rejected", func(t *testing.T) {
+		for _, url := range []string{
+			"https://tickets.example.com/browse/${issue}",
+			"ftp://tickets.example.com/${ticket}",
+			"/browse/${ticket}",
+		} {
+			res := create(externalBody(url))
+			require.Equal(t, http.StatusBadRequest, res.StatusCode, url)
+			require.NoError(t, res.Body.Close())
+		}
+	})
+
+	t.Run("external correlations can't have a target data source", func(t *testing.T) {
+		res := create(fmt.Sprintf(`{
+			"targetUID": "%s",
+			"config": {
+				"type": "external",
+				"field": "message",
+				"target": { "url": "https://tickets.example.com/${message}" }
+			}
+		}`, dataSource.Uid))
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("updates are validated against the resulting config", func(t *testing.T) {
+		update := func(body string) int {
+			res := ctx.Patch(PatchParams{
+				url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, uid),
+				body: body,
+				user: adminUser,
+			})
+			require.NoError(t, res.Body.Close())
+			return res.StatusCode
+		}
+
+		require.Equal(t, http.StatusBadRequest, update(`{"config": {"target": {"url": "https://tickets.example.com/${issue}"}}}`))
+		require.Equal(t, http.StatusBadRequest, update(`{"config": {"type": "query"}}`))
+		require.Equal(t, http.StatusOK, update(`{"config": {"target": {"url": "https://tickets.example.com/v2/${ticket}"}}}`))
+	})
+}
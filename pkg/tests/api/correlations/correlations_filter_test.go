@@ -0,0 +1,126 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationFilterCorrelations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDs := func(name string) *datasources.DataSource {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  "loki",
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result
+	}
+	loki := createDs("loki")
+	tempo := createDs("tempo")
+	prometheus := createDs("prometheus")
+
+	create := func(source, target *datasources.DataSource, label string) string {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: source.Uid,
+			TargetUID: &target.Uid,
+			OrgId:     1,
+			Label:     label,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		}).UID
+	}
+	lokiToTempo := create(loki, tempo, "Trace for this line")
+	lokiToPrometheus := create(loki, prometheus, "Error rate")
+	tempoToLoki := create(tempo, loki, "Logs for this span")
+	prometheusToTempo := create(prometheus, tempo, "Exemplar trace")
+	prometheusToLoki := create(prometheus, loki, "error_rate logs")
+
+	list := func(query string) ([]string, int64) {
+		res := ctx.Get(GetParams{
+			url:  "/api/datasources/correlations?page=1&" + query,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.GetCorrelationsResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		uids := make([]string, 0, len(response.Correlations))
+		for _, c := range response.Correlations {
+			uids = append(uids, c.UID)
+		}
+		return uids, response.TotalCount
+	}
+
+	t.Run("correlations can be filtered by source data sources", func(t *testing.T) {
+		uids, total := list(fmt.Sprintf("sourceUID=%s", loki.Uid))
+		require.ElementsMatch(t, []string{lokiToTempo, lokiToPrometheus}, uids)
+		require.EqualValues(t, 2, total)
+
+		uids, _ = list(fmt.Sprintf("sourceUID=%s&sourceUID=%s", tempo.Uid, prometheus.Uid))
+		require.ElementsMatch(t, []string{tempoToLoki, prometheusToTempo, prometheusToLoki}, uids)
+	})
+
+	t.Run("correlations can be filtered by target data sources", func(t *testing.T) {
+		uids, total := list(fmt.Sprintf("targetUID=%s", tempo.Uid))
+		require.ElementsMatch(t, []string{lokiToTempo, prometheusToTempo}, uids)
+		require.EqualValues(t, 2, total)
+	})
+
+	t.Run("correlations can be filtered by label", func(t *testing.T) {
+		uids, total := list("label=trace")
+		require.ElementsMatch(t, []string{lokiToTempo, prometheusToTempo}, uids)
+		require.EqualValues(t, 2, total)
+	})
+
+	t.Run("wildcards in the label filter are matched literally", func(t *testing.T) {
+		uids, total := list("label=error_rate")
+		require.ElementsMatch(t, []string{prometheusToLoki}, uids)
+		require.EqualValues(t, 1, total)
+
+		uids, total = list("label=" + url.QueryEscape("%rate"))
+		require.Empty(t, uids)
+		require.Zero(t, total)
+	})
+
+	t.Run("filters are combined", func(t *testing.T) {
+		uids, total := list(fmt.Sprintf("sourceUID=%s&targetUID=%s&label=trace", loki.Uid, tempo.Uid))
+		require.ElementsMatch(t, []string{lokiToTempo}, uids)
+		require.EqualValues(t, 1, total)
+
+		uids, total = list(fmt.Sprintf("sourceUID=%s&label=span", loki.Uid))
+		require.Empty(t, uids)
+		require.Zero(t, total)
+	})
+}
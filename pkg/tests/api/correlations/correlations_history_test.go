@@ -0,0 +1,185 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	viewerUser := User{
+		username: "viewer",
+		password: "viewer",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleViewer),
+		Password:       viewerUser.password,
+		Login:          viewerUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	getHistory := func(uid string) []correlations.CorrelationHistoryEntry {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s/history", dataSource.Uid, uid),
+			user: viewerUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var history []correlations.CorrelationHistoryEntry
+		require.NoError(t, json.Unmarshal(responseBody, &history))
+		return history
+	}
+
+	t.Run("records who created, updated and deleted a correlation", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: fmt.Sprintf(`{"targetUID": "%s", "label": "Logs", "config": {"type": "query", "field": "message", "target": {}}}`, dataSource.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		var created correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &created))
+		uid := created.Result.UID
+
+		res = ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, uid),
+			body: `{"label": "Logs to logs", "config": {"target": {"expr": "{job=\"app\"}"}}}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = ctx.Delete(DeleteParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		history := getHistory(uid)
+		require.Len(t, history, 3)
+
+		deleted, updated, createdEntry := history[0], history[1], history[2]
+		require.Equal(t, correlations.HistoryActionDeleted, deleted.Action)
+		require.Equal(t, "Logs to logs", deleted.Before.Label)
+		require.Nil(t, deleted.After)
+
+		require.Equal(t, correlations.HistoryActionUpdated, updated.Action)
+		require.Equal(t, "Logs", updated.Before.Label)
+		require.Equal(t, "Logs to logs", updated.After.Label)
+		require.Equal(t, []string{"label", "config.target"}, updated.Changes)
+
+		require.Equal(t, correlations.HistoryActionCreated, createdEntry.Action)
+		require.Nil(t, createdEntry.Before)
+		require.Equal(t, uid, createdEntry.After.UID)
+
+		for _, entry := range history {
+			require.NotZero(t, entry.UserID)
+			require.Equal(t, createdEntry.UserID, entry.UserID)
+			require.NotZero(t, entry.Created)
+		}
+		require.Equal(t, createdEntry.UserID, created.Result.CreatedBy)
+		require.Equal(t, createdEntry.UserID, updated.After.CreatedBy)
+	})
+
+	t.Run("changes made without a user are recorded with user ID 0", func(t *testing.T) {
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     1,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		})
+
+		history := getHistory(correlation.UID)
+		require.Len(t, history, 1)
+		require.Equal(t, correlations.HistoryActionCreated, history[0].Action)
+		require.Zero(t, history[0].UserID)
+		require.Zero(t, correlation.CreatedBy)
+	})
+
+	t.Run("correlations without changes have an empty history", func(t *testing.T) {
+		require.Empty(t, getHistory("missing"))
+	})
+
+	t.Run("correlations deleted along with their data sources are recorded as deleted", func(t *testing.T) {
+		createDs := func(name string) string {
+			cmd := &datasources.AddDataSourceCommand{Name: name, Type: "loki", OrgId: 1}
+			ctx.createDs(cmd)
+			return cmd.Result.Uid
+		}
+		source := createDs("deleted-source")
+		target := createDs("deleted-target")
+		create := func(sourceUID, targetUID string) correlations.Correlation {
+			return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+				SourceUID: sourceUID,
+				TargetUID: &targetUID,
+				OrgId:     1,
+				Config: correlations.CorrelationConfig{
+					Type:   correlations.ConfigTypeQuery,
+					Field:  correlations.CorrelationFields{"message"},
+					Target: map[string]interface{}{},
+				},
+			})
+		}
+		fromSource := create(source, dataSource.Uid)
+		toTarget := create(dataSource.Uid, target)
+
+		service := ctx.env.Server.HTTPServer.CorrelationsService
+		require.NoError(t, service.DeleteCorrelationsBySourceUID(context.Background(), correlations.DeleteCorrelationsBySourceUIDCommand{SourceUID: source, OrgId: 1}))
+		require.NoError(t, service.DeleteCorrelationsByTargetUID(context.Background(), correlations.DeleteCorrelationsByTargetUIDCommand{TargetUID: target, OrgId: 1}))
+
+		for _, correlation := range []correlations.Correlation{fromSource, toTarget} {
+			history, err := service.(*correlations.CorrelationsService).GetCorrelationHistory(context.Background(), correlations.GetCorrelationHistoryQuery{
+				UID:       correlation.UID,
+				SourceUID: correlation.SourceUID,
+				OrgId:     1,
+			})
+			require.NoError(t, err)
+			require.Len(t, history, 2)
+			require.Equal(t, correlations.HistoryActionDeleted, history[0].Action)
+			require.Equal(t, correlation.UID, history[0].Before.UID)
+			require.Nil(t, history[0].After)
+		}
+	})
+}
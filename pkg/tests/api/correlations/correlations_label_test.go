@@ -0,0 +1,104 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationLabelTokens(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "logs",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	source := createDsCommand.Result
+
+	createDsCommand = &datasources.AddDataSourceCommand{
+		Name:  "traces",
+		Type:  "tempo",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	target := createDsCommand.Result
+
+	correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+		SourceUID: source.Uid,
+		TargetUID: &target.Uid,
+		OrgId:     source.OrgId,
+		Label:     "${__sourceName} to ${__targetName}",
+		Config: correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeQuery,
+			Field:  correlations.CorrelationFields{"traceID"},
+			Target: map[string]interface{}{},
+		},
+	})
+
+	t.Run("labels are resolved when correlations are read", func(t *testing.T) {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", source.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var list []correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &list))
+		require.Len(t, list, 1)
+		require.Equal(t, "logs to traces", list[0].Label)
+
+		res = ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", source.Uid, correlation.UID),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var single correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &single))
+		require.Equal(t, "logs to traces", single.Label)
+	})
+
+	t.Run("unknown tokens are rejected", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", source.Uid),
+			body: fmt.Sprintf(`{
+					"targetUID": "%s",
+					"label": "${__targetColor}",
+					"config": {"type": "query", "field": "traceID", "target": {}}
+				}`, target.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
@@ -0,0 +1,105 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationsMultipleFields(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	create := func(field string) *http.Response {
+		return ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: fmt.Sprintf(`{
+					"targetUID": "%s",
+					"config": { "type": "query", "field": %s, "target": {} }
+				}`, dataSource.Uid, field),
+			user: adminUser,
+		})
+	}
+	read := func(res *http.Response) map[string]interface{} {
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response struct {
+			Result struct {
+				Config map[string]interface{} `json:"config"`
+			} `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response.Result.Config
+	}
+
+	t.Run("correlations can be attached to several fields", func(t *testing.T) {
+		config := read(create(`["traceID", "trace_id"]`))
+		require.Equal(t, []interface{}{"traceID", "trace_id"}, config["field"])
+	})
+
+	t.Run("correlations attached to a single field keep a string field", func(t *testing.T) {
+		config := read(create(`["traceID"]`))
+		require.Equal(t, "traceID", config["field"])
+
+		config = read(create(`"traceID"`))
+		require.Equal(t, "traceID", config["field"])
+	})
+
+	t.Run("fields can't be listed twice", func(t *testing.T) {
+		res := create(`["traceID", "traceID"]`)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("the fields of correlations can be listed", func(t *testing.T) {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var list []correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &list))
+		require.Len(t, list, 3)
+		fields := make([]correlations.CorrelationFields, 0, len(list))
+		for _, c := range list {
+			fields = append(fields, c.Config.Field)
+		}
+		require.Contains(t, fields, correlations.CorrelationFields{"traceID", "trace_id"})
+	})
+}
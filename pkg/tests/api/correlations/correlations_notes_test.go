@@ -0,0 +1,129 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationNotes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	getNotes := func(uid string) string {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response.Notes
+	}
+
+	updateNotes := func(uid string, notes string) *http.Response {
+		body, err := json.Marshal(map[string]string{"notes": notes})
+		require.NoError(t, err)
+		return ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, uid),
+			body: string(body),
+			user: adminUser,
+		})
+	}
+
+	createBody := func(notes string) string {
+		return fmt.Sprintf(`{
+			"targetUID": "%s",
+			"notes": "%s",
+			"config": {
+				"type": "query",
+				"field": "message",
+				"target": {}
+			}
+		}`, dataSource.Uid, notes)
+	}
+
+	var uid string
+
+	t.Run("notes are set on creation", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: createBody("see the tracing runbook"),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "see the tracing runbook", response.Result.Notes)
+
+		uid = response.Result.UID
+		require.Equal(t, "see the tracing runbook", getNotes(uid))
+	})
+
+	t.Run("notes can be updated and cleared", func(t *testing.T) {
+		res := updateNotes(uid, "target moved to the new cluster")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, "target moved to the new cluster", getNotes(uid))
+
+		res = updateNotes(uid, "")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, "", getNotes(uid))
+	})
+
+	t.Run("notes over the length cap are rejected", func(t *testing.T) {
+		tooLong := strings.Repeat("a", correlations.MaxNotesLength+1)
+
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: createBody(tooLong),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = updateNotes(uid, tooLong)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, "", getNotes(uid))
+	})
+}
@@ -0,0 +1,205 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationOrphanedCorrelations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDs := func(name string) *datasources.DataSource {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  name,
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result
+	}
+	loki := createDs("loki")
+	tempo := createDs("tempo")
+	jaeger := createDs("jaeger")
+	zipkin := createDs("zipkin")
+
+	getSettings := func() correlations.CorrelationSettings {
+		res := ctx.Get(GetParams{
+			url:  "/api/datasources/correlations/settings",
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var settings correlations.CorrelationSettings
+		require.NoError(t, json.Unmarshal(responseBody, &settings))
+		return settings
+	}
+	getCorrelation := func(uid string) correlations.Correlation {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var correlation correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &correlation))
+		return correlation
+	}
+	// Deleting a data source publishes an event the correlations service handles by deleting or orphaning the
+	// correlations targeting it. Events published by the store aren't delivered to the service in the test
+	// environment, so the data source is deleted and the handling of the event is done here.
+	deleteDs := func(uid string) {
+		res := ctx.Delete(DeleteParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s", uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		err := ctx.env.Server.HTTPServer.CorrelationsService.DeleteCorrelationsByTargetUID(context.Background(), correlations.DeleteCorrelationsByTargetUIDCommand{
+			TargetUID: uid,
+			OrgId:     1,
+			Orphan:    getSettings().OnTargetDeleted == correlations.TargetDeletionOrphan,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("correlations are deleted with their target data source by default", func(t *testing.T) {
+		require.Equal(t, correlations.TargetDeletionDelete, getSettings().OnTargetDeleted)
+
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: loki.Uid,
+			TargetUID: &zipkin.Uid,
+			OrgId:     1,
+			Config:    correlations.CorrelationConfig{Type: correlations.ConfigTypeQuery, Field: correlations.CorrelationFields{"traceId"}, Target: map[string]interface{}{}},
+		})
+		deleteDs(zipkin.Uid)
+
+		err := ctx.env.SQLStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+			found, err := sess.Where("uid = ?", correlation.UID).Exist(&correlations.Correlation{})
+			require.False(t, found)
+			return err
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		res := ctx.Put(PutParams{
+			url:  "/api/datasources/correlations/settings",
+			body: `{"onTargetDeleted": "keep"}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("orphaned correlations are kept and can be given a new target", func(t *testing.T) {
+		res := ctx.Put(PutParams{
+			url:  "/api/datasources/correlations/settings",
+			body: `{"onTargetDeleted": "orphan"}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, correlations.TargetDeletionOrphan, getSettings().OnTargetDeleted)
+
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: loki.Uid,
+			TargetUID: &tempo.Uid,
+			OrgId:     1,
+			Config:    correlations.CorrelationConfig{Type: correlations.ConfigTypeQuery, Field: correlations.CorrelationFields{"traceId"}, Target: map[string]interface{}{}},
+		})
+		require.False(t, correlation.Orphaned)
+		deleteDs(tempo.Uid)
+
+		orphaned := getCorrelation(correlation.UID)
+		require.True(t, orphaned.Orphaned)
+		require.Nil(t, orphaned.TargetUID)
+		require.Equal(t, correlation.Version+1, orphaned.Version)
+
+		res = ctx.Get(GetParams{
+			url:  "/api/datasources/correlations?orphaned=true",
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var list []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &list))
+		require.Len(t, list, 1)
+		require.Equal(t, correlation.UID, list[0].UID)
+
+		res = ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, correlation.UID),
+			body: fmt.Sprintf(`{"targetUID": "%s"}`, jaeger.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		repointed := getCorrelation(correlation.UID)
+		require.False(t, repointed.Orphaned)
+		require.Equal(t, jaeger.Uid, *repointed.TargetUID)
+
+		res = ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations?orphaned=true", loki.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var bySource []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &bySource))
+		require.Empty(t, bySource)
+	})
+
+	t.Run("the new target must exist", func(t *testing.T) {
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: loki.Uid,
+			TargetUID: &jaeger.Uid,
+			OrgId:     1,
+			Config:    correlations.CorrelationConfig{Type: correlations.ConfigTypeQuery, Field: correlations.CorrelationFields{"traceId"}, Target: map[string]interface{}{}},
+		})
+		res := ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, correlation.UID),
+			body: fmt.Sprintf(`{"targetUID": "%s"}`, tempo.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
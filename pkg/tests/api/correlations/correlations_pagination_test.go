@@ -0,0 +1,116 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationsPagination(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	for i := 0; i < 5; i++ {
+		ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+
+	getPage := func(url string) correlations.GetCorrelationsResponseBody {
+		res := ctx.Get(GetParams{
+			url:  url,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.GetCorrelationsResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response
+	}
+
+	t.Run("without paging parameters all correlations are listed as before", func(t *testing.T) {
+		res := ctx.Get(GetParams{
+			url:  "/api/datasources/correlations",
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Len(t, response, 5)
+	})
+
+	t.Run("all correlations fit in the default page", func(t *testing.T) {
+		page := getPage("/api/datasources/correlations?page=1")
+		require.Len(t, page.Correlations, 5)
+		require.EqualValues(t, 5, page.TotalCount)
+		require.EqualValues(t, 1, page.Page)
+		require.EqualValues(t, 100, page.Limit)
+	})
+
+	t.Run("pages don't overlap and report the total count", func(t *testing.T) {
+		seen := map[string]struct{}{}
+		for i, expected := range []int{2, 2, 1, 0} {
+			page := getPage(fmt.Sprintf("/api/datasources/correlations?limit=2&page=%d", i+1))
+			require.Len(t, page.Correlations, expected)
+			require.EqualValues(t, 5, page.TotalCount)
+			for _, c := range page.Correlations {
+				require.NotContains(t, seen, c.UID)
+				seen[c.UID] = struct{}{}
+			}
+		}
+		require.Len(t, seen, 5)
+	})
+
+	t.Run("invalid parameters fall back to their defaults", func(t *testing.T) {
+		page := getPage("/api/datasources/correlations?limit=-1&page=0")
+		require.Len(t, page.Correlations, 5)
+		require.EqualValues(t, 1, page.Page)
+		require.EqualValues(t, 100, page.Limit)
+
+		page = getPage("/api/datasources/correlations?limit=5000")
+		require.EqualValues(t, 1000, page.Limit)
+	})
+}
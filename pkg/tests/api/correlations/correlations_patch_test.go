@@ -0,0 +1,145 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationPatchCorrelations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	editorUser := User{
+		username: "editor",
+		password: "editor",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleEditor),
+		Password:       editorUser.password,
+		Login:          editorUser.username,
+	})
+
+	createDs := func(name, typ string, readOnly bool) *datasources.DataSource {
+		cmd := &datasources.AddDataSourceCommand{Name: name, Type: typ, OrgId: 1, ReadOnly: readOnly}
+		ctx.createDs(cmd)
+		return cmd.Result
+	}
+	loki := createDs("loki", "loki", false)
+	prometheus := createDs("prometheus", "prometheus", false)
+	readOnly := createDs("read-only", "loki", true)
+	oldTempo := createDs("old tempo", "tempo", false)
+	newTempo := createDs("new tempo", "tempo", false)
+
+	createCorrelation := func(source *datasources.DataSource, label string) correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID:         source.Uid,
+			TargetUID:         &oldTempo.Uid,
+			OrgId:             source.OrgId,
+			Label:             label,
+			Description:       "to old tempo",
+			SkipReadOnlyCheck: true,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"traceID"},
+				Target: map[string]interface{}{"query": "${traceID}"},
+			},
+		})
+	}
+	fromLoki := createCorrelation(loki, "from loki")
+	fromPrometheus := createCorrelation(prometheus, "from prometheus")
+	fromReadOnly := createCorrelation(readOnly, "from read only")
+
+	patch := func(user User, body string) *http.Response {
+		return ctx.Patch(PatchParams{url: "/api/datasources/correlations", body: body, user: user})
+	}
+	readError := func(res *http.Response) errorResponseBody {
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response
+	}
+	targetOf := func(c correlations.Correlation) string {
+		correlation, err := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService).GetCorrelation(context.Background(), correlations.GetCorrelationQuery{
+			UID:       c.UID,
+			SourceUID: c.SourceUID,
+			OrgId:     1,
+		})
+		require.NoError(t, err)
+		return *correlation.TargetUID
+	}
+
+	t.Run("requires the permission to write the correlations of all data sources", func(t *testing.T) {
+		res := patch(editorUser, fmt.Sprintf(`{"uids": ["%s"], "targetUID": "%s"}`, fromLoki.UID, newTempo.Uid))
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("requires at least one change", func(t *testing.T) {
+		res := patch(adminUser, fmt.Sprintf(`{"uids": ["%s"]}`, fromLoki.UID))
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.Equal(t, "At least one of label, description or targetUID is required", readError(res).Message)
+	})
+
+	t.Run("fails without changing anything if a correlation doesn't exist", func(t *testing.T) {
+		res := patch(adminUser, fmt.Sprintf(`{"uids": ["%s", "nonexistent"], "targetUID": "%s"}`, fromLoki.UID, newTempo.Uid))
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.Equal(t, "Correlation not found", readError(res).Message)
+		require.Equal(t, oldTempo.Uid, targetOf(fromLoki))
+	})
+
+	t.Run("fails without changing anything if a correlation can't be updated", func(t *testing.T) {
+		res := patch(adminUser, fmt.Sprintf(`{"uids": ["%s", "%s"], "targetUID": "%s"}`, fromLoki.UID, fromReadOnly.UID, newTempo.Uid))
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.Equal(t, "Data source is read only", readError(res).Message)
+		require.Equal(t, oldTempo.Uid, targetOf(fromLoki))
+	})
+
+	t.Run("updates every correlation", func(t *testing.T) {
+		res := patch(adminUser, fmt.Sprintf(`{"uids": ["%s", "%s"], "targetUID": "%s", "description": "to new tempo"}`, fromLoki.UID, fromPrometheus.UID, newTempo.Uid))
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.PatchCorrelationsResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Correlations updated", response.Message)
+		require.Len(t, response.Result, 2)
+		labels := make([]string, 0, len(response.Result))
+		for _, c := range response.Result {
+			require.Equal(t, newTempo.Uid, *c.TargetUID)
+			require.Equal(t, "to new tempo", c.Description)
+			labels = append(labels, c.Label)
+		}
+		require.ElementsMatch(t, []string{"from loki", "from prometheus"}, labels)
+
+		require.Equal(t, newTempo.Uid, targetOf(fromLoki))
+		require.Equal(t, newTempo.Uid, targetOf(fromPrometheus))
+		require.Equal(t, oldTempo.Uid, targetOf(fromReadOnly))
+	})
+}
@@ -0,0 +1,124 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationsPermissions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	viewerUser := User{
+		username: "viewer",
+		password: "viewer",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleViewer),
+		Password:       viewerUser.password,
+		Login:          viewerUser.username,
+	})
+
+	createDs := func(name, typ string) *datasources.DataSource {
+		cmd := &datasources.AddDataSourceCommand{Name: name, Type: typ, OrgId: 1}
+		ctx.createDs(cmd)
+		return cmd.Result
+	}
+	loki := createDs("loki", "loki")
+	prometheus := createDs("prometheus", "prometheus")
+	tempo := createDs("tempo", "tempo")
+
+	createCorrelation := func(source *datasources.DataSource) correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: source.Uid,
+			TargetUID: &tempo.Uid,
+			OrgId:     source.OrgId,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"traceID"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+	fromLoki := createCorrelation(loki)
+	fromPrometheus := createCorrelation(prometheus)
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+	// A reader of the correlations of the loki data source only, as a custom role would grant.
+	lokiReader := &user.SignedInUser{
+		OrgID: 1,
+		Permissions: map[int64]map[string][]string{
+			1: {correlations.ActionRead: {datasources.ScopeProvider.GetResourceScopeUID(loki.Uid)}},
+		},
+	}
+
+	t.Run("a reader scoped to one data source only lists the correlations originating from it", func(t *testing.T) {
+		query := correlations.GetCorrelationsQuery{OrgId: 1, SignedInUser: lokiReader}
+		result, err := service.GetCorrelations(context.Background(), query)
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		require.Equal(t, fromLoki.UID, result[0].UID)
+
+		count, err := service.CountCorrelations(context.Background(), query)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("a reader scoped to one data source only gets the correlations targeting a data source from it", func(t *testing.T) {
+		result, err := service.GetCorrelationsByTargetUID(context.Background(), correlations.GetCorrelationsByTargetUIDQuery{
+			TargetUID:    tempo.Uid,
+			OrgId:        1,
+			SignedInUser: lokiReader,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		require.Equal(t, fromLoki.UID, result[0].UID)
+	})
+
+	t.Run("a reader without any data source scope gets no correlation", func(t *testing.T) {
+		result, err := service.GetCorrelations(context.Background(), correlations.GetCorrelationsQuery{
+			OrgId: 1,
+			SignedInUser: &user.SignedInUser{
+				OrgID:       1,
+				Permissions: map[int64]map[string][]string{1: {}},
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, result)
+	})
+
+	t.Run("viewers read the correlations of all data sources", func(t *testing.T) {
+		res := ctx.Get(GetParams{url: "/api/datasources/correlations", user: viewerUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Len(t, response, 2)
+		require.ElementsMatch(t, []string{fromLoki.UID, fromPrometheus.UID}, []string{response[0].UID, response[1].UID})
+
+		res = ctx.Get(GetParams{url: "/api/datasources/correlations/target/" + tempo.Uid, user: viewerUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var byTarget []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &byTarget))
+		require.Len(t, byTarget, 2)
+	})
+}
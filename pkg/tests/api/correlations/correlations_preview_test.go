@@ -0,0 +1,84 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationPreviewCorrelation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	viewerUser := User{
+		username: "viewer",
+		password: "viewer",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleViewer),
+		Password:       viewerUser.password,
+		Login:          viewerUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+		SourceUID: dataSource.Uid,
+		TargetUID: &dataSource.Uid,
+		OrgId:     dataSource.OrgId,
+		Config: correlations.CorrelationConfig{
+			Type:  correlations.ConfigTypeQuery,
+			Field: correlations.CorrelationFields{"message"},
+			Target: map[string]interface{}{
+				"expr": `{source="${__sourceName}"} |= "${traceID}" ${unknown}`,
+				"from": "${__from}",
+			},
+			Transformations: correlations.Transformations{{Type: correlations.TransformationLogfmt}},
+		},
+	})
+
+	t.Run("resolves built-in and transformation variables", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s/preview", dataSource.Uid, correlation.UID),
+			body: `{"fields": {"message": "level=error traceID=abc123"}, "from": "2023-01-01T00:00:00Z", "to": "2023-01-01T01:00:00Z"}`,
+			user: viewerUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var preview correlations.CorrelationPreview
+		require.NoError(t, json.Unmarshal(responseBody, &preview))
+		require.Equal(t, `{source="loki"} |= "abc123" ${unknown}`, preview.Target["expr"])
+		require.Equal(t, "1672531200000", preview.Target["from"])
+		require.Equal(t, []string{"unknown"}, preview.Unresolved)
+	})
+
+	t.Run("fails for unknown correlations", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s/preview", dataSource.Uid, "missing"),
+			body: `{}`,
+			user: viewerUser,
+		})
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
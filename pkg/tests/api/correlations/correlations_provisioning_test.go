@@ -0,0 +1,201 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	provisioning "github.com/grafana/grafana/pkg/services/provisioning/correlations"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationProvisionedCorrelation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+	provision := func(label string) {
+		_, err := service.ProvisionCorrelation(context.Background(), correlations.CreateCorrelationCommand{
+			UID:       "provisioned",
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     1,
+			Label:     label,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		})
+		require.NoError(t, err)
+	}
+	url := fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid)
+	list := func() []correlations.CorrelationListItem {
+		res := ctx.Get(GetParams{url: url, user: adminUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response
+	}
+
+	t.Run("provisioning the same correlation again updates it", func(t *testing.T) {
+		provision("first")
+		provision("second")
+
+		found := list()
+		require.Len(t, found, 1)
+		require.Equal(t, "provisioned", found[0].UID)
+		require.Equal(t, "second", found[0].Label)
+		require.True(t, found[0].Provisioned)
+	})
+
+	t.Run("provisioned correlations can't be edited through the API", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{
+			url:  url + "/provisioned",
+			body: `{"label": "edited"}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = ctx.Delete(DeleteParams{
+			url:  url + "/provisioned",
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		found := list()
+		require.Len(t, found, 1)
+		require.Equal(t, "second", found[0].Label)
+	})
+
+	t.Run("correlations created through the API can still be edited", func(t *testing.T) {
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     1,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		})
+		require.False(t, correlation.Provisioned)
+
+		res := ctx.Delete(DeleteParams{
+			url:  fmt.Sprintf("%s/%s", url, correlation.UID),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
+
+func TestIntegrationProvisionedCorrelationRemovedFromFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+	dir := t.TempDir()
+	provision := func(uids ...string) {
+		config := "apiVersion: 1\n\ncorrelations:\n"
+		for _, uid := range uids {
+			config += fmt.Sprintf("  - uid: %s\n    sourceUID: %s\n    targetUID: %s\n    config:\n      type: query\n      field: message\n      target: {}\n", uid, dataSource.Uid, dataSource.Uid)
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "correlations.yaml"), []byte(config), 0600))
+		require.NoError(t, provisioning.Provision(context.Background(), dir, service))
+	}
+	url := fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid)
+	get := func(uid string) int {
+		res := ctx.Get(GetParams{url: url + "/" + uid, user: adminUser})
+		require.NoError(t, res.Body.Close())
+		return res.StatusCode
+	}
+
+	provision("kept", "removed")
+	require.Equal(t, http.StatusOK, get("kept"))
+	require.Equal(t, http.StatusOK, get("removed"))
+
+	provision("kept")
+	require.Equal(t, http.StatusOK, get("kept"))
+	require.Equal(t, http.StatusNotFound, get("removed"))
+
+	t.Run("the removal is recorded in the history of the correlation", func(t *testing.T) {
+		history, err := service.GetCorrelationHistory(context.Background(), correlations.GetCorrelationHistoryQuery{
+			UID:       "removed",
+			SourceUID: dataSource.Uid,
+			OrgId:     1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, correlations.HistoryActionDeleted, history[0].Action)
+	})
+
+	t.Run("a correlation removed from the files can be restored and edited", func(t *testing.T) {
+		restored, err := service.UndeleteCorrelation(context.Background(), correlations.UndeleteCorrelationCommand{
+			UID:       "removed",
+			SourceUID: dataSource.Uid,
+			OrgId:     1,
+		})
+		require.NoError(t, err)
+		require.False(t, restored.Provisioned)
+
+		res := ctx.Delete(DeleteParams{url: url + "/removed", user: adminUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
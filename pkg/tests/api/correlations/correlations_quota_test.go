@@ -0,0 +1,216 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/tests/testinfra"
+)
+
+func TestIntegrationCorrelationsQuota(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	correlationsQuota := int64(1)
+	dir, path := testinfra.CreateGrafDir(t, testinfra.GrafanaOpts{
+		DisableAnonymous:     true,
+		EnableQuota:          true,
+		CorrelationsOrgQuota: &correlationsQuota,
+	})
+	_, env := testinfra.StartGrafanaEnv(t, dir, path)
+	ctx := TestContext{
+		env: *env,
+		t:   t,
+	}
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	create := func() *http.Response {
+		return ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: fmt.Sprintf(`{"targetUID": "%s", "config": {"type": "query", "field": "message", "target": {}}}`, dataSource.Uid),
+			user: adminUser,
+		})
+	}
+
+	res := create()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, res.Body.Close())
+
+	t.Run("correlations beyond the org quota are rejected", func(t *testing.T) {
+		res := create()
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Quota reached", response.Message)
+	})
+}
+
+func TestIntegrationCorrelationsQuotaTemplates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	correlationsQuota := int64(2)
+	dir, path := testinfra.CreateGrafDir(t, testinfra.GrafanaOpts{
+		DisableAnonymous:     true,
+		EnableQuota:          true,
+		CorrelationsOrgQuota: &correlationsQuota,
+	})
+	_, env := testinfra.StartGrafanaEnv(t, dir, path)
+	ctx := TestContext{
+		env: *env,
+		t:   t,
+	}
+
+	createDs := func(name string) string {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  "loki",
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result.Uid
+	}
+	lokiA := createDs("loki-a")
+	lokiB := createDs("loki-b")
+	lokiC := createDs("loki-c")
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService
+	template, err := service.CreateCorrelationTemplate(context.Background(), correlations.CreateCorrelationTemplateCommand{
+		OrgId:     1,
+		TargetUID: &lokiA,
+		Label:     "Logs to logs",
+		Config: correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeQuery,
+			Field:  correlations.CorrelationFields{"traceID"},
+			Target: map[string]interface{}{},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("applying a template to more sources than the org quota allows is rejected", func(t *testing.T) {
+		_, err := service.ApplyTemplate(context.Background(), correlations.ApplyTemplateCommand{
+			TemplateID: template.UID,
+			SourceUIDs: []string{lokiA, lokiB, lokiC},
+			OrgId:      1,
+		})
+		require.ErrorIs(t, err, correlations.ErrQuotaReached)
+
+		count, err := service.(*correlations.CorrelationsService).CountCorrelations(context.Background(), correlations.GetCorrelationsQuery{OrgId: 1})
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+
+	t.Run("applying a template within the org quota materializes its correlations", func(t *testing.T) {
+		result, err := service.ApplyTemplate(context.Background(), correlations.ApplyTemplateCommand{
+			TemplateID: template.UID,
+			SourceUIDs: []string{lokiA, lokiB},
+			OrgId:      1,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+	})
+
+	t.Run("applying a template again once the org quota is reached only updates its correlations", func(t *testing.T) {
+		result, err := service.ApplyTemplate(context.Background(), correlations.ApplyTemplateCommand{
+			TemplateID: template.UID,
+			SourceUIDs: []string{lokiA, lokiB},
+			OrgId:      1,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+
+		_, err = service.ApplyTemplate(context.Background(), correlations.ApplyTemplateCommand{
+			TemplateID: template.UID,
+			SourceUIDs: []string{lokiC},
+			OrgId:      1,
+		})
+		require.ErrorIs(t, err, correlations.ErrQuotaReached)
+	})
+}
+
+func TestIntegrationCorrelationsQuotaBulk(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	correlationsQuota := int64(2)
+	dir, path := testinfra.CreateGrafDir(t, testinfra.GrafanaOpts{
+		DisableAnonymous:     true,
+		EnableQuota:          true,
+		CorrelationsOrgQuota: &correlationsQuota,
+	})
+	_, env := testinfra.StartGrafanaEnv(t, dir, path)
+	ctx := TestContext{
+		env: *env,
+		t:   t,
+	}
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	loki := createDsCommand.Result.Uid
+
+	command := correlations.CreateCorrelationCommand{
+		SourceUID: loki,
+		TargetUID: &loki,
+		OrgId:     1,
+		Config: correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeQuery,
+			Field:  correlations.CorrelationFields{"traceID"},
+			Target: map[string]interface{}{},
+		},
+	}
+	ctx.createCorrelation(command)
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+	count := func() int64 {
+		count, err := service.CountCorrelations(context.Background(), correlations.GetCorrelationsQuery{OrgId: 1})
+		require.NoError(t, err)
+		return count
+	}
+
+	t.Run("creating more correlations at once than the org quota has room for is rejected", func(t *testing.T) {
+		_, err := service.CreateCorrelations(context.Background(), []correlations.CreateCorrelationCommand{command, command})
+		require.ErrorIs(t, err, correlations.ErrQuotaReached)
+		require.Equal(t, int64(1), count())
+	})
+
+	t.Run("creating as many correlations at once as the org quota has room for succeeds", func(t *testing.T) {
+		_, err := service.CreateCorrelations(context.Background(), []correlations.CreateCorrelationCommand{command})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), count())
+	})
+}
@@ -55,7 +55,7 @@ func TestIntegrationReadCorrelation(t *testing.T) {
 			responseBody, err := io.ReadAll(res.Body)
 			require.NoError(t, err)
 
-			var response []correlations.Correlation
+			var response []correlations.CorrelationListItem
 			err = json.Unmarshal(responseBody, &response)
 			require.NoError(t, err)
 
@@ -78,7 +78,7 @@ func TestIntegrationReadCorrelation(t *testing.T) {
 		OrgId:     dsWithCorrelations.OrgId,
 		Config: correlations.CorrelationConfig{
 			Type:   correlations.ConfigTypeQuery,
-			Field:  "foo",
+			Field:  correlations.CorrelationFields{"foo"},
 			Target: map[string]interface{}{},
 		},
 	})
@@ -153,12 +153,12 @@ func TestIntegrationReadCorrelation(t *testing.T) {
 			responseBody, err := io.ReadAll(res.Body)
 			require.NoError(t, err)
 
-			var response []correlations.Correlation
+			var response []correlations.CorrelationListItem
 			err = json.Unmarshal(responseBody, &response)
 			require.NoError(t, err)
 
 			require.Len(t, response, 1)
-			require.EqualValues(t, correlation, response[0])
+			require.EqualValues(t, correlation, response[0].Correlation)
 
 			require.NoError(t, res.Body.Close())
 		})
@@ -0,0 +1,100 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationConfigSchema(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	readInvalid := func(t *testing.T, res *http.Response) []correlations.ConfigFieldError {
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.InvalidConfigResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Invalid correlation config", response.Message)
+		return response.Errors
+	}
+
+	t.Run("creating a correlation reports every field that doesn't fit the schema", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: fmt.Sprintf(`{
+					"targetUID": "%s",
+					"config": {
+						"type": "query",
+						"field": "message",
+						"target": {},
+						"openMode": "popup",
+						"targetTimeoutMs": -1,
+						"transformations": [{ "type": "logfmt" }, { "type": "sed" }]
+					}
+				}`, dataSource.Uid),
+			user: adminUser,
+		})
+		errors := readInvalid(t, res)
+		require.Equal(t, []correlations.ConfigFieldError{
+			{Field: "config.openMode", Message: `"popup" is not one of "", "explore", "newTab", "split"`},
+			{Field: "config.targetTimeoutMs", Message: "invalid value -1 (out of bound >=0)"},
+			{Field: "config.transformations[1].type", Message: `"sed" is not one of "grok", "jsonpath", "logfmt", "regex", "split"`},
+		}, errors)
+	})
+
+	t.Run("updating a correlation reports the fields it sets that don't fit the schema", func(t *testing.T) {
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		})
+
+		res := ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, correlation.UID),
+			body: `{"config": {"targetVisualization": "heatmap", "condition": {"type": "contains", "value": "x"}}}`,
+			user: adminUser,
+		})
+		errors := readInvalid(t, res)
+		require.Len(t, errors, 2)
+		require.Equal(t, "config.condition.type", errors[0].Field)
+		require.Equal(t, "config.targetVisualization", errors[1].Field)
+	})
+}
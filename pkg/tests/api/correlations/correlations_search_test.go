@@ -0,0 +1,78 @@
+package correlations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+func TestIntegrationSearchCorrelationsByConfig(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dsUID := createDsCommand.Result.Uid
+
+	create := func(label string, target map[string]interface{}) correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dsUID,
+			TargetUID: &dsUID,
+			OrgId:     1,
+			Label:     label,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"traceId"},
+				Target: target,
+			},
+		})
+	}
+	errorLogs := create("errors", map[string]interface{}{"expr": `{job="app"} |= "error"`})
+	create("latency", map[string]interface{}{"expr": `{job="gateway"} | unwrap duration`})
+	// the field name is part of the config, but not of the target query
+	create("database", map[string]interface{}{"expr": `{job="db"}`})
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+
+	search := func(term string) []string {
+		found, err := service.SearchCorrelationsByConfig(context.Background(), correlations.SearchCorrelationsByConfigQuery{
+			OrgId: 1,
+			Term:  term,
+		})
+		require.NoError(t, err)
+		uids := make([]string, 0, len(found))
+		for _, c := range found {
+			uids = append(uids, c.UID)
+		}
+		return uids
+	}
+
+	t.Run("finds correlations by a substring of their target query", func(t *testing.T) {
+		require.Equal(t, []string{errorLogs.UID}, search("job=\"app\"} |= \"error"))
+		require.Equal(t, []string{errorLogs.UID}, search("error"))
+	})
+
+	t.Run("excludes correlations whose config only matches outside the target query", func(t *testing.T) {
+		require.Empty(t, search("traceId"))
+		require.Empty(t, search("expr"))
+	})
+
+	t.Run("does not find correlations of other orgs", func(t *testing.T) {
+		found, err := service.SearchCorrelationsByConfig(context.Background(), correlations.SearchCorrelationsByConfigQuery{
+			OrgId: 2,
+			Term:  "error",
+		})
+		require.NoError(t, err)
+		require.Empty(t, found)
+	})
+}
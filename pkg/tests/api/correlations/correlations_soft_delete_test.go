@@ -0,0 +1,154 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationSoftDeleteCorrelation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	newCorrelation := func(label string) correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Label:     label,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"traceID"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+	kept := newCorrelation("kept")
+	deleted := newCorrelation("deleted")
+
+	correlationURL := func(c correlations.Correlation) string {
+		return fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", c.SourceUID, c.UID)
+	}
+	deleteCorrelation := func(c correlations.Correlation) *http.Response {
+		return ctx.Delete(DeleteParams{url: correlationURL(c), user: adminUser})
+	}
+	restoreCorrelation := func(c correlations.Correlation) *http.Response {
+		return ctx.Post(PostParams{url: correlationURL(c) + "/restore", user: adminUser})
+	}
+	getCorrelation := func(c correlations.Correlation) *http.Response {
+		return ctx.Get(GetParams{url: correlationURL(c), user: adminUser})
+	}
+	totalCount := func() int64 {
+		res := ctx.Get(GetParams{url: "/api/datasources/correlations?page=1", user: adminUser})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.GetCorrelationsResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response.TotalCount
+	}
+
+	t.Run("deleted correlations are hidden", func(t *testing.T) {
+		res := deleteCorrelation(deleted)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = getCorrelation(deleted)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.Equal(t, int64(1), totalCount())
+
+		res = deleteCorrelation(deleted)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("correlations that weren't deleted can't be restored", func(t *testing.T) {
+		res := restoreCorrelation(kept)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Deleted correlation not found", response.Message)
+	})
+
+	t.Run("deleted correlations are restored as a new version", func(t *testing.T) {
+		res := restoreCorrelation(deleted)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.UpdateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Correlation restored", response.Message)
+		require.Equal(t, deleted.UID, response.Result.UID)
+		require.Equal(t, "deleted", response.Result.Label)
+		require.Equal(t, int64(2), response.Result.Version)
+		require.Zero(t, response.Result.DeletedAt)
+
+		res = getCorrelation(deleted)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.Equal(t, int64(2), totalCount())
+	})
+
+	t.Run("purged correlations can't be restored", func(t *testing.T) {
+		res := deleteCorrelation(deleted)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		svc := ctx.env.Server.HTTPServer.CorrelationsService
+		purged, err := svc.PurgeDeletedCorrelations(context.Background(), time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, purged)
+
+		purged, err = svc.PurgeDeletedCorrelations(context.Background(), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, int64(1), purged)
+
+		res = restoreCorrelation(deleted)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.Equal(t, int64(1), totalCount())
+	})
+}
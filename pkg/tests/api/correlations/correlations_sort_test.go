@@ -0,0 +1,143 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationSortCorrelations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	// Correlations are created within the same second, so their times are set to tell them apart.
+	times := map[string][2]int64{
+		"bravo":   {300, 400},
+		"alpha":   {200, 600},
+		"charlie": {100, 500},
+	}
+	for _, label := range []string{"bravo", "alpha", "charlie"} {
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Label:     label,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		})
+		require.NotZero(t, correlation.Created)
+		require.NotZero(t, correlation.Updated)
+
+		err := ctx.env.SQLStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+			_, err := sess.Exec("UPDATE correlation SET created = ?, updated = ? WHERE uid = ?", times[label][0], times[label][1], correlation.UID)
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	labels := func(items []correlations.CorrelationListItem) []string {
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			result = append(result, item.Label)
+		}
+		return result
+	}
+	get := func(url string) []byte {
+		res := ctx.Get(GetParams{
+			url:  url,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		return responseBody
+	}
+	listAll := func(params string) []string {
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(get("/api/datasources/correlations?"+params), &response))
+		return labels(response)
+	}
+	listPage := func(params string) []string {
+		var response correlations.GetCorrelationsResponseBody
+		require.NoError(t, json.Unmarshal(get("/api/datasources/correlations?"+params), &response))
+		return labels(response.Correlations)
+	}
+	listBySource := func(params string) []string {
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(get(fmt.Sprintf("/api/datasources/uid/%s/correlations?%s", dataSource.Uid, params)), &response))
+		return labels(response)
+	}
+
+	t.Run("correlations are sorted by label", func(t *testing.T) {
+		require.Equal(t, []string{"alpha", "bravo", "charlie"}, listAll("sort=label"))
+		require.Equal(t, []string{"charlie", "bravo", "alpha"}, listAll("sort=label&sortDirection=desc"))
+		require.Equal(t, []string{"alpha", "bravo", "charlie"}, listBySource("sort=label&sortDirection=asc"))
+		require.Equal(t, []string{"charlie", "bravo", "alpha"}, listBySource("sort=label&sortDirection=desc"))
+	})
+
+	t.Run("correlations are sorted by creation and update times", func(t *testing.T) {
+		require.Equal(t, []string{"charlie", "alpha", "bravo"}, listAll("sort=created"))
+		require.Equal(t, []string{"alpha", "charlie", "bravo"}, listBySource("sort=updated&sortDirection=desc"))
+	})
+
+	t.Run("sorted lists are paginated", func(t *testing.T) {
+		require.Equal(t, []string{"bravo"}, listPage("sort=updated&limit=1&page=1"))
+		require.Equal(t, []string{"charlie"}, listPage("sort=updated&limit=1&page=2"))
+		require.Equal(t, []string{"alpha"}, listPage("sort=updated&limit=1&page=3"))
+	})
+
+	t.Run("unknown sorts are rejected", func(t *testing.T) {
+		for _, url := range []string{
+			"/api/datasources/correlations?sort=uid",
+			fmt.Sprintf("/api/datasources/uid/%s/correlations?sort=label&sortDirection=up", dataSource.Uid),
+		} {
+			res := ctx.Get(GetParams{
+				url:  url,
+				user: adminUser,
+			})
+			require.Equal(t, http.StatusBadRequest, res.StatusCode)
+			responseBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			var response errorResponseBody
+			require.NoError(t, json.Unmarshal(responseBody, &response))
+			require.Equal(t, "Invalid sort", response.Message)
+		}
+	})
+}
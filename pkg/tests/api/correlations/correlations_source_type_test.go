@@ -0,0 +1,117 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationsBySourceType(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDs := func(name, dsType string) string {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  dsType,
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result.Uid
+	}
+	lokiEU := createDs("loki-eu", datasources.DS_LOKI)
+	lokiUS := createDs("loki-us", datasources.DS_LOKI)
+	tempo := createDs("tempo", datasources.DS_TEMPO)
+
+	create := func(sourceType string) *http.Response {
+		return ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", lokiEU),
+			body: fmt.Sprintf(`{
+					"targetUID": "%s",
+					"sourceType": "%s",
+					"config": { "type": "query", "field": "traceId", "target": {} }
+				}`, tempo, sourceType),
+			user: adminUser,
+		})
+	}
+	list := func(sourceUID string) []correlations.Correlation {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", sourceUID),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var list []correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &list))
+		return list
+	}
+
+	ctx.createCorrelation(correlations.CreateCorrelationCommand{
+		SourceUID: lokiEU,
+		TargetUID: &tempo,
+		OrgId:     1,
+		Config:    correlations.CorrelationConfig{Type: correlations.ConfigTypeQuery, Field: correlations.CorrelationFields{"message"}, Target: map[string]interface{}{}},
+	})
+
+	var created correlations.Correlation
+	t.Run("correlations can apply to every data source of the source type", func(t *testing.T) {
+		res := create(datasources.DS_LOKI)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		created = response.Result
+		require.Equal(t, datasources.DS_LOKI, created.SourceType)
+		require.Equal(t, lokiEU, created.SourceUID)
+	})
+
+	t.Run("correlations of the source type are listed for every data source of the type", func(t *testing.T) {
+		require.Len(t, list(lokiEU), 2)
+
+		bySource := list(lokiUS)
+		require.Len(t, bySource, 1)
+		require.Equal(t, created.UID, bySource[0].UID)
+		require.Equal(t, lokiEU, bySource[0].SourceUID)
+
+		require.Empty(t, list(tempo))
+	})
+
+	t.Run("the source type must be the type of the source data source", func(t *testing.T) {
+		res := create(datasources.DS_TEMPO)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response errorResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Source type does not match the source data source", response.Message)
+	})
+}
@@ -0,0 +1,183 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationTags(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	loki := createDsCommand.Result
+
+	createDsCommand = &datasources.AddDataSourceCommand{
+		Name:  "prometheus",
+		Type:  "prometheus",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	prometheus := createDsCommand.Result
+
+	create := func(sourceUID string, tags string) correlations.Correlation {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", sourceUID),
+			body: fmt.Sprintf(`{"targetUID": "%s", "tags": %s, "config": {"type": "query", "field": "message", "target": {}}}`, sourceUID, tags),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response.Result
+	}
+	list := func(query string) []string {
+		res := ctx.Get(GetParams{
+			url:  "/api/datasources/correlations?" + query,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		uids := make([]string, 0, len(response))
+		for _, correlation := range response {
+			uids = append(uids, correlation.UID)
+		}
+		return uids
+	}
+
+	payments := create(loki.Uid, `["payments", " infra ", "payments"]`)
+	require.Equal(t, []string{"payments", "infra"}, payments.Tags)
+	infra := create(prometheus.Uid, `["infra"]`)
+	untagged := create(loki.Uid, `[]`)
+	require.Equal(t, []string{}, untagged.Tags)
+
+	t.Run("tags are returned when reading a correlation", func(t *testing.T) {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, payments.UID),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var correlation correlations.Correlation
+		require.NoError(t, json.Unmarshal(responseBody, &correlation))
+		require.Equal(t, []string{"payments", "infra"}, correlation.Tags)
+	})
+
+	t.Run("correlations are filtered by all given tags", func(t *testing.T) {
+		require.ElementsMatch(t, []string{payments.UID, infra.UID}, list("tag=infra"))
+		require.Equal(t, []string{payments.UID}, list("tag=infra&tag=payments"))
+		require.Empty(t, list("tag=infra&tag=unknown"))
+		require.Len(t, list(""), 3)
+	})
+
+	t.Run("correlations of a data source are filtered by tag", func(t *testing.T) {
+		res := ctx.Get(GetParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations?tag=payments", loki.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Len(t, response, 1)
+		require.Equal(t, payments.UID, response[0].UID)
+	})
+
+	t.Run("updating tags replaces them", func(t *testing.T) {
+		res := ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, untagged.UID),
+			body: `{"tags": ["payments"]}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.UpdateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, []string{"payments"}, response.Result.Tags)
+		require.ElementsMatch(t, []string{payments.UID, untagged.UID}, list("tag=payments"))
+
+		// Updates without tags keep them
+		res = ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, untagged.UID),
+			body: `{"label": "tagged"}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.ElementsMatch(t, []string{payments.UID, untagged.UID}, list("tag=payments"))
+
+		res = ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki.Uid, untagged.UID),
+			body: `{"tags": []}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, []string{payments.UID}, list("tag=payments"))
+	})
+
+	t.Run("invalid tags are rejected", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", loki.Uid),
+			body: fmt.Sprintf(`{"targetUID": "%s", "tags": [""], "config": {"type": "query", "field": "message", "target": {}}}`, loki.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("tags are removed with their correlation", func(t *testing.T) {
+		res := ctx.Delete(DeleteParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", prometheus.Uid, infra.UID),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, []string{payments.UID}, list("tag=infra"))
+	})
+}
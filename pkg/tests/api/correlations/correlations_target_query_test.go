@@ -0,0 +1,100 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationValidateTargetQuery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	loki := createDsCommand.Result
+
+	createDsCommand = &datasources.AddDataSourceCommand{
+		Name:  "prometheus",
+		Type:  "prometheus",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	prometheus := createDsCommand.Result
+
+	createBody := fmt.Sprintf(`{
+		"targetUID": "%s",
+		"config": {"type": "query", "field": "message", "target": {"expr": "sum(rate(http_requests_total[5m])"}}
+	}`, prometheus.Uid)
+
+	t.Run("warnings are only returned if validation is requested", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations", loki.Uid),
+			body: createBody,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Empty(t, response.Warnings)
+	})
+
+	t.Run("malformed target queries are saved with warnings", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations?validateTarget=true", loki.Uid),
+			body: createBody,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.CreateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, []correlations.TargetQueryWarning{{Key: "expr", Message: `unclosed '(' in expression`}}, response.Warnings)
+
+		res = ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s?validateTarget=true", loki.Uid, response.Result.UID),
+			body: `{"config": {"target": {"expr": "sum(rate(http_requests_total[5m]))"}}}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var updated correlations.UpdateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &updated))
+		require.Empty(t, updated.Warnings)
+	})
+}
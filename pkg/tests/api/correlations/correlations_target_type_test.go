@@ -0,0 +1,103 @@
+package correlations
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationTargetType(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDs := func(name, dsType string) string {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  dsType,
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result.Uid
+	}
+	loki := createDs("loki", datasources.DS_LOKI)
+	mysql := createDs("mysql", datasources.DS_MYSQL)
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+
+	create := func(targetUID string) int {
+		res := ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", loki),
+			body: fmt.Sprintf(`{
+					"targetUID": "%s",
+					"config": {
+						"type": "query",
+						"field": "message",
+						"target": { "expr": "{job=\"app\"}" }
+					}
+				}`, targetUID),
+			user: adminUser,
+		})
+		require.NoError(t, res.Body.Close())
+		return res.StatusCode
+	}
+
+	t.Run("mismatching target types are only logged by default", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, create(mysql))
+	})
+
+	t.Run("mismatching target types are rejected in strict mode", func(t *testing.T) {
+		service.StrictTargetTypeCheck = true
+		t.Cleanup(func() { service.StrictTargetTypeCheck = false })
+
+		require.Equal(t, http.StatusBadRequest, create(mysql))
+		require.Equal(t, http.StatusOK, create(loki))
+	})
+
+	t.Run("updated targets are checked in strict mode", func(t *testing.T) {
+		service.StrictTargetTypeCheck = true
+		t.Cleanup(func() { service.StrictTargetTypeCheck = false })
+
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: loki,
+			TargetUID: &mysql,
+			OrgId:     1,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{"rawSql": "SELECT 1"},
+			},
+		})
+
+		update := func(target string) int {
+			res := ctx.Patch(PatchParams{
+				url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki, correlation.UID),
+				body: fmt.Sprintf(`{"config": {"target": %s}}`, target),
+				user: adminUser,
+			})
+			require.NoError(t, res.Body.Close())
+			return res.StatusCode
+		}
+
+		require.Equal(t, http.StatusBadRequest, update(`{"expr": "up"}`))
+		require.Equal(t, http.StatusOK, update(`{"rawSql": "SELECT 2"}`))
+	})
+}
@@ -0,0 +1,173 @@
+package correlations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+)
+
+func TestIntegrationCorrelationTemplates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	createDs := func(name string, readOnly bool) string {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:     name,
+			Type:     "loki",
+			ReadOnly: readOnly,
+			OrgId:    1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result.Uid
+	}
+
+	lokiA := createDs("loki-a", false)
+	lokiB := createDs("loki-b", false)
+	lokiC := createDs("loki-c", false)
+	readOnly := createDs("read-only", true)
+	tempo := createDs("tempo", false)
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService
+
+	template, err := service.CreateCorrelationTemplate(context.Background(), correlations.CreateCorrelationTemplateCommand{
+		OrgId:     1,
+		TargetUID: &tempo,
+		Label:     "Logs to traces",
+		Config: correlations.CorrelationConfig{
+			Type:   correlations.ConfigTypeQuery,
+			Field:  correlations.CorrelationFields{"traceID"},
+			Target: map[string]interface{}{"query": "${traceID}"},
+		},
+	})
+	require.NoError(t, err)
+
+	bySource := func(t *testing.T, sourceUID string) []correlations.Correlation {
+		t.Helper()
+		result, err := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService).GetCorrelationsBySourceUID(context.Background(), correlations.GetCorrelationsBySourceUIDQuery{
+			SourceUID: sourceUID,
+			OrgId:     1,
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("applying a template materializes a correlation per source", func(t *testing.T) {
+		result, err := service.ApplyTemplate(context.Background(), correlations.ApplyTemplateCommand{
+			TemplateID: template.UID,
+			SourceUIDs: []string{lokiA, lokiB},
+			OrgId:      1,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+
+		for _, source := range []string{lokiA, lokiB} {
+			stored := bySource(t, source)
+			require.Len(t, stored, 1)
+			require.Equal(t, "Logs to traces", stored[0].Label)
+			require.Equal(t, tempo, *stored[0].TargetUID)
+			require.Equal(t, correlations.CorrelationFields{"traceID"}, stored[0].Config.Field)
+			require.Equal(t, template.UID, *stored[0].TemplateUID)
+		}
+		require.NotEqual(t, result[0].UID, result[1].UID)
+	})
+
+	t.Run("applying a template again does not duplicate correlations", func(t *testing.T) {
+		_, err := service.ApplyTemplate(context.Background(), correlations.ApplyTemplateCommand{
+			TemplateID: template.UID,
+			SourceUIDs: []string{lokiA, lokiB, lokiC},
+			OrgId:      1,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, bySource(t, lokiA), 1)
+		require.Len(t, bySource(t, lokiB), 1)
+		require.Len(t, bySource(t, lokiC), 1)
+	})
+
+	t.Run("editing a template re-syncs materialized correlations if requested", func(t *testing.T) {
+		label := "Logs to Tempo"
+		_, err := service.UpdateCorrelationTemplate(context.Background(), correlations.UpdateCorrelationTemplateCommand{
+			UID:   template.UID,
+			OrgId: 1,
+			Label: &label,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Logs to traces", bySource(t, lokiA)[0].Label)
+
+		deleted := bySource(t, lokiB)[0]
+		_, err = service.DeleteCorrelation(context.Background(), correlations.DeleteCorrelationCommand{
+			UID:       deleted.UID,
+			SourceUID: lokiB,
+			OrgId:     1,
+		})
+		require.NoError(t, err)
+
+		provisioned := bySource(t, lokiC)[0]
+		err = ctx.env.SQLStore.WithDbSession(context.Background(), func(sess *db.Session) error {
+			_, err := sess.Exec("UPDATE correlation SET provisioned = ? WHERE uid = ?", true, provisioned.UID)
+			return err
+		})
+		require.NoError(t, err)
+
+		before := bySource(t, lokiA)[0]
+		config := template.Config
+		config.Field = correlations.CorrelationFields{"trace_id"}
+		config.Target = map[string]interface{}{"query": "${trace_id}"}
+		_, err = service.UpdateCorrelationTemplate(context.Background(), correlations.UpdateCorrelationTemplateCommand{
+			UID:    template.UID,
+			OrgId:  1,
+			Config: &config,
+			Resync: true,
+		})
+		require.NoError(t, err)
+
+		stored := bySource(t, lokiA)
+		require.Len(t, stored, 1)
+		require.Equal(t, "Logs to Tempo", stored[0].Label)
+		require.Equal(t, correlations.CorrelationFields{"trace_id"}, stored[0].Config.Field)
+		require.Equal(t, lokiA, stored[0].SourceUID)
+		require.Equal(t, before.Version+1, stored[0].Version)
+
+		history, err := service.(*correlations.CorrelationsService).GetCorrelationHistory(context.Background(), correlations.GetCorrelationHistoryQuery{
+			UID:       before.UID,
+			SourceUID: lokiA,
+			OrgId:     1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, correlations.HistoryActionUpdated, history[0].Action)
+		require.Equal(t, []string{"label", "config.field", "config.target"}, history[0].Changes)
+
+		require.Empty(t, bySource(t, lokiB))
+
+		stored = bySource(t, lokiC)
+		require.Len(t, stored, 1)
+		require.Equal(t, "Logs to traces", stored[0].Label)
+		require.Equal(t, correlations.CorrelationFields{"traceID"}, stored[0].Config.Field)
+		require.Equal(t, provisioned.Version, stored[0].Version)
+	})
+
+	t.Run("applying a template to a read only data source fails", func(t *testing.T) {
+		_, err := service.ApplyTemplate(context.Background(), correlations.ApplyTemplateCommand{
+			TemplateID: template.UID,
+			SourceUIDs: []string{readOnly},
+			OrgId:      1,
+		})
+		require.ErrorIs(t, err, correlations.ErrSourceDataSourceReadOnly)
+	})
+
+	t.Run("templates of other orgs are not found", func(t *testing.T) {
+		_, err := service.ApplyTemplate(context.Background(), correlations.ApplyTemplateCommand{
+			TemplateID: template.UID,
+			SourceUIDs: []string{lokiA},
+			OrgId:      2,
+		})
+		require.ErrorIs(t, err, correlations.ErrCorrelationTemplateNotFound)
+	})
+}
@@ -0,0 +1,115 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationSearchCorrelationsByText(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDs := func(name string) *datasources.DataSource {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  name,
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result
+	}
+	loki := createDs("loki")
+	mysql := createDs("mysql")
+
+	create := func(label, description string, target map[string]interface{}) {
+		ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID:   loki.Uid,
+			TargetUID:   &mysql.Uid,
+			OrgId:       1,
+			Label:       label,
+			Description: description,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"id"},
+				Target: target,
+			},
+		})
+	}
+	create("Failed orders", "", map[string]interface{}{"rawSql": "SELECT * FROM orders WHERE status = 'failed'"})
+	create("Customers", "Customer of the order", map[string]interface{}{"rawSql": "SELECT * FROM customers WHERE id = ${id}"})
+	create("Refunds", "", map[string]interface{}{"rawSql": "SELECT * FROM refunds"})
+	create("Slow queries", "Over 100% of the budget", map[string]interface{}{"rawSql": "SELECT * FROM slow_queries"})
+
+	labels := func(items []correlations.CorrelationListItem) []string {
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			result = append(result, item.Label)
+		}
+		sort.Strings(result)
+		return result
+	}
+	get := func(path string) []byte {
+		res := ctx.Get(GetParams{
+			url:  path,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		return responseBody
+	}
+	search := func(text string) []string {
+		var response []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(get("/api/datasources/correlations?query="+url.QueryEscape(text)), &response))
+
+		var bySource []correlations.CorrelationListItem
+		require.NoError(t, json.Unmarshal(get(fmt.Sprintf("/api/datasources/uid/%s/correlations?query=%s", loki.Uid, url.QueryEscape(text))), &bySource))
+		require.Equal(t, labels(response), labels(bySource))
+		return labels(bySource)
+	}
+
+	t.Run("labels and descriptions are searched", func(t *testing.T) {
+		require.Equal(t, []string{"Refunds"}, search("refund"))
+		require.Equal(t, []string{"Customers", "Failed orders"}, search("order"))
+	})
+
+	t.Run("target queries are searched", func(t *testing.T) {
+		require.Equal(t, []string{"Failed orders"}, search("status = 'failed'"))
+		require.Equal(t, []string{"Customers"}, search("${id}"))
+	})
+
+	t.Run("wildcards in the text are matched literally", func(t *testing.T) {
+		require.Equal(t, []string{"Slow queries"}, search("100%"))
+		require.Empty(t, search("%budget"))
+		require.Empty(t, search("_order"))
+	})
+
+	t.Run("text matching nothing returns no correlations", func(t *testing.T) {
+		require.Empty(t, search("DELETE FROM"))
+	})
+}
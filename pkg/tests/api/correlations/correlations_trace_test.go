@@ -0,0 +1,91 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationTraceCorrelations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDs := func(name, dsType string) string {
+		createDsCommand := &datasources.AddDataSourceCommand{
+			Name:  name,
+			Type:  dsType,
+			OrgId: 1,
+		}
+		ctx.createDs(createDsCommand)
+		return createDsCommand.Result.Uid
+	}
+	loki := createDs("loki", datasources.DS_LOKI)
+	tempo := createDs("tempo", datasources.DS_TEMPO)
+	zipkin := createDs("zipkin", datasources.DS_ZIPKIN)
+
+	readCorrelation := func(res *http.Response) correlations.Correlation {
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response struct {
+			Result correlations.Correlation `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response.Result
+	}
+	create := func(targetUID string) *http.Response {
+		return ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", loki),
+			body: fmt.Sprintf(`{
+					"targetUID": "%s",
+					"config": { "type": "trace", "field": "traceId" }
+				}`, targetUID),
+			user: adminUser,
+		})
+	}
+
+	var created correlations.Correlation
+	t.Run("the target query is generated for the target data source", func(t *testing.T) {
+		created = readCorrelation(create(tempo))
+		require.Equal(t, correlations.ConfigTypeTrace, created.Config.Type)
+		require.Equal(t, map[string]interface{}{"queryType": "traceql", "query": "${traceId}"}, created.Config.Target)
+	})
+
+	t.Run("the target query is generated again when the target or field changes", func(t *testing.T) {
+		updated := readCorrelation(ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", loki, created.UID),
+			body: fmt.Sprintf(`{"targetUID": "%s", "config": {"field": "traceID"}}`, zipkin),
+			user: adminUser,
+		}))
+		require.Equal(t, map[string]interface{}{"queryType": "traceID", "query": "${traceID}"}, updated.Config.Target)
+	})
+
+	t.Run("non-tracing target data sources are rejected", func(t *testing.T) {
+		res := create(loki)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
@@ -0,0 +1,73 @@
+package correlations
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationTestTransformations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	viewerUser := User{
+		username: "viewer",
+		password: "viewer",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleViewer),
+		Password:       viewerUser.password,
+		Login:          viewerUser.username,
+	})
+
+	t.Run("returns the extracted variables", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url: "/api/datasources/correlations/transformations/test",
+			body: `{
+				"field": "message",
+				"value": "level=error traceID=abc123",
+				"transformations": [
+					{"type": "regex", "expression": "traceID=(\\w+)", "mapValue": "traceId"},
+					{"type": "regex", "expression": "spanID=(\\w+)", "mapValue": "spanId"}
+				]
+			}`,
+			user: viewerUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var result correlations.TestTransformationsResult
+		require.NoError(t, json.Unmarshal(responseBody, &result))
+		require.Equal(t, map[string]string{"traceId": "abc123"}, result.Variables)
+		require.Equal(t, []int{1}, result.Unmatched)
+	})
+
+	t.Run("rejects invalid transformations", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url:  "/api/datasources/correlations/transformations/test",
+			body: `{"field": "message", "value": "a", "transformations": [{"type": "regex", "expression": "(?<user-name>\\w+)"}]}`,
+			user: viewerUser,
+		})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = ctx.Post(PostParams{
+			url:  "/api/datasources/correlations/transformations/test",
+			body: `{"field": "message", "value": "a", "transformations": []}`,
+			user: viewerUser,
+		})
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
@@ -92,7 +92,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 		err = json.Unmarshal(responseBody, &response)
 		require.NoError(t, err)
 
-		require.Contains(t, response.Message, "Permissions needed: datasources:write")
+		require.Contains(t, response.Message, "Permissions needed: correlations:write")
 
 		require.NoError(t, res.Body.Close())
 	})
@@ -270,7 +270,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 			Label:       "0",
 			Description: "0",
 			Config: correlations.CorrelationConfig{
-				Field:  "fieldName",
+				Field:  correlations.CorrelationFields{"fieldName"},
 				Type:   "query",
 				Target: map[string]interface{}{"expr": "foo"},
 			},
@@ -302,7 +302,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 		require.Equal(t, "Correlation updated", response.Message)
 		require.Equal(t, "1", response.Result.Label)
 		require.Equal(t, "1", response.Result.Description)
-		require.Equal(t, "field", response.Result.Config.Field)
+		require.Equal(t, correlations.CorrelationFields{"field"}, response.Result.Config.Field)
 		require.Equal(t, map[string]interface{}{"expr": "bar"}, response.Result.Config.Target)
 		require.NoError(t, res.Body.Close())
 
@@ -325,7 +325,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 		require.Equal(t, "Correlation updated", response.Message)
 		require.Equal(t, "2", response.Result.Label)
 		require.Equal(t, "1", response.Result.Description)
-		require.Equal(t, "field", response.Result.Config.Field)
+		require.Equal(t, correlations.CorrelationFields{"field"}, response.Result.Config.Field)
 		require.Equal(t, map[string]interface{}{"expr": "bar"}, response.Result.Config.Target)
 		require.NoError(t, res.Body.Close())
 
@@ -348,7 +348,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 		require.Equal(t, "Correlation updated", response.Message)
 		require.Equal(t, "2", response.Result.Label)
 		require.Equal(t, "2", response.Result.Description)
-		require.Equal(t, "field", response.Result.Config.Field)
+		require.Equal(t, correlations.CorrelationFields{"field"}, response.Result.Config.Field)
 		require.Equal(t, map[string]interface{}{"expr": "bar"}, response.Result.Config.Target)
 		require.NoError(t, res.Body.Close())
 
@@ -375,7 +375,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 		require.Equal(t, "Correlation updated", response.Message)
 		require.Equal(t, "2", response.Result.Label)
 		require.Equal(t, "2", response.Result.Description)
-		require.Equal(t, "name", response.Result.Config.Field)
+		require.Equal(t, correlations.CorrelationFields{"name"}, response.Result.Config.Field)
 		require.Equal(t, map[string]interface{}{"expr": "baz"}, response.Result.Config.Target)
 		require.NoError(t, res.Body.Close())
 
@@ -400,7 +400,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 		require.Equal(t, "Correlation updated", response.Message)
 		require.Equal(t, "2", response.Result.Label)
 		require.Equal(t, "2", response.Result.Description)
-		require.Equal(t, "newName", response.Result.Config.Field)
+		require.Equal(t, correlations.CorrelationFields{"newName"}, response.Result.Config.Field)
 		require.Equal(t, map[string]interface{}{"expr": "baz"}, response.Result.Config.Target)
 		require.NoError(t, res.Body.Close())
 
@@ -425,7 +425,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 		require.Equal(t, "Correlation updated", response.Message)
 		require.Equal(t, "2", response.Result.Label)
 		require.Equal(t, "2", response.Result.Description)
-		require.Equal(t, "newName", response.Result.Config.Field)
+		require.Equal(t, correlations.CorrelationFields{"newName"}, response.Result.Config.Field)
 		require.Equal(t, map[string]interface{}{"expr": "foo"}, response.Result.Config.Target)
 		require.NoError(t, res.Body.Close())
 
@@ -452,7 +452,7 @@ func TestIntegrationUpdateCorrelation(t *testing.T) {
 		require.Equal(t, "Correlation updated", response.Message)
 		require.Equal(t, "", response.Result.Label)
 		require.Equal(t, "", response.Result.Description)
-		require.Equal(t, "", response.Result.Config.Field)
+		require.Empty(t, response.Result.Config.Field)
 		require.NoError(t, res.Body.Close())
 	})
 }
@@ -0,0 +1,98 @@
+package correlations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationUsage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	viewerUser := User{
+		username: "viewer",
+		password: "viewer",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleViewer),
+		Password:       viewerUser.password,
+		Login:          viewerUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	createCorrelation := func() correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"foo"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+	used := createCorrelation()
+	unused := createCorrelation()
+
+	service := ctx.env.Server.HTTPServer.CorrelationsService.(*correlations.CorrelationsService)
+
+	getCorrelation := func(uid string) correlations.Correlation {
+		correlation, err := service.GetCorrelation(context.Background(), correlations.GetCorrelationQuery{
+			UID:       uid,
+			SourceUID: dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+		})
+		require.NoError(t, err)
+		return correlation
+	}
+
+	t.Run("uses are recorded and surfaced in list queries", func(t *testing.T) {
+		require.Zero(t, getCorrelation(used.UID).LastUsed)
+
+		res := ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s/used", dataSource.Uid, used.UID),
+			user: viewerUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.NoError(t, service.FlushCorrelationUsage(context.Background()))
+
+		list, err := service.GetCorrelations(context.Background(), correlations.GetCorrelationsQuery{OrgId: dataSource.OrgId})
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		for _, c := range list {
+			if c.UID == used.UID {
+				require.NotZero(t, c.LastUsed)
+			} else {
+				require.Zero(t, c.LastUsed)
+			}
+		}
+	})
+
+	t.Run("uses in other orgs are not recorded", func(t *testing.T) {
+		service.MarkCorrelationUsed(unused.UID, dataSource.Uid, 2)
+		require.NoError(t, service.FlushCorrelationUsage(context.Background()))
+
+		require.Zero(t, getCorrelation(unused.UID).LastUsed)
+	})
+}
@@ -0,0 +1,135 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationCorrelationTargetVariables(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	readUnresolved := func(t *testing.T, res *http.Response) correlations.UnresolvedVariablesResponseBody {
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.UnresolvedVariablesResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Target references variables the correlation does not provide", response.Message)
+		return response
+	}
+
+	t.Run("creating a correlation fails listing every variable it does not provide", func(t *testing.T) {
+		res := ctx.Post(PostParams{
+			url: fmt.Sprintf("/api/datasources/uid/%s/correlations", dataSource.Uid),
+			body: fmt.Sprintf(`{
+					"targetUID": "%s",
+					"config": {
+						"type": "query",
+						"field": "message",
+						"target": { "expr": "{job=\"${job}\"} |= \"${traceId}\" |= \"${message}\"" },
+						"transformations": [{ "type": "regex", "expression": "span=(\\w+)", "mapValue": "span" }]
+					}
+				}`, dataSource.Uid),
+			user: adminUser,
+		})
+		require.Equal(t, []string{"job", "traceId"}, readUnresolved(t, res).MissingVariables)
+	})
+
+	t.Run("updating a correlation fails if its target uses a variable it does not provide", func(t *testing.T) {
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Config: correlations.CorrelationConfig{
+				Type:     correlations.ConfigTypeQuery,
+				Field:    correlations.CorrelationFields{"message"},
+				Target:   map[string]interface{}{"expr": "${traceId}"},
+				Mappings: correlations.Mappings{{Source: "trace_id", Target: "traceId"}},
+			},
+		})
+
+		res := ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, correlation.UID),
+			body: `{"config": {"target": {"expr": "${traceId} |= \"${span}\""}}}`,
+			user: adminUser,
+		})
+		require.Equal(t, []string{"span"}, readUnresolved(t, res).MissingVariables)
+
+		res = ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, correlation.UID),
+			body: `{"config": {"target": {"expr": "${traceId} |= \"${span}\""}, "mappings": [{"source": "trace_id", "target": "traceId"}, {"source": "span_id", "target": "span"}]}}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("updating only the mappings of a correlation checks its target against them", func(t *testing.T) {
+		correlation := ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     dataSource.OrgId,
+			Config: correlations.CorrelationConfig{
+				Type:     correlations.ConfigTypeQuery,
+				Field:    correlations.CorrelationFields{"message"},
+				Target:   map[string]interface{}{"expr": "${traceId}"},
+				Mappings: correlations.Mappings{{Source: "trace_id", Target: "traceId"}},
+			},
+		})
+		url := fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, correlation.UID)
+
+		res := ctx.Patch(PatchParams{
+			url:  url,
+			body: `{"config": {"mappings": [{"source": "traceID", "target": "traceId"}]}}`,
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.UpdateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, correlations.Mappings{{Source: "traceID", Target: "traceId"}}, response.Result.Config.Mappings)
+
+		res = ctx.Patch(PatchParams{
+			url:  url,
+			body: `{"config": {"mappings": []}}`,
+			user: adminUser,
+		})
+		require.Equal(t, []string{"traceId"}, readUnresolved(t, res).MissingVariables)
+	})
+}
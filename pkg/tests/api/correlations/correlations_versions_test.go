@@ -0,0 +1,157 @@
+package correlations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationRestoreCorrelation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := NewTestEnv(t)
+
+	adminUser := User{
+		username: "admin",
+		password: "admin",
+	}
+	editorUser := User{
+		username: "editor",
+		password: "editor",
+	}
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleAdmin),
+		Password:       adminUser.password,
+		Login:          adminUser.username,
+	})
+	ctx.createUser(user.CreateUserCommand{
+		DefaultOrgRole: string(org.RoleEditor),
+		Password:       editorUser.password,
+		Login:          editorUser.username,
+	})
+
+	createDsCommand := &datasources.AddDataSourceCommand{
+		Name:  "loki",
+		Type:  "loki",
+		OrgId: 1,
+	}
+	ctx.createDs(createDsCommand)
+	dataSource := createDsCommand.Result
+
+	newCorrelation := func() correlations.Correlation {
+		return ctx.createCorrelation(correlations.CreateCorrelationCommand{
+			SourceUID: dataSource.Uid,
+			TargetUID: &dataSource.Uid,
+			OrgId:     1,
+			Label:     "v1",
+			Config: correlations.CorrelationConfig{
+				Type:   correlations.ConfigTypeQuery,
+				Field:  correlations.CorrelationFields{"message"},
+				Target: map[string]interface{}{},
+			},
+		})
+	}
+	update := func(uid string, label string) correlations.Correlation {
+		res := ctx.Patch(PatchParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s", dataSource.Uid, uid),
+			body: fmt.Sprintf(`{"label": "%s"}`, label),
+			user: adminUser,
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		var response correlations.UpdateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		return response.Result
+	}
+	restore := func(uid string, version string, u User) *http.Response {
+		return ctx.Post(PostParams{
+			url:  fmt.Sprintf("/api/datasources/uid/%s/correlations/%s/restore/%s", dataSource.Uid, uid, version),
+			user: u,
+		})
+	}
+
+	t.Run("updates increment the version", func(t *testing.T) {
+		correlation := newCorrelation()
+		require.EqualValues(t, 1, correlation.Version)
+		require.EqualValues(t, 2, update(correlation.UID, "v2").Version)
+		require.EqualValues(t, 3, update(correlation.UID, "v3").Version)
+	})
+
+	t.Run("restoring saves an earlier version as a new version", func(t *testing.T) {
+		correlation := newCorrelation()
+		update(correlation.UID, "v2")
+
+		res := restore(correlation.UID, "1", adminUser)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		var response correlations.UpdateCorrelationResponseBody
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "Correlation restored", response.Message)
+		require.Equal(t, "v1", response.Result.Label)
+		require.EqualValues(t, 3, response.Result.Version)
+
+		// Restoring doesn't drop the versions in between
+		res = restore(correlation.UID, "2", adminUser)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		responseBody, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.NoError(t, json.Unmarshal(responseBody, &response))
+		require.Equal(t, "v2", response.Result.Label)
+		require.EqualValues(t, 4, response.Result.Version)
+	})
+
+	t.Run("only the last versions are kept", func(t *testing.T) {
+		correlation := newCorrelation()
+		for i := 2; i <= correlations.MaxCorrelationVersions+1; i++ {
+			update(correlation.UID, fmt.Sprintf("v%d", i))
+		}
+
+		res := restore(correlation.UID, "1", adminUser)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = restore(correlation.UID, "2", adminUser)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("restoring a missing version fails", func(t *testing.T) {
+		correlation := newCorrelation()
+
+		res := restore(correlation.UID, "5", adminUser)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = restore(correlation.UID, "latest", adminUser)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		res = restore("missing", "1", adminUser)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("restoring requires writing correlations", func(t *testing.T) {
+		correlation := newCorrelation()
+
+		res := restore(correlation.UID, "1", editorUser)
+		require.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
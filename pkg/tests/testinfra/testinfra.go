@@ -262,6 +262,10 @@ func CreateGrafDir(t *testing.T, opts ...GrafanaOpts) (string, string) {
 			}
 			_, err = quotaSection.NewKey("org_dashboard", strconv.FormatInt(dashboardQuota, 10))
 			require.NoError(t, err)
+			if o.CorrelationsOrgQuota != nil {
+				_, err = quotaSection.NewKey("org_correlations", strconv.FormatInt(*o.CorrelationsOrgQuota, 10))
+				require.NoError(t, err)
+			}
 		}
 		if o.DisableAnonymous {
 			anonSect, err := cfg.GetSection("auth.anonymous")
@@ -361,6 +365,7 @@ type GrafanaOpts struct {
 	AnonymousUserRole                     org.RoleType
 	EnableQuota                           bool
 	DashboardOrgQuota                     *int64
+	CorrelationsOrgQuota                  *int64
 	DisableAnonymous                      bool
 	CatalogAppEnabled                     bool
 	ViewersCanEdit                        bool
@@ -130,7 +130,14 @@ const renderWithContext = async (
     },
     fetch: (options: BackendSrvRequest) => {
       return new Observable((s) => {
-        s.next(merge(createFetchResponse({ url: options.url, data: correlations })));
+        s.next(
+          merge(
+            createFetchResponse({
+              url: options.url,
+              data: { correlations, totalCount: correlations.length, page: 1, limit: correlations.length },
+            })
+          )
+        );
 
         s.complete();
       });
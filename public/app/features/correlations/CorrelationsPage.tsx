@@ -52,7 +52,7 @@ export default function CorrelationsPage() {
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, []);
 
-  const canWriteCorrelations = contextSrv.hasPermission(AccessControlAction.DataSourcesWrite);
+  const canWriteCorrelations = contextSrv.hasPermission(AccessControlAction.CorrelationsWrite);
 
   const handleAdded = useCallback(() => {
     reportInteraction('grafana_correlations_added');
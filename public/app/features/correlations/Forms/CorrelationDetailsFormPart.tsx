@@ -27,6 +27,18 @@ const getStyles = (theme: GrafanaTheme2) => ({
   `,
 });
 
+// Several fields holding the same value are entered as a comma-separated list of their names.
+const parseFields = (value: string | string[]) => {
+  if (Array.isArray(value)) {
+    return value;
+  }
+  const fields = value
+    .split(',')
+    .map((field) => field.trim())
+    .filter((field) => field !== '');
+  return fields.length > 1 ? fields : value.trim();
+};
+
 type CorrelationBaseData = Pick<Correlation, 'uid' | 'sourceUID' | 'targetUID'>;
 interface Props {
   readOnly?: boolean;
@@ -64,13 +76,14 @@ export function CorrelationDetailsFormPart({ readOnly = false, correlation }: Pr
 
       <Field
         label="Target field"
+        description="Separate the names of several fields holding the same value with commas."
         className={styles.label}
         invalid={!!errors?.config?.field}
         error={errors?.config?.field?.message}
       >
         <Input
           id={getInputId('field', correlation)}
-          {...register('config.field', { required: 'This field is required.' })}
+          {...register('config.field', { required: 'This field is required.', setValueAs: parseFields })}
           readOnly={readOnly}
         />
       </Field>
@@ -0,0 +1,75 @@
+import { DataSourceInstanceSettings } from '@grafana/data';
+
+import { flushCorrelationAnalytics, reportCorrelationClicked, reportCorrelationRendered } from './analytics';
+import { CorrelationData } from './useCorrelations';
+
+const mockPost = jest.fn().mockResolvedValue({});
+jest.mock('@grafana/runtime', () => ({
+  ...(jest.requireActual('@grafana/runtime') as unknown as object),
+  getBackendSrv: () => ({ post: mockPost }),
+}));
+
+const correlation: CorrelationData = {
+  uid: 'loki-to-tempo',
+  label: 'logs to traces',
+  source: { uid: 'loki-uid', name: 'loki' } as DataSourceInstanceSettings,
+  target: { uid: 'tempo-uid', name: 'tempo' } as DataSourceInstanceSettings,
+  config: { type: 'query', field: 'traceID', target: { query: 'target Tempo query' } },
+};
+
+const rendered = { uid: 'loki-to-tempo', sourceUID: 'loki-uid', type: 'rendered' };
+const clicked = { uid: 'loki-to-tempo', sourceUID: 'loki-uid', type: 'clicked' };
+
+describe('correlations analytics', () => {
+  beforeEach(() => {
+    jest.useFakeTimers();
+    mockPost.mockClear();
+  });
+
+  afterEach(() => {
+    flushCorrelationAnalytics();
+    jest.useRealTimers();
+  });
+
+  it('reports shown links in batches', () => {
+    reportCorrelationRendered(correlation);
+    reportCorrelationRendered(correlation);
+    expect(mockPost).not.toHaveBeenCalled();
+
+    jest.runAllTimers();
+
+    expect(mockPost).toHaveBeenCalledTimes(1);
+    expect(mockPost).toHaveBeenCalledWith(
+      '/api/datasources/correlations/analytics',
+      { events: [rendered, rendered] },
+      { showErrorAlert: false }
+    );
+  });
+
+  it('reports followed links right away', () => {
+    reportCorrelationRendered(correlation);
+    reportCorrelationClicked(correlation);
+
+    expect(mockPost).toHaveBeenCalledTimes(1);
+    expect(mockPost).toHaveBeenCalledWith(
+      '/api/datasources/correlations/analytics',
+      { events: [rendered, clicked] },
+      { showErrorAlert: false }
+    );
+
+    jest.runAllTimers();
+    expect(mockPost).toHaveBeenCalledTimes(1);
+  });
+
+  it('reports at most 500 events at once', () => {
+    for (let i = 0; i < 501; i++) {
+      reportCorrelationRendered(correlation);
+    }
+    expect(mockPost).toHaveBeenCalledTimes(1);
+    expect(mockPost.mock.calls[0][1].events).toHaveLength(500);
+
+    jest.runAllTimers();
+    expect(mockPost).toHaveBeenCalledTimes(2);
+    expect(mockPost.mock.calls[1][1].events).toHaveLength(1);
+  });
+});
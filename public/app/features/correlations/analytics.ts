@@ -0,0 +1,60 @@
+import { getBackendSrv } from '@grafana/runtime';
+
+import { CorrelationData } from './useCorrelations';
+
+// the most events the API accepts in a single report
+const MAX_EVENTS_PER_REPORT = 500;
+// how long shown links are collected before they're reported together
+const REPORT_DELAY_MS = 5000;
+
+type CorrelationAnalyticsEventType = 'rendered' | 'clicked';
+
+interface CorrelationAnalyticsEvent {
+  uid: string;
+  sourceUID: string;
+  type: CorrelationAnalyticsEventType;
+}
+
+let pendingEvents: CorrelationAnalyticsEvent[] = [];
+let reportTimeout: ReturnType<typeof setTimeout> | undefined;
+
+/**
+ * Reports all events that haven't been reported yet. Failures are ignored, as counting the links of correlations
+ * shouldn't get in the way of using them.
+ */
+export const flushCorrelationAnalytics = () => {
+  if (reportTimeout !== undefined) {
+    clearTimeout(reportTimeout);
+    reportTimeout = undefined;
+  }
+  while (pendingEvents.length > 0) {
+    const events = pendingEvents.splice(0, MAX_EVENTS_PER_REPORT);
+    getBackendSrv()
+      .post('/api/datasources/correlations/analytics', { events }, { showErrorAlert: false })
+      .catch(() => {});
+  }
+};
+
+const reportCorrelationEvent = (correlation: CorrelationData, type: CorrelationAnalyticsEventType) => {
+  pendingEvents.push({ uid: correlation.uid, sourceUID: correlation.source.uid, type });
+  if (pendingEvents.length >= MAX_EVENTS_PER_REPORT) {
+    flushCorrelationAnalytics();
+  } else if (reportTimeout === undefined) {
+    reportTimeout = setTimeout(flushCorrelationAnalytics, REPORT_DELAY_MS);
+  }
+};
+
+/**
+ * Counts that the link of a correlation was shown. Shown links are reported in batches.
+ */
+export const reportCorrelationRendered = (correlation: CorrelationData) =>
+  reportCorrelationEvent(correlation, 'rendered');
+
+/**
+ * Counts that the link of a correlation was followed, which also records a use of the correlation. Followed links are
+ * reported right away, along with the shown links collected until then.
+ */
+export const reportCorrelationClicked = (correlation: CorrelationData) => {
+  reportCorrelationEvent(correlation, 'clicked');
+  flushCorrelationAnalytics();
+};
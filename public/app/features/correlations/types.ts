@@ -2,11 +2,17 @@ export interface AddCorrelationResponse {
   correlation: Correlation;
 }
 
-export type GetCorrelationsResponse = Correlation[];
+export interface GetCorrelationsResponse {
+  correlations: Correlation[];
+  totalCount: number;
+  page: number;
+  limit: number;
+}
 
 type CorrelationConfigType = 'query';
 export interface CorrelationConfig {
-  field: string;
+  // the name of the field the correlation is attached to, or the names of fields holding the same value
+  field: string | string[];
   target: object;
   type: CorrelationConfigType;
 }
@@ -17,6 +23,8 @@ export interface Correlation {
   targetUID: string;
   label?: string;
   description?: string;
+  // disabled correlations are kept, but not offered as links
+  enabled?: boolean;
   config: CorrelationConfig;
 }
 
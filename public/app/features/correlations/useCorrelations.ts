@@ -5,7 +5,16 @@ import { DataSourceInstanceSettings } from '@grafana/data';
 import { getDataSourceSrv, FetchResponse } from '@grafana/runtime';
 import { useGrafana } from 'app/core/context/GrafanaContext';
 
-import { Correlation, CreateCorrelationParams, RemoveCorrelationParams, UpdateCorrelationParams } from './types';
+import {
+  Correlation,
+  CreateCorrelationParams,
+  GetCorrelationsResponse,
+  RemoveCorrelationParams,
+  UpdateCorrelationParams,
+} from './types';
+
+// the largest page the API returns
+const CORRELATIONS_PAGE_LIMIT = 1000;
 
 export interface CorrelationData extends Omit<Correlation, 'sourceUID' | 'targetUID'> {
   source: DataSourceInstanceSettings;
@@ -32,15 +41,23 @@ function getData<T>(response: FetchResponse<T>) {
 export const useCorrelations = () => {
   const { backend } = useGrafana();
 
-  const [getInfo, get] = useAsyncFn<() => Promise<CorrelationData[]>>(
-    () =>
-      lastValueFrom(
-        backend.fetch<Correlation[]>({ url: '/api/datasources/correlations', method: 'GET', showErrorAlert: false })
-      )
-        .then(getData)
-        .then(toEnrichedCorrelationsData),
-    [backend]
-  );
+  const [getInfo, get] = useAsyncFn<() => Promise<CorrelationData[]>>(async () => {
+    const correlations: Correlation[] = [];
+    for (let page = 1; ; page++) {
+      const { correlations: pageCorrelations, totalCount } = await lastValueFrom(
+        backend.fetch<GetCorrelationsResponse>({
+          url: '/api/datasources/correlations',
+          params: { limit: CORRELATIONS_PAGE_LIMIT, page },
+          method: 'GET',
+          showErrorAlert: false,
+        })
+      ).then(getData);
+      correlations.push(...pageCorrelations);
+      if (pageCorrelations.length === 0 || correlations.length >= totalCount) {
+        return toEnrichedCorrelationsData(correlations);
+      }
+    }
+  }, [backend]);
 
   const [createInfo, create] = useAsyncFn<(params: CreateCorrelationParams) => Promise<CorrelationData>>(
     ({ sourceUID, ...correlation }) =>
@@ -1,8 +1,11 @@
 import { DataFrame, DataSourceInstanceSettings, FieldType, toDataFrame } from '@grafana/data';
 
+import { reportCorrelationClicked, reportCorrelationRendered } from './analytics';
 import { CorrelationData } from './useCorrelations';
 import { attachCorrelationsToDataFrames } from './utils';
 
+jest.mock('./analytics');
+
 describe('correlations utils', () => {
   it('attaches correlations defined in the configuration', () => {
     const loki = { uid: 'loki-uid', name: 'loki' } as DataSourceInstanceSettings;
@@ -91,4 +94,117 @@ describe('correlations utils', () => {
       },
     });
   });
+
+  it('attaches correlations to every field they list', () => {
+    const loki = { uid: 'loki-uid', name: 'loki' } as DataSourceInstanceSettings;
+    const tempo = { uid: 'tempo-uid', name: 'tempo' } as DataSourceInstanceSettings;
+
+    const testDataFrames: DataFrame[] = [
+      toDataFrame({
+        name: 'Loki Logs',
+        refId: 'Loki Query',
+        fields: [
+          { name: 'line', values: [] },
+          { name: 'traceID', values: [] },
+          { name: 'trace_id', values: [] },
+        ],
+      }),
+    ];
+
+    const correlations: CorrelationData[] = [
+      {
+        uid: 'loki-to-tempo',
+        label: 'logs to traces',
+        source: loki,
+        target: tempo,
+        config: { type: 'query', field: ['traceID', 'trace_id'], target: { query: 'target Tempo query' } },
+      },
+    ];
+
+    attachCorrelationsToDataFrames(testDataFrames, correlations, { 'Loki Query': loki.uid });
+
+    expect(testDataFrames[0].fields[0].config.links).toBeUndefined();
+    expect(testDataFrames[0].fields[1].config.links).toHaveLength(1);
+    expect(testDataFrames[0].fields[1].config.links![0]).toMatchObject({ title: 'logs to traces' });
+    expect(testDataFrames[0].fields[2].config.links).toHaveLength(1);
+    expect(testDataFrames[0].fields[2].config.links![0]).toMatchObject({ title: 'logs to traces' });
+  });
+
+  it('does not attach disabled correlations', () => {
+    const loki = { uid: 'loki-uid', name: 'loki' } as DataSourceInstanceSettings;
+    const tempo = { uid: 'tempo-uid', name: 'tempo' } as DataSourceInstanceSettings;
+
+    const testDataFrames: DataFrame[] = [
+      toDataFrame({
+        name: 'Loki Logs',
+        refId: 'Loki Query',
+        fields: [{ name: 'traceID', values: [] }],
+      }),
+    ];
+
+    const correlations: CorrelationData[] = [
+      {
+        uid: 'enabled',
+        label: 'enabled link',
+        source: loki,
+        target: tempo,
+        enabled: true,
+        config: { type: 'query', field: 'traceID', target: { query: 'target Tempo query' } },
+      },
+      {
+        uid: 'disabled',
+        label: 'disabled link',
+        source: loki,
+        target: tempo,
+        enabled: false,
+        config: { type: 'query', field: 'traceID', target: { query: 'target Tempo query' } },
+      },
+    ];
+
+    attachCorrelationsToDataFrames(testDataFrames, correlations, { 'Loki Query': loki.uid });
+
+    expect(testDataFrames[0].fields[0].config.links).toHaveLength(1);
+    expect(testDataFrames[0].fields[0].config.links![0]).toMatchObject({ title: 'enabled link' });
+  });
+
+  it('reports shown and followed links of correlations', () => {
+    const loki = { uid: 'loki-uid', name: 'loki' } as DataSourceInstanceSettings;
+    const tempo = { uid: 'tempo-uid', name: 'tempo' } as DataSourceInstanceSettings;
+
+    const testDataFrames: DataFrame[] = [
+      toDataFrame({
+        name: 'Loki Logs',
+        refId: 'Loki Query',
+        fields: [{ name: 'traceID', values: ['trace-1'] }],
+      }),
+      toDataFrame({
+        name: 'Empty Loki Logs',
+        refId: 'Empty Loki Query',
+        fields: [{ name: 'traceID', values: [] }],
+      }),
+    ];
+
+    const correlation: CorrelationData = {
+      uid: 'loki-to-tempo',
+      label: 'logs to traces',
+      source: loki,
+      target: tempo,
+      config: { type: 'query', field: 'traceID', target: { query: 'target Tempo query' } },
+    };
+
+    jest.mocked(reportCorrelationRendered).mockClear();
+    attachCorrelationsToDataFrames(testDataFrames, [correlation], {
+      'Loki Query': loki.uid,
+      'Empty Loki Query': loki.uid,
+    });
+
+    // links of frames without rows aren't shown
+    expect(reportCorrelationRendered).toHaveBeenCalledTimes(1);
+    expect(reportCorrelationRendered).toHaveBeenCalledWith(correlation);
+    expect(reportCorrelationClicked).not.toHaveBeenCalled();
+
+    testDataFrames[0].fields[0].config.links![0].onClick!({ origin: {}, replaceVariables: undefined });
+
+    expect(reportCorrelationClicked).toHaveBeenCalledWith(correlation);
+  });
 });
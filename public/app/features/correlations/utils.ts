@@ -1,5 +1,6 @@
 import { DataFrame } from '@grafana/data';
 
+import { reportCorrelationClicked, reportCorrelationRendered } from './analytics';
 import { CorrelationData } from './useCorrelations';
 
 type DataFrameRefIdToDataSourceUid = Record<string, string>;
@@ -22,17 +23,27 @@ export const attachCorrelationsToDataFrames = (
       return;
     }
     const dataSourceUid = dataFrameRefIdToDataSourceUid[frameRefId];
-    const sourceCorrelations = correlations.filter((correlation) => correlation.source.uid === dataSourceUid);
+    const sourceCorrelations = correlations.filter(
+      (correlation) => correlation.source.uid === dataSourceUid && correlation.enabled !== false
+    );
     decorateDataFrameWithInternalDataLinks(dataFrame, sourceCorrelations);
   });
 
   return dataFrames;
 };
 
+const getCorrelationFields = (correlation: CorrelationData): string[] => {
+  const field = correlation.config?.field;
+  if (field === undefined) {
+    return [];
+  }
+  return Array.isArray(field) ? field : [field];
+};
+
 const decorateDataFrameWithInternalDataLinks = (dataFrame: DataFrame, correlations: CorrelationData[]) => {
   dataFrame.fields.forEach((field) => {
     correlations.map((correlation) => {
-      if (correlation.config?.field === field.name) {
+      if (getCorrelationFields(correlation).includes(field.name)) {
         field.config.links = field.config.links || [];
         field.config.links.push({
           internal: {
@@ -42,7 +53,11 @@ const decorateDataFrameWithInternalDataLinks = (dataFrame: DataFrame, correlatio
           },
           url: '',
           title: correlation.label || correlation.target.name,
+          onClick: () => reportCorrelationClicked(correlation),
         });
+        if (dataFrame.length > 0) {
+          reportCorrelationRendered(correlation);
+        }
       }
     });
   });
@@ -102,6 +102,27 @@ describe('getFieldLinksForExplore', () => {
     });
   });
 
+  it('calls the click handler of an internal link before opening it', () => {
+    const onClick = jest.fn();
+    const { field, range } = setup({
+      title: '',
+      url: '',
+      onClick,
+      internal: {
+        query: { query: 'query_1' },
+        datasourceUid: 'uid_1',
+        datasourceName: 'test_ds',
+      },
+    });
+    const splitfn = jest.fn();
+    const links = getFieldLinksForExplore({ field, rowIndex: ROW_WITH_TEXT_VALUE.index, splitOpenFn: splitfn, range });
+
+    links[0].onClick!({});
+
+    expect(onClick).toBeCalledWith(expect.objectContaining({ origin: field }));
+    expect(splitfn).toBeCalledWith(expect.objectContaining({ datasourceUid: 'uid_1', query: { query: 'query_1' } }));
+  });
+
   it('returns correct link model for external link when user does not have access to explore', () => {
     const { field, range } = setup(
       {
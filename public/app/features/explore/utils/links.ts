@@ -121,13 +121,22 @@ export const getFieldLinksForExplore = (options: {
         }
         return linkModel;
       } else {
+        // Internal links are opened by splitOpenFn rather than by their own click handler, which is still called
+        // first, e.g. to record that a correlation was used.
+        const onClickFn: SplitOpen | undefined =
+          splitOpenFn && link.onClick
+            ? (options) => {
+                link.onClick!({ origin: field, replaceVariables: undefined });
+                splitOpenFn(options);
+              }
+            : splitOpenFn;
         return mapInternalLinkToExplore({
           link,
           internalLink: link.internal,
           scopedVars: scopedVars,
           range,
           field,
-          onClickFn: splitOpenFn,
+          onClickFn,
           replaceVariables: getTemplateSrv().replace.bind(getTemplateSrv()),
         });
       }
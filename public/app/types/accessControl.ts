@@ -53,6 +53,10 @@ export enum AccessControlAction {
   DataSourcesCachingRead = 'datasources.caching:read',
   DataSourcesInsightsRead = 'datasources.insights:read',
 
+  CorrelationsRead = 'correlations:read',
+  CorrelationsWrite = 'correlations:write',
+  CorrelationsDelete = 'correlations:delete',
+
   ActionServerStatsRead = 'server.stats:read',
 
   ActionTeamsCreate = 'teams:create',